@@ -1,7 +1,11 @@
 package ast
 
+import "github.com/yoh0xff/senbonzakura/source"
+
+// Node is any element of the tree, statements, expressions, parameters and type annotations
 type Node interface {
 	NodeType() NodeType
+	GetSpan() source.Span
 }
 
 // Statement represents different statement types in the AST
@@ -22,4 +26,11 @@ type Expression interface {
 type Parameter struct {
 	Name Expression
 	Type Type
+	Span source.Span
 }
+
+// NodeType implementation of Node interface method
+func (p *Parameter) NodeType() NodeType { return NodeParameter }
+
+// GetSpan implementation of Node interface method
+func (p *Parameter) GetSpan() source.Span { return p.Span }
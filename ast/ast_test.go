@@ -0,0 +1,337 @@
+package ast_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/formatter"
+	"github.com/yoh0xff/senbonzakura/parser"
+)
+
+const walkSource = `class A extends B {
+    def f(a: [number], m: Map[string, A]): number {
+        let x: number = a[0] + 1;
+        return x;
+    }
+}
+if (ok) { g(); } `
+
+func parse(t *testing.T, source string) *ast.ProgramStatement {
+	program, diagnostics := parser.ParseRootStatement(parser.NewParser(source))
+	if len(diagnostics) != 0 {
+		t.Fatalf("Expected no diagnostics, got %v", diagnostics)
+	}
+	return program.(*ast.ProgramStatement)
+}
+
+func TestWalk(t *testing.T) {
+	program := parse(t, walkSource)
+
+	var entered, left []string
+	ast.Walk(program, func(node ast.Node) bool {
+		entered = append(entered, node.NodeType().String())
+		return true
+	}, func(node ast.Node) {
+		left = append(left, node.NodeType().String())
+	})
+
+	expected := []string{
+		"ProgramStatement",
+		"ClassDeclarationStatement", "IdentifierExpression", "IdentifierExpression", "BlockStatement",
+		"FunctionDeclarationStatement", "IdentifierExpression",
+		"Parameter", "IdentifierExpression", "ArrayType", "PrimitiveType",
+		"Parameter", "IdentifierExpression", "GenericType", "PrimitiveType", "ClassType",
+		"PrimitiveType", "BlockStatement",
+		"VariableDeclarationStatement", "VariableExpression", "IdentifierExpression", "PrimitiveType",
+		"BinaryExpression", "MemberExpression", "IdentifierExpression", "NumericLiteralExpression", "NumericLiteralExpression",
+		"ReturnStatement", "IdentifierExpression",
+		"IfStatement", "IdentifierExpression", "BlockStatement",
+		"ExpressionStatement", "CallExpression", "IdentifierExpression",
+	}
+	if strings.Join(entered, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected nodes\n%v\ngot\n%v", expected, entered)
+	}
+
+	if len(left) != len(entered) || left[len(left)-1] != "ProgramStatement" || left[0] != "IdentifierExpression" {
+		t.Errorf("Expected every node to be left after its children, got %v", left)
+	}
+}
+
+func TestInspectSkipsSubtrees(t *testing.T) {
+	program := parse(t, walkSource)
+
+	var identifiers []string
+	ast.Inspect(program, func(node ast.Node) bool {
+		if identifier, ok := node.(*ast.IdentifierExpression); ok {
+			identifiers = append(identifiers, identifier.Name)
+		}
+		_, isFunction := node.(*ast.FunctionDeclarationStatement)
+		return !isFunction
+	})
+
+	if expected := "A B ok g"; strings.Join(identifiers, " ") != expected {
+		t.Errorf("Expected identifiers %s, got %s", expected, strings.Join(identifiers, " "))
+	}
+}
+
+func TestParentMap(t *testing.T) {
+	program := parse(t, walkSource)
+	parents := ast.NewParentMap(program)
+
+	class := program.Body[0].(*ast.ClassDeclarationStatement)
+	function := class.Body.Body[0].(*ast.FunctionDeclarationStatement)
+	declaration := function.Body.Body[0].(*ast.VariableDeclarationStatement)
+	variable := declaration.Variables[0]
+	call := program.Body[1].(*ast.IfStatement).Consequent.Body[0].(*ast.ExpressionStatement).Expression
+
+	if parents.Parent(program) != nil {
+		t.Errorf("Expected the root to have no parent, got %v", parents.Parent(program))
+	}
+	if parents.Parent(variable) != declaration {
+		t.Errorf("Expected the declaration to be the parent of the variable, got %v", parents.Parent(variable))
+	}
+	if parameter, ok := parents.Parent(function.Parameters[1].Type).(*ast.Parameter); !ok || parameter != &function.Parameters[1] {
+		t.Errorf("Expected the parameter to be the parent of its type, got %v", parents.Parent(function.Parameters[1].Type))
+	}
+
+	if parents.EnclosingFunction(variable.Initializer) != function || parents.EnclosingClass(variable.Initializer) != class {
+		t.Errorf("Expected the initializer to be in the method f of A")
+	}
+	if parents.EnclosingFunction(call) != nil || parents.EnclosingClass(call) != nil {
+		t.Errorf("Expected the call to be at the top level")
+	}
+}
+
+func TestApply(t *testing.T) {
+	program := parse(t, `let a: number = 1 + 2;
+;
+if (a > 1) { f(a); } else { g(); }
+def h(x: number): number { return x * 1; }`)
+
+	result := ast.Apply(program, func(cursor *ast.Cursor) bool {
+		switch node := cursor.Node().(type) {
+		case *ast.EmptyStatement:
+			cursor.Delete()
+		case *ast.IfStatement:
+			// Drop the else branch and log around the statement
+			node.Alternative = nil
+			cursor.InsertBefore(call("before"))
+			cursor.InsertAfter(call("after"))
+		case *ast.Parameter:
+			cursor.Replace(&ast.Parameter{Name: &ast.IdentifierExpression{Name: "y"}, Type: node.Type})
+		}
+		return true
+	}, func(cursor *ast.Cursor) bool {
+		// Fold additions of literals and multiplications by one, after their operands were rewritten
+		binary, ok := cursor.Node().(*ast.BinaryExpression)
+		if !ok {
+			return true
+		}
+		left, leftNumber := binary.Left.(*ast.NumericLiteralExpression)
+		right, rightNumber := binary.Right.(*ast.NumericLiteralExpression)
+		switch {
+		case binary.Operator == ast.OperatorAdd && leftNumber && rightNumber:
+			sum := left.IntValue + right.IntValue
+			cursor.Replace(&ast.NumericLiteralExpression{IntValue: sum, FloatValue: float64(sum)})
+		case binary.Operator == ast.OperatorMultiply && rightNumber && right.IntValue == 1:
+			cursor.Replace(binary.Left)
+		}
+		return true
+	})
+
+	if result != program {
+		t.Fatalf("Expected the root to be kept")
+	}
+	expected := `let a: number = 3;
+log("before");
+if (a > 1) {
+    f(a);
+}
+log("after");
+def h(y: number): number {
+    return x;
+}
+`
+	if actual := printSource(program); actual != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, actual)
+	}
+}
+
+func TestApplyReplaceRootAndStop(t *testing.T) {
+	program := parse(t, "a; b; c;")
+
+	var visited []string
+	ast.Apply(program, nil, func(cursor *ast.Cursor) bool {
+		if identifier, ok := cursor.Node().(*ast.IdentifierExpression); ok {
+			visited = append(visited, identifier.Name)
+			return identifier.Name != "b"
+		}
+		return true
+	})
+	if strings.Join(visited, " ") != "a b" {
+		t.Errorf("Expected the traversal to stop at b, got %v", visited)
+	}
+
+	replacement := &ast.ProgramStatement{}
+	result := ast.Apply(program, func(cursor *ast.Cursor) bool {
+		if cursor.Parent() == nil {
+			cursor.Replace(replacement)
+		}
+		return true
+	}, nil)
+	if result != replacement {
+		t.Errorf("Expected the replaced root, got %v", result)
+	}
+}
+
+func TestApplyDeleteOutOfList(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected a panic when deleting a node out of a list")
+		}
+	}()
+
+	ast.Apply(parse(t, "a;"), func(cursor *ast.Cursor) bool {
+		if cursor.Name() == "Expression" {
+			cursor.Delete()
+		}
+		return true
+	}, nil)
+}
+
+// call creates a statement calling log with the message
+func call(message string) ast.Statement {
+	return &ast.ExpressionStatement{Expression: &ast.CallExpression{
+		Callee:    &ast.IdentifierExpression{Name: "log"},
+		Arguments: []ast.Expression{&ast.StringLiteralExpression{Value: message}},
+	}}
+}
+
+// printSource returns the source text of the tree
+func printSource(statement ast.Statement) string {
+	printer := formatter.NewPrinter()
+	statement.Accept(printer)
+	return printer.String()
+}
+
+const jsonSource = `class A extends B {
+    let x: number = 0x1F;
+    def constructor(x: number) { super.init(); this.x = x; }
+}
+def f(a: [number], m: Map[string, A]): boolean {
+    for (let i: number = 0; i <= 3; i += 1) { a[i] = -a[i] * 2.5; }
+    while (!(a == nil) || false) { return true; }
+    do { ; } while (m != nil && 1 / 2 > 0);
+    if (a) { log("a\n", 'b', ` + "`t ${a} <&>`" + `); } else { new A(1); }
+    return false;
+}`
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"let x: number = f(1, 2);", "let   x : number =\n  f( 1,2 ) ;", true},
+		{"let x: number = 1;", "let x: number = 2;", false},
+		{"let x: number = 1;", "let x: number = 1.0;", false},
+		{"a + b;", "a - b;", false},
+		{"if (a) {}", "if (a) {} else {}", false},
+	}
+
+	for _, tt := range tests {
+		if equal := ast.Equal(parse(t, tt.a), parse(t, tt.b)); equal != tt.expected {
+			t.Errorf("%q and %q: Expected equal %t, got %t", tt.a, tt.b, tt.expected, equal)
+		}
+	}
+	if !ast.Equal(nil, nil) || ast.Equal(parse(t, ""), nil) {
+		t.Errorf("Expected only nil to equal nil")
+	}
+}
+
+func TestClassesInOrder(t *testing.T) {
+	program := parse(t, "class C extends B {} f(); class B extends A {} class D {} class A extends Base {} class E extends E {}")
+
+	var names []string
+	for _, class := range ast.ClassesInOrder(program.Body) {
+		names = append(names, class.Name.Name)
+	}
+	if order := strings.Join(names, " "); order != "A B C D E" {
+		t.Errorf("Expected the classes in order A B C D E, got %s", order)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	superClass := "B"
+	nodes := []ast.Node{
+		parse(t, jsonSource),
+		&ast.FunctionType{
+			Params:     []ast.Type{&ast.ClassType{Name: "A", SuperClass: &superClass}, &ast.VoidType{}},
+			ReturnType: &ast.PrimitiveType{Kind: ast.StringType},
+		},
+		&ast.Parameter{Name: &ast.IdentifierExpression{Name: "p"}},
+	}
+
+	for _, node := range nodes {
+		data, err := ast.MarshalJSON(node)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		decoded, err := ast.UnmarshalJSON(data)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !reflect.DeepEqual(decoded, node) {
+			t.Errorf("Expected the decoded tree to equal the %s", node.NodeType())
+		}
+		again, _ := ast.MarshalJSON(decoded)
+		if string(again) != string(data) {
+			t.Errorf("Expected the same JSON, got\n%s\nthen\n%s", data, again)
+		}
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	data, err := ast.MarshalJSON(parse(t, "a += 1 < b;"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := `{"kind":"ProgramStatement","body":[{"kind":"ExpressionStatement","expression":` +
+		`{"kind":"AssignmentExpression","operator":"+=","left":{"kind":"IdentifierExpression","name":"a",` +
+		`"span":{"file":"","start":0,"end":1,"line":1,"column":1}},"right":{"kind":"BinaryExpression","operator":"<",` +
+		`"left":{"kind":"NumericLiteralExpression","literalKind":"integer","intValue":1,"floatValue":1,"raw":"1",`
+	if !strings.HasPrefix(string(data), expected) {
+		t.Errorf("Expected JSON starting with\n%s\ngot\n%s", expected, data)
+	}
+
+	if _, err := ast.MarshalJSON(&ast.UnaryExpression{Operator: 7}); err == nil {
+		t.Errorf("Expected an error for an unknown operator")
+	}
+}
+
+func TestUnmarshalJSONErrors(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected string
+	}{
+		{`null`, "expected a node, got null"},
+		{`{"name":"x"}`, `expected a node with a kind, got {"name":"x"}`},
+		{`{"kind":"Foo"}`, "unknown node kind 'Foo'"},
+		{`{"kind":"IdentifierExpression","nam":"x"}`, "IdentifierExpression.nam: unknown field"},
+		{`{"kind":"ExpressionStatement","expression":{"kind":"EmptyStatement"}}`,
+			"ExpressionStatement.expression: cannot use EmptyStatement as Expression"},
+		{`{"kind":"IfStatement","consequent":{"kind":"IdentifierExpression"}}`,
+			"IfStatement.consequent: cannot use IdentifierExpression as BlockStatement"},
+		{`{"kind":"BinaryExpression","operator":"**"}`, "BinaryExpression.operator: unknown BinaryOperator '**'"},
+		{`{"kind":"FunctionDeclarationStatement","parameters":[null]}`,
+			"FunctionDeclarationStatement.parameters: 0: expected Parameter, got null"},
+	}
+
+	for _, test := range tests {
+		_, err := ast.UnmarshalJSON([]byte(test.data))
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("%s: Expected error %q, got %v", test.data, test.expected, err)
+		}
+	}
+}
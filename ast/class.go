@@ -0,0 +1,39 @@
+package ast
+
+// ClassesInOrder returns the classes declared in the statement list with every class after the class it extends,
+// when that class is declared in the same list
+//
+// Classes can extend classes declared after them, like the resolver allows, runtimes create them in this order.
+// Classes are otherwise kept in source order, cyclic inheritance included
+func ClassesInOrder(statements []Statement) []*ClassDeclarationStatement {
+	var classes []*ClassDeclarationStatement
+	byName := map[string]*ClassDeclarationStatement{}
+	for _, statement := range statements {
+		if class, ok := statement.(*ClassDeclarationStatement); ok {
+			classes = append(classes, class)
+			if _, ok := byName[class.Name.Name]; !ok {
+				byName[class.Name.Name] = class
+			}
+		}
+	}
+
+	ordered := make([]*ClassDeclarationStatement, 0, len(classes))
+	visited := map[*ClassDeclarationStatement]bool{}
+	var visit func(class *ClassDeclarationStatement)
+	visit = func(class *ClassDeclarationStatement) {
+		if visited[class] {
+			return
+		}
+		visited[class] = true
+		if class.SuperClass != nil {
+			if superClass, ok := byName[class.SuperClass.Name]; ok {
+				visit(superClass)
+			}
+		}
+		ordered = append(ordered, class)
+	}
+	for _, class := range classes {
+		visit(class)
+	}
+	return ordered
+}
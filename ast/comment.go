@@ -0,0 +1,108 @@
+package ast
+
+import "github.com/yoh0xff/senbonzakura/source"
+
+// Comment is a comment of the source with its delimiters
+type Comment struct {
+	Text string
+	Span source.Span
+}
+
+// CommentMap associates comments with the statements nearest to them
+type CommentMap struct {
+	Leading  map[Node][]Comment // comments before a statement, or before a block inside the statement owning it
+	Trailing map[Node][]Comment // comments after a statement on the line where it ends
+	Dangling map[Node][]Comment // comments after the last statement of a program or block
+}
+
+// NewCommentMap associates the comments of the root program or block with its statements
+//
+// A comment between statements trails the statement before it if it starts on the line where that statement
+// ends, otherwise it leads the statement after it. Comments at the end of a block dangle in the block.
+// Comments inside a statement, out of its blocks, lead the next block of the statement or else the statement
+func NewCommentMap(lines *source.LineTable, root Statement, comments []Comment) CommentMap {
+	commentMap := CommentMap{
+		Leading:  map[Node][]Comment{},
+		Trailing: map[Node][]Comment{},
+		Dangling: map[Node][]Comment{},
+	}
+	for _, comment := range comments {
+		commentMap.associate(lines, root, comment)
+	}
+	return commentMap
+}
+
+// associate adds the comment inside the program or block to the map
+func (m CommentMap) associate(lines *source.LineTable, container Statement, comment Comment) {
+	var body []Statement
+	switch container := container.(type) {
+	case *ProgramStatement:
+		body = container.Body
+	case *BlockStatement:
+		body = container.Body
+	default:
+		body = []Statement{container}
+	}
+
+	var previous Statement
+	for _, statement := range body {
+		span := statement.GetSpan()
+		if comment.Span.Start >= span.End {
+			previous = statement
+			continue
+		}
+
+		if comment.Span.Start < span.Start {
+			if previous != nil && endLine(lines, previous.GetSpan()) == comment.Span.Line {
+				m.Trailing[previous] = append(m.Trailing[previous], comment)
+			} else {
+				m.Leading[statement] = append(m.Leading[statement], comment)
+			}
+			return
+		}
+
+		// The comment is inside the statement, in one of its blocks or around them
+		blocks := childBlocks(statement)
+		for _, block := range blocks {
+			if comment.Span.Start >= block.Span.Start && comment.Span.End <= block.Span.End {
+				m.associate(lines, block, comment)
+				return
+			}
+		}
+		for _, block := range blocks {
+			if block.Span.Start > comment.Span.Start {
+				m.Leading[block] = append(m.Leading[block], comment)
+				return
+			}
+		}
+		m.Leading[statement] = append(m.Leading[statement], comment)
+		return
+	}
+
+	if previous != nil && endLine(lines, previous.GetSpan()) == comment.Span.Line {
+		m.Trailing[previous] = append(m.Trailing[previous], comment)
+		return
+	}
+	m.Dangling[container] = append(m.Dangling[container], comment)
+}
+
+// childBlocks returns the blocks of the statement in source order, a block is its own child
+func childBlocks(statement Statement) []*BlockStatement {
+	if block, ok := statement.(*BlockStatement); ok {
+		return []*BlockStatement{block}
+	}
+
+	var blocks []*BlockStatement
+	for _, child := range Children(statement) {
+		if block, ok := child.(*BlockStatement); ok {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// endLine returns the line of the last character of the span
+func endLine(lines *source.LineTable, span source.Span) int {
+	line, _ := lines.Position(max(span.Start, span.End-1))
+	return line
+}
@@ -0,0 +1,58 @@
+package ast
+
+import (
+	"reflect"
+
+	"github.com/yoh0xff/senbonzakura/source"
+)
+
+var spanType = reflect.TypeFor[source.Span]()
+
+// Equal checks if the trees have the same nodes with the same values, ignoring their spans
+//
+// Trees parsed from different layouts of the same source are equal, raw literals are compared
+func Equal(a Node, b Node) bool {
+	return equalValues(reflect.ValueOf(&a).Elem(), reflect.ValueOf(&b).Elem())
+}
+
+// equalValues compares the fields of nodes, spans are skipped
+func equalValues(a reflect.Value, b reflect.Value) bool {
+	if a.Kind() != b.Kind() {
+		return false
+	}
+	switch a.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		if a.Elem().Type() != b.Elem().Type() {
+			return false
+		}
+		return equalValues(a.Elem(), b.Elem())
+	case reflect.Struct:
+		if a.Type() != b.Type() {
+			return false
+		}
+		for i := 0; i < a.NumField(); i++ {
+			if a.Type().Field(i).Type == spanType {
+				continue
+			}
+			if !equalValues(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !equalValues(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a.Equal(b)
+	}
+}
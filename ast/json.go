@@ -0,0 +1,321 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MarshalJSON encodes the tree of the node as JSON
+//
+// Every node is an object with a "kind" member holding the name of its NodeType, followed by its fields in
+// lower camel case, the kinds of primitive types and numeric literals are "primitive" and "literalKind".
+// Operators and kinds are written as in String, spans as objects and missing optional nodes as null.
+// UnmarshalJSON decodes the output to an equal tree, spans included
+func MarshalJSON(node Node) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encodeValue(&buffer, encoder, reflect.ValueOf(&node).Elem()); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalJSON decodes a tree encoded by MarshalJSON
+func UnmarshalJSON(data []byte) (Node, error) {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil, fmt.Errorf("expected a node, got null")
+	}
+	return decodeNode(data)
+}
+
+var nodeInterface = reflect.TypeFor[Node]()
+
+// nodeTypes holds the struct type of every node kind
+var nodeTypes = newNodeTypes(
+	&ProgramStatement{}, &BlockStatement{}, &EmptyStatement{}, &ExpressionStatement{},
+	&VariableDeclarationStatement{}, &IfStatement{}, &WhileStatement{}, &DoWhileStatement{}, &ForStatement{},
+	&FunctionDeclarationStatement{}, &ReturnStatement{}, &ClassDeclarationStatement{}, &ErrorStatement{},
+	&VariableExpression{}, &AssignmentExpression{}, &BinaryExpression{}, &UnaryExpression{},
+	&LogicalExpression{}, &BooleanLiteralExpression{}, &NilLiteralExpression{}, &StringLiteralExpression{},
+	&NumericLiteralExpression{}, &IdentifierExpression{}, &MemberExpression{}, &CallExpression{},
+	&ThisExpression{}, &SuperExpression{}, &NewExpression{}, &TemplateLiteralExpression{},
+	&Parameter{},
+	&PrimitiveType{}, &ArrayType{}, &FunctionType{}, &ClassType{}, &GenericType{}, &VoidType{},
+)
+
+// newNodeTypes maps the kinds of the nodes to their struct types
+func newNodeTypes(nodes ...Node) map[string]reflect.Type {
+	nodeTypes := map[string]reflect.Type{}
+	for _, node := range nodes {
+		nodeTypes[node.NodeType().String()] = reflect.TypeOf(node).Elem()
+	}
+	return nodeTypes
+}
+
+// isNodeStruct checks if pointers to the type are nodes, parameters are held by value in their lists
+func isNodeStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && reflect.PointerTo(t).Implements(nodeInterface)
+}
+
+// encodeValue writes a field of a node, nodes are written with their kind
+func encodeValue(buffer *bytes.Buffer, encoder *json.Encoder, value reflect.Value) error {
+	switch {
+	case value.Kind() == reflect.Interface || value.Kind() == reflect.Pointer && value.Type().Implements(nodeInterface):
+		if value.IsNil() {
+			buffer.WriteString("null")
+			return nil
+		}
+		return encodeValue(buffer, encoder, value.Elem())
+	case isNodeStruct(value.Type()):
+		buffer.WriteString(`{"kind":`)
+		kind := value.Addr().Interface().(Node).NodeType().String()
+		if err := encodeLeaf(buffer, encoder, reflect.ValueOf(kind)); err != nil {
+			return err
+		}
+		return encodeFields(buffer, encoder, value, true)
+	case value.Kind() == reflect.Struct:
+		buffer.WriteString("{")
+		return encodeFields(buffer, encoder, value, false)
+	case value.Kind() == reflect.Slice && value.Type().Elem().Kind() != reflect.String:
+		if value.IsNil() {
+			buffer.WriteString("null")
+			return nil
+		}
+		buffer.WriteString("[")
+		for i := 0; i < value.Len(); i++ {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			if err := encodeValue(buffer, encoder, value.Index(i)); err != nil {
+				return err
+			}
+		}
+		buffer.WriteString("]")
+		return nil
+	default:
+		return encodeLeaf(buffer, encoder, value)
+	}
+}
+
+// encodeFields writes the fields of a struct and closes its object, the opening brace is already written
+func encodeFields(buffer *bytes.Buffer, encoder *json.Encoder, value reflect.Value, separate bool) error {
+	for i := 0; i < value.NumField(); i++ {
+		if separate {
+			buffer.WriteString(",")
+		}
+		separate = true
+		fmt.Fprintf(buffer, `"%s":`, fieldName(value.Type(), i))
+		if err := encodeValue(buffer, encoder, value.Field(i)); err != nil {
+			return fmt.Errorf("%s: %w", value.Type().Field(i).Name, err)
+		}
+	}
+	buffer.WriteString("}")
+	return nil
+}
+
+// encodeLeaf writes a value without nodes with the standard encoding, operators and kinds are text
+func encodeLeaf(buffer *bytes.Buffer, encoder *json.Encoder, value reflect.Value) error {
+	if err := encoder.Encode(value.Interface()); err != nil {
+		return err
+	}
+	// Drop the newline ending every encoded value
+	buffer.Truncate(buffer.Len() - 1)
+	return nil
+}
+
+// decodeNode decodes a node object to a pointer to its struct
+func decodeNode(data []byte) (Node, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	var kind string
+	if err := json.Unmarshal(fields["kind"], &kind); err != nil || kind == "" {
+		return nil, fmt.Errorf("expected a node with a kind, got %s", truncate(data))
+	}
+	t, ok := nodeTypes[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown node kind '%s'", kind)
+	}
+	delete(fields, "kind")
+
+	node := reflect.New(t)
+	if err := decodeFields(fields, node.Elem()); err != nil {
+		return nil, fmt.Errorf("%s.%w", kind, err)
+	}
+	return node.Interface().(Node), nil
+}
+
+// decodeFields decodes the members of an object to the fields of a struct, missing members leave zero values
+func decodeFields(fields map[string]json.RawMessage, value reflect.Value) error {
+	for i := 0; i < value.NumField(); i++ {
+		name := fieldName(value.Type(), i)
+		data, ok := fields[name]
+		if !ok {
+			continue
+		}
+		delete(fields, name)
+		if err := decodeValue(data, value.Field(i)); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	for name := range fields {
+		return fmt.Errorf("%s: unknown field", name)
+	}
+	return nil
+}
+
+// decodeValue decodes a field of a node, nodes must have the type of the field
+func decodeValue(data []byte, value reflect.Value) error {
+	t := value.Type()
+	switch {
+	case t.Kind() == reflect.Interface || t.Kind() == reflect.Pointer && t.Implements(nodeInterface) ||
+		isNodeStruct(t):
+		if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+			if isNodeStruct(t) {
+				return fmt.Errorf("expected %s, got null", t.Name())
+			}
+			value.SetZero()
+			return nil
+		}
+		node, err := decodeNode(data)
+		if err != nil {
+			return err
+		}
+		decoded := reflect.ValueOf(node)
+		if isNodeStruct(t) {
+			decoded = decoded.Elem()
+		}
+		if !decoded.Type().AssignableTo(t) {
+			return fmt.Errorf("cannot use %s as %s", node.NodeType(), typeName(t))
+		}
+		value.Set(decoded)
+		return nil
+	case t.Kind() == reflect.Struct:
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return err
+		}
+		return decodeFields(fields, value)
+	case t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.String:
+		var elements []json.RawMessage
+		if err := json.Unmarshal(data, &elements); err != nil {
+			return err
+		}
+		if elements == nil {
+			value.SetZero()
+			return nil
+		}
+		value.Set(reflect.MakeSlice(t, len(elements), len(elements)))
+		for i, element := range elements {
+			if err := decodeValue(element, value.Index(i)); err != nil {
+				return fmt.Errorf("%d: %w", i, err)
+			}
+		}
+		return nil
+	default:
+		return json.Unmarshal(data, value.Addr().Interface())
+	}
+}
+
+// renamedFields holds the JSON names of fields clashing with the kind of their node
+var renamedFields = map[string]string{
+	"PrimitiveType.Kind":            "primitive",
+	"NumericLiteralExpression.Kind": "literalKind",
+}
+
+// fieldName returns the JSON name of a field of the struct, the field name in lower camel case
+func fieldName(t reflect.Type, index int) string {
+	name := t.Field(index).Name
+	if renamed, ok := renamedFields[t.Name()+"."+name]; ok {
+		return renamed
+	}
+	first, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(first)) + name[size:]
+}
+
+// typeName returns the name of the type of a field for errors, Statement or BlockStatement
+func typeName(t reflect.Type) string {
+	if t.Kind() == reflect.Pointer {
+		return t.Elem().Name()
+	}
+	return t.Name()
+}
+
+// truncate shortens JSON text for errors
+func truncate(data []byte) string {
+	if len(data) > 40 {
+		return string(data[:40]) + "..."
+	}
+	return string(data)
+}
+
+// MarshalText encodes the operator as in String
+func (op AssignmentOperator) MarshalText() ([]byte, error) {
+	return marshalEnum(op, OperatorAssignDivide)
+}
+func (op BinaryOperator) MarshalText() ([]byte, error) {
+	return marshalEnum(op, OperatorLessThanOrEqualTo)
+}
+func (op UnaryOperator) MarshalText() ([]byte, error) {
+	return marshalEnum(op, OperatorNot)
+}
+func (op LogicalOperator) MarshalText() ([]byte, error) {
+	return marshalEnum(op, OperatorOr)
+}
+func (k NumericLiteralKind) MarshalText() ([]byte, error) {
+	return marshalEnum(k, FloatLiteral)
+}
+func (k PrimitiveTypeKind) MarshalText() ([]byte, error) {
+	return marshalEnum(k, StringType)
+}
+
+// UnmarshalText decodes the operator from its String representation
+func (op *AssignmentOperator) UnmarshalText(text []byte) error {
+	return unmarshalEnum(op, text, OperatorAssignDivide)
+}
+func (op *BinaryOperator) UnmarshalText(text []byte) error {
+	return unmarshalEnum(op, text, OperatorLessThanOrEqualTo)
+}
+func (op *UnaryOperator) UnmarshalText(text []byte) error {
+	return unmarshalEnum(op, text, OperatorNot)
+}
+func (op *LogicalOperator) UnmarshalText(text []byte) error {
+	return unmarshalEnum(op, text, OperatorOr)
+}
+func (k *NumericLiteralKind) UnmarshalText(text []byte) error {
+	return unmarshalEnum(k, text, FloatLiteral)
+}
+func (k *PrimitiveTypeKind) UnmarshalText(text []byte) error {
+	return unmarshalEnum(k, text, StringType)
+}
+
+// enum is an operator or a kind, its values go from zero to its last constant
+type enum interface {
+	~int
+	String() string
+}
+
+// marshalEnum encodes a value with its String representation, values out of the constants are errors
+func marshalEnum[T enum](value T, last T) ([]byte, error) {
+	if value < 0 || value > last {
+		return nil, fmt.Errorf("unknown %s %d", reflect.TypeFor[T]().Name(), int(value))
+	}
+	return []byte(value.String()), nil
+}
+
+// unmarshalEnum decodes a value from its String representation
+func unmarshalEnum[T enum](value *T, text []byte, last T) error {
+	for candidate := T(0); candidate <= last; candidate++ {
+		if candidate.String() == string(text) {
+			*value = candidate
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown %s '%s'", reflect.TypeFor[T]().Name(), text)
+}
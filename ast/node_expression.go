@@ -1,122 +1,197 @@
 package ast
 
+import (
+	"fmt"
+
+	"github.com/yoh0xff/senbonzakura/source"
+)
+
 type VariableExpression struct {
 	Identifier     *IdentifierExpression
 	TypeAnnotation Type
 	Initializer    Expression // can be nil
+	Span           source.Span
 }
 
 type AssignmentExpression struct {
 	Operator AssignmentOperator
 	Left     Expression
 	Right    Expression
+	Span     source.Span
 }
 
 type BinaryExpression struct {
 	Operator BinaryOperator
 	Left     Expression
 	Right    Expression
+	Span     source.Span
 }
 
 type UnaryExpression struct {
 	Operator UnaryOperator
 	Right    Expression
+	Span     source.Span
 }
 
 type LogicalExpression struct {
 	Operator LogicalOperator
 	Left     Expression
 	Right    Expression
+	Span     source.Span
 }
 
 type BooleanLiteralExpression struct {
 	Value bool
+	Span  source.Span
 }
 
 type NilLiteralExpression struct {
+	Span source.Span
 }
 
 type StringLiteralExpression struct {
-	Value string
+	Value string // decoded value
+	Raw   string // literal as written in the source, including quotes
+	Span  source.Span
+}
+
+// NumericLiteralKind tells how a numeric literal was written
+type NumericLiteralKind int
+
+const (
+	IntegerLiteral NumericLiteralKind = iota // decimal, hex, binary or octal integer
+	FloatLiteral                             // literal with a fraction or an exponent
+)
+
+// String returns the name of a NumericLiteralKind
+func (k NumericLiteralKind) String() string {
+	switch k {
+	case IntegerLiteral:
+		return "integer"
+	case FloatLiteral:
+		return "float"
+	default:
+		return fmt.Sprintf("Unknown numeric literal kind: %d", k)
+	}
 }
 
 type NumericLiteralExpression struct {
-	Value int32
+	Kind       NumericLiteralKind
+	IntValue   int64   // value of integer literals
+	FloatValue float64 // value of all literals, integers are converted as well
+	Raw        string  // literal as written in the source
+	Span       source.Span
 }
 
 type IdentifierExpression struct {
 	Name string
+	Span source.Span
 }
 
 type MemberExpression struct {
 	Computed bool
 	Object   Expression
 	Property Expression
+	Span     source.Span
 }
 
 type CallExpression struct {
 	Callee    Expression
 	Arguments []Expression
+	Span      source.Span
 }
 
 type ThisExpression struct {
+	Span source.Span
 }
 
 type SuperExpression struct {
+	Span source.Span
 }
 
 type NewExpression struct {
 	Callee    Expression
 	Arguments []Expression
+	Span      source.Span
+}
+
+// TemplateLiteralExpression is a string with embedded expressions, `chunk ${expression} chunk`
+//
+// Chunks and expressions interleave, there is always one chunk more than expressions
+type TemplateLiteralExpression struct {
+	Chunks      []string
+	Expressions []Expression
+	Span        source.Span
 }
 
 // Implementation of isExpression interface method
-func (e *VariableExpression) isExpression()       {}
-func (e *AssignmentExpression) isExpression()     {}
-func (e *BinaryExpression) isExpression()         {}
-func (e *UnaryExpression) isExpression()          {}
-func (e *LogicalExpression) isExpression()        {}
-func (e *BooleanLiteralExpression) isExpression() {}
-func (e *NilLiteralExpression) isExpression()     {}
-func (e *StringLiteralExpression) isExpression()  {}
-func (e *NumericLiteralExpression) isExpression() {}
-func (e *IdentifierExpression) isExpression()     {}
-func (e *MemberExpression) isExpression()         {}
-func (e *CallExpression) isExpression()           {}
-func (e *ThisExpression) isExpression()           {}
-func (e *SuperExpression) isExpression()          {}
-func (e *NewExpression) isExpression()            {}
+func (e *VariableExpression) isExpression()        {}
+func (e *AssignmentExpression) isExpression()      {}
+func (e *BinaryExpression) isExpression()          {}
+func (e *UnaryExpression) isExpression()           {}
+func (e *LogicalExpression) isExpression()         {}
+func (e *BooleanLiteralExpression) isExpression()  {}
+func (e *NilLiteralExpression) isExpression()      {}
+func (e *StringLiteralExpression) isExpression()   {}
+func (e *NumericLiteralExpression) isExpression()  {}
+func (e *IdentifierExpression) isExpression()      {}
+func (e *MemberExpression) isExpression()          {}
+func (e *CallExpression) isExpression()            {}
+func (e *ThisExpression) isExpression()            {}
+func (e *SuperExpression) isExpression()           {}
+func (e *NewExpression) isExpression()             {}
+func (e *TemplateLiteralExpression) isExpression() {}
 
 // NodeType Implementation of NodeType interface method
-func (e *VariableExpression) NodeType() NodeType       { return NodeVariableExpression }
-func (e *AssignmentExpression) NodeType() NodeType     { return NodeAssignmentExpression }
-func (e *BinaryExpression) NodeType() NodeType         { return NodeBinaryExpression }
-func (e *UnaryExpression) NodeType() NodeType          { return NodeUnaryExpression }
-func (e *LogicalExpression) NodeType() NodeType        { return NodeLogicalExpression }
-func (e *BooleanLiteralExpression) NodeType() NodeType { return NodeBooleanLiteralExpression }
-func (e *NilLiteralExpression) NodeType() NodeType     { return NodeNilLiteralExpression }
-func (e *StringLiteralExpression) NodeType() NodeType  { return NodeStringLiteralExpression }
-func (e *NumericLiteralExpression) NodeType() NodeType { return NodeNumericLiteralExpression }
-func (e *IdentifierExpression) NodeType() NodeType     { return NodeIdentifierExpression }
-func (e *MemberExpression) NodeType() NodeType         { return NodeMemberExpression }
-func (e *CallExpression) NodeType() NodeType           { return NodeCallExpression }
-func (e *ThisExpression) NodeType() NodeType           { return NodeThisExpression }
-func (e *SuperExpression) NodeType() NodeType          { return NodeSuperExpression }
-func (e *NewExpression) NodeType() NodeType            { return NodeNewExpression }
+func (e *VariableExpression) NodeType() NodeType        { return NodeVariableExpression }
+func (e *AssignmentExpression) NodeType() NodeType      { return NodeAssignmentExpression }
+func (e *BinaryExpression) NodeType() NodeType          { return NodeBinaryExpression }
+func (e *UnaryExpression) NodeType() NodeType           { return NodeUnaryExpression }
+func (e *LogicalExpression) NodeType() NodeType         { return NodeLogicalExpression }
+func (e *BooleanLiteralExpression) NodeType() NodeType  { return NodeBooleanLiteralExpression }
+func (e *NilLiteralExpression) NodeType() NodeType      { return NodeNilLiteralExpression }
+func (e *StringLiteralExpression) NodeType() NodeType   { return NodeStringLiteralExpression }
+func (e *NumericLiteralExpression) NodeType() NodeType  { return NodeNumericLiteralExpression }
+func (e *IdentifierExpression) NodeType() NodeType      { return NodeIdentifierExpression }
+func (e *MemberExpression) NodeType() NodeType          { return NodeMemberExpression }
+func (e *CallExpression) NodeType() NodeType            { return NodeCallExpression }
+func (e *ThisExpression) NodeType() NodeType            { return NodeThisExpression }
+func (e *SuperExpression) NodeType() NodeType           { return NodeSuperExpression }
+func (e *NewExpression) NodeType() NodeType             { return NodeNewExpression }
+func (e *TemplateLiteralExpression) NodeType() NodeType { return NodeTemplateLiteralExpression }
+
+// GetSpan implementation of Node interface method
+func (e *VariableExpression) GetSpan() source.Span        { return e.Span }
+func (e *AssignmentExpression) GetSpan() source.Span      { return e.Span }
+func (e *BinaryExpression) GetSpan() source.Span          { return e.Span }
+func (e *UnaryExpression) GetSpan() source.Span           { return e.Span }
+func (e *LogicalExpression) GetSpan() source.Span         { return e.Span }
+func (e *BooleanLiteralExpression) GetSpan() source.Span  { return e.Span }
+func (e *NilLiteralExpression) GetSpan() source.Span      { return e.Span }
+func (e *StringLiteralExpression) GetSpan() source.Span   { return e.Span }
+func (e *NumericLiteralExpression) GetSpan() source.Span  { return e.Span }
+func (e *IdentifierExpression) GetSpan() source.Span      { return e.Span }
+func (e *MemberExpression) GetSpan() source.Span          { return e.Span }
+func (e *CallExpression) GetSpan() source.Span            { return e.Span }
+func (e *ThisExpression) GetSpan() source.Span            { return e.Span }
+func (e *SuperExpression) GetSpan() source.Span           { return e.Span }
+func (e *NewExpression) GetSpan() source.Span             { return e.Span }
+func (e *TemplateLiteralExpression) GetSpan() source.Span { return e.Span }
 
 // Accept implementation of StatementDispatcher interface method
-func (e *VariableExpression) Accept(visitor Visitor)       { visitor.VisitExpression(e) }
-func (e *AssignmentExpression) Accept(visitor Visitor)     { visitor.VisitExpression(e) }
-func (e *BinaryExpression) Accept(visitor Visitor)         { visitor.VisitExpression(e) }
-func (e *UnaryExpression) Accept(visitor Visitor)          { visitor.VisitExpression(e) }
-func (e *LogicalExpression) Accept(visitor Visitor)        { visitor.VisitExpression(e) }
-func (e *BooleanLiteralExpression) Accept(visitor Visitor) { visitor.VisitExpression(e) }
-func (e *NilLiteralExpression) Accept(visitor Visitor)     { visitor.VisitExpression(e) }
-func (e *StringLiteralExpression) Accept(visitor Visitor)  { visitor.VisitExpression(e) }
-func (e *NumericLiteralExpression) Accept(visitor Visitor) { visitor.VisitExpression(e) }
-func (e *IdentifierExpression) Accept(visitor Visitor)     { visitor.VisitExpression(e) }
-func (e *MemberExpression) Accept(visitor Visitor)         { visitor.VisitExpression(e) }
-func (e *CallExpression) Accept(visitor Visitor)           { visitor.VisitExpression(e) }
-func (e *ThisExpression) Accept(visitor Visitor)           { visitor.VisitExpression(e) }
-func (e *SuperExpression) Accept(visitor Visitor)          { visitor.VisitExpression(e) }
-func (e *NewExpression) Accept(visitor Visitor)            { visitor.VisitExpression(e) }
+func (e *VariableExpression) Accept(visitor Visitor)        { visitor.VisitExpression(e) }
+func (e *AssignmentExpression) Accept(visitor Visitor)      { visitor.VisitExpression(e) }
+func (e *BinaryExpression) Accept(visitor Visitor)          { visitor.VisitExpression(e) }
+func (e *UnaryExpression) Accept(visitor Visitor)           { visitor.VisitExpression(e) }
+func (e *LogicalExpression) Accept(visitor Visitor)         { visitor.VisitExpression(e) }
+func (e *BooleanLiteralExpression) Accept(visitor Visitor)  { visitor.VisitExpression(e) }
+func (e *NilLiteralExpression) Accept(visitor Visitor)      { visitor.VisitExpression(e) }
+func (e *StringLiteralExpression) Accept(visitor Visitor)   { visitor.VisitExpression(e) }
+func (e *NumericLiteralExpression) Accept(visitor Visitor)  { visitor.VisitExpression(e) }
+func (e *IdentifierExpression) Accept(visitor Visitor)      { visitor.VisitExpression(e) }
+func (e *MemberExpression) Accept(visitor Visitor)          { visitor.VisitExpression(e) }
+func (e *CallExpression) Accept(visitor Visitor)            { visitor.VisitExpression(e) }
+func (e *ThisExpression) Accept(visitor Visitor)            { visitor.VisitExpression(e) }
+func (e *SuperExpression) Accept(visitor Visitor)           { visitor.VisitExpression(e) }
+func (e *NewExpression) Accept(visitor Visitor)             { visitor.VisitExpression(e) }
+func (e *TemplateLiteralExpression) Accept(visitor Visitor) { visitor.VisitExpression(e) }
@@ -20,6 +20,7 @@ const (
 	NodeFunctionDeclarationStatement
 	NodeReturnStatement
 	NodeClassDeclarationStatement
+	NodeErrorStatement
 
 	// Expression types
 
@@ -38,6 +39,20 @@ const (
 	NodeThisExpression
 	NodeSuperExpression
 	NodeNewExpression
+	NodeTemplateLiteralExpression
+
+	// Parts of declarations
+
+	NodeParameter
+
+	// Type annotation types
+
+	NodePrimitiveType
+	NodeArrayType
+	NodeFunctionType
+	NodeClassType
+	NodeGenericType
+	NodeVoidType
 )
 
 // String representation for debugging
@@ -71,6 +86,8 @@ func (t NodeType) String() string {
 		return "ReturnStatement"
 	case NodeClassDeclarationStatement:
 		return "ClassDeclarationStatement"
+	case NodeErrorStatement:
+		return "ErrorStatement"
 
 	// Expressions
 	case NodeVariableExpression:
@@ -103,6 +120,26 @@ func (t NodeType) String() string {
 		return "SuperExpression"
 	case NodeNewExpression:
 		return "NewExpression"
+	case NodeTemplateLiteralExpression:
+		return "TemplateLiteralExpression"
+
+	// Parts of declarations
+	case NodeParameter:
+		return "Parameter"
+
+	// Type annotations
+	case NodePrimitiveType:
+		return "PrimitiveType"
+	case NodeArrayType:
+		return "ArrayType"
+	case NodeFunctionType:
+		return "FunctionType"
+	case NodeClassType:
+		return "ClassType"
+	case NodeGenericType:
+		return "GenericType"
+	case NodeVoidType:
+		return "VoidType"
 	default:
 		return "InvalidNodeType"
 	}
@@ -110,18 +147,27 @@ func (t NodeType) String() string {
 
 // IsStatement Helper methods for node categories
 func (t NodeType) IsStatement() bool {
-	return t >= NodeProgramStatement && t <= NodeClassDeclarationStatement
+	return t >= NodeProgramStatement && t <= NodeErrorStatement
 }
 
 // IsExpression Helper methods for node categories
 func (t NodeType) IsExpression() bool {
-	return t >= NodeVariableExpression && t <= NodeNewExpression
+	return t >= NodeVariableExpression && t <= NodeTemplateLiteralExpression
+}
+
+// IsType Helper methods for node categories
+func (t NodeType) IsType() bool {
+	return t >= NodePrimitiveType && t <= NodeVoidType
 }
 
 // IsLiteral Helper methods for node categories
 func (t NodeType) IsLiteral() bool {
 	switch t {
-	case NodeBooleanLiteralExpression, NodeNilLiteralExpression, NodeStringLiteralExpression, NodeNumericLiteralExpression:
+	case NodeBooleanLiteralExpression,
+		NodeNilLiteralExpression,
+		NodeStringLiteralExpression,
+		NodeNumericLiteralExpression,
+		NodeTemplateLiteralExpression:
 		return true
 	default:
 		return false
@@ -1,38 +1,48 @@
 package ast
 
+import "github.com/yoh0xff/senbonzakura/source"
+
 type ProgramStatement struct {
 	Body []Statement
+	Span source.Span
 }
 
 type BlockStatement struct {
 	Body []Statement
+	Span source.Span
 }
 
 type EmptyStatement struct {
+	Span source.Span
 }
 
 type ExpressionStatement struct {
 	Expression Expression
+	Span       source.Span
 }
 
 type VariableDeclarationStatement struct {
 	Variables []*VariableExpression
+	Span      source.Span
 }
 
 type IfStatement struct {
 	Condition   Expression
 	Consequent  *BlockStatement
 	Alternative *BlockStatement // can be nil
+	Span        source.Span
 }
 
 type WhileStatement struct {
 	Condition Expression
 	Body      *BlockStatement
+	Span      source.Span
 }
 
 type DoWhileStatement struct {
 	Body      *BlockStatement
 	Condition Expression
+	Span      source.Span
 }
 
 type ForStatement struct {
@@ -40,6 +50,7 @@ type ForStatement struct {
 	Condition   Expression // can be nil
 	Increment   Expression // can be nil
 	Body        *BlockStatement
+	Span        source.Span
 }
 
 type FunctionDeclarationStatement struct {
@@ -47,16 +58,24 @@ type FunctionDeclarationStatement struct {
 	Parameters []Parameter
 	ReturnType Type
 	Body       *BlockStatement
+	Span       source.Span
 }
 
 type ReturnStatement struct {
 	Argument Expression // can be nil
+	Span     source.Span
 }
 
 type ClassDeclarationStatement struct {
 	Name       *IdentifierExpression
 	SuperClass *IdentifierExpression // can be nil
 	Body       *BlockStatement
+	Span       source.Span
+}
+
+// ErrorStatement is a placeholder for source text that could not be parsed
+type ErrorStatement struct {
+	Span source.Span
 }
 
 // Implementation of isStatement interface method
@@ -72,6 +91,7 @@ func (s *ForStatement) isStatement()                 {}
 func (s *FunctionDeclarationStatement) isStatement() {}
 func (s *ReturnStatement) isStatement()              {}
 func (s *ClassDeclarationStatement) isStatement()    {}
+func (s *ErrorStatement) isStatement()               {}
 
 // NodeType Implementation of NodeType interface method
 func (s *ProgramStatement) NodeType() NodeType             { return NodeProgramStatement }
@@ -86,6 +106,22 @@ func (s *ForStatement) NodeType() NodeType                 { return NodeForState
 func (s *FunctionDeclarationStatement) NodeType() NodeType { return NodeFunctionDeclarationStatement }
 func (s *ReturnStatement) NodeType() NodeType              { return NodeReturnStatement }
 func (s *ClassDeclarationStatement) NodeType() NodeType    { return NodeClassDeclarationStatement }
+func (s *ErrorStatement) NodeType() NodeType               { return NodeErrorStatement }
+
+// GetSpan implementation of Node interface method
+func (s *ProgramStatement) GetSpan() source.Span             { return s.Span }
+func (s *BlockStatement) GetSpan() source.Span               { return s.Span }
+func (s *EmptyStatement) GetSpan() source.Span               { return s.Span }
+func (s *ExpressionStatement) GetSpan() source.Span          { return s.Span }
+func (s *VariableDeclarationStatement) GetSpan() source.Span { return s.Span }
+func (s *IfStatement) GetSpan() source.Span                  { return s.Span }
+func (s *WhileStatement) GetSpan() source.Span               { return s.Span }
+func (s *DoWhileStatement) GetSpan() source.Span             { return s.Span }
+func (s *ForStatement) GetSpan() source.Span                 { return s.Span }
+func (s *FunctionDeclarationStatement) GetSpan() source.Span { return s.Span }
+func (s *ReturnStatement) GetSpan() source.Span              { return s.Span }
+func (s *ClassDeclarationStatement) GetSpan() source.Span    { return s.Span }
+func (s *ErrorStatement) GetSpan() source.Span               { return s.Span }
 
 // Accept implementation of Expression interface method
 func (s *ProgramStatement) Accept(visitor Visitor)             { visitor.VisitStatement(s) }
@@ -100,3 +136,4 @@ func (s *ForStatement) Accept(visitor Visitor)                 { visitor.VisitSt
 func (s *FunctionDeclarationStatement) Accept(visitor Visitor) { visitor.VisitStatement(s) }
 func (s *ReturnStatement) Accept(visitor Visitor)              { visitor.VisitStatement(s) }
 func (s *ClassDeclarationStatement) Accept(visitor Visitor)    { visitor.VisitStatement(s) }
+func (s *ErrorStatement) Accept(visitor Visitor)               { visitor.VisitStatement(s) }
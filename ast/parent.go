@@ -0,0 +1,45 @@
+package ast
+
+// ParentMap maps every node of a tree to the node containing it, the root has no parent
+type ParentMap map[Node]Node
+
+// NewParentMap records the parents of the descendants of the root
+func NewParentMap(root Node) ParentMap {
+	parents := ParentMap{}
+	var stack []Node
+	Walk(root, func(node Node) bool {
+		if len(stack) > 0 {
+			parents[node] = stack[len(stack)-1]
+		}
+		stack = append(stack, node)
+		return true
+	}, func(node Node) {
+		stack = stack[:len(stack)-1]
+	})
+	return parents
+}
+
+// Parent returns the node containing the node, nil for the root and nodes out of the tree
+func (m ParentMap) Parent(node Node) Node {
+	return m[node]
+}
+
+// EnclosingFunction returns the innermost function or method declaring the node, nil at the top level
+func (m ParentMap) EnclosingFunction(node Node) *FunctionDeclarationStatement {
+	for parent := m[node]; parent != nil; parent = m[parent] {
+		if function, ok := parent.(*FunctionDeclarationStatement); ok {
+			return function
+		}
+	}
+	return nil
+}
+
+// EnclosingClass returns the innermost class declaring the node, nil out of classes
+func (m ParentMap) EnclosingClass(node Node) *ClassDeclarationStatement {
+	for parent := m[node]; parent != nil; parent = m[parent] {
+		if class, ok := parent.(*ClassDeclarationStatement); ok {
+			return class
+		}
+	}
+	return nil
+}
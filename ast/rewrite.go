@@ -0,0 +1,251 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// ApplyFunc is called by Apply for each node with a cursor on it
+type ApplyFunc func(cursor *Cursor) bool
+
+// Apply rewrites the tree of the root in depth-first source order and returns the root, replaced or not
+//
+// pre is called before the children of every node, returning false skips them and the post call of the node.
+// post is called after the children, returning false stops the traversal. Both can be nil.
+// The cursor changes the tree in place: the children of a replacement made in pre are walked, nodes inserted
+// in a list are not. Resolution and type information of the old tree do not apply to the rewritten one
+func Apply(root Node, pre ApplyFunc, post ApplyFunc) Node {
+	rewriter := &rewriter{pre: pre, post: post}
+	rewriter.apply(&Cursor{node: root, set: func(node Node) { root = node }})
+	return root
+}
+
+// Cursor describes a node visited by Apply and its place in the parent
+type Cursor struct {
+	parent   Node
+	name     string
+	node     Node
+	set      func(node Node)
+	iterator *iterator // position in the list holding the node, nil if it is not in a list
+}
+
+// iterator is the position of the cursor in a list, Cursor methods update it
+type iterator struct {
+	index  int
+	step   int
+	insert func(index int, node Node)
+	remove func(index int)
+}
+
+// Node returns the current node, nil after it was deleted
+func (c *Cursor) Node() Node {
+	return c.node
+}
+
+// Parent returns the node holding the current node, nil for the root
+func (c *Cursor) Parent() Node {
+	return c.parent
+}
+
+// Name returns the name of the parent field holding the current node, like "Body" or "Condition"
+func (c *Cursor) Name() string {
+	return c.name
+}
+
+// Index returns the index of the current node in its list, -1 if it is not in a list
+func (c *Cursor) Index() int {
+	if c.iterator == nil {
+		return -1
+	}
+	return c.iterator.index
+}
+
+// Replace replaces the current node, the replacement must fit the field holding it and can be nil for
+// optional nodes
+func (c *Cursor) Replace(node Node) {
+	c.set(node)
+	c.node = node
+}
+
+// Delete removes the current node from its list
+func (c *Cursor) Delete() {
+	c.list("Delete").remove(c.iterator.index)
+	c.iterator.step--
+	c.node = nil
+}
+
+// InsertBefore inserts the node in the list before the current node, it is not walked
+func (c *Cursor) InsertBefore(node Node) {
+	c.list("InsertBefore").insert(c.iterator.index, node)
+	c.iterator.index++
+}
+
+// InsertAfter inserts the node in the list after the current node, it is not walked
+func (c *Cursor) InsertAfter(node Node) {
+	c.list("InsertAfter").insert(c.iterator.index+1, node)
+	c.iterator.step++
+}
+
+// list returns the position of the cursor in its list, the operation needs one
+func (c *Cursor) list(operation string) *iterator {
+	if c.iterator == nil {
+		panic(fmt.Errorf("%s: %s in %s is not in a list", operation, c.name, c.parent.NodeType()))
+	}
+	return c.iterator
+}
+
+// rewriter holds the callbacks of Apply
+type rewriter struct {
+	pre  ApplyFunc
+	post ApplyFunc
+}
+
+// apply visits the node of the cursor and its children, returns false if the traversal stopped
+func (r *rewriter) apply(cursor *Cursor) bool {
+	if r.pre != nil && !r.pre(cursor) {
+		return true
+	}
+	if cursor.node == nil {
+		return true
+	}
+	if !r.applyChildren(cursor.node) {
+		return false
+	}
+	return r.post == nil || r.post(cursor)
+}
+
+// applyChildren visits the children of the node in source order
+func (r *rewriter) applyChildren(node Node) bool {
+	switch node := node.(type) {
+	// Statements
+	case *ProgramStatement:
+		return applyList(r, node, "Body", &node.Body)
+	case *BlockStatement:
+		return applyList(r, node, "Body", &node.Body)
+	case *ExpressionStatement:
+		return applyField(r, node, "Expression", &node.Expression)
+	case *VariableDeclarationStatement:
+		return applyList(r, node, "Variables", &node.Variables)
+	case *IfStatement:
+		return applyField(r, node, "Condition", &node.Condition) &&
+			applyField(r, node, "Consequent", &node.Consequent) &&
+			applyField(r, node, "Alternative", &node.Alternative)
+	case *WhileStatement:
+		return applyField(r, node, "Condition", &node.Condition) &&
+			applyField(r, node, "Body", &node.Body)
+	case *DoWhileStatement:
+		return applyField(r, node, "Body", &node.Body) &&
+			applyField(r, node, "Condition", &node.Condition)
+	case *ForStatement:
+		return applyField(r, node, "Initializer", &node.Initializer) &&
+			applyField(r, node, "Condition", &node.Condition) &&
+			applyField(r, node, "Increment", &node.Increment) &&
+			applyField(r, node, "Body", &node.Body)
+	case *FunctionDeclarationStatement:
+		// Parameters are values, they are rewritten through pointers and copied back
+		parameters := make([]*Parameter, len(node.Parameters))
+		for i := range node.Parameters {
+			parameters[i] = &node.Parameters[i]
+		}
+		ok := applyField(r, node, "Name", &node.Name) && applyList(r, node, "Parameters", &parameters)
+		node.Parameters = make([]Parameter, len(parameters))
+		for i, parameter := range parameters {
+			node.Parameters[i] = *parameter
+		}
+		return ok && applyField(r, node, "ReturnType", &node.ReturnType) && applyField(r, node, "Body", &node.Body)
+	case *ReturnStatement:
+		return applyField(r, node, "Argument", &node.Argument)
+	case *ClassDeclarationStatement:
+		return applyField(r, node, "Name", &node.Name) &&
+			applyField(r, node, "SuperClass", &node.SuperClass) &&
+			applyField(r, node, "Body", &node.Body)
+
+	// Expressions
+	case *VariableExpression:
+		return applyField(r, node, "Identifier", &node.Identifier) &&
+			applyField(r, node, "TypeAnnotation", &node.TypeAnnotation) &&
+			applyField(r, node, "Initializer", &node.Initializer)
+	case *AssignmentExpression:
+		return applyField(r, node, "Left", &node.Left) && applyField(r, node, "Right", &node.Right)
+	case *BinaryExpression:
+		return applyField(r, node, "Left", &node.Left) && applyField(r, node, "Right", &node.Right)
+	case *UnaryExpression:
+		return applyField(r, node, "Right", &node.Right)
+	case *LogicalExpression:
+		return applyField(r, node, "Left", &node.Left) && applyField(r, node, "Right", &node.Right)
+	case *MemberExpression:
+		return applyField(r, node, "Object", &node.Object) && applyField(r, node, "Property", &node.Property)
+	case *CallExpression:
+		return applyField(r, node, "Callee", &node.Callee) && applyList(r, node, "Arguments", &node.Arguments)
+	case *NewExpression:
+		return applyField(r, node, "Callee", &node.Callee) && applyList(r, node, "Arguments", &node.Arguments)
+	case *TemplateLiteralExpression:
+		// Expressions must stay between their chunks, they can be replaced but not inserted or deleted
+		for i := range node.Expressions {
+			if !applyField(r, node, "Expressions", &node.Expressions[i]) {
+				return false
+			}
+		}
+		return true
+
+	// Parameters and type annotations
+	case *Parameter:
+		return applyField(r, node, "Name", &node.Name) && applyField(r, node, "Type", &node.Type)
+	case *ArrayType:
+		return applyField(r, node, "ElementType", &node.ElementType)
+	case *FunctionType:
+		return applyList(r, node, "Params", &node.Params) && applyField(r, node, "ReturnType", &node.ReturnType)
+	case *GenericType:
+		return applyList(r, node, "TypeArgs", &node.TypeArgs)
+	}
+	return true
+}
+
+// applyField visits the node held by a field of the parent, missing optional nodes are skipped
+func applyField[T Node](r *rewriter, parent Node, name string, field *T) bool {
+	if isNil(*field) {
+		return true
+	}
+	return r.apply(&Cursor{
+		parent: parent,
+		name:   name,
+		node:   *field,
+		set:    func(node Node) { *field = as[T](node) },
+	})
+}
+
+// applyList visits the nodes of a list of the parent, the cursor can insert and delete nodes
+func applyList[T Node](r *rewriter, parent Node, name string, list *[]T) bool {
+	iterator := &iterator{
+		insert: func(index int, node Node) { *list = slices.Insert(*list, index, as[T](node)) },
+		remove: func(index int) { *list = slices.Delete(*list, index, index+1) },
+	}
+	for iterator.index = 0; iterator.index < len(*list); iterator.index += iterator.step {
+		iterator.step = 1
+		cursor := &Cursor{
+			parent:   parent,
+			name:     name,
+			node:     (*list)[iterator.index],
+			set:      func(node Node) { (*list)[iterator.index] = as[T](node) },
+			iterator: iterator,
+		}
+		if !r.apply(cursor) {
+			return false
+		}
+	}
+	return true
+}
+
+// as converts the node to the type of the field or list holding it, nil becomes the zero value
+func as[T Node](node Node) T {
+	var zero T
+	if node == nil {
+		return zero
+	}
+	converted, ok := node.(T)
+	if !ok {
+		panic(fmt.Errorf("cannot use %s as %s", node.NodeType(), reflect.TypeFor[T]()))
+	}
+	return converted
+}
@@ -1,9 +1,14 @@
 package ast
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/yoh0xff/senbonzakura/source"
+)
 
 // Type represents different type annotations in the AST
 type Type interface {
+	Node
 	String() string
 	isType()
 }
@@ -17,36 +22,57 @@ const (
 	StringType
 )
 
+// String returns the keyword of a PrimitiveTypeKind
+func (k PrimitiveTypeKind) String() string {
+	switch k {
+	case NumberType:
+		return "number"
+	case BooleanType:
+		return "boolean"
+	case StringType:
+		return "string"
+	default:
+		return fmt.Sprintf("Unknown primitive type kind: %d", k)
+	}
+}
+
 // PrimitiveType represents a primitive type annotation
 type PrimitiveType struct {
 	Kind PrimitiveTypeKind
+	Span source.Span
 }
 
 // ArrayType represents an array type annotation
 type ArrayType struct {
 	ElementType Type
+	Span        source.Span
 }
 
 // FunctionType represents a function type annotation
 type FunctionType struct {
 	Params     []Type
 	ReturnType Type
+	Span       source.Span
 }
 
 // ClassType represents a class type annotation
 type ClassType struct {
 	Name       string
 	SuperClass *string
+	Span       source.Span
 }
 
 // GenericType represents a generic type annotation
 type GenericType struct {
 	Base     string
 	TypeArgs []Type
+	Span     source.Span
 }
 
 // VoidType represents a void type annotation
-type VoidType struct{}
+type VoidType struct {
+	Span source.Span
+}
 
 // Implementation of Type interface for all types
 func (t PrimitiveType) isType() {}
@@ -56,6 +82,22 @@ func (t ClassType) isType()     {}
 func (t GenericType) isType()   {}
 func (t VoidType) isType()      {}
 
+// NodeType implementations
+func (t PrimitiveType) NodeType() NodeType { return NodePrimitiveType }
+func (t ArrayType) NodeType() NodeType     { return NodeArrayType }
+func (t FunctionType) NodeType() NodeType  { return NodeFunctionType }
+func (t ClassType) NodeType() NodeType     { return NodeClassType }
+func (t GenericType) NodeType() NodeType   { return NodeGenericType }
+func (t VoidType) NodeType() NodeType      { return NodeVoidType }
+
+// GetSpan implementations
+func (t PrimitiveType) GetSpan() source.Span { return t.Span }
+func (t ArrayType) GetSpan() source.Span     { return t.Span }
+func (t FunctionType) GetSpan() source.Span  { return t.Span }
+func (t ClassType) GetSpan() source.Span     { return t.Span }
+func (t GenericType) GetSpan() source.Span   { return t.Span }
+func (t VoidType) GetSpan() source.Span      { return t.Span }
+
 // String implementations
 func (t PrimitiveType) String() string {
 	switch t.Kind {
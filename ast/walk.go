@@ -0,0 +1,129 @@
+package ast
+
+// Walk traverses the node and its descendants in depth-first source order
+//
+// pre is called before the children of every node, returning false skips them and the post call of the node.
+// post is called after the children and can be nil. Parameters and type annotations are visited as well
+func Walk(node Node, pre func(node Node) bool, post func(node Node)) {
+	if !pre(node) {
+		return
+	}
+	for _, child := range Children(node) {
+		Walk(child, pre, post)
+	}
+	if post != nil {
+		post(node)
+	}
+}
+
+// Inspect calls fn for the node and its descendants in depth-first source order, fn returning false skips the
+// children of the node
+func Inspect(node Node, fn func(node Node) bool) {
+	Walk(node, fn, nil)
+}
+
+// Children returns the direct children of the node in source order, missing optional children are left out
+func Children(node Node) []Node {
+	var children []Node
+	add := func(nodes ...Node) {
+		for _, node := range nodes {
+			if !isNil(node) {
+				children = append(children, node)
+			}
+		}
+	}
+
+	switch node := node.(type) {
+	// Statements
+	case *ProgramStatement:
+		for _, statement := range node.Body {
+			add(statement)
+		}
+	case *BlockStatement:
+		for _, statement := range node.Body {
+			add(statement)
+		}
+	case *ExpressionStatement:
+		add(node.Expression)
+	case *VariableDeclarationStatement:
+		for _, variable := range node.Variables {
+			add(variable)
+		}
+	case *IfStatement:
+		add(node.Condition, node.Consequent, node.Alternative)
+	case *WhileStatement:
+		add(node.Condition, node.Body)
+	case *DoWhileStatement:
+		add(node.Body, node.Condition)
+	case *ForStatement:
+		add(node.Initializer, node.Condition, node.Increment, node.Body)
+	case *FunctionDeclarationStatement:
+		add(node.Name)
+		for i := range node.Parameters {
+			add(&node.Parameters[i])
+		}
+		add(node.ReturnType, node.Body)
+	case *ReturnStatement:
+		add(node.Argument)
+	case *ClassDeclarationStatement:
+		add(node.Name, node.SuperClass, node.Body)
+
+	// Expressions
+	case *VariableExpression:
+		add(node.Identifier, node.TypeAnnotation, node.Initializer)
+	case *AssignmentExpression:
+		add(node.Left, node.Right)
+	case *BinaryExpression:
+		add(node.Left, node.Right)
+	case *UnaryExpression:
+		add(node.Right)
+	case *LogicalExpression:
+		add(node.Left, node.Right)
+	case *MemberExpression:
+		add(node.Object, node.Property)
+	case *CallExpression:
+		add(node.Callee)
+		for _, argument := range node.Arguments {
+			add(argument)
+		}
+	case *NewExpression:
+		add(node.Callee)
+		for _, argument := range node.Arguments {
+			add(argument)
+		}
+	case *TemplateLiteralExpression:
+		for _, expression := range node.Expressions {
+			add(expression)
+		}
+
+	// Parameters and type annotations
+	case *Parameter:
+		add(node.Name, node.Type)
+	case *ArrayType:
+		add(node.ElementType)
+	case *FunctionType:
+		for _, param := range node.Params {
+			add(param)
+		}
+		add(node.ReturnType)
+	case *GenericType:
+		for _, argument := range node.TypeArgs {
+			add(argument)
+		}
+	}
+	return children
+}
+
+// isNil checks if the node is missing, optional children are typed nil pointers in their interfaces
+func isNil(node Node) bool {
+	switch node := node.(type) {
+	case nil:
+		return true
+	case *BlockStatement:
+		return node == nil
+	case *IdentifierExpression:
+		return node == nil
+	default:
+		return false
+	}
+}
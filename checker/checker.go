@@ -0,0 +1,118 @@
+package checker
+
+import (
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/diagnostic"
+	"github.com/yoh0xff/senbonzakura/resolver"
+	"github.com/yoh0xff/senbonzakura/source"
+)
+
+// Builtin is a name provided by the host with its type
+type Builtin struct {
+	Name string
+	Type Type
+}
+
+// Info is the result of checking a program
+type Info struct {
+	Resolution *resolver.Resolution
+
+	// Types holds the type of every checked expression
+	Types map[ast.Expression]Type
+
+	// Symbols holds the type of every symbol, classes map to their instance type
+	Symbols map[*resolver.Symbol]Type
+
+	// Classes holds the type of every class declaration
+	Classes map[*ast.ClassDeclarationStatement]*Class
+
+	// Functions holds the signature of every function and method declaration
+	Functions map[*ast.FunctionDeclarationStatement]*Function
+}
+
+// TypeOf returns the type of the expression, nil if it was not checked
+func (i *Info) TypeOf(expression ast.Expression) Type {
+	return i.Types[expression]
+}
+
+// functionContext represents the function being checked
+type functionContext struct {
+	declaration *ast.FunctionDeclarationStatement
+	signature   *Function
+	constructor bool
+}
+
+// Checker computes the type of every expression and validates them against the annotations
+type Checker struct {
+	info        *Info
+	builtins    map[string]Type
+	function    *functionContext // nil outside of functions
+	class       *Class           // innermost enclosing class, nil outside of classes
+	checked     map[*ast.FunctionDeclarationStatement]bool
+	diagnostics []diagnostic.Diagnostic
+}
+
+// Check resolves the names of the program and checks its types
+//
+// Builtins are visible everywhere in the program, resolver diagnostics are included in the result
+func Check(program ast.Statement, builtins ...Builtin) (*Info, []diagnostic.Diagnostic) {
+	names := make([]string, len(builtins))
+	builtinTypes := map[string]Type{}
+	for i, builtin := range builtins {
+		names[i] = builtin.Name
+		builtinTypes[builtin.Name] = builtin.Type
+	}
+
+	resolution, diagnostics := resolver.Resolve(program, names...)
+
+	checker := &Checker{
+		info: &Info{
+			Resolution: resolution,
+			Types:      map[ast.Expression]Type{},
+			Symbols:    map[*resolver.Symbol]Type{},
+			Classes:    map[*ast.ClassDeclarationStatement]*Class{},
+			Functions:  map[*ast.FunctionDeclarationStatement]*Function{},
+		},
+		builtins:    builtinTypes,
+		checked:     map[*ast.FunctionDeclarationStatement]bool{},
+		diagnostics: diagnostics,
+	}
+
+	program.Accept(checker)
+
+	diagnostic.Sort(checker.diagnostics)
+	return checker.info, checker.diagnostics
+}
+
+// VisitStatement implements the ast.Visitor interface
+func (c *Checker) VisitStatement(statement ast.Statement) {
+	visitStatement(c, statement)
+}
+
+// VisitExpression implements the ast.Visitor interface
+func (c *Checker) VisitExpression(expression ast.Expression) {
+	c.info.Types[expression] = visitExpression(c, expression)
+}
+
+// typeOf checks the expression and returns its type
+func (c *Checker) typeOf(expression ast.Expression) Type {
+	expression.Accept(c)
+	return c.info.Types[expression]
+}
+
+// expectAssignable reports an error if the value of the source type can't be stored as the target type
+func (c *Checker) expectAssignable(target, source Type, span source.Span, context string) {
+	if !IsAssignable(target, source) {
+		c.reportError(diagnostic.CodeTypeMismatch, span, "Cannot use %s as %s in %s", source, target, context)
+	}
+}
+
+// report records a diagnostic
+func (c *Checker) report(d diagnostic.Diagnostic) {
+	c.diagnostics = append(c.diagnostics, d)
+}
+
+// reportError records an error diagnostic
+func (c *Checker) reportError(code diagnostic.Code, span source.Span, format string, args ...any) {
+	c.report(diagnostic.NewError(code, span, format, args...))
+}
@@ -0,0 +1,173 @@
+package checker
+
+import (
+	"testing"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/diagnostic"
+	"github.com/yoh0xff/senbonzakura/parser"
+)
+
+func checkSource(t *testing.T, source string, builtins ...Builtin) (*Info, []diagnostic.Diagnostic) {
+	t.Helper()
+
+	program, diagnostics := parser.ParseRootStatement(parser.NewParser(source))
+	if len(diagnostics) != 0 {
+		t.Fatalf("Expected no parse diagnostics, got %v", diagnostics)
+	}
+
+	return Check(program, builtins...)
+}
+
+func TestCheckDiagnostics(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected []diagnostic.Code
+	}{
+		{"valid declarations", `let x: number = 1 + 2 * 3; let s: string = "a" + "b"; let b: boolean = x > 2 && !false;`, nil},
+		{"initializer mismatch", `let x: number = "one";`, []diagnostic.Code{diagnostic.CodeTypeMismatch}},
+		{"assignment mismatch", `let x: number = 1; x = true;`, []diagnostic.Code{diagnostic.CodeTypeMismatch}},
+		{"compound assignment", `let s: string = "a"; s += "b"; s -= "c";`, []diagnostic.Code{diagnostic.CodeInvalidOperand}},
+		{"assign to function", `def f(): void {} f = nil;`, []diagnostic.Code{diagnostic.CodeInvalidValue}},
+		{"binary operands", `let x: number = 1 - "a";`, []diagnostic.Code{diagnostic.CodeInvalidOperand}},
+		{"mixed addition", `let x: string = "a" + 1;`, []diagnostic.Code{diagnostic.CodeInvalidOperand}},
+		{"comparison", `let b: boolean = 1 == "a";`, []diagnostic.Code{diagnostic.CodeInvalidOperand}},
+		{"unary operand", `let b: boolean = !1;`, []diagnostic.Code{diagnostic.CodeInvalidOperand}},
+		{"logical operands", `let b: boolean = 1 && true;`, []diagnostic.Code{diagnostic.CodeInvalidOperand}},
+		{"string index", `let s: string = "ab"; let c: string = s[0];`, []diagnostic.Code{diagnostic.CodeInvalidOperand}},
+		{"condition", `if (1) {}`, []diagnostic.Code{diagnostic.CodeInvalidCondition}},
+		{"call arity", `def f(a: number): number { return a; } f(1, 2);`, []diagnostic.Code{diagnostic.CodeArgumentCount}},
+		{"call argument", `def f(a: number): number { return a; } f("a");`, []diagnostic.Code{diagnostic.CodeTypeMismatch}},
+		{"call result", `def f(): string { return "a"; } let x: number = f();`, []diagnostic.Code{diagnostic.CodeTypeMismatch}},
+		{"not callable", `let x: number = 1; x();`, []diagnostic.Code{diagnostic.CodeNotCallable}},
+		{"return mismatch", `def f(): number { return "a"; }`, []diagnostic.Code{diagnostic.CodeTypeMismatch}},
+		{"return value from void", `def f(): void { return 1; }`, []diagnostic.Code{diagnostic.CodeInvalidReturn}},
+		{"missing return", `def f(a: boolean): number { if (a) { return 1; } }`, []diagnostic.Code{diagnostic.CodeMissingReturn}},
+		{"return on every path", `def f(a: boolean): number { if (a) { return 1; } else { return 2; } }`, nil},
+		{"endless loops", `def f(): number { while (true) { return 1; } } def g(): number { for (;;) {} } def h(): number { do {} while (true); }`, nil},
+		{"loop with a condition", `def f(a: boolean): number { while (a) { return 1; } }`, []diagnostic.Code{diagnostic.CodeMissingReturn}},
+		{"return outside function", `return 1;`, []diagnostic.Code{diagnostic.CodeInvalidReturn}},
+		{"undeclared names are not type errors", `let x: number = y + 1;`, []diagnostic.Code{diagnostic.CodeUndeclaredName}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, diagnostics := checkSource(t, tt.source)
+
+			if len(diagnostics) != len(tt.expected) {
+				t.Fatalf("Expected %d diagnostics, got %v", len(tt.expected), diagnostics)
+			}
+			for i, code := range tt.expected {
+				if diagnostics[i].Code != code {
+					t.Errorf("Expected diagnostic %d to be %s, got %s", i, code, diagnostics[i].Code)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckClasses(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected []diagnostic.Code
+	}{
+		{
+			"constructor fields",
+			`class P { def constructor(name: string) { this.name = name; } def get(): string { return this.name; } }
+			 let p: P = new P("a"); let s: string = p.name + p.get();`,
+			nil,
+		},
+		{
+			"declared fields",
+			`class P { let age: number = 0; } let p: P = new P(); p.age = 1;`,
+			nil,
+		},
+		{
+			"field type",
+			`class P { let age: number = 0; } let p: P = new P(); p.age = "one";`,
+			[]diagnostic.Code{diagnostic.CodeTypeMismatch},
+		},
+		{
+			"unknown member",
+			`class P {} let p: P = new P(); p.name;`,
+			[]diagnostic.Code{diagnostic.CodeUnknownMember},
+		},
+		{
+			"constructor arguments",
+			`class P { def constructor(a: number) {} } let p: P = new P();`,
+			[]diagnostic.Code{diagnostic.CodeArgumentCount},
+		},
+		{
+			"constructor argument types",
+			`class P { def constructor(a: number) {} } let p: P = new P("a");`,
+			[]diagnostic.Code{diagnostic.CodeTypeMismatch},
+		},
+		{
+			"subclass is assignable",
+			`class A { def m(): number { return 1; } } class B extends A {} let a: A = new B(); let x: number = a.m();`,
+			nil,
+		},
+		{
+			"superclass is not assignable",
+			`class A {} class B extends A {} let b: B = new A();`,
+			[]diagnostic.Code{diagnostic.CodeTypeMismatch},
+		},
+		{
+			"super constructor",
+			`class A { def constructor(a: number) {} } class B extends A { def constructor() { super("a"); } }`,
+			[]diagnostic.Code{diagnostic.CodeTypeMismatch},
+		},
+		{
+			"incompatible override",
+			`class A { def m(): number { return 1; } } class B extends A { def m(): string { return "a"; } }`,
+			[]diagnostic.Code{diagnostic.CodeTypeMismatch},
+		},
+		{
+			"class as value",
+			`class A {} let a: A = A;`,
+			[]diagnostic.Code{diagnostic.CodeInvalidValue},
+		},
+		{
+			"nil for class",
+			`class A {} let a: A = nil;`,
+			nil,
+		},
+		{
+			"cyclic inheritance",
+			`class A extends B {} class B extends A {}`,
+			[]diagnostic.Code{diagnostic.CodeNotAClass},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, diagnostics := checkSource(t, tt.source)
+
+			if len(diagnostics) != len(tt.expected) {
+				t.Fatalf("Expected %d diagnostics, got %v", len(tt.expected), diagnostics)
+			}
+			for i, code := range tt.expected {
+				if diagnostics[i].Code != code {
+					t.Errorf("Expected diagnostic %d to be %s, got %s", i, code, diagnostics[i].Code)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckBuiltins(t *testing.T) {
+	print := Builtin{Name: "print", Type: &Function{Params: []Type{Any}, Return: Void, Variadic: true}}
+
+	info, diagnostics := checkSource(t, `print("a", 1, true); let x: number = print();`, print)
+	if len(diagnostics) != 1 || diagnostics[0].Code != diagnostic.CodeTypeMismatch {
+		t.Fatalf("Expected a single %s diagnostic, got %v", diagnostic.CodeTypeMismatch, diagnostics)
+	}
+
+	program := info.Resolution.Program.Node.(*ast.ProgramStatement)
+	call := program.Body[0].(*ast.ExpressionStatement).Expression
+	if t1 := info.TypeOf(call); t1 != Void {
+		t.Errorf("Expected call type void, got %v", t1)
+	}
+}
@@ -0,0 +1,260 @@
+package checker
+
+import (
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/diagnostic"
+	"github.com/yoh0xff/senbonzakura/resolver"
+)
+
+// symbolType returns the type of the symbol, computed from its declaration
+func (c *Checker) symbolType(symbol *resolver.Symbol) Type {
+	if t, ok := c.info.Symbols[symbol]; ok {
+		return t
+	}
+
+	var t Type
+	switch symbol.Kind {
+	case resolver.SymbolVariable, resolver.SymbolField:
+		t = c.convertType(symbol.Declaration.(*ast.VariableExpression).TypeAnnotation)
+	case resolver.SymbolFunction, resolver.SymbolMethod:
+		t = c.signature(symbol.Declaration.(*ast.FunctionDeclarationStatement))
+	case resolver.SymbolClass:
+		return c.classOf(symbol)
+	case resolver.SymbolBuiltin:
+		t = Any
+		if builtin, ok := c.builtins[symbol.Name]; ok && builtin != nil {
+			t = builtin
+		}
+	default:
+		// Parameters get their type when the function is checked
+		return Unknown
+	}
+
+	c.info.Symbols[symbol] = t
+	return t
+}
+
+// convertType converts a type annotation into a type, a missing annotation is void
+func (c *Checker) convertType(annotation ast.Type) Type {
+	switch annotation := annotation.(type) {
+	case nil:
+		return Void
+	case *ast.PrimitiveType:
+		switch annotation.Kind {
+		case ast.NumberType:
+			return Number
+		case ast.StringType:
+			return String
+		default:
+			return Boolean
+		}
+	case *ast.VoidType:
+		return Void
+	case *ast.ArrayType:
+		return &Array{Element: c.convertType(annotation.ElementType)}
+	case *ast.FunctionType:
+		params := make([]Type, len(annotation.Params))
+		for i, param := range annotation.Params {
+			params[i] = c.convertType(param)
+		}
+		return &Function{Params: params, Return: c.convertType(annotation.ReturnType)}
+	case *ast.ClassType:
+		symbol, ok := c.info.Resolution.TypeReferences[annotation]
+		if !ok {
+			// Already reported by the resolver
+			return Unknown
+		}
+		return c.classOf(symbol)
+	case *ast.GenericType:
+		c.reportError(diagnostic.CodeUnsupportedType, annotation.Span, "Generic type '%s' is not supported", annotation.Base)
+		return Unknown
+	default:
+		return Unknown
+	}
+}
+
+// signature returns the type of a function declaration
+func (c *Checker) signature(declaration *ast.FunctionDeclarationStatement) *Function {
+	if signature, ok := c.info.Functions[declaration]; ok {
+		return signature
+	}
+
+	params := make([]Type, len(declaration.Parameters))
+	for i, parameter := range declaration.Parameters {
+		params[i] = c.convertType(parameter.Type)
+	}
+
+	signature := &Function{Params: params, Return: c.convertType(declaration.ReturnType)}
+	c.info.Functions[declaration] = signature
+	return signature
+}
+
+// classOf returns the instance type of a class symbol
+//
+// The class is built on first use: fields and methods come from the class body, the constructor is
+// checked right away so fields assigned through 'this' are known before any other use of the class
+func (c *Checker) classOf(symbol *resolver.Symbol) *Class {
+	if t, ok := c.info.Symbols[symbol]; ok {
+		return t.(*Class)
+	}
+
+	declaration := symbol.Declaration.(*ast.ClassDeclarationStatement)
+	class := &Class{
+		Name:        symbol.Name,
+		Fields:      map[string]Type{},
+		Methods:     map[string]*Function{},
+		Declaration: declaration,
+	}
+
+	// Cached before anything else, classes can refer to themselves and to each other
+	c.info.Symbols[symbol] = class
+	c.info.Classes[declaration] = class
+
+	if declaration.SuperClass != nil {
+		superSymbol := c.info.Resolution.Uses[declaration.SuperClass]
+		if superSymbol != nil && superSymbol.Kind == resolver.SymbolClass && superSymbol != symbol {
+			superClass := c.classOf(superSymbol)
+			if superClass.IsSubclassOf(class) {
+				c.reportError(
+					diagnostic.CodeNotAClass,
+					declaration.SuperClass.Span,
+					"Class '%s' can't extend '%s', the inheritance is cyclic",
+					class.Name, superClass.Name,
+				)
+			} else {
+				class.Super = superClass
+			}
+		}
+	}
+
+	var constructor *ast.FunctionDeclarationStatement
+	for _, statement := range declaration.Body.Body {
+		switch member := statement.(type) {
+		case *ast.VariableDeclarationStatement:
+			for _, variable := range member.Variables {
+				c.addField(class, variable.Identifier.Name, c.convertType(variable.TypeAnnotation))
+			}
+		case *ast.FunctionDeclarationStatement:
+			signature := c.signature(member)
+			if member.Name.Name == "constructor" {
+				constructor = member
+				class.Constructor = signature
+				continue
+			}
+
+			if _, ok := class.Methods[member.Name.Name]; ok {
+				// Already reported by the resolver
+				continue
+			}
+			if inherited, ok := class.Method(member.Name.Name); ok && !Identical(inherited, signature) {
+				c.reportError(
+					diagnostic.CodeTypeMismatch,
+					member.Name.Span,
+					"Method '%s' has type %s, the overridden method has type %s",
+					member.Name.Name, signature, inherited,
+				)
+			}
+			class.Methods[member.Name.Name] = signature
+		}
+	}
+
+	if constructor != nil {
+		c.checkFunction(constructor, class)
+	}
+
+	return class
+}
+
+// addField adds a field to the class, the first declaration wins
+func (c *Checker) addField(class *Class, name string, t Type) {
+	if _, ok := class.Fields[name]; ok {
+		return
+	}
+	class.Fields[name] = t
+	class.FieldOrder = append(class.FieldOrder, name)
+}
+
+// checkFunction checks the body of a function declaration once
+func (c *Checker) checkFunction(declaration *ast.FunctionDeclarationStatement, class *Class) {
+	if c.checked[declaration] {
+		return
+	}
+	c.checked[declaration] = true
+
+	signature := c.signature(declaration)
+	symbol := c.info.Resolution.Declarations[declaration.Name]
+	isConstructor := symbol != nil && symbol.Kind == resolver.SymbolMethod && declaration.Name.Name == "constructor"
+
+	enclosingFunction, enclosingClass := c.function, c.class
+	c.function = &functionContext{
+		declaration: declaration,
+		signature:   signature,
+		constructor: isConstructor,
+	}
+	c.class = class
+
+	for i, parameter := range declaration.Parameters {
+		if name, ok := parameter.Name.(*ast.IdentifierExpression); ok {
+			if symbol := c.info.Resolution.Declarations[name]; symbol != nil {
+				c.info.Symbols[symbol] = signature.Params[i]
+			}
+		}
+	}
+
+	for _, statement := range declaration.Body.Body {
+		statement.Accept(c)
+	}
+
+	if !isKind(signature.Return, VoidKind) && !isUnknown(signature.Return) && !alwaysReturns(declaration.Body.Body) {
+		c.reportError(
+			diagnostic.CodeMissingReturn,
+			declaration.Name.Span,
+			"Function '%s' must return a value of type %s on every path",
+			declaration.Name.Name, signature.Return,
+		)
+	}
+
+	c.function, c.class = enclosingFunction, enclosingClass
+}
+
+// alwaysReturns tells if the statements end with a return on every path
+//
+// Loops without a condition or with the literal true never complete, there is no break to leave them, so the
+// statements after them can't be reached
+func alwaysReturns(statements []ast.Statement) bool {
+	for _, statement := range statements {
+		switch statement := statement.(type) {
+		case *ast.ReturnStatement:
+			return true
+		case *ast.BlockStatement:
+			if alwaysReturns(statement.Body) {
+				return true
+			}
+		case *ast.IfStatement:
+			if statement.Alternative != nil &&
+				alwaysReturns(statement.Consequent.Body) &&
+				alwaysReturns(statement.Alternative.Body) {
+				return true
+			}
+		case *ast.WhileStatement:
+			if isLiteralTrue(statement.Condition) {
+				return true
+			}
+		case *ast.DoWhileStatement:
+			if isLiteralTrue(statement.Condition) || alwaysReturns(statement.Body.Body) {
+				return true
+			}
+		case *ast.ForStatement:
+			if statement.Condition == nil || isLiteralTrue(statement.Condition) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isLiteralTrue tells if the condition is the literal true
+func isLiteralTrue(condition ast.Expression) bool {
+	literal, ok := condition.(*ast.BooleanLiteralExpression)
+	return ok && literal.Value
+}
@@ -0,0 +1,230 @@
+package checker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+)
+
+// Type represents the static type of an expression
+type Type interface {
+	String() string
+	isType()
+}
+
+// PrimitiveKind represents the built-in types
+type PrimitiveKind int
+
+const (
+	NumberKind PrimitiveKind = iota
+	StringKind
+	BooleanKind
+	VoidKind
+	NilKind
+	AnyKind     // accepts every value, used for host functions
+	UnknownKind // type of erroneous expressions, compatible with everything
+)
+
+// Primitive is a built-in type
+type Primitive struct {
+	Kind PrimitiveKind
+}
+
+// Array is the type of arrays with elements of the same type
+type Array struct {
+	Element Type
+}
+
+// Function is the type of functions and methods
+type Function struct {
+	Params   []Type
+	Return   Type
+	Variadic bool // the last parameter accepts any number of arguments
+}
+
+// Class is the type of class instances
+//
+// Fields are declared with 'let' in the class body or assigned through 'this' in the constructor
+type Class struct {
+	Name        string
+	Super       *Class // can be nil
+	Fields      map[string]Type
+	FieldOrder  []string
+	Methods     map[string]*Function
+	Constructor *Function // can be nil, the class is constructed without arguments
+	Declaration *ast.ClassDeclarationStatement
+}
+
+// Shared instances of the primitive types
+var (
+	Number  Type = &Primitive{Kind: NumberKind}
+	String  Type = &Primitive{Kind: StringKind}
+	Boolean Type = &Primitive{Kind: BooleanKind}
+	Void    Type = &Primitive{Kind: VoidKind}
+	Nil     Type = &Primitive{Kind: NilKind}
+	Any     Type = &Primitive{Kind: AnyKind}
+	Unknown Type = &Primitive{Kind: UnknownKind}
+)
+
+// Implementation of Type interface for all types
+func (t *Primitive) isType() {}
+func (t *Array) isType()     {}
+func (t *Function) isType()  {}
+func (t *Class) isType()     {}
+
+// String implementations
+func (t *Primitive) String() string {
+	switch t.Kind {
+	case NumberKind:
+		return "number"
+	case StringKind:
+		return "string"
+	case BooleanKind:
+		return "boolean"
+	case VoidKind:
+		return "void"
+	case NilKind:
+		return "nil"
+	case AnyKind:
+		return "any"
+	case UnknownKind:
+		return "unknown"
+	default:
+		return fmt.Sprintf("PrimitiveKind(%d)", t.Kind)
+	}
+}
+
+func (t *Array) String() string {
+	return fmt.Sprintf("[%s]", t.Element)
+}
+
+func (t *Function) String() string {
+	params := make([]string, len(t.Params))
+	for i, param := range t.Params {
+		params[i] = param.String()
+		if t.Variadic && i == len(t.Params)-1 {
+			params[i] = "..." + params[i]
+		}
+	}
+	return fmt.Sprintf("(%s) -> %s", strings.Join(params, ", "), t.Return)
+}
+
+func (t *Class) String() string {
+	return t.Name
+}
+
+// Field finds the field in the class or its superclasses
+func (t *Class) Field(name string) (Type, bool) {
+	for class := t; class != nil; class = class.Super {
+		if field, ok := class.Fields[name]; ok {
+			return field, true
+		}
+	}
+	return nil, false
+}
+
+// Method finds the method in the class or its superclasses
+func (t *Class) Method(name string) (*Function, bool) {
+	for class := t; class != nil; class = class.Super {
+		if method, ok := class.Methods[name]; ok {
+			return method, true
+		}
+	}
+	return nil, false
+}
+
+// ConstructorType finds the constructor of the class or its superclasses
+func (t *Class) ConstructorType() *Function {
+	for class := t; class != nil; class = class.Super {
+		if class.Constructor != nil {
+			return class.Constructor
+		}
+	}
+	return &Function{Return: Void}
+}
+
+// IsSubclassOf tells if the class is the other class or extends it
+func (t *Class) IsSubclassOf(other *Class) bool {
+	for class := t; class != nil; class = class.Super {
+		if class == other {
+			return true
+		}
+	}
+	return false
+}
+
+// isKind tells if the type is the primitive of the kind
+func isKind(t Type, kind PrimitiveKind) bool {
+	primitive, ok := t.(*Primitive)
+	return ok && primitive.Kind == kind
+}
+
+// isUnknown tells if the type comes from an erroneous expression, no more errors are reported for it
+func isUnknown(t Type) bool {
+	return isKind(t, UnknownKind)
+}
+
+// IsAssignable tells if a value of the source type can be stored where the target type is expected
+func IsAssignable(target, source Type) bool {
+	if isUnknown(target) || isUnknown(source) || isKind(target, AnyKind) {
+		return true
+	}
+
+	switch target := target.(type) {
+	case *Primitive:
+		source, ok := source.(*Primitive)
+		return ok && source.Kind == target.Kind
+	case *Array:
+		if isKind(source, NilKind) {
+			return true
+		}
+		source, ok := source.(*Array)
+		return ok && Identical(target.Element, source.Element)
+	case *Function:
+		if isKind(source, NilKind) {
+			return true
+		}
+		source, ok := source.(*Function)
+		return ok && Identical(target, source)
+	case *Class:
+		if isKind(source, NilKind) {
+			return true
+		}
+		source, ok := source.(*Class)
+		return ok && source.IsSubclassOf(target)
+	default:
+		return false
+	}
+}
+
+// Identical tells if both types are the same type
+func Identical(a, b Type) bool {
+	if isUnknown(a) || isUnknown(b) {
+		return true
+	}
+
+	switch a := a.(type) {
+	case *Primitive:
+		b, ok := b.(*Primitive)
+		return ok && a.Kind == b.Kind
+	case *Array:
+		b, ok := b.(*Array)
+		return ok && Identical(a.Element, b.Element)
+	case *Function:
+		b, ok := b.(*Function)
+		if !ok || a.Variadic != b.Variadic || len(a.Params) != len(b.Params) {
+			return false
+		}
+		for i := range a.Params {
+			if !Identical(a.Params[i], b.Params[i]) {
+				return false
+			}
+		}
+		return Identical(a.Return, b.Return)
+	case *Class:
+		return a == b
+	default:
+		return false
+	}
+}
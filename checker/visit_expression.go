@@ -0,0 +1,457 @@
+package checker
+
+import (
+	"fmt"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/diagnostic"
+	"github.com/yoh0xff/senbonzakura/resolver"
+)
+
+func visitExpression(checker *Checker, expression ast.Expression) Type {
+	switch expression.NodeType() {
+	case ast.NodeVariableExpression:
+		return visitVariableExpression(checker, expression.(*ast.VariableExpression))
+	case ast.NodeAssignmentExpression:
+		return visitAssignmentExpression(checker, expression.(*ast.AssignmentExpression))
+	case ast.NodeBinaryExpression:
+		return visitBinaryExpression(checker, expression.(*ast.BinaryExpression))
+	case ast.NodeUnaryExpression:
+		return visitUnaryExpression(checker, expression.(*ast.UnaryExpression))
+	case ast.NodeLogicalExpression:
+		return visitLogicalExpression(checker, expression.(*ast.LogicalExpression))
+	case ast.NodeBooleanLiteralExpression:
+		return Boolean
+	case ast.NodeNilLiteralExpression:
+		return Nil
+	case ast.NodeNumericLiteralExpression:
+		return Number
+	case ast.NodeStringLiteralExpression:
+		return String
+	case ast.NodeTemplateLiteralExpression:
+		return visitTemplateLiteralExpression(checker, expression.(*ast.TemplateLiteralExpression))
+	case ast.NodeIdentifierExpression:
+		return visitIdentifierExpression(checker, expression.(*ast.IdentifierExpression))
+	case ast.NodeMemberExpression:
+		return visitMemberExpression(checker, expression.(*ast.MemberExpression))
+	case ast.NodeCallExpression:
+		return visitCallExpression(checker, expression.(*ast.CallExpression))
+	case ast.NodeThisExpression:
+		if checker.class == nil {
+			// Already reported by the resolver
+			return Unknown
+		}
+		return checker.class
+	case ast.NodeSuperExpression:
+		if checker.class == nil || checker.class.Super == nil {
+			return Unknown
+		}
+		return checker.class.Super
+	case ast.NodeNewExpression:
+		return visitNewExpression(checker, expression.(*ast.NewExpression))
+	default:
+		panic(fmt.Errorf("unknown expression type: %T", expression))
+	}
+}
+
+func visitVariableExpression(checker *Checker, expression *ast.VariableExpression) Type {
+	t := Unknown
+	if symbol := checker.info.Resolution.Declarations[expression.Identifier]; symbol != nil {
+		t = checker.symbolType(symbol)
+	}
+
+	if isKind(t, VoidKind) {
+		checker.reportError(
+			diagnostic.CodeInvalidValue,
+			expression.TypeAnnotation.GetSpan(),
+			"Variable '%s' can't have type void",
+			expression.Identifier.Name,
+		)
+	}
+
+	if expression.Initializer != nil {
+		checker.expectAssignable(
+			t,
+			checker.typeOf(expression.Initializer),
+			expression.Initializer.GetSpan(),
+			fmt.Sprintf("initializer of '%s'", expression.Identifier.Name),
+		)
+	}
+
+	return t
+}
+
+func visitAssignmentExpression(checker *Checker, expression *ast.AssignmentExpression) Type {
+	right := checker.typeOf(expression.Right)
+
+	if inferField(checker, expression.Left, right) {
+		return right
+	}
+
+	left := checker.typeOf(expression.Left)
+	checkAssignmentTarget(checker, expression.Left)
+
+	switch expression.Operator {
+	case ast.OperatorAssign:
+		checker.expectAssignable(left, right, expression.Right.GetSpan(), "assignment")
+	case ast.OperatorAssignAdd:
+		if !isUnknown(left) && !isUnknown(right) && !(isNumeric(left, right) || isString(left, right)) {
+			checker.reportError(
+				diagnostic.CodeInvalidOperand,
+				expression.Span,
+				"Operator %s needs two numbers or two strings, got %s and %s",
+				expression.Operator, left, right,
+			)
+		}
+	default:
+		if !isUnknown(left) && !isUnknown(right) && !isNumeric(left, right) {
+			checker.reportError(
+				diagnostic.CodeInvalidOperand,
+				expression.Span,
+				"Operator %s needs two numbers, got %s and %s",
+				expression.Operator, left, right,
+			)
+		}
+	}
+
+	return left
+}
+
+// inferField declares a field assigned through 'this' in a constructor, the field gets the type of the value
+//
+// Returns false if the assignment is not a new field declaration
+func inferField(checker *Checker, target ast.Expression, value Type) bool {
+	if checker.function == nil || !checker.function.constructor || checker.class == nil {
+		return false
+	}
+
+	member, ok := target.(*ast.MemberExpression)
+	if !ok || member.Computed {
+		return false
+	}
+	if _, ok := member.Object.(*ast.ThisExpression); !ok {
+		return false
+	}
+
+	name := member.Property.(*ast.IdentifierExpression).Name
+	if _, ok := checker.class.Field(name); ok {
+		return false
+	}
+	if _, ok := checker.class.Method(name); ok {
+		return false
+	}
+
+	if isKind(value, NilKind) || isKind(value, VoidKind) {
+		checker.reportError(
+			diagnostic.CodeTypeMismatch,
+			member.Span,
+			"Cannot infer the type of field '%s' from %s, declare it with 'let' in the class body",
+			name, value,
+		)
+		value = Unknown
+	}
+
+	checker.addField(checker.class, name, value)
+	checker.info.Types[member.Object] = checker.class
+	checker.info.Types[member] = value
+	return true
+}
+
+// checkAssignmentTarget reports assignments to names and members that are not variables or fields
+func checkAssignmentTarget(checker *Checker, target ast.Expression) {
+	switch target := target.(type) {
+	case *ast.IdentifierExpression:
+		symbol := checker.info.Resolution.Uses[target]
+		if symbol == nil {
+			return
+		}
+		switch symbol.Kind {
+		case resolver.SymbolVariable, resolver.SymbolParameter:
+			// Assignable
+		default:
+			checker.reportError(diagnostic.CodeInvalidValue, target.Span, "Cannot assign to %s '%s'", symbol.Kind, symbol.Name)
+		}
+	case *ast.MemberExpression:
+		if target.Computed {
+			return
+		}
+		class, ok := checker.info.Types[target.Object].(*Class)
+		if !ok {
+			return
+		}
+		name := target.Property.(*ast.IdentifierExpression).Name
+		if _, ok := class.Field(name); ok {
+			return
+		}
+		if _, ok := class.Method(name); ok {
+			checker.reportError(diagnostic.CodeInvalidValue, target.Span, "Cannot assign to method '%s'", name)
+		}
+	}
+}
+
+func visitBinaryExpression(checker *Checker, expression *ast.BinaryExpression) Type {
+	left := checker.typeOf(expression.Left)
+	right := checker.typeOf(expression.Right)
+
+	switch expression.Operator {
+	case ast.OperatorAdd:
+		switch {
+		case isUnknown(left) || isUnknown(right):
+			return Unknown
+		case isNumeric(left, right):
+			return Number
+		case isString(left, right):
+			return String
+		}
+		reportOperands(checker, expression, "two numbers or two strings", left, right)
+		return Unknown
+	case ast.OperatorSubtract, ast.OperatorMultiply, ast.OperatorDivide:
+		if !isUnknown(left) && !isUnknown(right) && !isNumeric(left, right) {
+			reportOperands(checker, expression, "two numbers", left, right)
+		}
+		return Number
+	case ast.OperatorEqual, ast.OperatorNotEqual:
+		if !IsAssignable(left, right) && !IsAssignable(right, left) {
+			checker.reportError(diagnostic.CodeInvalidOperand, expression.Span, "Cannot compare %s with %s", left, right)
+		}
+		return Boolean
+	default:
+		if !isUnknown(left) && !isUnknown(right) && !isNumeric(left, right) && !isString(left, right) {
+			reportOperands(checker, expression, "two numbers or two strings", left, right)
+		}
+		return Boolean
+	}
+}
+
+func reportOperands(checker *Checker, expression *ast.BinaryExpression, expected string, left, right Type) {
+	checker.reportError(
+		diagnostic.CodeInvalidOperand,
+		expression.Span,
+		"Operator %s needs %s, got %s and %s",
+		expression.Operator, expected, left, right,
+	)
+}
+
+func isNumeric(left, right Type) bool {
+	return isKind(left, NumberKind) && isKind(right, NumberKind)
+}
+
+func isString(left, right Type) bool {
+	return isKind(left, StringKind) && isKind(right, StringKind)
+}
+
+func visitUnaryExpression(checker *Checker, expression *ast.UnaryExpression) Type {
+	operand := checker.typeOf(expression.Right)
+
+	expected := Number
+	if expression.Operator == ast.OperatorNot {
+		expected = Boolean
+	}
+
+	if !IsAssignable(expected, operand) {
+		checker.reportError(
+			diagnostic.CodeInvalidOperand,
+			expression.Span,
+			"Operator %s needs a %s, got %s",
+			expression.Operator, expected, operand,
+		)
+	}
+	return expected
+}
+
+func visitLogicalExpression(checker *Checker, expression *ast.LogicalExpression) Type {
+	left := checker.typeOf(expression.Left)
+	right := checker.typeOf(expression.Right)
+
+	if !IsAssignable(Boolean, left) || !IsAssignable(Boolean, right) {
+		checker.reportError(
+			diagnostic.CodeInvalidOperand,
+			expression.Span,
+			"Operator %s needs two booleans, got %s and %s",
+			expression.Operator, left, right,
+		)
+	}
+	return Boolean
+}
+
+func visitTemplateLiteralExpression(checker *Checker, expression *ast.TemplateLiteralExpression) Type {
+	for _, part := range expression.Expressions {
+		if t := checker.typeOf(part); isKind(t, VoidKind) {
+			checker.reportError(diagnostic.CodeInvalidOperand, part.GetSpan(), "Cannot interpolate a void value")
+		}
+	}
+	return String
+}
+
+func visitIdentifierExpression(checker *Checker, expression *ast.IdentifierExpression) Type {
+	symbol := checker.info.Resolution.Uses[expression]
+	if symbol == nil {
+		// Already reported by the resolver
+		return Unknown
+	}
+
+	if symbol.Kind == resolver.SymbolClass {
+		checker.reportError(
+			diagnostic.CodeInvalidValue,
+			expression.Span,
+			"'%s' is a class, not a value, use 'new %s(...)' to create an instance",
+			symbol.Name, symbol.Name,
+		)
+		return Unknown
+	}
+
+	return checker.symbolType(symbol)
+}
+
+func visitMemberExpression(checker *Checker, expression *ast.MemberExpression) Type {
+	object := checker.typeOf(expression.Object)
+
+	if expression.Computed {
+		index := checker.typeOf(expression.Property)
+		if !IsAssignable(Number, index) {
+			checker.reportError(diagnostic.CodeInvalidOperand, expression.Property.GetSpan(), "Index must be a number, got %s", index)
+		}
+
+		switch object := object.(type) {
+		case *Array:
+			return object.Element
+		default:
+			if isUnknown(object) {
+				return object
+			}
+			checker.reportError(diagnostic.CodeInvalidOperand, expression.Object.GetSpan(), "Cannot index %s", object)
+			return Unknown
+		}
+	}
+
+	name := expression.Property.(*ast.IdentifierExpression).Name
+	switch object := object.(type) {
+	case *Class:
+		if field, ok := object.Field(name); ok {
+			return field
+		}
+		if method, ok := object.Method(name); ok {
+			return method
+		}
+		checker.reportError(
+			diagnostic.CodeUnknownMember,
+			expression.Property.GetSpan(),
+			"Class '%s' has no member '%s'",
+			object.Name, name,
+		)
+		return Unknown
+	case *Array:
+		if name == "length" {
+			return Number
+		}
+	default:
+		if isUnknown(object) {
+			return Unknown
+		}
+		if isKind(object, StringKind) && name == "length" {
+			return Number
+		}
+	}
+
+	checker.reportError(diagnostic.CodeUnknownMember, expression.Property.GetSpan(), "Type %s has no member '%s'", object, name)
+	return Unknown
+}
+
+func visitCallExpression(checker *Checker, expression *ast.CallExpression) Type {
+	if _, ok := expression.Callee.(*ast.SuperExpression); ok {
+		return visitSuperCall(checker, expression)
+	}
+
+	callee := checker.typeOf(expression.Callee)
+
+	switch callee := callee.(type) {
+	case *Function:
+		checkArguments(checker, callee, expression.Arguments, expression)
+		return callee.Return
+	default:
+		if !isUnknown(callee) {
+			checker.reportError(diagnostic.CodeNotCallable, expression.Callee.GetSpan(), "Cannot call a value of type %s", callee)
+		}
+		for _, argument := range expression.Arguments {
+			checker.typeOf(argument)
+		}
+		return Unknown
+	}
+}
+
+// visitSuperCall checks the call of the superclass constructor
+func visitSuperCall(checker *Checker, expression *ast.CallExpression) Type {
+	checker.info.Types[expression.Callee] = Unknown
+
+	switch {
+	case checker.function == nil || !checker.function.constructor:
+		checker.reportError(
+			diagnostic.CodeInvalidThisOrSuper,
+			expression.Callee.GetSpan(),
+			"The superclass constructor can only be called in a constructor",
+		)
+	case checker.class != nil && checker.class.Super != nil:
+		checker.info.Types[expression.Callee] = checker.class.Super
+		checkArguments(checker, checker.class.Super.ConstructorType(), expression.Arguments, expression)
+		return Void
+	}
+
+	for _, argument := range expression.Arguments {
+		checker.typeOf(argument)
+	}
+	return Void
+}
+
+func visitNewExpression(checker *Checker, expression *ast.NewExpression) Type {
+	if identifier, ok := expression.Callee.(*ast.IdentifierExpression); ok {
+		symbol := checker.info.Resolution.Uses[identifier]
+		if symbol != nil && symbol.Kind == resolver.SymbolClass {
+			class := checker.classOf(symbol)
+			checker.info.Types[identifier] = class
+			checkArguments(checker, class.ConstructorType(), expression.Arguments, expression)
+			return class
+		}
+	}
+
+	callee := checker.typeOf(expression.Callee)
+	if !isUnknown(callee) {
+		checker.reportError(diagnostic.CodeNotAClass, expression.Callee.GetSpan(), "Cannot instantiate a value of type %s", callee)
+	}
+	for _, argument := range expression.Arguments {
+		checker.typeOf(argument)
+	}
+	return Unknown
+}
+
+// checkArguments checks the number and the types of the arguments against the function parameters
+func checkArguments(checker *Checker, function *Function, arguments []ast.Expression, call ast.Expression) {
+	count := len(function.Params)
+	if function.Variadic {
+		if len(arguments) < count-1 {
+			checker.reportError(
+				diagnostic.CodeArgumentCount,
+				call.GetSpan(),
+				"Expected at least %d arguments, got %d",
+				count-1, len(arguments),
+			)
+		}
+	} else if len(arguments) != count {
+		checker.reportError(diagnostic.CodeArgumentCount, call.GetSpan(), "Expected %d arguments, got %d", count, len(arguments))
+	}
+
+	for i, argument := range arguments {
+		t := checker.typeOf(argument)
+
+		var param Type
+		switch {
+		case i < count-1 || (i < count && !function.Variadic):
+			param = function.Params[i]
+		case function.Variadic:
+			// The last parameter takes the remaining arguments
+			param = function.Params[count-1]
+		default:
+			continue
+		}
+		checker.expectAssignable(param, t, argument.GetSpan(), fmt.Sprintf("argument %d", i+1))
+	}
+}
@@ -0,0 +1,135 @@
+package checker
+
+import (
+	"fmt"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/diagnostic"
+)
+
+func visitStatement(checker *Checker, statement ast.Statement) {
+	switch statement.NodeType() {
+	case ast.NodeProgramStatement:
+		checkStatementList(checker, statement.(*ast.ProgramStatement).Body)
+	case ast.NodeBlockStatement:
+		checkStatementList(checker, statement.(*ast.BlockStatement).Body)
+	case ast.NodeEmptyStatement, ast.NodeErrorStatement:
+		// Nothing to check
+	case ast.NodeExpressionStatement:
+		checker.typeOf(statement.(*ast.ExpressionStatement).Expression)
+	case ast.NodeVariableDeclarationStatement:
+		for _, variable := range statement.(*ast.VariableDeclarationStatement).Variables {
+			checker.typeOf(variable)
+		}
+	case ast.NodeIfStatement:
+		visitIfStatement(checker, statement.(*ast.IfStatement))
+	case ast.NodeWhileStatement:
+		statement := statement.(*ast.WhileStatement)
+		checkCondition(checker, statement.Condition)
+		statement.Body.Accept(checker)
+	case ast.NodeDoWhileStatement:
+		statement := statement.(*ast.DoWhileStatement)
+		statement.Body.Accept(checker)
+		checkCondition(checker, statement.Condition)
+	case ast.NodeForStatement:
+		visitForStatement(checker, statement.(*ast.ForStatement))
+	case ast.NodeFunctionDeclarationStatement:
+		checker.checkFunction(statement.(*ast.FunctionDeclarationStatement), checker.class)
+	case ast.NodeReturnStatement:
+		visitReturnStatement(checker, statement.(*ast.ReturnStatement))
+	case ast.NodeClassDeclarationStatement:
+		visitClassDeclarationStatement(checker, statement.(*ast.ClassDeclarationStatement))
+	default:
+		panic(fmt.Errorf("unknown statement type: %T", statement))
+	}
+}
+
+func checkStatementList(checker *Checker, statements []ast.Statement) {
+	for _, statement := range statements {
+		statement.Accept(checker)
+	}
+}
+
+// checkCondition checks that the condition of a branch or a loop is a boolean
+func checkCondition(checker *Checker, condition ast.Expression) {
+	t := checker.typeOf(condition)
+	if !IsAssignable(Boolean, t) {
+		checker.reportError(diagnostic.CodeInvalidCondition, condition.GetSpan(), "Condition must be boolean, got %s", t)
+	}
+}
+
+func visitIfStatement(checker *Checker, statement *ast.IfStatement) {
+	checkCondition(checker, statement.Condition)
+	statement.Consequent.Accept(checker)
+
+	if statement.Alternative != nil {
+		statement.Alternative.Accept(checker)
+	}
+}
+
+func visitForStatement(checker *Checker, statement *ast.ForStatement) {
+	if statement.Initializer != nil {
+		statement.Initializer.Accept(checker)
+	}
+	if statement.Condition != nil {
+		checkCondition(checker, statement.Condition)
+	}
+	if statement.Increment != nil {
+		checker.typeOf(statement.Increment)
+	}
+	statement.Body.Accept(checker)
+}
+
+func visitReturnStatement(checker *Checker, statement *ast.ReturnStatement) {
+	var argument Type
+	if statement.Argument != nil {
+		argument = checker.typeOf(statement.Argument)
+	}
+
+	function := checker.function
+	switch {
+	case function == nil:
+		checker.reportError(diagnostic.CodeInvalidReturn, statement.Span, "Return is only allowed inside a function")
+	case function.constructor && argument != nil:
+		checker.reportError(diagnostic.CodeInvalidReturn, statement.Argument.GetSpan(), "Constructor can't return a value")
+	case isKind(function.signature.Return, VoidKind) && argument != nil:
+		checker.reportError(
+			diagnostic.CodeInvalidReturn,
+			statement.Argument.GetSpan(),
+			"Function '%s' returns void, it can't return a value",
+			function.declaration.Name.Name,
+		)
+	case !isKind(function.signature.Return, VoidKind) && argument == nil:
+		checker.reportError(
+			diagnostic.CodeInvalidReturn,
+			statement.Span,
+			"Function '%s' must return a value of type %s",
+			function.declaration.Name.Name, function.signature.Return,
+		)
+	case argument != nil:
+		checker.expectAssignable(
+			function.signature.Return,
+			argument,
+			statement.Argument.GetSpan(),
+			fmt.Sprintf("return of '%s'", function.declaration.Name.Name),
+		)
+	}
+}
+
+func visitClassDeclarationStatement(checker *Checker, statement *ast.ClassDeclarationStatement) {
+	symbol := checker.info.Resolution.Declarations[statement.Name]
+	class := checker.classOf(symbol)
+
+	enclosingClass := checker.class
+	checker.class = class
+
+	for _, member := range statement.Body.Body {
+		if function, ok := member.(*ast.FunctionDeclarationStatement); ok {
+			checker.checkFunction(function, class)
+			continue
+		}
+		member.Accept(checker)
+	}
+
+	checker.class = enclosingClass
+}
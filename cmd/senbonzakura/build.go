@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/yoh0xff/senbonzakura/wasm"
+)
+
+// runBuild compiles the program to a WebAssembly module, written as binary or as text for .wat outputs
+func runBuild(cli *cli, args []string) int {
+	flags := cli.newFlagSet("build", "[file]")
+	output := flags.String("o", "out.wasm", "output file, - for the standard output")
+	if ok, status := parseFlags(flags, args); !ok {
+		return status
+	}
+	file, source, ok := cli.readSource(flags.Arg(0))
+	if !ok {
+		return exitError
+	}
+
+	program, info, ok := cli.check(file, source)
+	if !ok {
+		return exitError
+	}
+	module, diagnostics := wasm.Compile(program, info)
+	if cli.printDiagnostics(diagnostics) {
+		return exitError
+	}
+
+	var content []byte
+	if strings.HasSuffix(*output, ".wat") {
+		printer := wasm.NewWATPrinterWithConfig(wasm.WATConfig{Pretty: true, IndentSize: 2})
+		printer.PrintModule(module)
+		content = []byte(printer.String() + "\n")
+	} else {
+		content = wasm.Encode(module)
+	}
+
+	if *output == "-" {
+		_, err := cli.stdout.Write(content)
+		if err != nil {
+			fmt.Fprintf(cli.stderr, "senbonzakura: %s\n", err)
+			return exitError
+		}
+		return exitOK
+	}
+	if err := os.WriteFile(*output, content, 0o644); err != nil {
+		fmt.Fprintf(cli.stderr, "senbonzakura: %s\n", err)
+		return exitError
+	}
+	return exitOK
+}
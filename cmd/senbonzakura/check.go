@@ -0,0 +1,38 @@
+package main
+
+import (
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/checker"
+	"github.com/yoh0xff/senbonzakura/parser"
+)
+
+// runCheck reports the diagnostics of the program, the exit status tells whether it has errors
+func runCheck(cli *cli, args []string) int {
+	flags := cli.newFlagSet("check", "[file]")
+	if ok, status := parseFlags(flags, args); !ok {
+		return status
+	}
+	file, source, ok := cli.readSource(flags.Arg(0))
+	if !ok {
+		return exitError
+	}
+
+	if _, _, ok := cli.check(file, source); !ok {
+		return exitError
+	}
+	return exitOK
+}
+
+// check parses and checks the program and prints its diagnostics, returns false if it has errors
+func (c *cli) check(file string, source string) (ast.Statement, *checker.Info, bool) {
+	program, diagnostics := parser.ParseRootStatement(parser.NewParserWithFile(file, source))
+	if c.printDiagnostics(diagnostics) {
+		return nil, nil, false
+	}
+
+	info, diagnostics := checker.Check(program, builtins...)
+	if c.printDiagnostics(diagnostics) {
+		return nil, nil, false
+	}
+	return program, info, true
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/yoh0xff/senbonzakura/formatter"
+)
+
+// runFmt prints the program in the canonical layout, writes it back to the file or checks it is formatted
+func runFmt(cli *cli, args []string) int {
+	flags := cli.newFlagSet("fmt", "[file]")
+	write := flags.Bool("w", false, "write the result to the file instead of the standard output")
+	check := flags.Bool("check", false, "print the file name and fail if the file is not formatted")
+	if ok, status := parseFlags(flags, args); !ok {
+		return status
+	}
+	file, source, ok := cli.readSource(flags.Arg(0))
+	if !ok {
+		return exitError
+	}
+	if *write && file == stdinName {
+		fmt.Fprintln(cli.stderr, "senbonzakura: -w needs a file")
+		return exitUsage
+	}
+
+	formatted, diagnostics := formatter.Format(file, source)
+	if cli.printDiagnostics(diagnostics) {
+		return exitError
+	}
+
+	switch {
+	case *check:
+		if formatted != source {
+			fmt.Fprintln(cli.stdout, file)
+			return exitError
+		}
+	case *write:
+		if formatted == source {
+			return exitOK
+		}
+		if err := os.WriteFile(file, []byte(formatted), 0o644); err != nil {
+			fmt.Fprintf(cli.stderr, "senbonzakura: %s\n", err)
+			return exitError
+		}
+	default:
+		fmt.Fprint(cli.stdout, formatted)
+	}
+	return exitOK
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/yoh0xff/senbonzakura/diagnostic"
+	"github.com/yoh0xff/senbonzakura/lexer"
+)
+
+// runLex prints one token per line with its position, type and text
+func runLex(cli *cli, args []string) int {
+	flags := cli.newFlagSet("lex", "[file]")
+	if ok, status := parseFlags(flags, args); !ok {
+		return status
+	}
+	file, source, ok := cli.readSource(flags.Arg(0))
+	if !ok {
+		return exitError
+	}
+
+	if cli.printDiagnostics(printTokens(cli.stdout, file, source)) {
+		return exitError
+	}
+	return exitOK
+}
+
+// printTokens prints the tokens of the source and returns the lexer diagnostics
+func printTokens(w io.Writer, file string, source string) []diagnostic.Diagnostic {
+	l := lexer.NewLexerWithFile(file, source)
+	for {
+		token := l.NextToken()
+		if token.TokenType == lexer.TokenEnd {
+			break
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", token.Span(), token.TokenType, strconv.Quote(source[token.Start:token.End]))
+	}
+	return l.Diagnostics()
+}
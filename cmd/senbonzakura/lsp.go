@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/yoh0xff/senbonzakura/lsp"
+)
+
+// runLsp serves the Language Server Protocol over the standard streams
+func runLsp(cli *cli, args []string) int {
+	flags := cli.newFlagSet("lsp", "")
+	if ok, status := parseFlags(flags, args); !ok {
+		return status
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	if err := lsp.NewServer(cli.stdin, cli.stdout).Run(); err != nil {
+		fmt.Fprintf(cli.stderr, "senbonzakura: %s\n", err)
+		return exitError
+	}
+	return exitOK
+}
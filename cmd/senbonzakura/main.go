@@ -0,0 +1,161 @@
+// Command senbonzakura lexes, parses, checks, runs and compiles programs
+//
+// Every command reads the file given as its last argument, or the standard input when the file is missing or "-".
+// Errors are reported as file:line:col and make the command exit with a non-zero status
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/yoh0xff/senbonzakura/checker"
+	"github.com/yoh0xff/senbonzakura/diagnostic"
+)
+
+// Exit statuses of the commands
+const (
+	exitOK    = 0
+	exitError = 1 // the program has errors or failed at runtime
+	exitUsage = 2 // the command line is invalid
+)
+
+// stdinName is the file name of sources read from the standard input
+const stdinName = "<stdin>"
+
+// command is a subcommand of the tool
+type command struct {
+	name        string
+	description string
+	run         func(cli *cli, args []string) int
+}
+
+// commands lists the subcommands in the order of the usage message
+var commands = []command{
+	{"lex", "print the tokens of a program with their positions", runLex},
+	{"parse", "print the syntax tree of a program", runParse},
+	{"fmt", "print a program in the canonical layout", runFmt},
+	{"check", "report the syntax, name and type errors of a program", runCheck},
+	{"run", "run a program", runRun},
+	{"build", "compile a program to a WebAssembly module", runBuild},
+	{"repl", "evaluate expressions and statements interactively", runRepl},
+	{"lsp", "serve the Language Server Protocol over the standard streams", runLsp},
+}
+
+// builtins are the names provided to every program
+var builtins = []checker.Builtin{{
+	Name: "print",
+	Type: &checker.Function{Params: []checker.Type{checker.Any}, Return: checker.Void, Variadic: true},
+}}
+
+// cli holds the standard streams of the commands
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line and returns the exit status
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	cli := &cli{stdin: stdin, stdout: stdout, stderr: stderr}
+	if len(args) == 0 {
+		cli.usage(stderr)
+		return exitUsage
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		cli.usage(stdout)
+		return exitOK
+	}
+	for _, command := range commands {
+		if command.name == args[0] {
+			return command.run(cli, args[1:])
+		}
+	}
+
+	fmt.Fprintf(stderr, "senbonzakura: unknown command '%s'\n", args[0])
+	cli.usage(stderr)
+	return exitUsage
+}
+
+// usage prints the list of commands
+func (c *cli) usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: senbonzakura <command> [flags] [file]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, command := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", command.name, command.description)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'senbonzakura <command> -h' for the flags of a command.")
+}
+
+// newFlagSet creates the flag set of a command, its usage message shows the arguments after the flags
+func (c *cli) newFlagSet(name string, arguments string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: senbonzakura %s [flags] %s\n", name, arguments)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseFlags parses the flags of a command followed by at most one file, when it fails it returns false
+// and the exit status
+func parseFlags(flags *flag.FlagSet, args []string) (bool, int) {
+	err := flags.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return false, exitOK
+	}
+	if err != nil {
+		return false, exitUsage
+	}
+	if flags.NArg() > 1 {
+		fmt.Fprintf(flags.Output(), "expected at most one file, got %d\n", flags.NArg())
+		flags.Usage()
+		return false, exitUsage
+	}
+	return true, exitOK
+}
+
+// readSource reads the named file, the standard input when the name is empty or "-"
+func (c *cli) readSource(name string) (file string, source string, ok bool) {
+	var content []byte
+	var err error
+	if name == "" || name == "-" {
+		file = stdinName
+		content, err = io.ReadAll(c.stdin)
+	} else {
+		file = name
+		content, err = os.ReadFile(file)
+	}
+	if err != nil {
+		fmt.Fprintf(c.stderr, "senbonzakura: %s\n", err)
+		return "", "", false
+	}
+	return file, string(content), true
+}
+
+// printDiagnostics prints the diagnostics in source order, one per line prefixed by their position
+//
+// Returns whether any of them is an error
+func (c *cli) printDiagnostics(diagnostics []diagnostic.Diagnostic) bool {
+	diagnostics = append([]diagnostic.Diagnostic{}, diagnostics...)
+	diagnostic.Sort(diagnostics)
+
+	for _, d := range diagnostics {
+		fmt.Fprintf(c.stderr, "%s: %s[%s]: %s\n", d.Span, d.Severity, d.Code, d.Message)
+		for _, note := range d.Notes {
+			fmt.Fprintf(c.stderr, "%s: note: %s\n", note.Span, note.Message)
+		}
+	}
+	return diagnostic.HasErrors(diagnostics)
+}
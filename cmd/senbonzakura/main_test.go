@@ -0,0 +1,172 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yoh0xff/senbonzakura/wasm"
+)
+
+func TestCommands(t *testing.T) {
+	directory := t.TempDir()
+	file := filepath.Join(directory, "main.sz")
+	err := os.WriteFile(file, []byte("let x: number = 1;\nprint(\"x is\", x + 1);\n"), 0o644)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		stdin  string
+		status int
+		stdout string
+		stderr string
+	}{
+		{"no command", nil, "", exitUsage, "", "Usage: senbonzakura"},
+		{"unknown command", []string{"fly"}, "", exitUsage, "", "unknown command 'fly'"},
+		{"help", []string{"help"}, "", exitOK, "Commands:", ""},
+		{"command help", []string{"run", "-h"}, "", exitOK, "", "-fuel"},
+		{"too many files", []string{"check", "a.sz", "b.sz"}, "", exitUsage, "", "at most one file"},
+		{"missing file", []string{"check", filepath.Join(directory, "missing.sz")}, "", exitError, "", "missing.sz"},
+
+		{"lex", []string{"lex", file}, "", exitOK, file + ":1:1\tTokenLetKeyword\t\"let\"\n", ""},
+		{"lex stdin", []string{"lex", "-"}, "x", exitOK, "<stdin>:1:1\tTokenIdentifier\t\"x\"\n", ""},
+		{"lex error", []string{"lex"}, "\"a", exitError, "", "<stdin>:1:1: error[L"},
+
+		{"parse", []string{"parse", "-compact"}, "x;", exitOK, "(program (expr (id x)))\n", ""},
+		{"parse pretty", []string{"parse"}, "x;", exitOK, "(program\n", ""},
+		{"parse json", []string{"parse", "-format=json", "-compact"}, "x;", exitOK,
+			`{"kind":"ProgramStatement","body":[{"kind":"ExpressionStatement","expression":{"kind":"IdentifierExpression","name":"x"`, ""},
+		{"parse positions", []string{"parse", "-compact", "-positions"}, "x;", exitOK, "(program :at 1:1 (expr :at 1:1 (id :at 1:1 x)))\n", ""},
+		{"parse types", []string{"parse", "-compact", "-types"}, "1;", exitOK, `(expr (number :type "number" 1))`, ""},
+		{"parse width", []string{"parse", "-width=16"}, "x;", exitOK, "(program\n  (expr (id x)))\n", ""},
+		{"parse unknown format", []string{"parse", "-format=xml"}, "x;", exitUsage, "", "unknown format"},
+		{"parse error", []string{"parse"}, "let x: number = ;", exitError, "", "<stdin>:1:17: error[P"},
+
+		{"fmt", []string{"fmt"}, "let  x:number=1 ;", exitOK, "let x: number = 1;\n", ""},
+		{"fmt check formatted", []string{"fmt", "-check", file}, "", exitOK, "", ""},
+		{"fmt check unformatted", []string{"fmt", "--check"}, "x ;", exitError, "<stdin>\n", ""},
+		{"fmt write stdin", []string{"fmt", "-w"}, "x;", exitUsage, "", "-w needs a file"},
+		{"fmt error", []string{"fmt"}, "let x: number = ;", exitError, "", "<stdin>:1:17: error[P"},
+
+		{"check", []string{"check", file}, "", exitOK, "", ""},
+		{"check type error", []string{"check"}, "\nlet x: number = \"a\";", exitError, "", "<stdin>:2:17: error[T0001]"},
+		{"check name error", []string{"check"}, "y;", exitError, "", "<stdin>:1:1: error[R"},
+
+		{"run", []string{"run", file}, "", exitOK, "x is 2\n", ""},
+		{"run compile error", []string{"run"}, "print(1 + \"a\");", exitError, "", "<stdin>:1:7: error[T"},
+		{"run runtime error", []string{"run"}, "def f(n: number): number { return f(n); }\nf(1);", exitError, "", "<stdin>:1:35: runtime error: Stack overflow"},
+		{"run fuel", []string{"run", "-fuel=100"}, "while (true) {}", exitError, "", "runtime error: Fuel exhausted"},
+
+		{"lsp without shutdown", []string{"lsp"}, "", exitError, "", "exit without shutdown"},
+
+		{"build unsupported", []string{"build", "-o", "-"}, "class A {}", exitError, "", "<stdin>:1:1: error[G0001]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			status := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+			if status != tt.status {
+				t.Errorf("Expected status %d, got %d (stderr %q)", tt.status, status, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.stdout) {
+				t.Errorf("Expected stdout containing %q, got %q", tt.stdout, stdout.String())
+			}
+			if !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("Expected stderr containing %q, got %q", tt.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+	directory := t.TempDir()
+	source := "def square(n: number): number { return n * n; }\nlet y: number = square(3);\n"
+
+	output := filepath.Join(directory, "out.wasm")
+	var stdout, stderr strings.Builder
+	if status := run([]string{"build", "-o", output}, strings.NewReader(source), &stdout, &stderr); status != exitOK {
+		t.Fatalf("Expected status 0, got %d (stderr %q)", status, stderr.String())
+	}
+	module, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := wasm.Validate(module); err != nil {
+		t.Errorf("Expected a valid module, got %v", err)
+	}
+
+	output = filepath.Join(directory, "out.wat")
+	if status := run([]string{"build", "-o", output}, strings.NewReader(source), &stdout, &stderr); status != exitOK {
+		t.Fatalf("Expected status 0, got %d (stderr %q)", status, stderr.String())
+	}
+	text, _ := os.ReadFile(output)
+	if !strings.Contains(string(text), `(export "square"`) {
+		t.Errorf("Expected the module as text, got %s", text)
+	}
+}
+
+func TestFmtWrite(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "main.sz")
+	if err := os.WriteFile(file, []byte("def f(){return;}"), 0o644); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var stdout, stderr strings.Builder
+	if status := run([]string{"fmt", "-w", file}, strings.NewReader(""), &stdout, &stderr); status != exitOK {
+		t.Fatalf("Expected status 0, got %d (stderr %q)", status, stderr.String())
+	}
+	content, _ := os.ReadFile(file)
+	if expected := "def f() {\n    return;\n}\n"; string(content) != expected || stdout.String() != "" {
+		t.Errorf("Expected the file to contain %q and no output, got %q and %q", expected, content, stdout.String())
+	}
+}
+
+func TestRepl(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		stdout []string
+		stderr string
+	}{
+		{"expression", "1 + 2\n", []string{"> 3\n"}, ""},
+		{"string", "\"a\" + \"b\";\n", []string{"\"ab\""}, ""},
+		{"state", "let x: number = 2;\nx * 3\n", []string{"(let (init (id x) (type Number) (number 2)))", "> 6\n"}, ""},
+		{"multi-line", "def f(n: number): number {\nreturn n + 1;\n}\nf(\n1\n)\n", []string{"> ... ... (def (id f)", "> ... ... 2\n"}, ""},
+		{"missing semicolon", "let x: number = 1\n;\nx\n", []string{"> ... (let", "> 1\n"}, ""},
+		{"empty line ends entry", "let x: number = 1\n\n", []string{"> ... > "}, "<repl>:3:1: error[P0001]"},
+		{"comment", "/* a\n*/ 5\n", []string{"> ... 5\n"}, ""},
+		{"syntax error", "1 + 2 )\n", []string{"> > "}, "<repl>:1:7: error[P0001]"},
+		{"runtime error", "def f(): number { return f(); }\nf()\n1\n", []string{"> 1\n"}, "<repl>:1:26: runtime error: Stack overflow"},
+		{"name error", "\nnope;\n1\n", []string{"> 1\n"}, "<repl>:1:1: error[R0001]: 'nope' is not declared"},
+		{"checked entry", "let y: string = 5;\n:type y\n", nil, "<repl>:1:17: error[T0001]: Cannot use number as string in initializer of 'y'\n<repl>:1:1: error[R0001]: 'y' is not declared"},
+		{"print", "print(1, 2)\n", []string{"> 1 2\n> "}, ""},
+		{"ast", ":ast x\n", []string{"(id x)"}, ""},
+		{"tokens", ":tokens let\n", []string{"<repl>:1:1\tTokenLetKeyword\t\"let\""}, ""},
+		{"type", "class A { def g(): string { return \"\"; } }\n:type new A().g\n", []string{"() -> string"}, ""},
+		{"type error", "let s: string = \"a\";\n:type s * 2\n", nil, "<repl>:1:1: error[T0002]"},
+		{"unknown command", ":fly\n", nil, "unknown command ':fly'"},
+		{"quit", ":quit\n1\n", []string{"> "}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			if status := run([]string{"repl"}, strings.NewReader(tt.input), &stdout, &stderr); status != exitOK {
+				t.Errorf("Expected status 0, got %d", status)
+			}
+			for _, expected := range tt.stdout {
+				if !strings.Contains(stdout.String(), expected) {
+					t.Errorf("Expected stdout containing %q, got %q", expected, stdout.String())
+				}
+			}
+			if !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("Expected stderr containing %q, got %q", tt.stderr, stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/checker"
+	"github.com/yoh0xff/senbonzakura/parser"
+	"github.com/yoh0xff/senbonzakura/visitor_s_expression"
+)
+
+// runParse prints the syntax tree of the program as an S-expression or as JSON
+func runParse(cli *cli, args []string) int {
+	flags := cli.newFlagSet("parse", "[file]")
+	format := flags.String("format", "sexpr", "output format, sexpr or json")
+	compact := flags.Bool("compact", false, "print the tree on a single line")
+	indent := flags.Int("indent", 2, "size of each indent level")
+	width := flags.Int("width", 0, "keep S-expression forms fitting in the width on one line, 0 breaks every form")
+	positions := flags.Bool("positions", false, "annotate S-expression forms with their line:col")
+	types := flags.Bool("types", false, "check the program and annotate S-expressions with their types")
+	color := flags.Bool("color", false, "colorize the S-expression output")
+	if ok, status := parseFlags(flags, args); !ok {
+		return status
+	}
+	if *format != "sexpr" && *format != "json" {
+		fmt.Fprintf(cli.stderr, "senbonzakura: unknown format '%s', expected sexpr or json\n", *format)
+		return exitUsage
+	}
+	file, source, ok := cli.readSource(flags.Arg(0))
+	if !ok {
+		return exitError
+	}
+
+	program, diagnostics := parser.ParseRootStatement(parser.NewParserWithFile(file, source))
+	if cli.printDiagnostics(diagnostics) {
+		return exitError
+	}
+	var info *checker.Info
+	if *types && *format == "sexpr" {
+		info, diagnostics = checker.Check(program, builtins...)
+		if cli.printDiagnostics(diagnostics) {
+			return exitError
+		}
+	}
+
+	switch *format {
+	case "json":
+		output, err := ast.MarshalJSON(program)
+		if err != nil {
+			fmt.Fprintf(cli.stderr, "senbonzakura: %s\n", err)
+			return exitError
+		}
+		if !*compact {
+			var indented bytes.Buffer
+			json.Indent(&indented, output, "", fmt.Sprintf("%*s", *indent, ""))
+			output = indented.Bytes()
+		}
+		fmt.Fprintln(cli.stdout, string(output))
+	default:
+		visitor := visitor_s_expression.NewSExpressionVisitorWithConfig(visitor_s_expression.SExpressionConfig{
+			Pretty:     !*compact,
+			IndentSize: *indent,
+			MaxWidth:   *width,
+			Positions:  *positions,
+			Types:      info,
+			Color:      *color,
+		})
+		program.Accept(visitor)
+		fmt.Fprintln(cli.stdout, visitor.String())
+	}
+	return exitOK
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/checker"
+	"github.com/yoh0xff/senbonzakura/diagnostic"
+	"github.com/yoh0xff/senbonzakura/interpreter"
+	"github.com/yoh0xff/senbonzakura/parser"
+	"github.com/yoh0xff/senbonzakura/source"
+	"github.com/yoh0xff/senbonzakura/visitor_s_expression"
+)
+
+// replFile is the file name of the entries typed in the REPL
+const replFile = "<repl>"
+
+// Prompts of the first line of an entry and of the following lines
+const (
+	replPrompt             = "> "
+	replContinuationPrompt = "... "
+)
+
+// replHelp describes the meta-commands
+const replHelp = `Enter an expression to print its value, or statements to run them.
+Incomplete entries continue on the next line, an empty line ends them.
+
+  :ast <code>      print the syntax tree of the code
+  :tokens <code>   print the tokens of the code
+  :type <expr>     print the type of the expression
+  :help            print this message
+  :quit            exit the REPL`
+
+// repl evaluates entries one after another, globals stay defined between entries
+type repl struct {
+	cli         *cli
+	interpreter *interpreter.Interpreter
+	history     strings.Builder // entries that ran, checked again with :type expressions
+}
+
+// runRepl reads entries from the standard input until :quit or the end of the input
+//
+// The file, if any, is run before the first entry
+func runRepl(cli *cli, args []string) int {
+	flags := cli.newFlagSet("repl", "[file]")
+	if ok, status := parseFlags(flags, args); !ok {
+		return status
+	}
+
+	r := &repl{
+		cli: cli,
+		interpreter: interpreter.NewInterpreterWithConfig(interpreter.Config{
+			Stdout:       cli.stdout,
+			MaxCallDepth: 1000,
+		}),
+	}
+	if flags.NArg() > 0 {
+		file, source, ok := cli.readSource(flags.Arg(0))
+		if !ok || !r.run(file, source) {
+			return exitError
+		}
+	}
+
+	scanner := bufio.NewScanner(cli.stdin)
+	var entry strings.Builder
+	fmt.Fprint(cli.stdout, replPrompt)
+	for scanner.Scan() {
+		line := scanner.Text()
+		continued := entry.Len() > 0
+		entry.WriteString(line)
+		entry.WriteString("\n")
+
+		if !(continued && strings.TrimSpace(line) == "") && r.incomplete(entry.String()) {
+			fmt.Fprint(cli.stdout, replContinuationPrompt)
+			continue
+		}
+
+		source := entry.String()
+		entry.Reset()
+		if !r.evaluate(source) {
+			return exitOK
+		}
+		fmt.Fprint(cli.stdout, replPrompt)
+	}
+	fmt.Fprintln(cli.stdout)
+
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(cli.stderr, "senbonzakura: %s\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// splitCommand splits a meta-command from its operand, the command is empty for code
+func splitCommand(entry string) (string, string) {
+	trimmed := strings.TrimSpace(entry)
+	if !strings.HasPrefix(trimmed, ":") {
+		return "", entry
+	}
+
+	end := strings.IndexFunc(trimmed, unicode.IsSpace)
+	if end < 0 {
+		return trimmed, ""
+	}
+	return trimmed[:end], trimmed[end+1:]
+}
+
+// incomplete checks whether the entry stops before the end of an expression or a statement
+//
+// An entry is incomplete when all of its syntax errors are at the end of the input, or when it ends inside a comment
+func (r *repl) incomplete(entry string) bool {
+	command, code := splitCommand(entry)
+	switch command {
+	case "":
+		if _, diagnostics := parser.ParseRootExpression(parser.NewParser(code)); !diagnostic.HasErrors(diagnostics) {
+			return false
+		}
+		_, diagnostics := parser.ParseRootStatement(parser.NewParser(code))
+		return endsEarly(code, diagnostics)
+	case ":ast":
+		return code != "" && r.incomplete(code)
+	case ":type":
+		_, diagnostics := parser.ParseRootExpression(parser.NewParser(code))
+		return code != "" && endsEarly(code, diagnostics)
+	default:
+		return false
+	}
+}
+
+// endsEarly checks whether the diagnostics only report errors at the end of the code
+func endsEarly(code string, diagnostics []diagnostic.Diagnostic) bool {
+	end := len(strings.TrimRightFunc(code, unicode.IsSpace))
+	found := false
+	for _, d := range diagnostics {
+		if d.Severity != diagnostic.SeverityError {
+			continue
+		}
+		if d.Code != diagnostic.CodeUnterminatedComment && d.Span.Start < end {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// evaluate runs a meta-command or code, returns false when the REPL must stop
+func (r *repl) evaluate(entry string) bool {
+	command, code := splitCommand(entry)
+	switch command {
+	case "":
+		if strings.TrimSpace(code) != "" {
+			r.run(replFile, code)
+		}
+	case ":quit", ":q":
+		return false
+	case ":help":
+		fmt.Fprintln(r.cli.stdout, replHelp)
+	case ":ast":
+		r.printTree(code)
+	case ":tokens":
+		r.cli.printDiagnostics(printTokens(r.cli.stdout, replFile, code))
+	case ":type":
+		r.printType(code)
+	default:
+		fmt.Fprintf(r.cli.stderr, "unknown command '%s', enter :help for the list of commands\n", command)
+	}
+	return true
+}
+
+// run evaluates an expression and prints its value, or runs statements and prints their S-expression
+//
+// The code is checked after the previous entries first. Returns false if the code has syntax, name or type errors
+// or fails at runtime
+func (r *repl) run(file string, code string) bool {
+	if expression, diagnostics := parser.ParseRootExpression(parser.NewParserWithFile(file, code)); !diagnostic.HasErrors(diagnostics) {
+		if _, _, ok := r.checkEntry(file, code+"\n;"); !ok {
+			return false
+		}
+		return r.printValue(expression)
+	}
+
+	program, diagnostics := parser.ParseRootStatement(parser.NewParserWithFile(file, code))
+	if r.cli.printDiagnostics(diagnostics) {
+		return false
+	}
+	if _, _, ok := r.checkEntry(file, code); !ok {
+		return false
+	}
+	body := program.(*ast.ProgramStatement).Body
+	if len(body) == 1 && body[0].NodeType() == ast.NodeExpressionStatement {
+		return r.printValue(body[0].(*ast.ExpressionStatement).Expression)
+	}
+
+	if err := r.interpreter.Run(program); err != nil {
+		r.printError(err)
+		return false
+	}
+	r.history.WriteString(code)
+	if !strings.HasSuffix(code, "\n") {
+		r.history.WriteString("\n")
+	}
+
+	if file != replFile {
+		return true
+	}
+	for _, statement := range body {
+		if statement.NodeType() == ast.NodeEmptyStatement {
+			continue
+		}
+		visitor := visitor_s_expression.NewSExpressionVisitor()
+		statement.Accept(visitor)
+		fmt.Fprintln(r.cli.stdout, visitor.String())
+	}
+	return true
+}
+
+// printValue evaluates the expression and prints its value, nothing is printed for calls without a result
+func (r *repl) printValue(expression ast.Expression) bool {
+	value, err := r.interpreter.Evaluate(expression)
+	if err != nil {
+		r.printError(err)
+		return false
+	}
+
+	switch value := value.(type) {
+	case nil:
+		if expression.NodeType() != ast.NodeCallExpression {
+			fmt.Fprintln(r.cli.stdout, "nil")
+		}
+	case string:
+		fmt.Fprintln(r.cli.stdout, strconv.Quote(value))
+	default:
+		fmt.Fprintln(r.cli.stdout, interpreter.Stringify(value))
+	}
+	return true
+}
+
+// printError prints a runtime error with its position
+func (r *repl) printError(err error) {
+	var runtimeError *interpreter.RuntimeError
+	if errors.As(err, &runtimeError) {
+		fmt.Fprintf(r.cli.stderr, "%s: runtime error: %s\n", runtimeError.Span, runtimeError.Message)
+		return
+	}
+	fmt.Fprintf(r.cli.stderr, "senbonzakura: %s\n", err)
+}
+
+// printTree prints the pretty S-expression of the code, an expression or statements
+func (r *repl) printTree(code string) {
+	visitor := visitor_s_expression.NewSExpressionVisitorWithConfig(visitor_s_expression.SExpressionConfig{
+		Pretty:     true,
+		IndentSize: 2,
+	})
+
+	if expression, diagnostics := parser.ParseRootExpression(parser.NewParserWithFile(replFile, code)); !diagnostic.HasErrors(diagnostics) {
+		expression.Accept(visitor)
+	} else {
+		program, diagnostics := parser.ParseRootStatement(parser.NewParserWithFile(replFile, code))
+		if r.cli.printDiagnostics(diagnostics) {
+			return
+		}
+		program.Accept(visitor)
+	}
+	fmt.Fprintln(r.cli.stdout, visitor.String())
+}
+
+// printType checks the expression after the previous entries and prints its type
+func (r *repl) printType(code string) {
+	if _, diagnostics := parser.ParseRootExpression(parser.NewParserWithFile(replFile, code)); r.cli.printDiagnostics(diagnostics) {
+		return
+	}
+
+	program, info, ok := r.checkEntry(replFile, code+"\n;")
+	if !ok {
+		return
+	}
+	statement := program.Body[len(program.Body)-1].(*ast.ExpressionStatement)
+	fmt.Fprintln(r.cli.stdout, info.TypeOf(statement.Expression))
+}
+
+// checkEntry checks the code after the previous entries with the builtins and prints its diagnostics like check
+//
+// Diagnostics of the previous entries are left out, those of the code are reported relative to it. Returns the
+// program of the previous entries and the code, and false if the code has errors
+func (r *repl) checkEntry(file string, code string) (*ast.ProgramStatement, *checker.Info, bool) {
+	history := r.history.String()
+	program, _ := parser.ParseRootStatement(parser.NewParserWithFile(file, history+code))
+	info, diagnostics := checker.Check(program, builtins...)
+
+	offset, lines := len(history), strings.Count(history, "\n")
+	relative := func(span source.Span) source.Span {
+		if span.Start >= offset {
+			span.Start -= offset
+			span.End -= offset
+			span.Line -= lines
+		}
+		return span
+	}
+	var own []diagnostic.Diagnostic
+	for _, d := range diagnostics {
+		if d.Span.Start < offset {
+			continue
+		}
+		d.Span = relative(d.Span)
+		notes := make([]diagnostic.Note, len(d.Notes))
+		for i, note := range d.Notes {
+			notes[i] = diagnostic.Note{Message: note.Message, Span: relative(note.Span)}
+		}
+		d.Notes = notes
+		own = append(own, d)
+	}
+	if r.cli.printDiagnostics(own) {
+		return nil, nil, false
+	}
+	return program.(*ast.ProgramStatement), info, true
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/yoh0xff/senbonzakura"
+)
+
+// runRun compiles the program to bytecode and runs it, print writes to the standard output
+func runRun(cli *cli, args []string) int {
+	flags := cli.newFlagSet("run", "[file]")
+	fuel := flags.Int64("fuel", 0, "maximum number of executed instructions, 0 for no limit")
+	maxHeap := flags.Int64("max-heap", 0, "maximum number of bytes allocated, 0 for no limit")
+	maxFrames := flags.Int("max-frames", 0, "maximum number of nested calls, 0 for the default")
+	timeout := flags.Duration("timeout", 0, "maximum running time, 0 for no limit")
+	if ok, status := parseFlags(flags, args); !ok {
+		return status
+	}
+	file, source, ok := cli.readSource(flags.Arg(0))
+	if !ok {
+		return exitError
+	}
+
+	program, err := senbonzakura.CompileWithConfig(source, senbonzakura.Config{
+		File:      file,
+		Stdout:    cli.stdout,
+		MaxFrames: *maxFrames,
+		Fuel:      *fuel,
+		MaxHeap:   *maxHeap,
+	})
+	var compileError *senbonzakura.CompileError
+	if errors.As(err, &compileError) {
+		cli.printDiagnostics(compileError.Diagnostics)
+		return exitError
+	}
+	if err != nil {
+		fmt.Fprintf(cli.stderr, "senbonzakura: %s\n", err)
+		return exitError
+	}
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	err = program.Run(ctx)
+	var runtimeError *senbonzakura.RuntimeError
+	if errors.As(err, &runtimeError) {
+		fmt.Fprintf(cli.stderr, "%s: runtime error: %s\n", runtimeError.Span, runtimeError.Message)
+		return exitError
+	}
+	if err != nil {
+		fmt.Fprintf(cli.stderr, "senbonzakura: %s\n", err)
+		return exitError
+	}
+	return exitOK
+}
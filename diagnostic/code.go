@@ -0,0 +1,53 @@
+package diagnostic
+
+// Code identifies the kind of diagnostic
+type Code string
+
+// Lexer diagnostics
+const (
+	CodeInvalidToken        Code = "L0001"
+	CodeUnterminatedComment Code = "L0002"
+	CodeUnterminatedString  Code = "L0003"
+)
+
+// Parser diagnostics
+const (
+	CodeUnexpectedToken         Code = "P0001"
+	CodeExpectedType            Code = "P0002"
+	CodeExpectedExpression      Code = "P0003"
+	CodeInvalidAssignmentTarget Code = "P0004"
+	CodeInvalidNumericLiteral   Code = "P0005"
+	CodeNumericLiteralOverflow  Code = "P0006"
+	CodeInvalidEscapeSequence   Code = "P0007"
+)
+
+// Resolver diagnostics
+const (
+	CodeUndeclaredName        Code = "R0001"
+	CodeDuplicateDeclaration  Code = "R0002"
+	CodeUsedBeforeDeclaration Code = "R0003"
+	CodeInvalidThisOrSuper    Code = "R0004"
+	CodeUnknownType           Code = "R0005"
+	CodeNotAClass             Code = "R0006"
+	CodeInvalidClassMember    Code = "R0007"
+)
+
+// Type checker diagnostics
+const (
+	CodeTypeMismatch     Code = "T0001"
+	CodeInvalidOperand   Code = "T0002"
+	CodeArgumentCount    Code = "T0003"
+	CodeNotCallable      Code = "T0004"
+	CodeUnknownMember    Code = "T0005"
+	CodeMissingReturn    Code = "T0006"
+	CodeInvalidReturn    Code = "T0007"
+	CodeInvalidValue     Code = "T0008"
+	CodeUnsupportedType  Code = "T0009"
+	CodeInvalidCondition Code = "T0010"
+)
+
+// Code generation diagnostics
+const (
+	CodeUnsupportedFeature Code = "G0001"
+	CodeCompilerLimit      Code = "G0002"
+)
@@ -0,0 +1,110 @@
+package diagnostic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yoh0xff/senbonzakura/source"
+)
+
+// Severity represents how serious a diagnostic is
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+// String returns a string representation of the Severity
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	default:
+		return fmt.Sprintf("Severity(%d)", s)
+	}
+}
+
+// Note is an additional message attached to a diagnostic
+type Note struct {
+	Message string
+	Span    source.Span
+}
+
+// Diagnostic represents a single problem found in the source code
+type Diagnostic struct {
+	Severity Severity
+	Code     Code
+	Message  string
+	Span     source.Span
+	Notes    []Note
+}
+
+// String returns a string representation of the Diagnostic
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s[%s] at %s: %s", d.Severity.String(), d.Code, d.Span.String(), d.Message)
+}
+
+// NewError creates an error diagnostic
+func NewError(code Code, span source.Span, format string, args ...any) Diagnostic {
+	return Diagnostic{
+		Severity: SeverityError,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+		Span:     span,
+	}
+}
+
+// WithNote returns a copy of the diagnostic with an additional note
+func (d Diagnostic) WithNote(span source.Span, format string, args ...any) Diagnostic {
+	d.Notes = append(append([]Note{}, d.Notes...), Note{
+		Message: fmt.Sprintf(format, args...),
+		Span:    span,
+	})
+	return d
+}
+
+// HasErrors checks if any of the diagnostics is an error
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Sort orders diagnostics by their position in the source, keeping the report order for equal positions
+func Sort(diagnostics []Diagnostic) {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return diagnostics[i].Span.Start < diagnostics[j].Span.Start
+	})
+}
+
+// Error wraps a list of diagnostics into an error value
+type Error struct {
+	Diagnostics []Diagnostic
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	messages := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		messages[i] = d.String()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// AsError returns an *Error if the diagnostics contain at least one error, nil otherwise
+func AsError(diagnostics []Diagnostic) error {
+	if !HasErrors(diagnostics) {
+		return nil
+	}
+	return &Error{Diagnostics: diagnostics}
+}
@@ -0,0 +1,60 @@
+package formatter
+
+import (
+	"github.com/yoh0xff/senbonzakura/ast"
+)
+
+// printComments prints the comments on their own lines, keeping one empty line before those starting before the
+// offset where the source had one
+//
+// Comments from inside a statement are printed above it, after the offset, and never separated
+func (p *Printer) printComments(comments []ast.Comment, offset int) {
+	for _, comment := range comments {
+		if comment.Span.Start < offset {
+			p.separate(comment.Span.Line)
+		}
+		p.writeIndent()
+		p.writeString(comment.Text)
+		p.writeString("\n")
+
+		// Comments from inside a statement may come from lines after it
+		p.lastLine = max(p.lastLine, p.endLine(comment.Span))
+		p.printed++
+	}
+}
+
+// printTrailingComments prints the comments at the end of the output line
+func (p *Printer) printTrailingComments(comments []ast.Comment) {
+	for _, comment := range comments {
+		p.writeString(" ")
+		p.writeString(comment.Text)
+		p.lastLine = p.endLine(comment.Span)
+		p.printed++
+	}
+}
+
+// braceComments removes and returns the comments on the line of the opening brace of the block, they lead its
+// first statement or dangle in the block when it is empty
+func (p *Printer) braceComments(block *ast.BlockStatement) []ast.Comment {
+	comments, owner := p.comments.Dangling, ast.Node(block)
+	if len(block.Body) > 0 {
+		comments, owner = p.comments.Leading, block.Body[0]
+	}
+
+	line := p.line(block.Span.Start)
+	count := 0
+	for count < len(comments[owner]) && comments[owner][count].Span.Line == line {
+		count++
+	}
+	if count == 0 {
+		return nil
+	}
+	result := comments[owner][:count]
+	comments[owner] = comments[owner][count:]
+	return result
+}
+
+// hasComments checks if comments are associated with the node
+func (p *Printer) hasComments(node ast.Node) bool {
+	return len(p.comments.Leading[node]) > 0 || len(p.comments.Trailing[node]) > 0 || len(p.comments.Dangling[node]) > 0
+}
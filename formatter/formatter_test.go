@@ -0,0 +1,119 @@
+package formatter
+
+import (
+	"testing"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/diagnostic"
+	"github.com/yoh0xff/senbonzakura/parser"
+)
+
+var formatTests = []struct {
+	name     string
+	source   string
+	expected string
+}{
+	{"spacing", "let a:number=1+2*3,b : string;", "let a: number = 1 + 2 * 3, b: string;\n"},
+	{"types", "let a: [number]; let m: Map[string,[boolean]];", "let a: [number];\nlet m: Map[string, [boolean]];\n"},
+	{"redundant parentheses", "x = ((a + (b * c)));", "x = a + b * c;\n"},
+	{"needed parentheses", "x = (a + b) * (c - d) / (e / f);", "x = (a + b) * (c - d) / (e / f);\n"},
+	{"left associative", "x = (1 - 2) - 3; y = 1 - (2 - 3);", "x = 1 - 2 - 3;\ny = 1 - (2 - 3);\n"},
+	{"logical", "x = a || b && c; y = (a || b) && c; z = a || (b || c);", "x = a || b && c;\ny = (a || b) && c;\nz = a || (b || c);\n"},
+	{"comparison", "x = (a < b) == (c >= d);", "x = a < b == c >= d;\n"},
+	{"assignment", "a = (b = 1); a += (b || c);", "a = b = 1;\na += b || c;\n"},
+	{"unary", "x = -(-a) + !(a == b) - (-1);", "x = -(-a) + !(a == b) - -1;\n"},
+	{"members and calls", "x = (f(1)).y + (a + b).c + (1).d + a.b[c + 1](2)(3) + new A().b;",
+		"x = (f(1)).y + (a + b).c + (1).d + a.b[c + 1](2)(3) + new A().b;\n"},
+	{"literals", "print('a\\n', \"b\", `c ${1+2} d`, 0x1F, 1.5e3, true, nil);",
+		"print('a\\n', \"b\", `c ${1 + 2} d`, 0x1F, 1.5e3, true, nil);\n"},
+	{"function", "def f(a:number,b:string):number{return a;} def g() { return; } def h(): void {}",
+		"def f(a: number, b: string): number {\n    return a;\n}\ndef g() {\n    return;\n}\ndef h(): void {}\n"},
+	{"class", "class A extends B{let x:number; def constructor(x: number){this.x=x;}}",
+		"class A extends B {\n    let x: number;\n    def constructor(x: number) {\n        this.x = x;\n    }\n}\n"},
+	{"if without blocks", "if (a) b(); else c();", "if (a) {\n    b();\n} else {\n    c();\n}\n"},
+	{"else if", "if (a) { b(); } else { if (c) { d(); } }", "if (a) {\n    b();\n} else if (c) {\n    d();\n}\n"},
+	{"loops", "while(a)a-=1; do{a+=1;}while(a<3); for(let i:number=0;i<3;i+=1){} for(;;){}",
+		"while (a) {\n    a -= 1;\n}\ndo {\n    a += 1;\n} while (a < 3);\nfor (let i: number = 0; i < 3; i += 1) {}\nfor (;;) {}\n"},
+	{"empty statements", ";{;}", ";\n{\n    ;\n}\n"},
+	{"blank lines", "a();\n\n\n\nb();\nc();\n", "a();\n\nb();\nc();\n"},
+	{"comments", "// header\n\nlet a: number; // trailing\n/* before */ b();\ndef f() { // brace\n  c(); /* after */\n  // last\n}\n// end",
+		"// header\n\nlet a: number; // trailing\n/* before */\nb();\ndef f() { // brace\n    c(); /* after */\n    // last\n}\n// end\n"},
+	{"comments inside expressions", "f(1, // one\n  2);\ng();",
+		"// one\nf(1, 2);\ng();\n"},
+	{"comments in headers", "def f(a: number, /* inline */ b: number) {}\ndef g() /* c */ { h(); }\nfor (/* a */;;/* b */) {}",
+		"def f(a: number, b: number) {\n    /* inline */\n}\ndef g() {\n    /* c */\n    h();\n}\nfor (;;) {\n    /* a */\n    /* b */\n}\n"},
+	{"comments in templates", "print(`a${ /* c */ x}`);", "/* c */\nprint(`a${x}`);\n"},
+	{"comment in empty block", "def f() {\n  // todo\n}", "def f() {\n    // todo\n}\n"},
+	{"comment before else", "if (a) {\n} // no\nelse if (b) {}", "if (a) {} else {\n    // no\n    if (b) {}\n}\n"},
+}
+
+func TestFormat(t *testing.T) {
+	for _, test := range formatTests {
+		formatted, diagnostics := Format("test", test.source)
+		if len(diagnostics) != 0 {
+			t.Errorf("%s: Expected no diagnostics, got %v", test.name, diagnostics)
+			continue
+		}
+		if formatted != test.expected {
+			t.Errorf("%s: Expected\n%s\ngot\n%s", test.name, test.expected, formatted)
+		}
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	for _, test := range formatTests {
+		formatted, _ := Format("test", test.source)
+		again, diagnostics := Format("test", formatted)
+		if len(diagnostics) != 0 {
+			t.Errorf("%s: Expected the formatted source to parse, got %v", test.name, diagnostics)
+			continue
+		}
+		if again != formatted {
+			t.Errorf("%s: Expected formatting to be idempotent, got\n%s\nthen\n%s", test.name, formatted, again)
+		}
+
+		original, _ := parser.ParseRootStatement(parser.NewParser(test.source))
+		reparsed, _ := parser.ParseRootStatement(parser.NewParser(formatted))
+		if !ast.Equal(original, reparsed) {
+			t.Errorf("%s: Expected the formatted source to parse to the same tree", test.name)
+		}
+	}
+}
+
+func TestFormatSyntaxError(t *testing.T) {
+	formatted, diagnostics := Format("test", "let a: number = ;")
+
+	if formatted != "" || !diagnostic.HasErrors(diagnostics) {
+		t.Errorf("Expected syntax errors and no output, got %q and %v", formatted, diagnostics)
+	}
+}
+
+func TestPrinterWithoutSource(t *testing.T) {
+	tree := &ast.ExpressionStatement{Expression: &ast.BinaryExpression{
+		Operator: ast.OperatorMultiply,
+		Left: &ast.BinaryExpression{
+			Operator: ast.OperatorAdd,
+			Left:     &ast.IdentifierExpression{Name: "a"},
+			Right:    &ast.NumericLiteralExpression{Kind: ast.IntegerLiteral, IntValue: 1, FloatValue: 1},
+		},
+		Right: &ast.StringLiteralExpression{Value: "b\n"},
+	}}
+
+	printer := NewPrinterWithConfig(Config{IndentSize: 2})
+	tree.Accept(printer)
+
+	if expected := `(a + 1) * "b\n";`; printer.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, printer.String())
+	}
+}
+
+func TestPrinterWithoutComments(t *testing.T) {
+	program, _ := parser.ParseRootStatement(parser.NewParser("while (a) { f(); } def g() {}"))
+
+	printer := NewPrinter()
+	program.Accept(printer)
+
+	if expected := "while (a) {\n    f();\n}\ndef g() {}\n"; printer.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, printer.String())
+	}
+}
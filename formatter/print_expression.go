@@ -0,0 +1,260 @@
+package formatter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+)
+
+// Precedence levels of expressions, from the loosest to the tightest binding
+const (
+	precedenceAssignment = iota
+	precedenceOr
+	precedenceAnd
+	precedenceEquality
+	precedenceRelational
+	precedenceAdditive
+	precedenceFactor
+	precedenceUnary
+	precedenceCall
+	precedencePrimary // member expressions, new expressions, literals and names
+)
+
+func printExpression(p *Printer, expression ast.Expression) {
+	switch expression.NodeType() {
+	case ast.NodeVariableExpression:
+		printVariableExpression(p, expression.(*ast.VariableExpression))
+	case ast.NodeAssignmentExpression:
+		printAssignmentExpression(p, expression.(*ast.AssignmentExpression))
+	case ast.NodeBinaryExpression:
+		printBinaryExpression(p, expression.(*ast.BinaryExpression))
+	case ast.NodeUnaryExpression:
+		printUnaryExpression(p, expression.(*ast.UnaryExpression))
+	case ast.NodeLogicalExpression:
+		printLogicalExpression(p, expression.(*ast.LogicalExpression))
+	case ast.NodeBooleanLiteralExpression:
+		p.writeString(strconv.FormatBool(expression.(*ast.BooleanLiteralExpression).Value))
+	case ast.NodeNilLiteralExpression:
+		p.writeString("nil")
+	case ast.NodeStringLiteralExpression:
+		printStringLiteralExpression(p, expression.(*ast.StringLiteralExpression))
+	case ast.NodeNumericLiteralExpression:
+		printNumericLiteralExpression(p, expression.(*ast.NumericLiteralExpression))
+	case ast.NodeIdentifierExpression:
+		p.writeString(expression.(*ast.IdentifierExpression).Name)
+	case ast.NodeMemberExpression:
+		printMemberExpression(p, expression.(*ast.MemberExpression))
+	case ast.NodeCallExpression:
+		printCallExpression(p, expression.(*ast.CallExpression))
+	case ast.NodeThisExpression:
+		p.writeString("this")
+	case ast.NodeSuperExpression:
+		p.writeString("super")
+	case ast.NodeNewExpression:
+		printNewExpression(p, expression.(*ast.NewExpression))
+	case ast.NodeTemplateLiteralExpression:
+		printTemplateLiteralExpression(p, expression.(*ast.TemplateLiteralExpression))
+	default:
+		panic(fmt.Errorf("unknown expression type: %T", expression))
+	}
+}
+
+// precedence returns the precedence level of the expression
+func precedence(expression ast.Expression) int {
+	switch expression := expression.(type) {
+	case *ast.VariableExpression, *ast.AssignmentExpression:
+		return precedenceAssignment
+	case *ast.LogicalExpression:
+		if expression.Operator == ast.OperatorOr {
+			return precedenceOr
+		}
+		return precedenceAnd
+	case *ast.BinaryExpression:
+		return binaryPrecedence(expression.Operator)
+	case *ast.UnaryExpression:
+		return precedenceUnary
+	case *ast.CallExpression:
+		return precedenceCall
+	default:
+		return precedencePrimary
+	}
+}
+
+// binaryPrecedence returns the precedence level of the binary operator
+func binaryPrecedence(operator ast.BinaryOperator) int {
+	switch operator {
+	case ast.OperatorEqual, ast.OperatorNotEqual:
+		return precedenceEquality
+	case ast.OperatorGreaterThan, ast.OperatorGreaterThanOrEqualTo, ast.OperatorLessThan, ast.OperatorLessThanOrEqualTo:
+		return precedenceRelational
+	case ast.OperatorAdd, ast.OperatorSubtract:
+		return precedenceAdditive
+	default:
+		return precedenceFactor
+	}
+}
+
+// printOperand prints the expression, in parentheses if it binds looser than the level
+func printOperand(p *Printer, expression ast.Expression, level int) {
+	if precedence(expression) < level {
+		p.writeString("(")
+		printExpression(p, expression)
+		p.writeString(")")
+		return
+	}
+	printExpression(p, expression)
+}
+
+func printVariableExpression(p *Printer, expression *ast.VariableExpression) {
+	printExpression(p, expression.Identifier)
+	p.writeString(": " + typeString(expression.TypeAnnotation))
+	if expression.Initializer != nil {
+		p.writeString(" = ")
+		printOperand(p, expression.Initializer, precedenceAssignment)
+	}
+}
+
+// printAssignmentExpression prints the assignment, which is right associative
+func printAssignmentExpression(p *Printer, expression *ast.AssignmentExpression) {
+	printOperand(p, expression.Left, precedencePrimary)
+	p.writeString(" " + expression.Operator.String() + " ")
+	printOperand(p, expression.Right, precedenceAssignment)
+}
+
+// printBinaryExpression prints the operation, which is left associative
+func printBinaryExpression(p *Printer, expression *ast.BinaryExpression) {
+	level := binaryPrecedence(expression.Operator)
+	printOperand(p, expression.Left, level)
+	p.writeString(" " + expression.Operator.String() + " ")
+	printOperand(p, expression.Right, level+1)
+}
+
+// printLogicalExpression prints the operation, which is left associative
+func printLogicalExpression(p *Printer, expression *ast.LogicalExpression) {
+	level := precedence(expression)
+	printOperand(p, expression.Left, level)
+	p.writeString(" " + expression.Operator.String() + " ")
+	printOperand(p, expression.Right, level+1)
+}
+
+// printUnaryExpression prints the operation, a nested sign gets parentheses so '- -x' reads as '-(-x)'
+func printUnaryExpression(p *Printer, expression *ast.UnaryExpression) {
+	p.writeString(expression.Operator.String())
+	if operand, ok := expression.Right.(*ast.UnaryExpression); ok && isSign(expression.Operator) && isSign(operand.Operator) {
+		printOperand(p, expression.Right, precedencePrimary)
+		return
+	}
+	printOperand(p, expression.Right, precedenceUnary)
+}
+
+// isSign checks if the unary operator is a sign
+func isSign(operator ast.UnaryOperator) bool {
+	return operator == ast.OperatorPlus || operator == ast.OperatorMinus
+}
+
+func printStringLiteralExpression(p *Printer, expression *ast.StringLiteralExpression) {
+	if expression.Raw == "" {
+		p.writeString(strconv.Quote(expression.Value))
+		return
+	}
+	p.writeString(expression.Raw)
+}
+
+func printNumericLiteralExpression(p *Printer, expression *ast.NumericLiteralExpression) {
+	switch {
+	case expression.Raw != "":
+		p.writeString(expression.Raw)
+	case expression.Kind == ast.IntegerLiteral:
+		p.writeString(strconv.FormatInt(expression.IntValue, 10))
+	default:
+		p.writeString(strconv.FormatFloat(expression.FloatValue, 'g', -1, 64))
+	}
+}
+
+// printMemberExpression prints the property access, calls and numbers as objects need parentheses
+func printMemberExpression(p *Printer, expression *ast.MemberExpression) {
+	if _, ok := expression.Object.(*ast.NumericLiteralExpression); ok {
+		p.writeString("(")
+		printExpression(p, expression.Object)
+		p.writeString(")")
+	} else {
+		printOperand(p, expression.Object, precedencePrimary)
+	}
+
+	if expression.Computed {
+		p.writeString("[")
+		printOperand(p, expression.Property, precedenceAssignment)
+		p.writeString("]")
+		return
+	}
+	p.writeString(".")
+	printExpression(p, expression.Property)
+}
+
+func printCallExpression(p *Printer, expression *ast.CallExpression) {
+	printOperand(p, expression.Callee, precedenceCall)
+	printArguments(p, expression.Arguments)
+}
+
+func printNewExpression(p *Printer, expression *ast.NewExpression) {
+	p.writeString("new ")
+	printOperand(p, expression.Callee, precedencePrimary)
+	printArguments(p, expression.Arguments)
+}
+
+// printArguments prints the arguments of a call in parentheses
+func printArguments(p *Printer, arguments []ast.Expression) {
+	p.writeString("(")
+	for i, argument := range arguments {
+		if i > 0 {
+			p.writeString(", ")
+		}
+		printOperand(p, argument, precedenceAssignment)
+	}
+	p.writeString(")")
+}
+
+// printTemplateLiteralExpression prints the template with its chunks as written
+func printTemplateLiteralExpression(p *Printer, expression *ast.TemplateLiteralExpression) {
+	p.writeString("`")
+	for i, chunk := range expression.Chunks {
+		p.writeString(chunk)
+		if i < len(expression.Expressions) {
+			p.writeString("${")
+			printOperand(p, expression.Expressions[i], precedenceAssignment)
+			p.writeString("}")
+		}
+	}
+	p.writeString("`")
+}
+
+// typeString returns the type annotation in source syntax
+func typeString(t ast.Type) string {
+	switch t := t.(type) {
+	case *ast.PrimitiveType:
+		switch t.Kind {
+		case ast.NumberType:
+			return "number"
+		case ast.BooleanType:
+			return "boolean"
+		case ast.StringType:
+			return "string"
+		}
+	case *ast.VoidType:
+		return "void"
+	case *ast.ClassType:
+		return t.Name
+	case *ast.ArrayType:
+		return "[" + typeString(t.ElementType) + "]"
+	case *ast.GenericType:
+		arguments := make([]string, len(t.TypeArgs))
+		for i, argument := range t.TypeArgs {
+			arguments[i] = typeString(argument)
+		}
+		return t.Base + "[" + strings.Join(arguments, ", ") + "]"
+	}
+	// Function types have no source syntax
+	return t.String()
+}
@@ -0,0 +1,212 @@
+package formatter
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+)
+
+func printStatement(p *Printer, statement ast.Statement) {
+	switch statement.NodeType() {
+	case ast.NodeProgramStatement:
+		printProgramStatement(p, statement.(*ast.ProgramStatement))
+	case ast.NodeBlockStatement:
+		printBlockStatement(p, statement.(*ast.BlockStatement))
+	case ast.NodeEmptyStatement:
+		p.writeString(";")
+	case ast.NodeExpressionStatement:
+		printExpressionStatement(p, statement.(*ast.ExpressionStatement))
+	case ast.NodeVariableDeclarationStatement:
+		printVariableDeclarationStatement(p, statement.(*ast.VariableDeclarationStatement))
+		p.writeString(";")
+	case ast.NodeIfStatement:
+		printIfStatement(p, statement.(*ast.IfStatement))
+	case ast.NodeWhileStatement:
+		printWhileStatement(p, statement.(*ast.WhileStatement))
+	case ast.NodeDoWhileStatement:
+		printDoWhileStatement(p, statement.(*ast.DoWhileStatement))
+	case ast.NodeForStatement:
+		printForStatement(p, statement.(*ast.ForStatement))
+	case ast.NodeFunctionDeclarationStatement:
+		printFunctionDeclarationStatement(p, statement.(*ast.FunctionDeclarationStatement))
+	case ast.NodeReturnStatement:
+		printReturnStatement(p, statement.(*ast.ReturnStatement))
+	case ast.NodeClassDeclarationStatement:
+		printClassDeclarationStatement(p, statement.(*ast.ClassDeclarationStatement))
+	case ast.NodeErrorStatement:
+		// The source of statements that failed to parse is unknown, they print as empty statements
+		p.writeString(";")
+	default:
+		panic(fmt.Errorf("unknown statement type: %T", statement))
+	}
+}
+
+func printProgramStatement(p *Printer, statement *ast.ProgramStatement) {
+	p.lastLine = 0
+	printStatementList(p, statement.Body)
+	p.printComments(p.comments.Dangling[statement], math.MaxInt)
+}
+
+// printStatementList prints each statement on its own lines at the current indent level, with its comments
+func printStatementList(p *Printer, statements []ast.Statement) {
+	for _, statement := range statements {
+		span := statement.GetSpan()
+		p.printComments(p.comments.Leading[statement], span.Start)
+		p.separate(p.line(span.Start))
+
+		p.writeIndent()
+		statement.Accept(p)
+
+		p.lastLine = p.endLine(span)
+		p.printTrailingComments(p.comments.Trailing[statement])
+		p.writeString("\n")
+	}
+}
+
+// printBlockStatement prints the braces on the lines of the enclosing statement and the body indented between
+func printBlockStatement(p *Printer, statement *ast.BlockStatement) {
+	leading, dangling := p.comments.Leading[statement], p.comments.Dangling[statement]
+	if len(statement.Body) == 0 && len(leading) == 0 && len(dangling) == 0 {
+		p.writeString("{}")
+		return
+	}
+
+	// Comments on the line of the opening brace stay there
+	p.writeString("{")
+	p.lastLine = p.line(statement.Span.Start)
+	p.printTrailingComments(p.braceComments(statement))
+	p.writeString("\n")
+
+	p.indentLevel++
+	p.printComments(leading, 0)
+	printStatementList(p, statement.Body)
+	p.printComments(p.comments.Dangling[statement], math.MaxInt)
+	p.indentLevel--
+
+	p.writeIndent()
+	p.writeString("}")
+}
+
+func printExpressionStatement(p *Printer, statement *ast.ExpressionStatement) {
+	printOperand(p, statement.Expression, precedenceAssignment)
+	p.writeString(";")
+}
+
+// printVariableDeclarationStatement prints the declaration without its ';', for loops print it in their header
+func printVariableDeclarationStatement(p *Printer, statement *ast.VariableDeclarationStatement) {
+	p.writeString("let ")
+	for i, variable := range statement.Variables {
+		if i > 0 {
+			p.writeString(", ")
+		}
+		printExpression(p, variable)
+	}
+}
+
+func printIfStatement(p *Printer, statement *ast.IfStatement) {
+	p.writeString("if (")
+	printOperand(p, statement.Condition, precedenceAssignment)
+	p.writeString(") ")
+	printBlockStatement(p, statement.Consequent)
+
+	if statement.Alternative == nil {
+		return
+	}
+	p.writeString(" else ")
+
+	// A block holding only an if statement is printed as 'else if' unless comments are in the way
+	alternative := statement.Alternative
+	if len(alternative.Body) == 1 {
+		nested, ok := alternative.Body[0].(*ast.IfStatement)
+		if ok && !p.hasComments(alternative) && !p.hasComments(nested) {
+			printIfStatement(p, nested)
+			return
+		}
+	}
+	printBlockStatement(p, alternative)
+}
+
+func printWhileStatement(p *Printer, statement *ast.WhileStatement) {
+	p.writeString("while (")
+	printOperand(p, statement.Condition, precedenceAssignment)
+	p.writeString(") ")
+	printBlockStatement(p, statement.Body)
+}
+
+func printDoWhileStatement(p *Printer, statement *ast.DoWhileStatement) {
+	p.writeString("do ")
+	printBlockStatement(p, statement.Body)
+	p.writeString(" while (")
+	printOperand(p, statement.Condition, precedenceAssignment)
+	p.writeString(");")
+}
+
+func printForStatement(p *Printer, statement *ast.ForStatement) {
+	p.writeString("for (")
+	switch initializer := statement.Initializer.(type) {
+	case *ast.VariableDeclarationStatement:
+		printVariableDeclarationStatement(p, initializer)
+	case *ast.ExpressionStatement:
+		printOperand(p, initializer.Expression, precedenceAssignment)
+	}
+	p.writeString(";")
+	if statement.Condition != nil {
+		p.writeString(" ")
+		printOperand(p, statement.Condition, precedenceAssignment)
+	}
+	p.writeString(";")
+	if statement.Increment != nil {
+		p.writeString(" ")
+		printOperand(p, statement.Increment, precedenceAssignment)
+	}
+	p.writeString(") ")
+	printBlockStatement(p, statement.Body)
+}
+
+func printFunctionDeclarationStatement(p *Printer, statement *ast.FunctionDeclarationStatement) {
+	p.writeString("def ")
+	printExpression(p, statement.Name)
+
+	p.writeString("(")
+	for i, parameter := range statement.Parameters {
+		if i > 0 {
+			p.writeString(", ")
+		}
+		printExpression(p, parameter.Name)
+		p.writeString(": " + typeString(parameter.Type))
+	}
+	p.writeString(")")
+
+	if statement.ReturnType != nil && !isImplicitVoid(statement.ReturnType) {
+		p.writeString(": " + typeString(statement.ReturnType))
+	}
+	p.writeString(" ")
+	printBlockStatement(p, statement.Body)
+}
+
+// isImplicitVoid checks if the return type was left out, the parser then makes a void type at the ')'
+func isImplicitVoid(t ast.Type) bool {
+	void, ok := t.(*ast.VoidType)
+	return ok && void.Span.End-void.Span.Start != len("void")
+}
+
+func printReturnStatement(p *Printer, statement *ast.ReturnStatement) {
+	p.writeString("return")
+	if statement.Argument != nil {
+		p.writeString(" ")
+		printOperand(p, statement.Argument, precedenceAssignment)
+	}
+	p.writeString(";")
+}
+
+func printClassDeclarationStatement(p *Printer, statement *ast.ClassDeclarationStatement) {
+	p.writeString("class ")
+	printExpression(p, statement.Name)
+	if statement.SuperClass != nil {
+		p.writeString(" extends ")
+		printExpression(p, statement.SuperClass)
+	}
+	p.writeString(" ")
+	printBlockStatement(p, statement.Body)
+}
@@ -0,0 +1,126 @@
+// Package formatter prints syntax trees back to source text in the canonical layout
+//
+// Statements go on their own lines with blocks indented, operators are surrounded by single spaces and
+// parentheses are only kept where precedence requires them. Formatting a formatted program gives the same text
+package formatter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/diagnostic"
+	"github.com/yoh0xff/senbonzakura/parser"
+	"github.com/yoh0xff/senbonzakura/source"
+)
+
+// Config defines formatting options for the printer
+type Config struct {
+	IndentSize int // number of spaces of each indent level
+}
+
+// Printer walks the AST and outputs source text
+type Printer struct {
+	config      Config
+	indentLevel int
+	buffer      strings.Builder
+
+	// Known only when formatting source text, see Format
+	lines    *source.LineTable
+	comments ast.CommentMap
+	printed  int // number of comments printed
+	lastLine int // source line of the last statement or comment printed, 0 at the start of a list
+}
+
+// NewPrinter creates a new printer with default configuration
+func NewPrinter() *Printer {
+	return NewPrinterWithConfig(Config{
+		IndentSize: 4,
+	})
+}
+
+// NewPrinterWithConfig creates a new printer with the given configuration
+func NewPrinterWithConfig(config Config) *Printer {
+	return &Printer{
+		config:      config,
+		indentLevel: 0,
+		buffer:      strings.Builder{},
+	}
+}
+
+// VisitStatement implements the ast.Visitor interface
+func (p *Printer) VisitStatement(statement ast.Statement) {
+	printStatement(p, statement)
+}
+
+// VisitExpression implements the ast.Visitor interface
+func (p *Printer) VisitExpression(expression ast.Expression) {
+	printExpression(p, expression)
+}
+
+// String returns the printed source text
+func (p *Printer) String() string {
+	return p.buffer.String()
+}
+
+// Format parses the source and prints it in the canonical layout with the default configuration
+func Format(file string, sourceText string) (string, []diagnostic.Diagnostic) {
+	return FormatWithConfig(file, sourceText, NewPrinter().config)
+}
+
+// FormatWithConfig parses the source and prints it in the canonical layout, comments are kept
+//
+// Comments always print on their own lines or at the end of one, never between the tokens of a statement.
+// Comments inside a statement and out of its blocks, like among the parameters of a function, in a for header or
+// in a condition, move to the top of the next block of the statement, or above the statement when no block
+// follows them, like in a template literal of an expression statement.
+// Sources with syntax errors are not formatted, their diagnostics are returned instead
+func FormatWithConfig(file string, sourceText string, config Config) (string, []diagnostic.Diagnostic) {
+	p := parser.NewParserWithConfig(sourceText, parser.Config{File: file, Comments: true})
+	program, diagnostics := parser.ParseRootStatement(p)
+	if diagnostic.HasErrors(diagnostics) {
+		return "", diagnostics
+	}
+
+	printer := NewPrinterWithConfig(config)
+	printer.lines = p.Lines()
+	printer.comments = ast.NewCommentMap(p.Lines(), program, p.Comments())
+	program.Accept(printer)
+
+	if printer.printed != len(p.Comments()) {
+		panic(fmt.Errorf("formatter: %d comments were not printed", len(p.Comments())-printer.printed))
+	}
+	return printer.String(), diagnostics
+}
+
+// writeIndent writes the indentation of the current level
+func (p *Printer) writeIndent() {
+	p.buffer.WriteString(strings.Repeat(" ", p.indentLevel*p.config.IndentSize))
+}
+
+// writeString writes a string to the output
+func (p *Printer) writeString(s string) {
+	p.buffer.WriteString(s)
+}
+
+// line returns the source line of the offset, 0 when formatting a tree without source
+func (p *Printer) line(offset int) int {
+	if p.lines == nil {
+		return 0
+	}
+	line, _ := p.lines.Position(offset)
+	return line
+}
+
+// endLine returns the source line of the last character of the span
+func (p *Printer) endLine(span source.Span) int {
+	return p.line(max(span.Start, span.End-1))
+}
+
+// separate writes an empty line if the source had at least one between the last item and the line,
+// longer runs of empty lines are collapsed into one
+func (p *Printer) separate(line int) {
+	if p.lastLine > 0 && line > p.lastLine+1 {
+		p.writeString("\n")
+	}
+}
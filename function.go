@@ -0,0 +1,116 @@
+package senbonzakura
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/yoh0xff/senbonzakura/checker"
+	"github.com/yoh0xff/senbonzakura/vm"
+)
+
+var (
+	contextType = reflect.TypeFor[context.Context]()
+	errorType   = reflect.TypeFor[error]()
+)
+
+// wrapFunction turns a Go function into a native function and derives its script signature
+//
+// The function can take a context.Context first, it receives the context of the call. It can return nothing,
+// a value, an error, or a value and an error. Returned errors stop the script with a runtime error
+func (p *Program) wrapFunction(name string, fn any) (*vm.Native, checker.Type, error) {
+	function := reflect.ValueOf(fn)
+	if function.Kind() != reflect.Func || function.IsNil() {
+		return nil, nil, fmt.Errorf("senbonzakura: '%s' is a %T, not a function", name, fn)
+	}
+	functionType := function.Type()
+
+	withContext := functionType.NumIn() > 0 && functionType.In(0) == contextType
+	variadic := functionType.IsVariadic()
+	signature := &checker.Function{Return: checker.Void, Variadic: variadic}
+
+	var params []reflect.Type
+	for i := range functionType.NumIn() {
+		if i == 0 && withContext {
+			continue
+		}
+		param := functionType.In(i)
+		if variadic && i == functionType.NumIn()-1 {
+			param = param.Elem()
+		}
+		params = append(params, param)
+		signature.Params = append(signature.Params, scriptType(param, checker.Any))
+	}
+
+	returnsValue, returnsError := false, false
+	switch functionType.NumOut() {
+	case 0:
+	case 1:
+		returnsError = functionType.Out(0) == errorType
+		returnsValue = !returnsError
+	case 2:
+		if functionType.Out(1) != errorType {
+			return nil, nil, fmt.Errorf("senbonzakura: the second result of '%s' must be an error", name)
+		}
+		returnsValue, returnsError = true, true
+	default:
+		return nil, nil, fmt.Errorf("senbonzakura: '%s' must return at most a value and an error", name)
+	}
+	if returnsValue {
+		signature.Return = scriptType(functionType.Out(0), checker.Unknown)
+	}
+
+	arity := len(params)
+	if variadic {
+		arity = -1
+	}
+
+	native := &vm.Native{
+		Name:  name,
+		Arity: arity,
+		Fn: func(args []vm.Value) (vm.Value, error) {
+			if variadic && len(args) < len(params)-1 {
+				return vm.Value{}, fmt.Errorf("Expected at least %d arguments, got %d", len(params)-1, len(args))
+			}
+
+			in := make([]reflect.Value, 0, len(args)+1)
+			if withContext {
+				in = append(in, reflect.ValueOf(&p.ctx).Elem())
+			}
+			for i, arg := range args {
+				value, err := p.decodeValue(arg, params[min(i, len(params)-1)])
+				if err != nil {
+					return vm.Value{}, fmt.Errorf("Argument %d of '%s': %s", i+1, name, err)
+				}
+				in = append(in, value)
+			}
+
+			out := function.Call(in)
+			if returnsError && !out[len(out)-1].IsNil() {
+				return vm.Value{}, out[len(out)-1].Interface().(error)
+			}
+			if returnsValue {
+				return p.toValue(out[0].Interface())
+			}
+			return vm.NilValue(), nil
+		},
+	}
+
+	return native, signature, nil
+}
+
+// scriptType returns the script type of a Go type, other types are dynamic
+func scriptType(t reflect.Type, dynamic checker.Type) checker.Type {
+	switch t.Kind() {
+	case reflect.Bool:
+		return checker.Boolean
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return checker.Number
+	case reflect.String:
+		return checker.String
+	default:
+		return dynamic
+	}
+}
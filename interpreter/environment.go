@@ -0,0 +1,85 @@
+package interpreter
+
+import (
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/resolver"
+)
+
+// environment holds the variables of a scope, blocks and calls create nested environments
+//
+// Local variables are keyed by their resolver symbol, so a name refers to the declaration the resolver bound it
+// to even before that declaration runs. Globals, which outlive the run that declared them, are keyed by name,
+// as are 'this' and 'super'
+type environment struct {
+	values map[any]Value
+	parent *environment
+}
+
+// newEnvironment creates an environment nested in the parent environment
+func newEnvironment(parent *environment) *environment {
+	return &environment{
+		values: map[any]Value{},
+		parent: parent,
+	}
+}
+
+// define creates or replaces a variable in this environment
+func (e *environment) define(key any, value Value) {
+	e.values[key] = value
+}
+
+// get finds the value of the variable in this environment or an enclosing one
+func (e *environment) get(key any) (Value, bool) {
+	for env := e; env != nil; env = env.parent {
+		if value, ok := env.values[key]; ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// assign updates the variable in the environment that defines it
+func (e *environment) assign(key any, value Value) bool {
+	for env := e; env != nil; env = env.parent {
+		if _, ok := env.values[key]; ok {
+			env.values[key] = value
+			return true
+		}
+	}
+	return false
+}
+
+// bindings maps the identifiers of a run to the symbols the resolver bound them to
+type bindings map[*ast.IdentifierExpression]*resolver.Symbol
+
+// resolve resolves the names of the statement, errors are left to the checker and surface at runtime
+func resolve(statement ast.Statement) bindings {
+	resolution, _ := resolver.Resolve(statement)
+	result := bindings{}
+	for identifier, symbol := range resolution.Uses {
+		result[identifier] = symbol
+	}
+	for identifier, symbol := range resolution.Declarations {
+		result[identifier] = symbol
+	}
+	return result
+}
+
+// key returns the environment key of the variable named by the identifier, its symbol for local variables and
+// its name for globals and names the resolver could not bind
+func (b bindings) key(identifier *ast.IdentifierExpression) any {
+	symbol := b[identifier]
+	if symbol == nil || symbol.Scope == nil ||
+		symbol.Scope.Kind == resolver.ScopeProgram || symbol.Scope.Kind == resolver.ScopeBuiltin {
+		return identifier.Name
+	}
+	return symbol
+}
+
+// missing returns the error of a variable that is not in the environments
+func (b bindings) missing(identifier *ast.IdentifierExpression) *RuntimeError {
+	if _, ok := b.key(identifier).(*resolver.Symbol); ok {
+		return runtimeError(identifier.Span, "Variable '%s' used before initialization", identifier.Name)
+	}
+	return runtimeError(identifier.Span, "Undefined variable '%s'", identifier.Name)
+}
@@ -0,0 +1,499 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/source"
+)
+
+func evaluate(interpreter *Interpreter, expression ast.Expression) (Value, error) {
+	switch expression.NodeType() {
+	case ast.NodeAssignmentExpression:
+		return evaluateAssignmentExpression(interpreter, expression.(*ast.AssignmentExpression))
+	case ast.NodeBinaryExpression:
+		return evaluateBinaryExpression(interpreter, expression.(*ast.BinaryExpression))
+	case ast.NodeUnaryExpression:
+		return evaluateUnaryExpression(interpreter, expression.(*ast.UnaryExpression))
+	case ast.NodeLogicalExpression:
+		return evaluateLogicalExpression(interpreter, expression.(*ast.LogicalExpression))
+	case ast.NodeBooleanLiteralExpression:
+		return expression.(*ast.BooleanLiteralExpression).Value, nil
+	case ast.NodeNilLiteralExpression:
+		return nil, nil
+	case ast.NodeNumericLiteralExpression:
+		return expression.(*ast.NumericLiteralExpression).FloatValue, nil
+	case ast.NodeStringLiteralExpression:
+		return expression.(*ast.StringLiteralExpression).Value, nil
+	case ast.NodeTemplateLiteralExpression:
+		return evaluateTemplateLiteralExpression(interpreter, expression.(*ast.TemplateLiteralExpression))
+	case ast.NodeIdentifierExpression:
+		return evaluateIdentifierExpression(interpreter, expression.(*ast.IdentifierExpression))
+	case ast.NodeMemberExpression:
+		return evaluateMemberExpression(interpreter, expression.(*ast.MemberExpression))
+	case ast.NodeCallExpression:
+		return evaluateCallExpression(interpreter, expression.(*ast.CallExpression))
+	case ast.NodeThisExpression:
+		if value, ok := interpreter.env.get("this"); ok {
+			return value, nil
+		}
+		return nil, runtimeError(expression.GetSpan(), "'this' can only be used inside a class")
+	case ast.NodeSuperExpression:
+		return nil, runtimeError(expression.GetSpan(), "'super' can only be used to call the superclass constructor or a superclass method")
+	case ast.NodeNewExpression:
+		return evaluateNewExpression(interpreter, expression.(*ast.NewExpression))
+	case ast.NodeVariableExpression:
+		return nil, runtimeError(expression.GetSpan(), "Variable declaration is not an expression")
+	default:
+		panic(fmt.Errorf("unknown expression type: %T", expression))
+	}
+}
+
+func evaluateAssignmentExpression(interpreter *Interpreter, expression *ast.AssignmentExpression) (Value, error) {
+	switch target := expression.Left.(type) {
+	case *ast.IdentifierExpression:
+		value, err := evaluateAssignedValue(interpreter, expression, func() (Value, error) {
+			return evaluateIdentifierExpression(interpreter, target)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !interpreter.env.assign(interpreter.bindings.key(target), value) {
+			return nil, interpreter.bindings.missing(target)
+		}
+		return value, nil
+
+	case *ast.MemberExpression:
+		object, err := evaluate(interpreter, target.Object)
+		if err != nil {
+			return nil, err
+		}
+		instance, ok := object.(*Instance)
+		if !ok {
+			return nil, runtimeError(target.Object.GetSpan(), "Only instances have fields, got %s", typeName(object))
+		}
+		name, err := propertyName(interpreter, target)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := evaluateAssignedValue(interpreter, expression, func() (Value, error) {
+			return getProperty(instance, name, target.Span)
+		})
+		if err != nil {
+			return nil, err
+		}
+		instance.Fields[name] = value
+		return value, nil
+
+	default:
+		return nil, runtimeError(expression.Left.GetSpan(), "Invalid assignment target")
+	}
+}
+
+// evaluateAssignedValue evaluates the right side, compound assignments combine it with the current value
+func evaluateAssignedValue(
+	interpreter *Interpreter,
+	expression *ast.AssignmentExpression,
+	current func() (Value, error),
+) (Value, error) {
+	var left Value
+	if expression.Operator != ast.OperatorAssign {
+		var err error
+		if left, err = current(); err != nil {
+			return nil, err
+		}
+	}
+
+	right, err := evaluate(interpreter, expression.Right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch expression.Operator {
+	case ast.OperatorAssignAdd:
+		return binaryOperation(ast.OperatorAdd, left, right, expression.Span)
+	case ast.OperatorAssignSubtract:
+		return binaryOperation(ast.OperatorSubtract, left, right, expression.Span)
+	case ast.OperatorAssignMultiply:
+		return binaryOperation(ast.OperatorMultiply, left, right, expression.Span)
+	case ast.OperatorAssignDivide:
+		return binaryOperation(ast.OperatorDivide, left, right, expression.Span)
+	default:
+		return right, nil
+	}
+}
+
+func evaluateBinaryExpression(interpreter *Interpreter, expression *ast.BinaryExpression) (Value, error) {
+	left, err := evaluate(interpreter, expression.Left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := evaluate(interpreter, expression.Right)
+	if err != nil {
+		return nil, err
+	}
+
+	return binaryOperation(expression.Operator, left, right, expression.Span)
+}
+
+// binaryOperation applies the operator to the operands
+func binaryOperation(operator ast.BinaryOperator, left, right Value, span source.Span) (Value, error) {
+	switch operator {
+	case ast.OperatorEqual:
+		return left == right, nil
+	case ast.OperatorNotEqual:
+		return left != right, nil
+	}
+
+	if l, ok := left.(float64); ok {
+		if r, ok := right.(float64); ok {
+			switch operator {
+			case ast.OperatorAdd:
+				return l + r, nil
+			case ast.OperatorSubtract:
+				return l - r, nil
+			case ast.OperatorMultiply:
+				return l * r, nil
+			case ast.OperatorDivide:
+				return l / r, nil
+			case ast.OperatorGreaterThan:
+				return l > r, nil
+			case ast.OperatorGreaterThanOrEqualTo:
+				return l >= r, nil
+			case ast.OperatorLessThan:
+				return l < r, nil
+			case ast.OperatorLessThanOrEqualTo:
+				return l <= r, nil
+			}
+		}
+	}
+
+	if l, ok := left.(string); ok {
+		if r, ok := right.(string); ok {
+			switch operator {
+			case ast.OperatorAdd:
+				return l + r, nil
+			case ast.OperatorGreaterThan:
+				return l > r, nil
+			case ast.OperatorGreaterThanOrEqualTo:
+				return l >= r, nil
+			case ast.OperatorLessThan:
+				return l < r, nil
+			case ast.OperatorLessThanOrEqualTo:
+				return l <= r, nil
+			}
+		}
+	}
+
+	return nil, runtimeError(span, "Cannot apply operator %s to %s and %s", operator, typeName(left), typeName(right))
+}
+
+func evaluateUnaryExpression(interpreter *Interpreter, expression *ast.UnaryExpression) (Value, error) {
+	operand, err := evaluate(interpreter, expression.Right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch expression.Operator {
+	case ast.OperatorNot:
+		if value, ok := operand.(bool); ok {
+			return !value, nil
+		}
+	case ast.OperatorMinus:
+		if value, ok := operand.(float64); ok {
+			return -value, nil
+		}
+	case ast.OperatorPlus:
+		if value, ok := operand.(float64); ok {
+			return value, nil
+		}
+	}
+
+	return nil, runtimeError(expression.Span, "Cannot apply operator %s to %s", expression.Operator, typeName(operand))
+}
+
+// evaluateLogicalExpression short-circuits, the right operand is only evaluated if it decides the result
+func evaluateLogicalExpression(interpreter *Interpreter, expression *ast.LogicalExpression) (Value, error) {
+	left, err := evaluateCondition(interpreter, expression.Left)
+	if err != nil {
+		return nil, err
+	}
+
+	if expression.Operator == ast.OperatorAnd && !left {
+		return false, nil
+	}
+	if expression.Operator == ast.OperatorOr && left {
+		return true, nil
+	}
+
+	return evaluateCondition(interpreter, expression.Right)
+}
+
+func evaluateTemplateLiteralExpression(interpreter *Interpreter, expression *ast.TemplateLiteralExpression) (Value, error) {
+	var builder strings.Builder
+
+	for i, chunk := range expression.Chunks {
+		builder.WriteString(chunk)
+		if i < len(expression.Expressions) {
+			value, err := evaluate(interpreter, expression.Expressions[i])
+			if err != nil {
+				return nil, err
+			}
+			builder.WriteString(Stringify(value))
+		}
+	}
+
+	return builder.String(), nil
+}
+
+func evaluateIdentifierExpression(interpreter *Interpreter, expression *ast.IdentifierExpression) (Value, error) {
+	if value, ok := interpreter.env.get(interpreter.bindings.key(expression)); ok {
+		return value, nil
+	}
+	return nil, interpreter.bindings.missing(expression)
+}
+
+func evaluateMemberExpression(interpreter *Interpreter, expression *ast.MemberExpression) (Value, error) {
+	// super.method() calls the superclass method on the current instance
+	if _, ok := expression.Object.(*ast.SuperExpression); ok && !expression.Computed {
+		return evaluateSuperMethod(interpreter, expression)
+	}
+
+	object, err := evaluate(interpreter, expression.Object)
+	if err != nil {
+		return nil, err
+	}
+	name, err := propertyName(interpreter, expression)
+	if err != nil {
+		return nil, err
+	}
+
+	switch object := object.(type) {
+	case *Instance:
+		return getProperty(object, name, expression.Span)
+	case string:
+		if !expression.Computed && name == "length" {
+			return float64(utf8.RuneCountInString(object)), nil
+		}
+	}
+
+	return nil, runtimeError(expression.Span, "Type %s has no property '%s'", typeName(object), name)
+}
+
+// evaluateSuperMethod returns the superclass method bound to the current instance
+func evaluateSuperMethod(interpreter *Interpreter, expression *ast.MemberExpression) (Value, error) {
+	superClass, this, err := superContext(interpreter, expression.Object.GetSpan())
+	if err != nil {
+		return nil, err
+	}
+
+	name := expression.Property.(*ast.IdentifierExpression).Name
+	method := superClass.findMethod(name)
+	if method == nil {
+		return nil, runtimeError(expression.Property.GetSpan(), "Superclass '%s' has no method '%s'", superClass.Name, name)
+	}
+	return method.bind(this), nil
+}
+
+// superContext returns the superclass of the method being executed and the current instance
+func superContext(interpreter *Interpreter, span source.Span) (*Class, *Instance, error) {
+	superValue, ok := interpreter.env.get("super")
+	if !ok {
+		return nil, nil, runtimeError(span, "'super' can only be used inside a class that extends another class")
+	}
+	this, _ := interpreter.env.get("this")
+	return superValue.(*Class), this.(*Instance), nil
+}
+
+// propertyName returns the name of the accessed property, computed properties must evaluate to strings
+func propertyName(interpreter *Interpreter, expression *ast.MemberExpression) (string, error) {
+	if !expression.Computed {
+		return expression.Property.(*ast.IdentifierExpression).Name, nil
+	}
+
+	value, err := evaluate(interpreter, expression.Property)
+	if err != nil {
+		return "", err
+	}
+	name, ok := value.(string)
+	if !ok {
+		return "", runtimeError(expression.Property.GetSpan(), "Property name must be a string, got %s", typeName(value))
+	}
+	return name, nil
+}
+
+// getProperty returns the field of the instance or the method bound to it
+func getProperty(instance *Instance, name string, span source.Span) (Value, error) {
+	if value, ok := instance.Fields[name]; ok {
+		return value, nil
+	}
+	if method := instance.Class.findMethod(name); method != nil {
+		return method.bind(instance), nil
+	}
+	return nil, runtimeError(span, "Undefined property '%s' of %s", name, instance.Class.Name)
+}
+
+func evaluateCallExpression(interpreter *Interpreter, expression *ast.CallExpression) (Value, error) {
+	// super(...) runs the superclass constructor on the current instance
+	if _, ok := expression.Callee.(*ast.SuperExpression); ok {
+		superClass, this, err := superContext(interpreter, expression.Callee.GetSpan())
+		if err != nil {
+			return nil, err
+		}
+		args, err := evaluateArguments(interpreter, expression.Arguments)
+		if err != nil {
+			return nil, err
+		}
+		if constructor := superClass.findConstructor(); constructor != nil {
+			_, err = callFunction(interpreter, constructor.bind(this), args, expression.Span)
+		} else if len(args) > 0 {
+			err = runtimeError(expression.Span, "Expected 0 arguments, got %d", len(args))
+		}
+		return nil, err
+	}
+
+	callee, err := evaluate(interpreter, expression.Callee)
+	if err != nil {
+		return nil, err
+	}
+	args, err := evaluateArguments(interpreter, expression.Arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	return callValue(interpreter, callee, args, expression.Span)
+}
+
+func evaluateArguments(interpreter *Interpreter, arguments []ast.Expression) ([]Value, error) {
+	args := make([]Value, len(arguments))
+	for i, argument := range arguments {
+		value, err := evaluate(interpreter, argument)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+	return args, nil
+}
+
+// callValue calls a script or native function
+func callValue(interpreter *Interpreter, callee Value, args []Value, span source.Span) (Value, error) {
+	switch callee := callee.(type) {
+	case *Function:
+		return callFunction(interpreter, callee, args, span)
+	case *NativeFunction:
+		if callee.Arity >= 0 && len(args) != callee.Arity {
+			return nil, runtimeError(span, "Expected %d arguments, got %d", callee.Arity, len(args))
+		}
+		value, err := callee.Fn(args)
+		if err != nil {
+			if _, ok := err.(*RuntimeError); !ok {
+				err = runtimeError(span, "%s", err)
+			}
+		}
+		return value, err
+	case *Class:
+		return nil, runtimeError(span, "Class '%s' must be instantiated with 'new'", callee.Name)
+	default:
+		return nil, runtimeError(span, "Cannot call a value of type %s", typeName(callee))
+	}
+}
+
+// callFunction runs the function body in a new environment nested in its closure
+func callFunction(interpreter *Interpreter, function *Function, args []Value, span source.Span) (Value, error) {
+	if len(args) != function.Arity() {
+		return nil, runtimeError(span, "Function '%s' expects %d arguments, got %d", function.Name(), function.Arity(), len(args))
+	}
+
+	if interpreter.config.MaxCallDepth > 0 && interpreter.callDepth >= interpreter.config.MaxCallDepth {
+		return nil, runtimeError(span, "Stack overflow, more than %d nested calls", interpreter.config.MaxCallDepth)
+	}
+	interpreter.callDepth++
+	defer func() { interpreter.callDepth-- }()
+
+	enclosing := interpreter.bindings
+	interpreter.bindings = function.bindings
+	defer func() { interpreter.bindings = enclosing }()
+
+	env := newEnvironment(function.closure)
+	for i, parameter := range function.Declaration.Parameters {
+		if identifier, ok := parameter.Name.(*ast.IdentifierExpression); ok {
+			env.define(interpreter.bindings.key(identifier), args[i])
+		}
+	}
+
+	err := executeBlock(interpreter, function.Declaration.Body.Body, env)
+	if signal, ok := err.(*returnSignal); ok {
+		return signal.value, nil
+	}
+	return nil, err
+}
+
+// evaluateNewExpression creates an instance, initializes the fields from the base class down and runs the constructor
+func evaluateNewExpression(interpreter *Interpreter, expression *ast.NewExpression) (Value, error) {
+	callee, err := evaluate(interpreter, expression.Callee)
+	if err != nil {
+		return nil, err
+	}
+	class, ok := callee.(*Class)
+	if !ok {
+		return nil, runtimeError(expression.Callee.GetSpan(), "Cannot instantiate a value of type %s", typeName(callee))
+	}
+
+	args, err := evaluateArguments(interpreter, expression.Arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	instance := &Instance{Class: class, Fields: map[string]Value{}}
+	if err := initializeFields(interpreter, class, instance); err != nil {
+		return nil, err
+	}
+
+	constructor := class.findConstructor()
+	if constructor == nil {
+		if len(args) > 0 {
+			return nil, runtimeError(expression.Span, "Class '%s' expects 0 arguments, got %d", class.Name, len(args))
+		}
+		return instance, nil
+	}
+
+	if _, err := callFunction(interpreter, constructor.bind(instance), args, expression.Span); err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
+// initializeFields evaluates the field initializers of the class and its superclasses with 'this' bound
+func initializeFields(interpreter *Interpreter, class *Class, instance *Instance) error {
+	if class.Super != nil {
+		if err := initializeFields(interpreter, class.Super, instance); err != nil {
+			return err
+		}
+	}
+	if len(class.Fields) == 0 {
+		return nil
+	}
+
+	env := newEnvironment(class.closure)
+	env.define("this", instance)
+
+	enclosing, enclosingBindings := interpreter.env, interpreter.bindings
+	interpreter.env, interpreter.bindings = env, class.bindings
+	defer func() { interpreter.env, interpreter.bindings = enclosing, enclosingBindings }()
+
+	for _, field := range class.Fields {
+		value := zeroValue(field.TypeAnnotation)
+		if field.Initializer != nil {
+			var err error
+			if value, err = evaluate(interpreter, field.Initializer); err != nil {
+				return err
+			}
+		}
+		instance.Fields[field.Identifier.Name] = value
+	}
+	return nil
+}
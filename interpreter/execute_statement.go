@@ -0,0 +1,234 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+)
+
+func execute(interpreter *Interpreter, statement ast.Statement) error {
+	switch statement.NodeType() {
+	case ast.NodeProgramStatement:
+		return executeStatementList(interpreter, statement.(*ast.ProgramStatement).Body)
+	case ast.NodeBlockStatement:
+		return executeBlock(interpreter, statement.(*ast.BlockStatement).Body, newEnvironment(interpreter.env))
+	case ast.NodeEmptyStatement:
+		return nil
+	case ast.NodeErrorStatement:
+		return runtimeError(statement.GetSpan(), "Cannot execute a statement with syntax errors")
+	case ast.NodeExpressionStatement:
+		_, err := evaluate(interpreter, statement.(*ast.ExpressionStatement).Expression)
+		return err
+	case ast.NodeVariableDeclarationStatement:
+		return executeVariableDeclarationStatement(interpreter, statement.(*ast.VariableDeclarationStatement))
+	case ast.NodeIfStatement:
+		return executeIfStatement(interpreter, statement.(*ast.IfStatement))
+	case ast.NodeWhileStatement:
+		return executeWhileStatement(interpreter, statement.(*ast.WhileStatement))
+	case ast.NodeDoWhileStatement:
+		return executeDoWhileStatement(interpreter, statement.(*ast.DoWhileStatement))
+	case ast.NodeForStatement:
+		return executeForStatement(interpreter, statement.(*ast.ForStatement))
+	case ast.NodeFunctionDeclarationStatement, ast.NodeClassDeclarationStatement:
+		// Declared when the enclosing statement list starts
+		return nil
+	case ast.NodeReturnStatement:
+		return executeReturnStatement(interpreter, statement.(*ast.ReturnStatement))
+	default:
+		panic(fmt.Errorf("unknown statement type: %T", statement))
+	}
+}
+
+// executeBlock executes the statements in the environment and restores the current environment
+func executeBlock(interpreter *Interpreter, statements []ast.Statement, env *environment) error {
+	enclosing := interpreter.env
+	interpreter.env = env
+	defer func() { interpreter.env = enclosing }()
+
+	return executeStatementList(interpreter, statements)
+}
+
+// executeStatementList executes the statements in the current environment
+//
+// Functions and classes are declared first, they can be used anywhere in the list like the resolver allows.
+// Classes are declared after the classes they extend
+func executeStatementList(interpreter *Interpreter, statements []ast.Statement) error {
+	for _, statement := range statements {
+		if function, ok := statement.(*ast.FunctionDeclarationStatement); ok {
+			interpreter.env.define(interpreter.bindings.key(function.Name), &Function{
+				Declaration: function,
+				closure:     interpreter.env,
+				bindings:    interpreter.bindings,
+			})
+		}
+	}
+	for _, class := range ast.ClassesInOrder(statements) {
+		if err := declareClass(interpreter, class); err != nil {
+			return err
+		}
+	}
+
+	for _, statement := range statements {
+		if err := execute(interpreter, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// declareClass creates the class with its methods and field declarations
+func declareClass(interpreter *Interpreter, statement *ast.ClassDeclarationStatement) error {
+	class := &Class{
+		Name:     statement.Name.Name,
+		Methods:  map[string]*Function{},
+		closure:  interpreter.env,
+		bindings: interpreter.bindings,
+	}
+
+	if statement.SuperClass != nil {
+		value, ok := interpreter.env.get(interpreter.bindings.key(statement.SuperClass))
+		if !ok {
+			return runtimeError(statement.SuperClass.Span, "Undefined superclass '%s'", statement.SuperClass.Name)
+		}
+		superClass, ok := value.(*Class)
+		if !ok {
+			return runtimeError(statement.SuperClass.Span, "Superclass must be a class, got %s", typeName(value))
+		}
+		class.Super = superClass
+	}
+
+	for _, member := range statement.Body.Body {
+		switch member := member.(type) {
+		case *ast.VariableDeclarationStatement:
+			class.Fields = append(class.Fields, member.Variables...)
+		case *ast.FunctionDeclarationStatement:
+			method := &Function{
+				Declaration:   member,
+				closure:       interpreter.env,
+				bindings:      interpreter.bindings,
+				class:         class,
+				isConstructor: member.Name.Name == "constructor",
+			}
+			if method.isConstructor {
+				class.Constructor = method
+			} else {
+				class.Methods[member.Name.Name] = method
+			}
+		case *ast.EmptyStatement:
+			// Nothing to declare
+		default:
+			return runtimeError(member.GetSpan(), "Only fields and methods can be declared in a class body")
+		}
+	}
+
+	interpreter.env.define(interpreter.bindings.key(statement.Name), class)
+	return nil
+}
+
+func executeVariableDeclarationStatement(interpreter *Interpreter, statement *ast.VariableDeclarationStatement) error {
+	for _, variable := range statement.Variables {
+		value := zeroValue(variable.TypeAnnotation)
+		if variable.Initializer != nil {
+			var err error
+			if value, err = evaluate(interpreter, variable.Initializer); err != nil {
+				return err
+			}
+		}
+		interpreter.env.define(interpreter.bindings.key(variable.Identifier), value)
+	}
+	return nil
+}
+
+func executeIfStatement(interpreter *Interpreter, statement *ast.IfStatement) error {
+	condition, err := evaluateCondition(interpreter, statement.Condition)
+	if err != nil {
+		return err
+	}
+
+	if condition {
+		return execute(interpreter, statement.Consequent)
+	}
+	if statement.Alternative != nil {
+		return execute(interpreter, statement.Alternative)
+	}
+	return nil
+}
+
+func executeWhileStatement(interpreter *Interpreter, statement *ast.WhileStatement) error {
+	for {
+		condition, err := evaluateCondition(interpreter, statement.Condition)
+		if err != nil || !condition {
+			return err
+		}
+		if err := execute(interpreter, statement.Body); err != nil {
+			return err
+		}
+	}
+}
+
+func executeDoWhileStatement(interpreter *Interpreter, statement *ast.DoWhileStatement) error {
+	for {
+		if err := execute(interpreter, statement.Body); err != nil {
+			return err
+		}
+		condition, err := evaluateCondition(interpreter, statement.Condition)
+		if err != nil || !condition {
+			return err
+		}
+	}
+}
+
+// executeForStatement runs the loop in its own environment, the initializer variables are only visible in the loop
+func executeForStatement(interpreter *Interpreter, statement *ast.ForStatement) error {
+	enclosing := interpreter.env
+	interpreter.env = newEnvironment(enclosing)
+	defer func() { interpreter.env = enclosing }()
+
+	if statement.Initializer != nil {
+		if err := execute(interpreter, statement.Initializer); err != nil {
+			return err
+		}
+	}
+
+	for {
+		if statement.Condition != nil {
+			condition, err := evaluateCondition(interpreter, statement.Condition)
+			if err != nil || !condition {
+				return err
+			}
+		}
+		if err := execute(interpreter, statement.Body); err != nil {
+			return err
+		}
+		if statement.Increment != nil {
+			if _, err := evaluate(interpreter, statement.Increment); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func executeReturnStatement(interpreter *Interpreter, statement *ast.ReturnStatement) error {
+	var value Value
+	if statement.Argument != nil {
+		var err error
+		if value, err = evaluate(interpreter, statement.Argument); err != nil {
+			return err
+		}
+	}
+	return &returnSignal{value: value}
+}
+
+// evaluateCondition evaluates the condition of a branch or a loop, it must be a boolean
+func evaluateCondition(interpreter *Interpreter, condition ast.Expression) (bool, error) {
+	value, err := evaluate(interpreter, condition)
+	if err != nil {
+		return false, err
+	}
+
+	result, ok := value.(bool)
+	if !ok {
+		return false, runtimeError(condition.GetSpan(), "Condition must be a boolean, got %s", typeName(value))
+	}
+	return result, nil
+}
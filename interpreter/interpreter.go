@@ -0,0 +1,126 @@
+package interpreter
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/source"
+)
+
+// RuntimeError is an error raised while a script runs
+type RuntimeError struct {
+	Message string
+	Span    source.Span // position of the expression or statement that failed
+}
+
+// Error implements the error interface
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("runtime error at %s: %s", e.Span, e.Message)
+}
+
+// returnSignal unwinds the statements of a function up to the call on return
+type returnSignal struct {
+	value Value
+}
+
+// Error implements the error interface, it is only seen if a return escapes a function
+func (r *returnSignal) Error() string {
+	return "return outside of a function"
+}
+
+// Config defines the options of the interpreter
+type Config struct {
+	Stdout       io.Writer // output of the print builtin
+	MaxCallDepth int       // maximum number of nested calls, 0 for no limit
+}
+
+// Interpreter evaluates programs directly on the AST
+//
+// Globals persist between runs, so the same interpreter can run several programs one after another
+type Interpreter struct {
+	config    Config
+	globals   *environment
+	env       *environment // environment of the code being executed
+	bindings  bindings     // resolved names of the code being executed
+	callDepth int
+}
+
+// NewInterpreter creates a new interpreter with default configuration
+func NewInterpreter() *Interpreter {
+	return NewInterpreterWithConfig(Config{
+		Stdout:       os.Stdout,
+		MaxCallDepth: 1000,
+	})
+}
+
+// NewInterpreterWithConfig creates a new interpreter with the given configuration
+func NewInterpreterWithConfig(config Config) *Interpreter {
+	if config.Stdout == nil {
+		config.Stdout = io.Discard
+	}
+
+	globals := newEnvironment(nil)
+	interpreter := &Interpreter{
+		config:  config,
+		globals: globals,
+		env:     globals,
+	}
+
+	interpreter.Define("print", &NativeFunction{
+		Name:  "print",
+		Arity: -1,
+		Fn: func(args []Value) (Value, error) {
+			_, err := fmt.Fprintln(interpreter.config.Stdout, joinValues(args))
+			return nil, err
+		},
+	})
+
+	return interpreter
+}
+
+// Define creates or replaces a global variable, hosts use it to expose native functions
+func (i *Interpreter) Define(name string, value Value) {
+	i.globals.define(name, value)
+}
+
+// Global returns the value of a global variable
+func (i *Interpreter) Global(name string) (Value, bool) {
+	return i.globals.get(name)
+}
+
+// Run executes the program, errors are *RuntimeError
+//
+// Names are bound like the resolver binds them, a local variable read before its declaration runs is an error
+// even if an enclosing scope has a variable with the same name
+func (i *Interpreter) Run(program ast.Statement) error {
+	i.env = i.globals
+	i.bindings = resolve(program)
+	i.callDepth = 0
+
+	err := execute(i, program)
+	if signal, ok := err.(*returnSignal); ok {
+		return &RuntimeError{Message: signal.Error(), Span: program.GetSpan()}
+	}
+	return err
+}
+
+// Evaluate evaluates the expression in the global environment
+func (i *Interpreter) Evaluate(expression ast.Expression) (Value, error) {
+	i.env = i.globals
+	i.bindings = resolve(&ast.ExpressionStatement{Expression: expression})
+	i.callDepth = 0
+
+	return evaluate(i, expression)
+}
+
+// Call calls a script or native function with the arguments
+func (i *Interpreter) Call(callee Value, args ...Value) (Value, error) {
+	return callValue(i, callee, args, source.Span{})
+}
+
+// runtimeError creates a runtime error at the span
+func runtimeError(span source.Span, format string, args ...any) *RuntimeError {
+	return &RuntimeError{Message: fmt.Sprintf(format, args...), Span: span}
+}
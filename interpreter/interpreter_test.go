@@ -0,0 +1,194 @@
+package interpreter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/yoh0xff/senbonzakura/parser"
+)
+
+func runSource(t *testing.T, source string) (string, error) {
+	t.Helper()
+
+	program, diagnostics := parser.ParseRootStatement(parser.NewParser(source))
+	if len(diagnostics) != 0 {
+		t.Fatalf("Expected no parse diagnostics, got %v", diagnostics)
+	}
+
+	var output strings.Builder
+	interpreter := NewInterpreterWithConfig(Config{Stdout: &output, MaxCallDepth: 100})
+	err := interpreter.Run(program)
+	return output.String(), err
+}
+
+func TestInterpreterOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"arithmetic", `print(1 + 2 * 3, 10 / 4, -(2 - 5), 0.1 + 0.2);`, "7 2.5 3 0.30000000000000004\n"},
+		{"strings", "let name: string = \"world\"; print(\"hello \" + name, `${name}!`, name.length);", "hello world world! 5\n"},
+		{"comparison", `print(1 < 2, 2 <= 1, "a" < "b", 1 == 1, nil == nil, "a" != "a");`, "true false true true true false\n"},
+		{"logical", `def f(): boolean { print("called"); return true; } print(false && f(), true || f(), true && f());`, "called\nfalse true true\n"},
+		{"compound assignment", `let x: number = 10; x += 5; x -= 3; x *= 2; x /= 4; print(x);`, "6\n"},
+		{"block scope", `let x: number = 1; { let x: number = 2; print(x); } print(x);`, "2\n1\n"},
+		{"if else", `let x: number = 5; if (x > 3) { print("big"); } else { print("small"); }`, "big\n"},
+		{"while", `let i: number = 0; while (i < 3) { print(i); i = i + 1; }`, "0\n1\n2\n"},
+		{"do while", `let i: number = 10; do { print(i); } while (i < 3);`, "10\n"},
+		{"for", `for (let i: number = 0; i < 3; i += 1) { print(i); }`, "0\n1\n2\n"},
+		{"recursion", `def fib(n: number): number { if (n < 2) { return n; } return fib(n - 1) + fib(n - 2); } print(fib(15));`, "610\n"},
+		{"hoisting", `print(double(2)); def double(n: number): number { return n * 2; }`, "4\n"},
+		{
+			"superclass declared later",
+			`class B extends A { def f(): number { return this.g() + 1; } } class A { def g(): number { return 1; } }
+			print(new B().f()); def h(): void { class D extends C {} class C { let v: number = 3; } print(new D().v); } h();`,
+			"2\n3\n",
+		},
+		{
+			"zero values",
+			`let y: number; let s: string; let b: boolean; let a: [number]; class P { let x: number; } print(y + 1, s + "a", !b, a, new P().x + 1);`,
+			"1 a true nil 1\n",
+		},
+		{
+			"closures",
+			`def counter(): Function {
+				let count: number = 0;
+				def next(): number { count += 1; return count; }
+				return next;
+			}
+			let a: Function = counter();
+			let b: Function = counter();
+			print(a(), a(), b());`,
+			"1 2 1\n",
+		},
+		{
+			"closures over loop blocks",
+			`def keep(n: number): Function { def get(): number { return n; } return get; }
+			let first: Function = nil;
+			for (let i: number = 0; i < 3; i += 1) { let j: number = i * 10; if (i == 1) { first = keep(j); } }
+			print(first());`,
+			"10\n",
+		},
+		{
+			"classes",
+			`class Person {
+				def constructor(name: string, age: number) { this.name = name; this.age = age; }
+				def greet(): string { return "I am " + this.name; }
+			}
+			let p: Person = new Person("Ann", 30);
+			print(p.greet(), p.age);
+			p.age += 1;
+			print(p.age);`,
+			"I am Ann 30\n31\n",
+		},
+		{
+			"fields",
+			`class Counter { let count: number = 1; def inc(): void { this.count += 1; } }
+			let c: Counter = new Counter(); c.inc(); c.inc(); print(c.count);`,
+			"3\n",
+		},
+		{
+			"inheritance",
+			`class Animal {
+				def constructor(name: string) { this.name = name; }
+				def speak(): string { return this.name + " makes a sound"; }
+				def describe(): string { return this.speak(); }
+			}
+			class Dog extends Animal {
+				def constructor(name: string) { super(name); this.tricks = 2; }
+				def speak(): string { return super.speak() + ", woof"; }
+			}
+			let d: Animal = new Dog("Rex");
+			print(d.describe());`,
+			"Rex makes a sound, woof\n",
+		},
+		{
+			"inherited constructor",
+			`class A { def constructor(x: number) { this.x = x; } } class B extends A {} print(new B(7).x);`,
+			"7\n",
+		},
+		{
+			"methods are bound",
+			`class A { let v: number = 4; def get(): number { return this.v; } } let f: Function = new A().get; print(f());`,
+			"4\n",
+		},
+		{"stringify", `class A {} def f(): void {} print(nil, true, A, new A(), f, print, 1e21);`, "nil true <class A> <A instance> <fn f> <native fn print> 1e+21\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := runSource(t, tt.source)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if output != tt.expected {
+				t.Errorf("Expected output %q, got %q", tt.expected, output)
+			}
+		})
+	}
+}
+
+func TestInterpreterRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		message string
+		line    int
+		column  int
+	}{
+		{"undefined variable", `let x: number = 1;
+print(y);`, "Undefined variable 'y'", 2, 7},
+		{"operand types", `print(1 + "a");`, "Cannot apply operator + to number and string", 1, 7},
+		{"condition", `if (1) {}`, "Condition must be a boolean, got number", 1, 5},
+		{"arity", `def f(a: number): void {} f();`, "Function 'f' expects 1 arguments, got 0", 1, 27},
+		{"not callable", `let x: number = 1; x();`, "Cannot call a value of type number", 1, 20},
+		{"undefined property", `class A {} new A().x;`, "Undefined property 'x' of A", 1, 12},
+		{"class without new", `class A {} A();`, "Class 'A' must be instantiated with 'new'", 1, 12},
+		{"stack overflow", `def f(): void { f(); } f();`, "Stack overflow, more than 100 nested calls", 1, 17},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runSource(t, tt.source)
+
+			var runtimeError *RuntimeError
+			if !errors.As(err, &runtimeError) {
+				t.Fatalf("Expected a runtime error, got %v", err)
+			}
+			if runtimeError.Message != tt.message {
+				t.Errorf("Expected message %q, got %q", tt.message, runtimeError.Message)
+			}
+			if runtimeError.Span.Line != tt.line || runtimeError.Span.Column != tt.column {
+				t.Errorf("Expected error at %d:%d, got %s", tt.line, tt.column, runtimeError.Span)
+			}
+		})
+	}
+}
+
+func TestInterpreterHost(t *testing.T) {
+	program, _ := parser.ParseRootStatement(parser.NewParser(`let total: number = add(2, 3); def twice(n: number): number { return n * 2; }`))
+
+	interpreter := NewInterpreterWithConfig(Config{})
+	interpreter.Define("add", &NativeFunction{
+		Name:  "add",
+		Arity: 2,
+		Fn: func(args []Value) (Value, error) {
+			return args[0].(float64) + args[1].(float64), nil
+		},
+	})
+
+	if err := interpreter.Run(program); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if total, _ := interpreter.Global("total"); total != 5.0 {
+		t.Errorf("Expected total 5, got %v", total)
+	}
+
+	twice, _ := interpreter.Global("twice")
+	result, err := interpreter.Call(twice, 21.0)
+	if err != nil || result != 42.0 {
+		t.Errorf("Expected 42, got %v (%v)", result, err)
+	}
+}
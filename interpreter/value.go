@@ -0,0 +1,182 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+)
+
+// Value is a runtime value
+//
+// Values are represented by Go values:
+//   - nil for nil
+//   - bool for booleans
+//   - float64 for numbers
+//   - string for strings
+//   - *Function, *NativeFunction for functions and bound methods
+//   - *Class for classes
+//   - *Instance for class instances
+type Value any
+
+// Function is a function declared in the script, closed over the environment of its declaration
+type Function struct {
+	Declaration   *ast.FunctionDeclarationStatement
+	closure       *environment
+	bindings      bindings // resolved names of the run that declared the function
+	class         *Class   // class that declares the method, nil for plain functions
+	isConstructor bool
+}
+
+// Name returns the declared name of the function
+func (f *Function) Name() string {
+	return f.Declaration.Name.Name
+}
+
+// Arity returns the number of parameters
+func (f *Function) Arity() int {
+	return len(f.Declaration.Parameters)
+}
+
+// bind returns the method bound to the instance, 'this' and 'super' are visible in its body
+func (f *Function) bind(instance *Instance) *Function {
+	env := newEnvironment(f.closure)
+	env.define("this", instance)
+	if f.class != nil && f.class.Super != nil {
+		env.define("super", f.class.Super)
+	}
+
+	return &Function{
+		Declaration:   f.Declaration,
+		closure:       env,
+		bindings:      f.bindings,
+		class:         f.class,
+		isConstructor: f.isConstructor,
+	}
+}
+
+// NativeFunction is a function implemented by the host
+type NativeFunction struct {
+	Name  string
+	Arity int // number of parameters, -1 for any number of arguments
+	Fn    func(args []Value) (Value, error)
+}
+
+// Class is a class declared in the script
+type Class struct {
+	Name        string
+	Super       *Class // can be nil
+	Methods     map[string]*Function
+	Fields      []*ast.VariableExpression // field declarations in the class body, in order
+	Constructor *Function                 // can be nil
+	closure     *environment
+	bindings    bindings // resolved names of the run that declared the class
+}
+
+// findMethod finds the method in the class or its superclasses
+func (c *Class) findMethod(name string) *Function {
+	for class := c; class != nil; class = class.Super {
+		if method, ok := class.Methods[name]; ok {
+			return method
+		}
+	}
+	return nil
+}
+
+// findConstructor finds the constructor in the class or its superclasses
+func (c *Class) findConstructor() *Function {
+	for class := c; class != nil; class = class.Super {
+		if class.Constructor != nil {
+			return class.Constructor
+		}
+	}
+	return nil
+}
+
+// Instance is an instance of a class
+type Instance struct {
+	Class  *Class
+	Fields map[string]Value
+}
+
+// Stringify returns the text of a value as printed by scripts
+func Stringify(value Value) string {
+	switch value := value.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return strconv.FormatBool(value)
+	case float64:
+		return formatNumber(value)
+	case string:
+		return value
+	case *Function:
+		return fmt.Sprintf("<fn %s>", value.Name())
+	case *NativeFunction:
+		return fmt.Sprintf("<native fn %s>", value.Name)
+	case *Class:
+		return fmt.Sprintf("<class %s>", value.Name)
+	case *Instance:
+		return fmt.Sprintf("<%s instance>", value.Class.Name)
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+// zeroValue returns the value of a variable declared without an initializer, primitives start from their zero
+// value like in the wasm backend, other types from nil
+func zeroValue(typeAnnotation ast.Type) Value {
+	primitive, ok := typeAnnotation.(*ast.PrimitiveType)
+	if !ok {
+		return nil
+	}
+	switch primitive.Kind {
+	case ast.NumberType:
+		return 0.0
+	case ast.BooleanType:
+		return false
+	default:
+		return ""
+	}
+}
+
+// formatNumber prints integers without a fraction and other numbers in the shortest form
+func formatNumber(value float64) string {
+	if value == math.Trunc(value) && math.Abs(value) < 1e15 {
+		return strconv.FormatFloat(value, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// typeName returns the name of the type of a value for error messages
+func typeName(value Value) string {
+	switch value := value.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case *Function, *NativeFunction:
+		return "function"
+	case *Class:
+		return "class"
+	case *Instance:
+		return value.Class.Name
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// joinValues stringifies the values separated by spaces
+func joinValues(values []Value) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = Stringify(value)
+	}
+	return strings.Join(parts, " ")
+}
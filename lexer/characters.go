@@ -0,0 +1,61 @@
+package lexer
+
+// keywords maps reserved words to their token types
+var keywords = map[string]TokenType{
+	"true":    TokenBoolean,
+	"false":   TokenBoolean,
+	"nil":     TokenNil,
+	"let":     TokenLetKeyword,
+	"if":      TokenIfKeyword,
+	"else":    TokenElseKeyword,
+	"while":   TokenWhileKeyword,
+	"do":      TokenDoKeyword,
+	"for":     TokenForKeyword,
+	"def":     TokenDefKeyword,
+	"return":  TokenReturnKeyword,
+	"class":   TokenClassKeyword,
+	"extends": TokenExtendsKeyword,
+	"this":    TokenThisKeyword,
+	"super":   TokenSuperKeyword,
+	"new":     TokenNewKeyword,
+	"type":    TokenTypeKeyword,
+	"number":  TokenNumberTypeKeyword,
+	"string":  TokenStringTypeKeyword,
+	"boolean": TokenBooleanTypeKeyword,
+	"void":    TokenVoidTypeKeyword,
+}
+
+// isWhitespace checks if the character is whitespace, matches the regex class \s
+func isWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// isDigit checks if the character is a decimal digit, matches the regex class \d
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isHexDigit checks if the character is a hexadecimal digit
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// isOctalDigit checks if the character is an octal digit
+func isOctalDigit(c byte) bool {
+	return c >= '0' && c <= '7'
+}
+
+// isBinaryDigit checks if the character is a binary digit
+func isBinaryDigit(c byte) bool {
+	return c == '0' || c == '1'
+}
+
+// isIdentifierStart checks if the character can start an identifier
+func isIdentifierStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+}
+
+// isIdentifierPart checks if the character can continue an identifier, matches the regex class \w
+func isIdentifierPart(c byte) bool {
+	return isIdentifierStart(c) || isDigit(c)
+}
@@ -0,0 +1,194 @@
+package lexer
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// differentialSources are the inputs of lexer_test.go plus a few complete programs
+var differentialSources = []string{
+	"\n        // This is single line comment\n        17\n    ",
+	"\n        /*\n        This is multi line comment,\n        and we should skip it\n        */\n        1719\n    ",
+	"\n        /**\n        * This is multi line comment,\n        * and we should skip it\n        */\n        1719\n    ",
+	"12",
+	"    12",
+	`  "Hello" 'world'  `,
+	`  " Hello "  `,
+	"42",
+	`let x = 42;`,
+	"@invalid",
+	`
+		// Complex test
+		class Person {
+			def constructor(name: string, age: number) {
+				this.name = name;
+				this.age = age;
+			}
+			
+			def getName(): string {
+				return this.name;
+			}
+		}
+		
+		let person = new Person("John", 30);
+	`,
+	`x+=1;y-=2;z*=3;w/=4;a==b;a!=b;!a;a&&b||c;a>=b;a<=b;a>b;a<b;a=b/c*d-e+f;`,
+	`letx ifelse true_ false1 _nil nil 12abc returnValue`,
+	`3.14 1e9 1E-9 2.5e+3 0xFF 0b1010 0o777 1_000_000 0x_1 1__0 1_ 0x 1e 1.e5 1.x 0b102 0o8`,
+	`m[1].n(2, "3")['4'];{}`,
+	`let s = "unterminated + 'x' 1;`,
+	"let s = `unterminated raw + 'x' 1;",
+	`"ends with escaped quote\" + 1`,
+}
+
+// corpusFragments are combined randomly to generate the differential corpus
+var corpusFragments = []string{
+	";", "{", "}", "(", ")", "[", "]", ",", ".", ":",
+	"true", "false", "nil", "let", "if", "else", "while", "do", "for", "def", "return",
+	"class", "extends", "this", "super", "new", "type", "number", "string", "boolean", "void",
+	"x", "foo", "_bar", "Baz9", "letter", "doer", "classy", "__",
+	"==", "!=", "=", "+=", "-=", "*=", "/=", "+", "-", "*", "/",
+	">", ">=", "<", "<=", "&&", "||", "!",
+	"0", "7", "42", "1000000", "3.14", "1e9", "2.5E-3", "1e+2", "0xFF", "0Xab_cd", "0b1010", "0o17", "1_000_000",
+	"0x", "0b2", "1e", "1.", "1._5", "e5", "x1",
+	`"hello"`, `""`, `"multi
+line"`, `'single'`, `'it"s'`, `"it's"`,
+	`"say \"hi\""`, `'it\'s'`, `"tab\tnew\nline"`, `"\u{1F600}"`, `"\\"`, "`raw \\n`", "`multi\nline raw`", "``",
+	"ü", "@", "#", "&", "|",
+}
+
+// corpusSeparators are placed between fragments, an empty separator glues fragments together
+var corpusSeparators = []string{
+	"", "", " ", "  ", "\n", "\t", "\r\n", " // comment\n", " /* comment */ ", "/**/", "\n/* multi\nline */\n",
+}
+
+// generateCorpus creates random sources from the token fragments
+func generateCorpus(seed int64, count int, length int) []string {
+	random := rand.New(rand.NewSource(seed))
+	corpus := make([]string, 0, count)
+
+	for i := 0; i < count; i++ {
+		var builder strings.Builder
+		for j := 0; j < length; j++ {
+			fragment := corpusFragments[random.Intn(len(corpusFragments))]
+			separator := corpusSeparators[random.Intn(len(corpusSeparators))]
+
+			// Gluing '/' with '*' or '/' would start a comment, that is not part of this corpus
+			current := builder.String()
+			if separator == "" && strings.HasSuffix(current, "/") &&
+				(strings.HasPrefix(fragment, "/") || strings.HasPrefix(fragment, "*")) {
+				separator = " "
+			}
+			if strings.HasSuffix(separator, "/") && strings.HasPrefix(fragment, "*") {
+				separator += " "
+			}
+
+			builder.WriteString(separator)
+			builder.WriteString(fragment)
+		}
+		corpus = append(corpus, builder.String())
+	}
+
+	return corpus
+}
+
+// collectTokens reads all tokens until the end token
+func collectTokens(next func() Token) []Token {
+	var tokens []Token
+	for {
+		token := next()
+		tokens = append(tokens, token)
+		if token.TokenType == TokenEnd {
+			return tokens
+		}
+	}
+}
+
+func assertSameTokens(t *testing.T, source string) {
+	t.Helper()
+
+	scanner := NewLexer(source)
+	reference := NewRegexLexer(source)
+
+	scannerTokens := collectTokens(scanner.NextToken)
+	referenceTokens := collectTokens(reference.NextToken)
+
+	if len(scannerTokens) != len(referenceTokens) {
+		t.Fatalf("Source %q: expected %d tokens, got %d", source, len(referenceTokens), len(scannerTokens))
+	}
+
+	for i := range referenceTokens {
+		if scannerTokens[i] != referenceTokens[i] {
+			t.Fatalf("Source %q: token %d: expected %v, got %v", source, i, referenceTokens[i], scannerTokens[i])
+		}
+	}
+
+	if len(scanner.Diagnostics()) != len(reference.Diagnostics()) {
+		t.Fatalf(
+			"Source %q: expected %d diagnostics, got %d",
+			source, len(reference.Diagnostics()), len(scanner.Diagnostics()),
+		)
+	}
+}
+
+func TestLexerMatchesRegexLexer(t *testing.T) {
+	for _, source := range differentialSources {
+		assertSameTokens(t, source)
+	}
+}
+
+func TestLexerMatchesRegexLexerOnGeneratedCorpus(t *testing.T) {
+	for _, source := range generateCorpus(17, 500, 60) {
+		assertSameTokens(t, source)
+	}
+}
+
+func TestLexerLongCommentRun(t *testing.T) {
+	source := strings.Repeat("// comment\n/* comment */\n", 200000) + "1"
+
+	token := NewLexer(source).NextToken()
+	if token.TokenType != TokenNumber || token.Line != 400001 {
+		t.Errorf("Expected number token on line 400001, got %v at line %d", token, token.Line)
+	}
+}
+
+func TestLexerUnterminatedComment(t *testing.T) {
+	lexer := NewLexer("1 /* never closed")
+
+	collectTokens(lexer.NextToken)
+
+	if len(lexer.Diagnostics()) != 1 {
+		t.Errorf("Expected 1 diagnostic, got %v", lexer.Diagnostics())
+	}
+}
+
+// benchmarkSource is a large script used to compare the lexers
+var benchmarkSource = strings.Repeat(differentialSources[10]+differentialSources[11], 200)
+
+func BenchmarkLexerLargeScript(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		lexer := NewLexer(benchmarkSource)
+		for token := lexer.NextToken(); token.TokenType != TokenEnd; token = lexer.NextToken() {
+			// Process all tokens
+		}
+	}
+}
+
+func BenchmarkLexerLargeScriptWithTrivia(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		lexer := NewLexerWithConfig(benchmarkSource, Config{Trivia: true})
+		for token := lexer.NextToken(); token.TokenType != TokenEnd; token = lexer.NextToken() {
+			// Process all tokens
+		}
+	}
+}
+
+func BenchmarkRegexLexerLargeScript(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		lexer := NewRegexLexer(benchmarkSource)
+		for token := lexer.NextToken(); token.TokenType != TokenEnd; token = lexer.NextToken() {
+			// Process all tokens
+		}
+	}
+}
@@ -1,82 +1,455 @@
 package lexer
 
 import (
-	"fmt"
+	"unicode/utf8"
+
+	"github.com/yoh0xff/senbonzakura/diagnostic"
+	"github.com/yoh0xff/senbonzakura/source"
 )
 
 // Lexer lazily pulls tokens from a stream
+//
+// The lexer is a single pass scanner that dispatches on the current character,
+// it produces the same tokens as the rules in GetRegexRules
 type Lexer struct {
-	source string
-	index  int
-	rules  []RegexRule
+	config      Config
+	file        string
+	source      string
+	index       int
+	lines       *source.LineTable
+	position    positionCursor
+	templates   []int // open braces inside every active template substitution, innermost last
+	diagnostics []diagnostic.Diagnostic
+}
+
+// positionCursor remembers the line and column of an offset, so positions of
+// following tokens can be computed without rescanning the source from the line start
+type positionCursor struct {
+	offset int
+	line   int
+	column int
+}
+
+// Config defines the options of the lexer
+type Config struct {
+	File   string // name of the source file recorded in token positions, can be empty
+	Trivia bool   // whether to attach the comments and blank lines around tokens to them
 }
 
 // NewLexer creates a new lexer instance
 func NewLexer(source string) *Lexer {
+	return NewLexerWithFile("", source)
+}
+
+// NewLexerWithFile creates a new lexer instance for source read from the given file
+func NewLexerWithFile(file string, sourceText string) *Lexer {
+	return NewLexerWithConfig(sourceText, Config{File: file})
+}
+
+// NewLexerWithConfig creates a new lexer instance with the given configuration
+func NewLexerWithConfig(sourceText string, config Config) *Lexer {
 	return &Lexer{
-		source: source,
-		index:  0,
-		rules:  GetRegexRules(),
+		config:   config,
+		file:     config.File,
+		source:   sourceText,
+		index:    0,
+		lines:    source.NewLineTable(sourceText),
+		position: positionCursor{offset: 0, line: 1, column: 1},
 	}
 }
 
 // NextToken obtains the next token from the source
 func (l *Lexer) NextToken() Token {
-	currentIndex := l.index
+	// Trivia mode calls back with the mode turned off, scanning in another function would cost a call per token
+	if l.config.Trivia {
+		return l.nextTokenWithTrivia()
+	}
+	l.skipWhitespaceAndComments()
 
 	// Check if we're at the end of the source
-	if currentIndex >= len(l.source) {
-		return Token{
-			TokenType: TokenEnd,
-			Start:     currentIndex,
-			End:       currentIndex,
+	start := l.index
+	if start >= len(l.source) {
+		return l.newToken(TokenEnd, start, start)
+	}
+
+	c := l.source[start]
+	switch {
+	case isIdentifierStart(c):
+		return l.scanIdentifierOrKeyword()
+	case isDigit(c):
+		return l.scanNumber()
+	}
+
+	switch c {
+	// Symbols, delimiters
+	case ';':
+		return l.singleCharToken(TokenStatementEnd)
+	case '{':
+		if len(l.templates) > 0 {
+			l.templates[len(l.templates)-1]++
+		}
+		return l.singleCharToken(TokenOpeningBrace)
+	case '}':
+		if len(l.templates) > 0 {
+			if l.templates[len(l.templates)-1] == 0 {
+				// The brace closes a template substitution, continue with the template text
+				l.templates = l.templates[:len(l.templates)-1]
+				return l.scanTemplateContinuation()
+			}
+			l.templates[len(l.templates)-1]--
+		}
+		return l.singleCharToken(TokenClosingBrace)
+	case '(':
+		return l.singleCharToken(TokenOpeningParenthesis)
+	case ')':
+		return l.singleCharToken(TokenClosingParenthesis)
+	case '[':
+		return l.singleCharToken(TokenOpeningBracket)
+	case ']':
+		return l.singleCharToken(TokenClosingBracket)
+	case ',':
+		return l.singleCharToken(TokenComma)
+	case '.':
+		return l.singleCharToken(TokenDot)
+	case ':':
+		return l.singleCharToken(TokenColon)
+
+	// Operators
+	case '=':
+		if l.peek(1) == '=' {
+			return l.multiCharToken(TokenEqualityOperator, 2)
+		}
+		return l.singleCharToken(TokenSimpleAssignmentOperator)
+	case '!':
+		if l.peek(1) == '=' {
+			return l.multiCharToken(TokenEqualityOperator, 2)
+		}
+		return l.singleCharToken(TokenLogicalNotOperator)
+	case '+', '-':
+		if l.peek(1) == '=' {
+			return l.multiCharToken(TokenComplexAssignmentOperator, 2)
+		}
+		return l.singleCharToken(TokenAdditiveOperator)
+	case '*', '/':
+		// Comments are skipped before, so '/' starts an operator here
+		if l.peek(1) == '=' {
+			return l.multiCharToken(TokenComplexAssignmentOperator, 2)
+		}
+		return l.singleCharToken(TokenFactorOperator)
+	case '>', '<':
+		if l.peek(1) == '=' {
+			return l.multiCharToken(TokenRelationalOperator, 2)
+		}
+		return l.singleCharToken(TokenRelationalOperator)
+	case '&':
+		if l.peek(1) == '&' {
+			return l.multiCharToken(TokenLogicalAndOperator, 2)
+		}
+	case '|':
+		if l.peek(1) == '|' {
+			return l.multiCharToken(TokenLogicalOrOperator, 2)
+		}
+
+	// Strings
+	case '"', '\'':
+		return l.scanString(c)
+	case '`':
+		return l.scanRawString()
+	}
+
+	// If we get here, no token matched, report it and skip a single character
+	_, size := utf8.DecodeRuneInString(l.source[start:])
+	l.index = start + size
+	token := l.newToken(TokenInvalid, start, start+size)
+	l.reportError(diagnostic.CodeInvalidToken, token.Span(), "Invalid token '%s'", l.source[start:start+size])
+
+	return token
+}
+
+// skipWhitespaceAndComments advances past whitespace and comments
+func (l *Lexer) skipWhitespaceAndComments() {
+	for l.index < len(l.source) {
+		switch c := l.source[l.index]; {
+		case isWhitespace(c):
+			l.index++
+		case c == '/' && l.peek(1) == '/':
+			l.skipSingleLineComment()
+		case c == '/' && l.peek(1) == '*':
+			l.skipMultiLineComment()
+		default:
+			return
 		}
 	}
+}
+
+// skipSingleLineComment advances to the end of the current line
+func (l *Lexer) skipSingleLineComment() {
+	for l.index < len(l.source) && l.source[l.index] != '\n' {
+		l.index++
+	}
+}
 
-	// Slice the string starting from the current position
-	expression := l.source[currentIndex:]
+// skipMultiLineComment advances past the closing '*/'
+func (l *Lexer) skipMultiLineComment() {
+	start := l.index
+	l.index += 2
 
-	for _, rule := range l.rules {
-		match := rule.Pattern.FindStringIndex(expression)
-		if match == nil {
-			continue // Try to match other token
+	for l.index < len(l.source) {
+		if l.source[l.index] == '*' && l.peek(1) == '/' {
+			l.index += 2
+			return
 		}
+		l.index++
+	}
 
-		// match[0] is the start index (should be 0 for our patterns)
-		// match[1] is the end index
-		tokenText := expression[match[0]:match[1]]
-		tokenLen := len(tokenText)
+	l.reportError(
+		diagnostic.CodeUnterminatedComment,
+		l.newToken(TokenMultiLineComment, start, l.index).Span(),
+		"Unterminated multi line comment",
+	)
+}
 
-		switch rule.TokenType {
-		case TokenWhitespace, TokenSingleLineComment, TokenMultiLineComment:
-			// Skip whitespace and comments
-			l.index = currentIndex + tokenLen
-			return l.NextToken()
+// scanIdentifierOrKeyword scans a word and classifies it as keyword or identifier
+func (l *Lexer) scanIdentifierOrKeyword() Token {
+	start := l.index
+	for l.index < len(l.source) && isIdentifierPart(l.source[l.index]) {
+		l.index++
+	}
+
+	if tokenType, ok := keywords[l.source[start:l.index]]; ok {
+		return l.newToken(tokenType, start, l.index)
+	}
+
+	return l.newToken(TokenIdentifier, start, l.index)
+}
+
+// scanNumber scans a number literal
+//
+// NumericLiteral
+//
+//	: '0x' HexDigits
+//	| '0b' BinaryDigits
+//	| '0o' OctalDigits
+//	| Digits ['.' Digits] [('e' | 'E') ['+' | '-'] Digits]
+//	;
+//
+// Digits can contain '_' separators, their placement is validated by the parser
+func (l *Lexer) scanNumber() Token {
+	start := l.index
+
+	// Prefixed integers, the prefix only counts when a digit of the base follows
+	if l.source[start] == '0' {
+		var isBaseDigit func(byte) bool
+		switch l.peek(1) {
+		case 'x', 'X':
+			isBaseDigit = isHexDigit
+		case 'b', 'B':
+			isBaseDigit = isBinaryDigit
+		case 'o', 'O':
+			isBaseDigit = isOctalDigit
+		}
+
+		if isBaseDigit != nil && (isBaseDigit(l.peek(2)) || l.peek(2) == '_') {
+			l.index += 2
+			l.skipDigits(isBaseDigit)
+			return l.newToken(TokenNumber, start, l.index)
+		}
+	}
+
+	l.skipDigits(isDigit)
+
+	// Fraction, the dot is a member access unless a digit follows
+	if l.peek(0) == '.' && isDigit(l.peek(1)) {
+		l.index++
+		l.skipDigits(isDigit)
+	}
+
+	// Exponent, the 'e' starts an identifier unless digits follow
+	if c := l.peek(0); c == 'e' || c == 'E' {
+		if isDigit(l.peek(1)) {
+			l.index++
+			l.skipDigits(isDigit)
+		} else if (l.peek(1) == '+' || l.peek(1) == '-') && isDigit(l.peek(2)) {
+			l.index += 2
+			l.skipDigits(isDigit)
+		}
+	}
+
+	return l.newToken(TokenNumber, start, l.index)
+}
+
+// skipDigits advances past digits and '_' separators
+func (l *Lexer) skipDigits(isBaseDigit func(byte) bool) {
+	for l.index < len(l.source) && (isBaseDigit(l.source[l.index]) || l.source[l.index] == '_') {
+		l.index++
+	}
+}
+
+// scanString scans a string literal delimited by the quote character
+//
+// Escape sequences are skipped here, they are decoded and validated by the parser
+func (l *Lexer) scanString(quote byte) Token {
+	start := l.index
+	l.index++
+
+	for l.index < len(l.source) {
+		switch l.source[l.index] {
+		case quote:
+			l.index++
+			return l.newToken(TokenString, start, l.index)
+		case '\\':
+			l.index += 2
 		default:
-			// Return the matched token
-			l.index = currentIndex + tokenLen
-			return Token{
-				TokenType: rule.TokenType,
-				Start:     currentIndex,
-				End:       currentIndex + tokenLen,
-			}
+			l.index++
 		}
 	}
 
-	// If we get here, no token matched
-	panic(fmt.Sprintf(
-		"Invalid token at index %d, remaining text: '%s'",
-		currentIndex,
-		l.source[currentIndex:],
-	))
+	return l.rejectUnterminatedString(start)
+}
+
+// scanRawString scans a raw string literal, raw strings can span multiple lines and have no escape sequences
+//
+// A raw string containing '${' is a template literal, only its text up to the substitution is
+// scanned, see scanTemplateText
+func (l *Lexer) scanRawString() Token {
+	start := l.index
+	l.index++
+
+	if token, ok := l.scanTemplateText(start, TokenString, TokenTemplateHead); ok {
+		return token
+	}
+
+	return l.rejectUnterminatedString(start)
+}
+
+// scanTemplateContinuation scans the template text after a substitution, starting at its closing '}'
+func (l *Lexer) scanTemplateContinuation() Token {
+	start := l.index
+	l.index++
+
+	if token, ok := l.scanTemplateText(start, TokenTemplateTail, TokenTemplateMiddle); ok {
+		return token
+	}
+
+	// The template is closed at the end of the source, so the parser can finish the expression
+	token := l.newToken(TokenTemplateTail, start, l.index)
+	l.reportError(diagnostic.CodeUnterminatedString, token.Span(), "Unterminated template literal")
+
+	return token
+}
+
+// scanTemplateText scans template text until the closing '`' or the next '${'
+//
+// The text ending with '`' becomes a closedType token, the text ending with '${' becomes
+// a substitutionType token and starts a template substitution
+func (l *Lexer) scanTemplateText(start int, closedType TokenType, substitutionType TokenType) (Token, bool) {
+	for l.index < len(l.source) {
+		switch {
+		case l.source[l.index] == '`':
+			l.index++
+			return l.newToken(closedType, start, l.index), true
+		case l.source[l.index] == '$' && l.peek(1) == '{':
+			l.index += 2
+			l.templates = append(l.templates, 0)
+			return l.newToken(substitutionType, start, l.index), true
+		default:
+			l.index++
+		}
+	}
+
+	return Token{}, false
+}
+
+// rejectUnterminatedString reports a string without closing quote
+func (l *Lexer) rejectUnterminatedString(start int) Token {
+	// Only the opening quote is rejected, the text after it is tokenized again
+	l.index = start + 1
+	token := l.newToken(TokenInvalid, start, l.index)
+	l.reportError(diagnostic.CodeUnterminatedString, token.Span(), "Unterminated string literal")
+
+	return token
+}
+
+// singleCharToken creates a token for the current character and advances past it
+func (l *Lexer) singleCharToken(tokenType TokenType) Token {
+	return l.multiCharToken(tokenType, 1)
+}
+
+// multiCharToken creates a token for the next length characters and advances past them
+func (l *Lexer) multiCharToken(tokenType TokenType, length int) Token {
+	start := l.index
+	l.index += length
+	return l.newToken(tokenType, start, l.index)
+}
+
+// peek returns the character at the given distance from the current one, or 0 past the end
+func (l *Lexer) peek(distance int) byte {
+	if l.index+distance >= len(l.source) {
+		return 0
+	}
+	return l.source[l.index+distance]
+}
+
+// newToken creates a token with its position information filled in
+func (l *Lexer) newToken(tokenType TokenType, start int, end int) Token {
+	line, column := l.positionOf(start)
+	return Token{
+		TokenType: tokenType,
+		Start:     start,
+		End:       end,
+		Line:      line,
+		Column:    column,
+		File:      l.file,
+	}
+}
+
+// positionOf returns the line and column of the offset
+//
+// Tokens are requested in source order, so the position is computed incrementally from the
+// previous one, the line table is only used when going backwards
+func (l *Lexer) positionOf(offset int) (int, int) {
+	cursor := l.position
+	if offset < cursor.offset {
+		return l.lines.Position(offset)
+	}
+
+	for cursor.offset < offset {
+		c := l.source[cursor.offset]
+		switch {
+		case c == '\n':
+			cursor.line++
+			cursor.column = 1
+			cursor.offset++
+		case c < utf8.RuneSelf:
+			cursor.column++
+			cursor.offset++
+		default:
+			_, size := utf8.DecodeRuneInString(l.source[cursor.offset:])
+			cursor.column++
+			cursor.offset += size
+		}
+	}
+
+	l.position = cursor
+	return cursor.line, cursor.column
+}
+
+// reportError records an error diagnostic
+func (l *Lexer) reportError(code diagnostic.Code, span source.Span, format string, args ...any) {
+	l.diagnostics = append(l.diagnostics, diagnostic.NewError(code, span, format, args...))
 }
 
 // Clone creates a copy of the lexer at its current state
 func (l *Lexer) Clone() *Lexer {
 	return &Lexer{
-		source: l.source,
-		index:  l.index,
-		rules:  l.rules,
+		config:      l.config,
+		file:        l.file,
+		source:      l.source,
+		index:       l.index,
+		lines:       l.lines,
+		position:    l.position,
+		templates:   append([]int(nil), l.templates...),
+		diagnostics: append([]diagnostic.Diagnostic(nil), l.diagnostics...),
 	}
 }
 
@@ -92,3 +465,13 @@ func (l *Lexer) Remaining() string {
 func (l *Lexer) Position() int {
 	return l.index
 }
+
+// Lines returns the line table of the source
+func (l *Lexer) Lines() *source.LineTable {
+	return l.lines
+}
+
+// Diagnostics returns the problems found while tokenizing the source
+func (l *Lexer) Diagnostics() []diagnostic.Diagnostic {
+	return l.diagnostics
+}
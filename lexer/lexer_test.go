@@ -1,8 +1,11 @@
 package lexer
 
 import (
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/yoh0xff/senbonzakura/diagnostic"
 )
 
 // Helper function to find substring index
@@ -98,6 +101,39 @@ func TestNumberToken(t *testing.T) {
 	}
 }
 
+func TestNumberLiteralForms(t *testing.T) {
+	literals := []string{"3.14", "1e9", "2.5E-3", "0xFF", "0b1010", "0o17", "1_000_000"}
+	source := strings.Join(literals, " ")
+	lexer := NewLexer(source)
+
+	for _, literal := range literals {
+		nextToken := lexer.NextToken()
+
+		expected := Token{
+			TokenType: TokenNumber,
+			Start:     strings.Index(source, literal),
+			End:       strings.Index(source, literal) + len(literal),
+		}
+
+		if !nextToken.Equal(expected) {
+			t.Errorf("Expected token %v, got %v", expected, nextToken)
+		}
+	}
+}
+
+func TestNumberFollowedByMember(t *testing.T) {
+	source := "1.x"
+	lexer := NewLexer(source)
+
+	expectedTokens := []TokenType{TokenNumber, TokenDot, TokenIdentifier, TokenEnd}
+	for i, expectedType := range expectedTokens {
+		token := lexer.NextToken()
+		if token.TokenType != expectedType {
+			t.Errorf("Token %d: expected %v, got %v", i, expectedType, token.TokenType)
+		}
+	}
+}
+
 func TestSkipWhitespace(t *testing.T) {
 	source := "    12"
 	lexer := NewLexer(source)
@@ -163,6 +199,58 @@ func TestStringTokenWithWhitespace(t *testing.T) {
 	}
 }
 
+// Test template literals
+func TestTemplateLiteralTokens(t *testing.T) {
+	source := "`a ${x} b ${ {c} } d` `plain $ {}`"
+	lexer := NewLexer(source)
+
+	expectedTokens := []struct {
+		tokenType TokenType
+		text      string
+	}{
+		{TokenTemplateHead, "`a ${"},
+		{TokenIdentifier, "x"},
+		{TokenTemplateMiddle, "} b ${"},
+		{TokenOpeningBrace, "{"},
+		{TokenIdentifier, "c"},
+		{TokenClosingBrace, "}"},
+		{TokenTemplateTail, "} d`"},
+		{TokenString, "`plain $ {}`"},
+		{TokenEnd, ""},
+	}
+
+	for i, expected := range expectedTokens {
+		token := lexer.NextToken()
+		if token.TokenType != expected.tokenType || source[token.Start:token.End] != expected.text {
+			t.Errorf(
+				"Token %d: expected %v %q, got %v %q",
+				i, expected.tokenType, expected.text, token.TokenType, source[token.Start:token.End],
+			)
+		}
+	}
+}
+
+func TestNestedTemplateLiteralTokens(t *testing.T) {
+	source := "`outer ${ `inner ${x}` } end`"
+	lexer := NewLexer(source)
+
+	expectedTokens := []TokenType{
+		TokenTemplateHead,
+		TokenTemplateHead,
+		TokenIdentifier,
+		TokenTemplateTail,
+		TokenTemplateTail,
+		TokenEnd,
+	}
+
+	for i, expectedType := range expectedTokens {
+		token := lexer.NextToken()
+		if token.TokenType != expectedType {
+			t.Errorf("Token %d: expected %v, got %v", i, expectedType, token.TokenType)
+		}
+	}
+}
+
 // Test helper functions for comprehensive testing
 func TestLexerEndOfInput(t *testing.T) {
 	source := "42"
@@ -204,13 +292,94 @@ func TestLexerInvalidToken(t *testing.T) {
 	source := "@invalid"
 	lexer := NewLexer(source)
 
-	defer func() {
-		if r := recover(); r == nil {
-			t.Errorf("Expected panic for invalid token, but got none")
+	invalidToken := lexer.NextToken()
+	expected := Token{
+		TokenType: TokenInvalid,
+		Start:     0,
+		End:       1,
+	}
+
+	if !invalidToken.Equal(expected) {
+		t.Errorf("Expected token %v, got %v", expected, invalidToken)
+	}
+
+	diagnostics := lexer.Diagnostics()
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic for invalid token, got %d", len(diagnostics))
+	}
+
+	if diagnostics[0].Code != diagnostic.CodeInvalidToken {
+		t.Errorf("Expected diagnostic code %s, got %s", diagnostic.CodeInvalidToken, diagnostics[0].Code)
+	}
+
+	// Lexing should continue after the invalid character
+	nextToken := lexer.NextToken()
+	if nextToken.TokenType != TokenIdentifier {
+		t.Errorf("Expected TokenIdentifier after invalid token, got %v", nextToken.TokenType)
+	}
+}
+
+func TestLexerTrivia(t *testing.T) {
+	source := "// header\n\n  \nlet /* a */ x; // end of x\n/* b */\n"
+	lexer := NewLexerWithConfig(source, Config{Trivia: true})
+
+	tests := []struct {
+		tokenType TokenType
+		leading   []string
+		trailing  []string
+	}{
+		{TokenLetKeyword, []string{"// header", "", "  "}, []string{"/* a */"}},
+		{TokenIdentifier, nil, nil},
+		{TokenStatementEnd, nil, []string{"// end of x"}},
+		{TokenEnd, []string{"/* b */"}, nil},
+	}
+
+	texts := func(trivia []Trivia) []string {
+		var result []string
+		for _, piece := range trivia {
+			result = append(result, piece.Text)
+		}
+		return result
+	}
+
+	for _, test := range tests {
+		token := lexer.NextToken()
+		if token.TokenType != test.tokenType {
+			t.Fatalf("Expected %v, got %v", test.tokenType, token.TokenType)
 		}
-	}()
 
-	lexer.NextToken()
+		var leading, trailing []string
+		if token.Trivia != nil {
+			leading, trailing = texts(token.Trivia.Leading), texts(token.Trivia.Trailing)
+		}
+		if !reflect.DeepEqual(leading, test.leading) || !reflect.DeepEqual(trailing, test.trailing) {
+			t.Errorf("%v: expected trivia %q and %q, got %q and %q", test.tokenType, test.leading, test.trailing, leading, trailing)
+		}
+	}
+}
+
+func TestLexerTriviaKinds(t *testing.T) {
+	lexer := NewLexerWithConfig("\n/* a\nb */ x", Config{File: "test", Trivia: true})
+
+	token := lexer.NextToken()
+	if token.Trivia == nil || len(token.Trivia.Leading) != 2 {
+		t.Fatalf("Expected a blank line and a comment, got %v", token.Trivia)
+	}
+	blank, comment := token.Trivia.Leading[0], token.Trivia.Leading[1]
+	if blank.Kind != TriviaBlankLine || blank.Span.Line != 1 {
+		t.Errorf("Expected a blank line at line 1, got %v at %s", blank.Kind, blank.Span)
+	}
+	if comment.Kind != TriviaComment || comment.Span.String() != "test:2:1" || comment.Text != "/* a\nb */" {
+		t.Errorf("Expected the comment at test:2:1, got %q at %s", comment.Text, comment.Span)
+	}
+}
+
+func TestLexerWithoutTrivia(t *testing.T) {
+	lexer := NewLexer("// a\nx")
+
+	if token := lexer.NextToken(); token.Trivia != nil {
+		t.Errorf("Expected no trivia by default, got %v", token.Trivia)
+	}
 }
 
 // Benchmark tests
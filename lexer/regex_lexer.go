@@ -0,0 +1,135 @@
+package lexer
+
+import (
+	"unicode/utf8"
+
+	"github.com/yoh0xff/senbonzakura/diagnostic"
+	"github.com/yoh0xff/senbonzakura/source"
+)
+
+// RegexLexer lazily pulls tokens from a stream by matching the regex rules in order
+//
+// It is the reference implementation of the token grammar, Lexer produces the same
+// token stream with a hand-written scanner and should be used instead.
+// Template literals need a lexer mode and are not supported, they are lexed as raw strings
+type RegexLexer struct {
+	file        string
+	source      string
+	index       int
+	rules       []RegexRule
+	lines       *source.LineTable
+	diagnostics []diagnostic.Diagnostic
+}
+
+// NewRegexLexer creates a new regex lexer instance
+func NewRegexLexer(source string) *RegexLexer {
+	return NewRegexLexerWithFile("", source)
+}
+
+// NewRegexLexerWithFile creates a new regex lexer instance for source read from the given file
+func NewRegexLexerWithFile(file string, sourceText string) *RegexLexer {
+	return &RegexLexer{
+		file:   file,
+		source: sourceText,
+		index:  0,
+		rules:  GetRegexRules(),
+		lines:  source.NewLineTable(sourceText),
+	}
+}
+
+// NextToken obtains the next token from the source
+func (l *RegexLexer) NextToken() Token {
+	currentIndex := l.index
+
+	// Check if we're at the end of the source
+	if currentIndex >= len(l.source) {
+		return l.newToken(TokenEnd, currentIndex, currentIndex)
+	}
+
+	// Slice the string starting from the current position
+	expression := l.source[currentIndex:]
+
+	for _, rule := range l.rules {
+		match := rule.Pattern.FindStringIndex(expression)
+		if match == nil {
+			continue // Try to match other token
+		}
+
+		// match[0] is the start index (should be 0 for our patterns)
+		// match[1] is the end index
+		tokenText := expression[match[0]:match[1]]
+		tokenLen := len(tokenText)
+
+		switch rule.TokenType {
+		case TokenWhitespace, TokenSingleLineComment, TokenMultiLineComment:
+			// Skip whitespace and comments
+			l.index = currentIndex + tokenLen
+			return l.NextToken()
+		default:
+			// Return the matched token
+			l.index = currentIndex + tokenLen
+			return l.newToken(rule.TokenType, currentIndex, currentIndex+tokenLen)
+		}
+	}
+
+	// If we get here, no token matched, report it and skip a single character
+	_, size := utf8.DecodeRuneInString(expression)
+	l.index = currentIndex + size
+	token := l.newToken(TokenInvalid, currentIndex, currentIndex+size)
+	l.diagnostics = append(l.diagnostics, diagnostic.NewError(
+		diagnostic.CodeInvalidToken,
+		token.Span(),
+		"Invalid token '%s'",
+		expression[:size],
+	))
+
+	return token
+}
+
+// newToken creates a token with its position information filled in
+func (l *RegexLexer) newToken(tokenType TokenType, start int, end int) Token {
+	line, column := l.lines.Position(start)
+	return Token{
+		TokenType: tokenType,
+		Start:     start,
+		End:       end,
+		Line:      line,
+		Column:    column,
+		File:      l.file,
+	}
+}
+
+// Clone creates a copy of the lexer at its current state
+func (l *RegexLexer) Clone() *RegexLexer {
+	return &RegexLexer{
+		file:        l.file,
+		source:      l.source,
+		index:       l.index,
+		rules:       l.rules,
+		lines:       l.lines,
+		diagnostics: append([]diagnostic.Diagnostic(nil), l.diagnostics...),
+	}
+}
+
+// Remaining returns the remaining source text
+func (l *RegexLexer) Remaining() string {
+	if l.index >= len(l.source) {
+		return ""
+	}
+	return l.source[l.index:]
+}
+
+// Position returns the current position in the source
+func (l *RegexLexer) Position() int {
+	return l.index
+}
+
+// Lines returns the line table of the source
+func (l *RegexLexer) Lines() *source.LineTable {
+	return l.lines
+}
+
+// Diagnostics returns the problems found while tokenizing the source
+func (l *RegexLexer) Diagnostics() []diagnostic.Diagnostic {
+	return l.diagnostics
+}
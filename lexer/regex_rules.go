@@ -92,11 +92,15 @@ func initRegexRules() {
 		{`^!`, TokenLogicalNotOperator, "logical not operator"},
 
 		// Numbers
-		{`^\d+`, TokenNumber, "number literal"},
+		{`^0[xX][0-9a-fA-F_]+`, TokenNumber, "hexadecimal number literal"},
+		{`^0[bB][01_]+`, TokenNumber, "binary number literal"},
+		{`^0[oO][0-7_]+`, TokenNumber, "octal number literal"},
+		{`^\d[\d_]*(?:\.\d[\d_]*)?(?:[eE][+-]?\d[\d_]*)?`, TokenNumber, "decimal number literal"},
 
 		// Strings
-		{`^"[^"]*"`, TokenString, "double quote string literal"},
-		{`^'[^']*'`, TokenString, "single quote string literal"},
+		{`^"(?:[^"\\]|\\[\s\S])*"`, TokenString, "double quote string literal"},
+		{`^'(?:[^'\\]|\\[\s\S])*'`, TokenString, "single quote string literal"},
+		{"^`[^`]*`", TokenString, "raw string literal"},
 
 		// Identifiers
 		{`^\w+`, TokenIdentifier, "identifiers"},
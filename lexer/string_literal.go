@@ -0,0 +1,139 @@
+package lexer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// EscapeError describes an invalid escape sequence in a string literal
+type EscapeError struct {
+	Offset  int // Offset of the escape sequence in the literal text
+	Length  int // Length of the escape sequence in bytes
+	Message string
+}
+
+// DecodeStringLiteral returns the value of a string token text, including its quotes
+//
+// Quoted strings support the escape sequences
+//
+//	\n \t \r \b \f \v \0 \\ \' \" \xHH \uHHHH \u{H...}
+//
+// raw strings delimited by '`' are returned as written. Invalid escape sequences are
+// reported and left out of the value, the rest of the literal is still decoded
+func DecodeStringLiteral(text string) (string, []EscapeError) {
+	if len(text) < 2 {
+		return "", nil
+	}
+
+	content := text[1 : len(text)-1]
+	if text[0] == '`' || !strings.ContainsRune(content, '\\') {
+		return content, nil
+	}
+
+	var builder strings.Builder
+	var errors []EscapeError
+
+	for i := 0; i < len(content); {
+		if content[i] != '\\' {
+			builder.WriteByte(content[i])
+			i++
+			continue
+		}
+
+		value, length, message := decodeEscape(content[i:])
+		if message != "" {
+			// +1 for the opening quote
+			errors = append(errors, EscapeError{Offset: i + 1, Length: length, Message: message})
+		} else {
+			builder.WriteString(value)
+		}
+		i += length
+	}
+
+	return builder.String(), errors
+}
+
+// decodeEscape decodes the escape sequence at the start of the text
+//
+// Returns the decoded value, the length of the sequence and an error message if the sequence is invalid
+func decodeEscape(text string) (string, int, string) {
+	if len(text) < 2 {
+		return "", len(text), "Incomplete escape sequence"
+	}
+
+	switch text[1] {
+	case 'n':
+		return "\n", 2, ""
+	case 't':
+		return "\t", 2, ""
+	case 'r':
+		return "\r", 2, ""
+	case 'b':
+		return "\b", 2, ""
+	case 'f':
+		return "\f", 2, ""
+	case 'v':
+		return "\v", 2, ""
+	case '0':
+		return "\x00", 2, ""
+	case '\\', '\'', '"':
+		return text[1:2], 2, ""
+	case 'x':
+		return decodeHexEscape(text, 2, 2)
+	case 'u':
+		if len(text) > 2 && text[2] == '{' {
+			return decodeBracedUnicodeEscape(text)
+		}
+		return decodeHexEscape(text, 2, 4)
+	default:
+		_, size := utf8.DecodeRuneInString(text[1:])
+		return "", 1 + size, fmt.Sprintf("Unknown escape sequence '%s'", text[:1+size])
+	}
+}
+
+// decodeHexEscape decodes an escape with a fixed number of hex digits after the prefix
+func decodeHexEscape(text string, prefixLength int, digits int) (string, int, string) {
+	end := prefixLength
+	for end < len(text) && end < prefixLength+digits && isHexDigit(text[end]) {
+		end++
+	}
+
+	if end-prefixLength != digits {
+		return "", end, fmt.Sprintf("Escape sequence '%s' needs %d hex digits", text[:end], digits)
+	}
+
+	codePoint, _ := strconv.ParseUint(text[prefixLength:end], 16, 32)
+	return encodeCodePoint(text[:end], rune(codePoint), end)
+}
+
+// decodeBracedUnicodeEscape decodes an escape of the form \u{H...} with 1 to 6 hex digits
+func decodeBracedUnicodeEscape(text string) (string, int, string) {
+	end := 3
+	for end < len(text) && isHexDigit(text[end]) {
+		end++
+	}
+
+	if end >= len(text) || text[end] != '}' {
+		return "", end, fmt.Sprintf("Unclosed unicode escape sequence '%s'", text[:end])
+	}
+
+	digits := text[3:end]
+	end++ // closing brace
+
+	if len(digits) == 0 || len(digits) > 6 {
+		return "", end, fmt.Sprintf("Unicode escape sequence '%s' needs 1 to 6 hex digits", text[:end])
+	}
+
+	codePoint, _ := strconv.ParseUint(digits, 16, 32)
+	return encodeCodePoint(text[:end], rune(codePoint), end)
+}
+
+// encodeCodePoint encodes the code point of an escape sequence as UTF-8
+func encodeCodePoint(sequence string, codePoint rune, length int) (string, int, string) {
+	if codePoint > utf8.MaxRune || (codePoint >= 0xD800 && codePoint <= 0xDFFF) {
+		return "", length, fmt.Sprintf("Escape sequence '%s' is not a valid unicode code point", sequence)
+	}
+	return string(codePoint), length, ""
+}
@@ -0,0 +1,63 @@
+package lexer
+
+import "testing"
+
+func TestDecodeStringLiteral(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{`"plain"`, "plain"},
+		{`'single'`, "single"},
+		{`"say \"hi\""`, `say "hi"`},
+		{`'it\'s'`, "it's"},
+		{`"a\nb\tc\rd\\e"`, "a\nb\tc\rd\\e"},
+		{`"\b\f\v\0"`, "\b\f\v\x00"},
+		{`"\x41\u00e9\u{1F600}"`, "Aé😀"},
+		{"`raw \\n \"text\"\nline`", "raw \\n \"text\"\nline"},
+	}
+
+	for _, test := range tests {
+		value, errors := DecodeStringLiteral(test.text)
+		if len(errors) != 0 {
+			t.Errorf("%s: expected no errors, got %v", test.text, errors)
+		}
+		if value != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.text, test.expected, value)
+		}
+	}
+}
+
+func TestDecodeStringLiteralErrors(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+		offset   int
+		length   int
+	}{
+		{`"a\qb"`, "ab", 2, 2},
+		{`"\x4"`, "", 1, 3},
+		{`"ab\u12"`, "ab", 3, 4},
+		{`"\u{110000}"`, "", 1, 10},
+		{`"\u{D800}"`, "", 1, 8},
+		{`"\u{}"`, "", 1, 4},
+		{`"\u{12"`, "", 1, 5},
+	}
+
+	for _, test := range tests {
+		value, errors := DecodeStringLiteral(test.text)
+		if len(errors) != 1 {
+			t.Errorf("%s: expected 1 error, got %v", test.text, errors)
+			continue
+		}
+		if errors[0].Offset != test.offset || errors[0].Length != test.length {
+			t.Errorf(
+				"%s: expected error at %d+%d, got %d+%d",
+				test.text, test.offset, test.length, errors[0].Offset, errors[0].Length,
+			)
+		}
+		if value != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.text, test.expected, value)
+		}
+	}
+}
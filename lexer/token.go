@@ -1,6 +1,10 @@
 package lexer
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/yoh0xff/senbonzakura/source"
+)
 
 // TokenType represents the different types of tokens in the language
 type TokenType int
@@ -81,6 +85,16 @@ const (
 	TokenNumber
 	TokenString
 
+	// Template literals, `head ${expr} middle ${expr} tail`
+
+	TokenTemplateHead   // from '`' to the first '${'
+	TokenTemplateMiddle // from '}' to the next '${'
+	TokenTemplateTail   // from '}' to the closing '`'
+
+	// TokenInvalid marks source text that does not match any token
+
+	TokenInvalid
+
 	// TokenEnd
 
 	TokenEnd
@@ -179,6 +193,14 @@ func (t TokenType) String() string {
 		return "TokenNumber"
 	case TokenString:
 		return "TokenString"
+	case TokenTemplateHead:
+		return "TokenTemplateHead"
+	case TokenTemplateMiddle:
+		return "TokenTemplateMiddle"
+	case TokenTemplateTail:
+		return "TokenTemplateTail"
+	case TokenInvalid:
+		return "TokenInvalid"
 	case TokenEnd:
 		return "TokenEnd"
 	default:
@@ -189,8 +211,24 @@ func (t TokenType) String() string {
 // Token represents a lexical token in the source code
 type Token struct {
 	TokenType TokenType
-	Start     int // Start position
-	End       int // End position
+	Start     int    // Start position
+	End       int    // End position
+	Line      int    // Line of the start position, 1-based
+	Column    int    // Column of the start position in runes, 1-based
+	File      string // Source file name, can be empty
+
+	Trivia *TokenTrivia // comments and blank lines around the token, only kept when the lexer is configured to
+}
+
+// Span returns the source span covered by the token
+func (t Token) Span() source.Span {
+	return source.Span{
+		File:   t.File,
+		Start:  t.Start,
+		End:    t.End,
+		Line:   t.Line,
+		Column: t.Column,
+	}
 }
 
 // TokenString returns a string representation of the Token
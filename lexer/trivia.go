@@ -0,0 +1,114 @@
+package lexer
+
+import (
+	"fmt"
+
+	"github.com/yoh0xff/senbonzakura/source"
+)
+
+// TriviaKind tells what a piece of trivia is
+type TriviaKind int
+
+const (
+	TriviaComment   TriviaKind = iota // single or multi line comment
+	TriviaBlankLine                   // line with only whitespace, without its line break
+)
+
+// String returns the name of the trivia kind
+func (k TriviaKind) String() string {
+	switch k {
+	case TriviaComment:
+		return "TriviaComment"
+	case TriviaBlankLine:
+		return "TriviaBlankLine"
+	default:
+		return fmt.Sprintf("TriviaKind(%d)", k)
+	}
+}
+
+// Trivia is source text between tokens that has no meaning for the parser
+type Trivia struct {
+	Kind TriviaKind
+	Text string // comment with its delimiters, or the whitespace of the blank line
+	Span source.Span
+}
+
+// TokenTrivia holds the trivia around a token
+//
+// The trailing trivia of a token ends with its line, everything else before the next token is leading trivia of
+// that token. The end token gets the trivia at the end of the source
+type TokenTrivia struct {
+	Leading  []Trivia
+	Trailing []Trivia
+}
+
+// nextTokenWithTrivia scans the next token with its leading and trailing trivia
+func (l *Lexer) nextTokenWithTrivia() Token {
+	leading := l.scanTrivia(false)
+
+	// Nothing is left to skip, the token itself is scanned like in the default mode
+	l.config.Trivia = false
+	token := l.NextToken()
+	l.config.Trivia = true
+	if token.TokenType == TokenEnd {
+		if len(leading) > 0 {
+			token.Trivia = &TokenTrivia{Leading: leading}
+		}
+		return token
+	}
+
+	trailing := l.scanTrivia(true)
+	if len(leading) > 0 || len(trailing) > 0 {
+		token.Trivia = &TokenTrivia{Leading: leading, Trailing: trailing}
+	}
+	return token
+}
+
+// scanTrivia collects the comments and blank lines up to the next token, or only up to the end of the line
+func (l *Lexer) scanTrivia(untilLineEnd bool) []Trivia {
+	var trivia []Trivia
+
+	// Start of the current line if it has only had whitespace so far, -1 otherwise
+	lineStart := -1
+	if l.index == 0 || l.source[l.index-1] == '\n' {
+		lineStart = l.index
+	}
+
+	for l.index < len(l.source) {
+		start := l.index
+		switch c := l.source[start]; {
+		case c == '\n':
+			if untilLineEnd {
+				return trivia
+			}
+			if lineStart >= 0 {
+				trivia = append(trivia, l.newTrivia(TriviaBlankLine, lineStart, start))
+			}
+			l.index++
+			lineStart = l.index
+		case isWhitespace(c):
+			l.index++
+		case c == '/' && l.peek(1) == '/':
+			l.skipSingleLineComment()
+			trivia = append(trivia, l.newTrivia(TriviaComment, start, l.index))
+			lineStart = -1
+		case c == '/' && l.peek(1) == '*':
+			l.skipMultiLineComment()
+			trivia = append(trivia, l.newTrivia(TriviaComment, start, l.index))
+			lineStart = -1
+		default:
+			return trivia
+		}
+	}
+	return trivia
+}
+
+// newTrivia creates trivia for the source text between the offsets
+func (l *Lexer) newTrivia(kind TriviaKind, start int, end int) Trivia {
+	line, column := l.positionOf(start)
+	return Trivia{
+		Kind: kind,
+		Text: l.source[start:end],
+		Span: source.Span{File: l.file, Start: start, End: end, Line: line, Column: column},
+	}
+}
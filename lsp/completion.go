@@ -0,0 +1,76 @@
+package lsp
+
+import (
+	"strings"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/checker"
+)
+
+// completionPlaceholder completes a member expression being typed, so the text around the cursor parses
+const completionPlaceholder = "__complete__"
+
+// completion proposes the fields and methods of the object before the '.' preceding the offset
+//
+// The name typed so far after the '.' is replaced by a placeholder and the text is analyzed again,
+// the type of the object of the member expression at the placeholder gives the members
+func (d *document) completion(offset int, builtins []checker.Builtin) CompletionList {
+	list := CompletionList{Items: []CompletionItem{}}
+
+	start := offset
+	for start > 0 && isIdentifierByte(d.text[start-1]) {
+		start--
+	}
+	if start == 0 || d.text[start-1] != '.' {
+		return list
+	}
+	prefix := d.text[start:offset]
+
+	// Statements being typed often miss their ';', the statement is dropped if it does not parse
+	var class *checker.Class
+	for _, placeholder := range []string{completionPlaceholder, completionPlaceholder + ";"} {
+		text := d.text[:start] + placeholder + d.text[offset:]
+		_, info, _ := analyze(fileName(d.uri), text, builtins)
+		if member, _ := memberAt(info, start); member != nil {
+			class = classOf(info, member)
+			break
+		}
+	}
+	if class == nil {
+		return list
+	}
+
+	seen := map[string]bool{}
+	add := func(name string, kind int, t checker.Type) {
+		if seen[name] || !strings.HasPrefix(name, prefix) {
+			return
+		}
+		seen[name] = true
+		list.Items = append(list.Items, CompletionItem{Label: name, Kind: kind, Detail: t.String()})
+	}
+
+	// Members of subclasses hide those of their superclasses
+	for ; class != nil; class = class.Super {
+		for _, name := range class.FieldOrder {
+			add(name, CompletionKindField, class.Fields[name])
+		}
+		if class.Declaration == nil {
+			continue
+		}
+		for _, statement := range class.Declaration.Body.Body {
+			method, ok := statement.(*ast.FunctionDeclarationStatement)
+			if !ok || method.Name.Name == "constructor" {
+				continue
+			}
+			if signature, ok := class.Methods[method.Name.Name]; ok {
+				add(method.Name.Name, CompletionKindMethod, signature)
+			}
+		}
+	}
+	return list
+}
+
+// isIdentifierByte checks if the byte can be part of an identifier
+func isIdentifierByte(b byte) bool {
+	return b == '_' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
@@ -0,0 +1,171 @@
+package lsp
+
+import (
+	"net/url"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/checker"
+	"github.com/yoh0xff/senbonzakura/diagnostic"
+	"github.com/yoh0xff/senbonzakura/parser"
+	"github.com/yoh0xff/senbonzakura/resolver"
+	"github.com/yoh0xff/senbonzakura/source"
+)
+
+// document is an open text document with the result of its analysis
+type document struct {
+	uri         string
+	version     int
+	text        string
+	lines       *source.LineTable
+	program     *ast.ProgramStatement
+	parents     ast.ParentMap
+	info        *checker.Info
+	diagnostics []diagnostic.Diagnostic
+}
+
+// newDocument parses and checks the text of a document
+func newDocument(uri string, version int, text string, builtins []checker.Builtin) *document {
+	d := &document{
+		uri:     uri,
+		version: version,
+		text:    text,
+		lines:   source.NewLineTable(text),
+	}
+	d.program, d.info, d.diagnostics = analyze(fileName(uri), text, builtins)
+	d.parents = ast.NewParentMap(d.program)
+	return d
+}
+
+// analyze parses and checks the text, type errors are only reported when there are no syntax errors
+//
+// Statements that failed to parse are skipped, so the rest of the program is still checked
+func analyze(file string, text string, builtins []checker.Builtin) (*ast.ProgramStatement, *checker.Info, []diagnostic.Diagnostic) {
+	program, diagnostics := parser.ParseRootStatement(parser.NewParserWithFile(file, text))
+	info, checked := checker.Check(program, builtins...)
+	if !diagnostic.HasErrors(diagnostics) {
+		diagnostics = append(diagnostics, checked...)
+	}
+	diagnostic.Sort(diagnostics)
+	return program.(*ast.ProgramStatement), info, diagnostics
+}
+
+// fileName returns the path of file URIs, other URIs are used as they are
+func fileName(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "file" {
+		return uri
+	}
+	return parsed.Path
+}
+
+// position converts a byte offset to an LSP position
+func (d *document) position(offset int) Position {
+	offset = max(0, min(offset, len(d.text)))
+	line, _ := d.lines.Position(offset)
+
+	character := 0
+	for _, r := range d.text[d.lines.LineStart(line):offset] {
+		character += utf16Length(r)
+	}
+	return Position{Line: line - 1, Character: character}
+}
+
+// offset converts an LSP position to a byte offset, positions past the end of a line are clamped to it
+func (d *document) offset(position Position) int {
+	if position.Line < 0 {
+		return 0
+	}
+	if position.Line >= d.lines.LineCount() {
+		return len(d.text)
+	}
+
+	offset := d.lines.LineStart(position.Line + 1)
+	for character := 0; character < position.Character && offset < len(d.text) && d.text[offset] != '\n'; {
+		r, size := utf8.DecodeRuneInString(d.text[offset:])
+		character += utf16Length(r)
+		offset += size
+	}
+	return offset
+}
+
+// rangeOf converts a span to an LSP range
+func (d *document) rangeOf(span source.Span) Range {
+	return Range{Start: d.position(span.Start), End: d.position(span.End)}
+}
+
+// utf16Length returns the number of UTF-16 code units of the rune, invalid runes count as one
+func utf16Length(r rune) int {
+	return max(1, utf16.RuneLen(r))
+}
+
+// contains checks if the offset is inside the span or right after it, where the cursor is after typing a name
+func contains(span source.Span, offset int) bool {
+	return span.Start <= offset && offset <= span.End
+}
+
+// identifierAt returns the identifier at the offset with the symbol it declares or refers to
+func (d *document) identifierAt(offset int) (*ast.IdentifierExpression, *resolver.Symbol) {
+	for _, bindings := range []map[*ast.IdentifierExpression]*resolver.Symbol{
+		d.info.Resolution.Uses,
+		d.info.Resolution.Declarations,
+	} {
+		for identifier, symbol := range bindings {
+			if contains(identifier.Span, offset) {
+				return identifier, symbol
+			}
+		}
+	}
+	return nil, nil
+}
+
+// typeReferenceAt returns the class type annotation at the offset with the class it refers to
+func (d *document) typeReferenceAt(offset int) (*ast.ClassType, *resolver.Symbol) {
+	for reference, symbol := range d.info.Resolution.TypeReferences {
+		if contains(reference.Span, offset) {
+			return reference, symbol
+		}
+	}
+	return nil, nil
+}
+
+// memberAt returns the member expression whose property name is at the offset
+func memberAt(info *checker.Info, offset int) (*ast.MemberExpression, *ast.IdentifierExpression) {
+	for expression := range info.Types {
+		member, ok := expression.(*ast.MemberExpression)
+		if !ok || member.Computed {
+			continue
+		}
+		property, ok := member.Property.(*ast.IdentifierExpression)
+		if ok && contains(property.Span, offset) {
+			return member, property
+		}
+	}
+	return nil, nil
+}
+
+// classOf returns the class of the object of a member expression, nil if it is not an instance
+func classOf(info *checker.Info, member *ast.MemberExpression) *checker.Class {
+	class, _ := info.TypeOf(member.Object).(*checker.Class)
+	return class
+}
+
+// memberSymbol finds the field or method declared in the class or its superclasses
+//
+// Fields assigned through 'this' without a declaration have no symbol
+func memberSymbol(info *checker.Info, class *checker.Class, name string) *resolver.Symbol {
+	for ; class != nil; class = class.Super {
+		if class.Declaration == nil {
+			continue
+		}
+		scope := info.Resolution.Scopes[class.Declaration]
+		if scope == nil {
+			continue
+		}
+		if symbol, ok := scope.Symbols[name]; ok {
+			return symbol
+		}
+	}
+	return nil
+}
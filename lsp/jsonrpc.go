@@ -0,0 +1,88 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// connection reads and writes JSON-RPC messages framed with a Content-Length header
+type connection struct {
+	reader *bufio.Reader
+	writer io.Writer
+	mutex  sync.Mutex // serializes writes
+}
+
+// newConnection creates a connection over the reader and the writer
+func newConnection(r io.Reader, w io.Writer) *connection {
+	return &connection{reader: bufio.NewReader(r), writer: w}
+}
+
+// read returns the content of the next message, io.EOF when the input is closed between messages
+func (c *connection) read() ([]byte, error) {
+	header, err := textproto.NewReader(c.reader).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF && len(header) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("invalid message header: %w", err)
+	}
+
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length header '%s'", header.Get("Content-Length"))
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, content); err != nil {
+		return nil, fmt.Errorf("truncated message: %w", err)
+	}
+	return content, nil
+}
+
+// write sends a message
+func (c *connection) write(message any) error {
+	content, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, err := fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n", len(content)); err != nil {
+		return err
+	}
+	_, err = c.writer.Write(content)
+	return err
+}
+
+// reply sends the result of a request, or its error if err is not nil
+func (c *connection) reply(id json.RawMessage, result any, err error) error {
+	message := response{JSONRPC: "2.0", ID: id}
+	if err != nil {
+		responseError, ok := err.(*ResponseError)
+		if !ok {
+			responseError = &ResponseError{Code: CodeInternalError, Message: err.Error()}
+		}
+		message.Error = responseError
+		return c.write(message)
+	}
+
+	content, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	raw := json.RawMessage(content)
+	message.Result = &raw
+	return c.write(message)
+}
+
+// notify sends a notification
+func (c *connection) notify(method string, params any) error {
+	return c.write(notification{JSONRPC: "2.0", Method: method, Params: params})
+}
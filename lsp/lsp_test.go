@@ -0,0 +1,384 @@
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeClient drives a server running in the same process through pipes
+type fakeClient struct {
+	t      *testing.T
+	conn   *connection
+	done   chan error
+	nextID int
+}
+
+func newFakeClient(t *testing.T) *fakeClient {
+	t.Helper()
+
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- NewServer(serverIn, serverOut).Run()
+		serverOut.Close()
+	}()
+
+	client := &fakeClient{t: t, conn: newConnection(clientIn, clientOut), done: done}
+	t.Cleanup(func() { clientOut.Close() })
+	return client
+}
+
+// message is any message sent by the server
+type message struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *ResponseError  `json:"error"`
+}
+
+func (c *fakeClient) receive() message {
+	c.t.Helper()
+	content, err := c.conn.read()
+	if err != nil {
+		c.t.Fatalf("Expected a message, got %v", err)
+	}
+	var received message
+	if err := json.Unmarshal(content, &received); err != nil {
+		c.t.Fatalf("Expected a JSON message, got %v", err)
+	}
+	return received
+}
+
+// request sends a request and decodes the result of its response into result
+func (c *fakeClient) request(method string, params any, result any) *ResponseError {
+	c.t.Helper()
+	c.nextID++
+	err := c.conn.write(map[string]any{"jsonrpc": "2.0", "id": c.nextID, "method": method, "params": params})
+	if err != nil {
+		c.t.Fatalf("Expected no error, got %v", err)
+	}
+
+	received := c.receive()
+	if string(received.ID) != strconv.Itoa(c.nextID) {
+		c.t.Fatalf("Expected the response to request %d, got %+v", c.nextID, received)
+	}
+	if received.Error != nil {
+		return received.Error
+	}
+	if err := json.Unmarshal(received.Result, result); err != nil {
+		c.t.Fatalf("Expected a result, got %s (%v)", received.Result, err)
+	}
+	return nil
+}
+
+func (c *fakeClient) notify(method string, params any) {
+	c.t.Helper()
+	if err := c.conn.write(map[string]any{"jsonrpc": "2.0", "method": method, "params": params}); err != nil {
+		c.t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+// diagnostics receives the next diagnostics published by the server
+func (c *fakeClient) diagnostics() PublishDiagnosticsParams {
+	c.t.Helper()
+	received := c.receive()
+	var params PublishDiagnosticsParams
+	if received.Method != "textDocument/publishDiagnostics" || json.Unmarshal(received.Params, &params) != nil {
+		c.t.Fatalf("Expected diagnostics, got %+v", received)
+	}
+	return params
+}
+
+// open initializes the server and opens the document
+func (c *fakeClient) open(uri string, text string) PublishDiagnosticsParams {
+	c.t.Helper()
+	var result InitializeResult
+	if err := c.request("initialize", map[string]any{}, &result); err != nil {
+		c.t.Fatalf("Expected no error, got %v", err)
+	}
+	c.notify("initialized", map[string]any{})
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: uri, LanguageID: "senbonzakura", Version: 1, Text: text},
+	})
+	return c.diagnostics()
+}
+
+// find returns the position of the substring in the text, moved right by shift characters
+func find(t *testing.T, text string, substring string, shift int) Position {
+	t.Helper()
+	offset := strings.Index(text, substring)
+	if offset < 0 {
+		t.Fatalf("Expected %q in the text", substring)
+	}
+	offset += shift
+	line := strings.Count(text[:offset], "\n")
+	return Position{Line: line, Character: offset - (strings.LastIndex(text[:offset], "\n") + 1)}
+}
+
+const uri = "file:///work/main.sz"
+
+const sample = `class Animal {
+  let name: string = "";
+  def speak(): string { return this.name; }
+}
+class Dog extends Animal {
+  def constructor(name: string) { this.name = name; this.tricks = 0; }
+  def fetch(times: number): number { return times; }
+}
+let d: Dog = new Dog("Rex");
+let s: string = d.speak();
+def twice(n: number): number { return n * 2; }
+print(twice(d.fetch(1)));
+`
+
+func TestServerLifecycle(t *testing.T) {
+	client := newFakeClient(t)
+
+	var result InitializeResult
+	if err := client.request("initialize", map[string]any{"capabilities": map[string]any{}}, &result); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	capabilities := result.Capabilities
+	if capabilities.TextDocumentSync != SyncFull || !capabilities.HoverProvider || !capabilities.DefinitionProvider ||
+		!capabilities.DocumentSymbolProvider || capabilities.CompletionProvider == nil {
+		t.Errorf("Expected all capabilities, got %+v", capabilities)
+	}
+
+	if err := client.request("workspace/symbol", map[string]any{}, nil); err == nil || err.Code != CodeMethodNotFound {
+		t.Errorf("Expected a method not found error, got %v", err)
+	}
+	var hover *Hover
+	err := client.request("textDocument/hover", TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}}, &hover)
+	if err == nil || err.Code != CodeInvalidParams {
+		t.Errorf("Expected an invalid params error for a closed document, got %v", err)
+	}
+
+	var shutdown any
+	if err := client.request("shutdown", nil, &shutdown); err != nil || shutdown != nil {
+		t.Errorf("Expected a null result, got %v (%v)", shutdown, err)
+	}
+	client.notify("exit", nil)
+	if err := <-client.done; err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	client = newFakeClient(t)
+	client.notify("exit", nil)
+	if err := <-client.done; !errors.Is(err, ErrNoShutdown) {
+		t.Errorf("Expected ErrNoShutdown, got %v", err)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	client := newFakeClient(t)
+
+	published := client.open(uri, "let x: number = 1;\nlet y: number = ;\n")
+	if published.URI != uri || len(published.Diagnostics) != 1 {
+		t.Fatalf("Expected one diagnostic, got %+v", published)
+	}
+	d := published.Diagnostics[0]
+	if d.Severity != SeverityError || d.Code != "P0003" || d.Range.Start != (Position{Line: 1, Character: 16}) {
+		t.Errorf("Expected a syntax error at 1:16, got %+v", d)
+	}
+
+	client.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: uri, Version: 2},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "let s: string = \"😀\"; let x: number = s;"}},
+	})
+	published = client.diagnostics()
+	if published.Version != 2 || len(published.Diagnostics) != 1 {
+		t.Fatalf("Expected one diagnostic, got %+v", published)
+	}
+	// The emoji counts as two UTF-16 code units
+	d = published.Diagnostics[0]
+	expected := Range{Start: Position{Line: 0, Character: 38}, End: Position{Line: 0, Character: 39}}
+	if d.Code != "T0001" || d.Range != expected {
+		t.Errorf("Expected a type error at %+v, got %+v", expected, d)
+	}
+
+	client.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: uri, Version: 3},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: sample}},
+	})
+	if published = client.diagnostics(); len(published.Diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics, got %+v", published.Diagnostics)
+	}
+
+	client.notify("textDocument/didClose", DidCloseTextDocumentParams{TextDocument: TextDocumentIdentifier{URI: uri}})
+	if published = client.diagnostics(); published.URI != uri || len(published.Diagnostics) != 0 {
+		t.Errorf("Expected diagnostics to be cleared, got %+v", published)
+	}
+}
+
+func TestHover(t *testing.T) {
+	client := newFakeClient(t)
+	client.open(uri, sample)
+
+	tests := []struct {
+		name      string
+		substring string
+		shift     int
+		expected  string
+	}{
+		{"variable", "s: string = d", 0, "let s: string"},
+		{"class annotation", "d: Dog", 3, "class Dog extends Animal"},
+		{"class", "new Dog", 4, "class Dog extends Animal"},
+		{"method", "d.speak", 2, "(method) Animal.speak(): string"},
+		{"parameter", "times;", 0, "(parameter) times: number"},
+		{"function", "twice(d", 0, "def twice(n: number): number"},
+		{"declared field", "this.name;", 5, "(field) Animal.name: string"},
+		{"assigned field", "tricks", 0, "(field) Dog.tricks: number"},
+		{"builtin", "print", 0, "(builtin) print: (...any) -> void"},
+		{"nothing", "\"Rex\"", 1, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hover *Hover
+			params := TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: uri},
+				Position:     find(t, sample, tt.substring, tt.shift),
+			}
+			if err := client.request("textDocument/hover", params, &hover); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if tt.expected == "" {
+				if hover != nil {
+					t.Errorf("Expected no hover, got %+v", hover)
+				}
+				return
+			}
+			if hover == nil || hover.Contents.Value != "```senbonzakura\n"+tt.expected+"\n```" {
+				t.Errorf("Expected hover %q, got %+v", tt.expected, hover)
+			}
+		})
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	client := newFakeClient(t)
+	client.open(uri, sample)
+
+	tests := []struct {
+		name       string
+		substring  string
+		shift      int
+		definition string // substring at the start of the declared name, empty for no definition
+	}{
+		{"variable", "d.speak", 0, "d: Dog"},
+		{"class", "new Dog", 4, "Dog extends"},
+		{"type annotation", "d: Dog", 3, "Dog extends"},
+		{"superclass", "extends Animal", 8, "Animal {"},
+		{"method", "d.speak", 2, "speak()"},
+		{"subclass method", "d.fetch", 2, "fetch("},
+		{"field", "this.name;", 5, "name: string ="},
+		{"parameter", "name; this", 0, "name: string)"},
+		{"declaration", "twice(n", 0, "twice(n"},
+		{"builtin", "print", 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var location *Location
+			params := TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: uri},
+				Position:     find(t, sample, tt.substring, tt.shift),
+			}
+			if err := client.request("textDocument/definition", params, &location); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if tt.definition == "" {
+				if location != nil {
+					t.Errorf("Expected no definition, got %+v", location)
+				}
+				return
+			}
+			expected := find(t, sample, tt.definition, 0)
+			if location == nil || location.URI != uri || location.Range.Start != expected {
+				t.Errorf("Expected definition at %+v, got %+v", expected, location)
+			}
+		})
+	}
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	client := newFakeClient(t)
+	client.open(uri, sample+"def outer(): void { def inner(): void {} }\n")
+
+	var symbols []DocumentSymbol
+	if err := client.request("textDocument/documentSymbol", DocumentSymbolParams{TextDocument: TextDocumentIdentifier{URI: uri}}, &symbols); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var describe func(symbols []DocumentSymbol) string
+	describe = func(symbols []DocumentSymbol) string {
+		var parts []string
+		for _, symbol := range symbols {
+			part := strconv.Itoa(symbol.Kind) + ":" + symbol.Name
+			if len(symbol.Children) > 0 {
+				part += "(" + describe(symbol.Children) + ")"
+			}
+			parts = append(parts, part)
+		}
+		return strings.Join(parts, " ")
+	}
+	expected := "5:Animal(8:name 6:speak) 5:Dog(9:constructor 6:fetch) 12:twice 12:outer(12:inner)"
+	if describe(symbols) != expected {
+		t.Errorf("Expected symbols %s, got %s", expected, describe(symbols))
+	}
+
+	dog := symbols[1]
+	if dog.Detail != "extends Animal" || dog.SelectionRange.Start != find(t, sample, "Dog extends", 0) ||
+		dog.Range.Start != find(t, sample, "class Dog", 0) || dog.Range.End != find(t, sample, "}\nlet d", 1) {
+		t.Errorf("Expected the ranges of Dog, got %+v", dog)
+	}
+	if fetch := dog.Children[1]; fetch.Detail != "fetch(times: number): number" {
+		t.Errorf("Expected the signature of fetch, got %q", fetch.Detail)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		cursor   string // the cursor is placed after the first occurrence
+		expected []string
+	}{
+		{"instance", sample + "d.", "\nd.", []string{"tricks", "fetch", "name", "speak"}},
+		{"prefix", sample + "d.sp", "\nd.sp", []string{"speak"}},
+		{"this", "class A { let x: number = 1; def f(): number { return this. } }", "this.", []string{"x", "f"}},
+		{"before other statements", sample + "let n: number = d.\nprint(n);", "= d.", []string{"tricks", "fetch", "name", "speak"}},
+		{"not an instance", "let n: number = 1; n.", "n.", nil},
+		{"no dot", sample + "d", "\nd", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeClient(t)
+			client.open(uri, tt.text)
+
+			var list CompletionList
+			params := TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: uri},
+				Position:     find(t, tt.text, tt.cursor, len(tt.cursor)),
+			}
+			if err := client.request("textDocument/completion", params, &list); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			var labels []string
+			for _, item := range list.Items {
+				labels = append(labels, item.Label)
+			}
+			if strings.Join(labels, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected completions %v, got %v", tt.expected, labels)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/checker"
+	"github.com/yoh0xff/senbonzakura/resolver"
+	"github.com/yoh0xff/senbonzakura/source"
+)
+
+// hover describes the declaration of the name at the offset, nil if there is none
+func (d *document) hover(offset int) *Hover {
+	var description string
+	var span source.Span
+
+	if identifier, symbol := d.identifierAt(offset); identifier != nil {
+		description, span = d.describe(symbol), identifier.Span
+	} else if reference, symbol := d.typeReferenceAt(offset); reference != nil && symbol != nil {
+		description, span = d.describe(symbol), reference.Span
+	} else if member, property := memberAt(d.info, offset); member != nil {
+		class := classOf(d.info, member)
+		if class == nil {
+			return nil
+		}
+		if symbol := memberSymbol(d.info, class, property.Name); symbol != nil {
+			description = d.describe(symbol)
+		} else if field, ok := class.Field(property.Name); ok {
+			description = fmt.Sprintf("(field) %s.%s: %s", class.Name, property.Name, field)
+		}
+		span = property.Span
+	}
+
+	if description == "" {
+		return nil
+	}
+	hoverRange := d.rangeOf(span)
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: "```senbonzakura\n" + description + "\n```"},
+		Range:    &hoverRange,
+	}
+}
+
+// describe returns the declaration of the symbol with its type annotations as written
+func (d *document) describe(symbol *resolver.Symbol) string {
+	switch declaration := symbol.Declaration.(type) {
+	case *ast.VariableExpression:
+		annotation := d.annotation(declaration.TypeAnnotation, symbol)
+		if symbol.Kind == resolver.SymbolField {
+			return fmt.Sprintf("(field) %s.%s: %s", className(symbol), symbol.Name, annotation)
+		}
+		return fmt.Sprintf("let %s: %s", symbol.Name, annotation)
+	case *ast.IdentifierExpression:
+		return fmt.Sprintf("(parameter) %s: %s", symbol.Name, d.annotation(d.parameterType(declaration), symbol))
+	case *ast.FunctionDeclarationStatement:
+		if symbol.Kind == resolver.SymbolMethod {
+			return fmt.Sprintf("(method) %s.%s", className(symbol), d.signature(declaration))
+		}
+		return "def " + d.signature(declaration)
+	case *ast.ClassDeclarationStatement:
+		if declaration.SuperClass != nil {
+			return fmt.Sprintf("class %s extends %s", symbol.Name, declaration.SuperClass.Name)
+		}
+		return "class " + symbol.Name
+	default:
+		return fmt.Sprintf("(%s) %s: %s", symbol.Kind, symbol.Name, d.info.Symbols[symbol])
+	}
+}
+
+// signature returns the name, parameters and return type of the function as written
+func (d *document) signature(declaration *ast.FunctionDeclarationStatement) string {
+	params := make([]string, len(declaration.Parameters))
+	for i, parameter := range declaration.Parameters {
+		name := d.text[parameter.Name.GetSpan().Start:parameter.Name.GetSpan().End]
+		params[i] = name + ": " + d.annotation(parameter.Type, nil)
+	}
+
+	result := fmt.Sprintf("%s(%s)", declaration.Name.Name, strings.Join(params, ", "))
+	if declaration.ReturnType != nil {
+		result += ": " + d.annotation(declaration.ReturnType, nil)
+	}
+	return result
+}
+
+// annotation returns the type annotation as written, or the checked type of the symbol if there is none
+func (d *document) annotation(annotation ast.Type, symbol *resolver.Symbol) string {
+	if annotation != nil {
+		span := annotation.GetSpan()
+		return d.text[span.Start:span.End]
+	}
+	if t, ok := d.info.Symbols[symbol]; ok && symbol != nil {
+		return t.String()
+	}
+	return checker.Unknown.String()
+}
+
+// parameterType finds the type annotation of the parameter declared by the name
+func (d *document) parameterType(name *ast.IdentifierExpression) ast.Type {
+	if parameter, ok := d.parents.Parent(name).(*ast.Parameter); ok {
+		return parameter.Type
+	}
+	return nil
+}
+
+// className returns the name of the class declaring the member symbol
+func className(symbol *resolver.Symbol) string {
+	if class, ok := symbol.Scope.Node.(*ast.ClassDeclarationStatement); ok {
+		return class.Name.Name
+	}
+	return ""
+}
+
+// definition returns the location of the declaration of the name at the offset, nil if there is none
+func (d *document) definition(offset int) *Location {
+	var symbol *resolver.Symbol
+	if identifier, found := d.identifierAt(offset); identifier != nil {
+		symbol = found
+	} else if reference, found := d.typeReferenceAt(offset); reference != nil {
+		symbol = found
+	} else if member, property := memberAt(d.info, offset); member != nil {
+		if class := classOf(d.info, member); class != nil {
+			symbol = memberSymbol(d.info, class, property.Name)
+		}
+	}
+
+	if symbol == nil || symbol.Identifier == nil {
+		return nil
+	}
+	return &Location{URI: d.uri, Range: d.rangeOf(symbol.Span())}
+}
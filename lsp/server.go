@@ -0,0 +1,264 @@
+// Package lsp implements a Language Server Protocol server for editors
+//
+// The server speaks JSON-RPC over a reader and a writer, usually the standard streams, and keeps the open
+// documents in memory. Every change parses and checks the whole document again
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/yoh0xff/senbonzakura/checker"
+	"github.com/yoh0xff/senbonzakura/diagnostic"
+)
+
+// ServerName is the name reported to clients
+const ServerName = "senbonzakura"
+
+// ErrNoShutdown is returned by Run when the client exits or disconnects without a shutdown request
+var ErrNoShutdown = errors.New("lsp: exit without shutdown")
+
+// Config defines the options of the server
+type Config struct {
+	Builtins []checker.Builtin // names provided by the host to every document
+}
+
+// Server answers the requests of one client
+type Server struct {
+	config    Config
+	conn      *connection
+	documents map[string]*document
+	shutdown  bool
+}
+
+// NewServer creates a server with the print builtin
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return NewServerWithConfig(in, out, Config{
+		Builtins: []checker.Builtin{{
+			Name: "print",
+			Type: &checker.Function{Params: []checker.Type{checker.Any}, Return: checker.Void, Variadic: true},
+		}},
+	})
+}
+
+// NewServerWithConfig creates a server with the given configuration
+func NewServerWithConfig(in io.Reader, out io.Writer, config Config) *Server {
+	return &Server{
+		config:    config,
+		conn:      newConnection(in, out),
+		documents: map[string]*document{},
+	}
+}
+
+// Run handles messages until the exit notification or the end of the input
+func (s *Server) Run() error {
+	for {
+		content, err := s.conn.read()
+		if err == io.EOF {
+			if !s.shutdown {
+				return ErrNoShutdown
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var message request
+		if err := json.Unmarshal(content, &message); err != nil {
+			err = s.conn.reply(json.RawMessage("null"), nil, &ResponseError{Code: CodeParseError, Message: err.Error()})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if message.Method == "exit" {
+			if !s.shutdown {
+				return ErrNoShutdown
+			}
+			return nil
+		}
+		if err := s.handle(message); err != nil {
+			return err
+		}
+	}
+}
+
+// handle dispatches a message, only errors writing to the client are returned
+func (s *Server) handle(message request) error {
+	if message.ID == nil {
+		return s.handleNotification(message)
+	}
+
+	var result any
+	var err error
+	if s.shutdown {
+		err = &ResponseError{Code: CodeInvalidRequest, Message: "server is shut down"}
+	} else {
+		result, err = s.handleRequest(message)
+	}
+	return s.conn.reply(message.ID, result, err)
+}
+
+// handleRequest returns the result of a request
+func (s *Server) handleRequest(message request) (any, error) {
+	switch message.Method {
+	case "initialize":
+		return InitializeResult{
+			Capabilities: ServerCapabilities{
+				TextDocumentSync:       SyncFull,
+				HoverProvider:          true,
+				DefinitionProvider:     true,
+				DocumentSymbolProvider: true,
+				CompletionProvider:     &CompletionOptions{TriggerCharacters: []string{"."}},
+			},
+			ServerInfo: ServerInfo{Name: ServerName},
+		}, nil
+
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		document, err := s.positionParams(message.Params, &params)
+		if err != nil {
+			return nil, err
+		}
+		return document.hover(document.offset(params.Position)), nil
+
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		document, err := s.positionParams(message.Params, &params)
+		if err != nil {
+			return nil, err
+		}
+		return document.definition(document.offset(params.Position)), nil
+
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		document, err := s.positionParams(message.Params, &params)
+		if err != nil {
+			return nil, err
+		}
+		return document.completion(document.offset(params.Position), s.config.Builtins), nil
+
+	case "textDocument/documentSymbol":
+		var params DocumentSymbolParams
+		if err := decodeParams(message.Params, &params); err != nil {
+			return nil, err
+		}
+		document, err := s.document(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return document.symbols(document.program.Body, false), nil
+
+	default:
+		return nil, &ResponseError{Code: CodeMethodNotFound, Message: fmt.Sprintf("method '%s' is not supported", message.Method)}
+	}
+}
+
+// handleNotification updates the documents, unknown notifications are ignored
+func (s *Server) handleNotification(message request) error {
+	switch message.Method {
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if decodeParams(message.Params, &params) != nil {
+			return nil
+		}
+		item := params.TextDocument
+		return s.update(item.URI, item.Version, item.Text)
+
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if decodeParams(message.Params, &params) != nil || len(params.ContentChanges) == 0 {
+			return nil
+		}
+		// With full synchronization the last change holds the whole text
+		text := params.ContentChanges[len(params.ContentChanges)-1].Text
+		return s.update(params.TextDocument.URI, params.TextDocument.Version, text)
+
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if decodeParams(message.Params, &params) != nil {
+			return nil
+		}
+		delete(s.documents, params.TextDocument.URI)
+		return s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []Diagnostic{},
+		})
+	}
+	return nil
+}
+
+// update analyzes the new text of a document and publishes its diagnostics
+func (s *Server) update(uri string, version int, text string) error {
+	document := newDocument(uri, version, text, s.config.Builtins)
+	s.documents[uri] = document
+
+	diagnostics := make([]Diagnostic, 0, len(document.diagnostics))
+	for _, d := range document.diagnostics {
+		diagnostics = append(diagnostics, document.convertDiagnostic(d))
+	}
+	return s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         uri,
+		Version:     version,
+		Diagnostics: diagnostics,
+	})
+}
+
+// convertDiagnostic converts a diagnostic to the protocol, notes become related information
+func (d *document) convertDiagnostic(from diagnostic.Diagnostic) Diagnostic {
+	severity := SeverityError
+	switch from.Severity {
+	case diagnostic.SeverityWarning:
+		severity = SeverityWarning
+	case diagnostic.SeverityInfo:
+		severity = SeverityInformation
+	}
+
+	result := Diagnostic{
+		Range:    d.rangeOf(from.Span),
+		Severity: severity,
+		Code:     string(from.Code),
+		Source:   ServerName,
+		Message:  from.Message,
+	}
+	for _, note := range from.Notes {
+		result.RelatedInformation = append(result.RelatedInformation, DiagnosticRelatedInformation{
+			Location: Location{URI: d.uri, Range: d.rangeOf(note.Span)},
+			Message:  note.Message,
+		})
+	}
+	return result
+}
+
+// positionParams decodes the parameters of a request about a position and returns the document
+func (s *Server) positionParams(raw json.RawMessage, params *TextDocumentPositionParams) (*document, error) {
+	if err := decodeParams(raw, params); err != nil {
+		return nil, err
+	}
+	return s.document(params.TextDocument.URI)
+}
+
+// document returns an open document
+func (s *Server) document(uri string) (*document, error) {
+	document, ok := s.documents[uri]
+	if !ok {
+		return nil, &ResponseError{Code: CodeInvalidParams, Message: fmt.Sprintf("document '%s' is not open", uri)}
+	}
+	return document, nil
+}
+
+// decodeParams decodes the parameters of a message
+func decodeParams(raw json.RawMessage, params any) error {
+	if err := json.Unmarshal(raw, params); err != nil {
+		return &ResponseError{Code: CodeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
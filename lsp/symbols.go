@@ -0,0 +1,59 @@
+package lsp
+
+import (
+	"github.com/yoh0xff/senbonzakura/ast"
+)
+
+// symbols returns the functions and classes declared in the statements, with the members of classes and
+// the functions nested in functions as children
+func (d *document) symbols(statements []ast.Statement, inClass bool) []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+	for _, statement := range statements {
+		switch statement := statement.(type) {
+		case *ast.FunctionDeclarationStatement:
+			kind := SymbolKindFunction
+			if inClass {
+				kind = SymbolKindMethod
+				if statement.Name.Name == "constructor" {
+					kind = SymbolKindConstructor
+				}
+			}
+			symbols = append(symbols, DocumentSymbol{
+				Name:           statement.Name.Name,
+				Detail:         d.signature(statement),
+				Kind:           kind,
+				Range:          d.rangeOf(statement.Span),
+				SelectionRange: d.rangeOf(statement.Name.Span),
+				Children:       d.symbols(statement.Body.Body, false),
+			})
+
+		case *ast.ClassDeclarationStatement:
+			symbol := DocumentSymbol{
+				Name:           statement.Name.Name,
+				Kind:           SymbolKindClass,
+				Range:          d.rangeOf(statement.Span),
+				SelectionRange: d.rangeOf(statement.Name.Span),
+				Children:       d.symbols(statement.Body.Body, true),
+			}
+			if statement.SuperClass != nil {
+				symbol.Detail = "extends " + statement.SuperClass.Name
+			}
+			symbols = append(symbols, symbol)
+
+		case *ast.VariableDeclarationStatement:
+			if !inClass {
+				continue
+			}
+			for _, variable := range statement.Variables {
+				symbols = append(symbols, DocumentSymbol{
+					Name:           variable.Identifier.Name,
+					Detail:         d.annotation(variable.TypeAnnotation, d.info.Resolution.Declarations[variable.Identifier]),
+					Kind:           SymbolKindField,
+					Range:          d.rangeOf(variable.Span),
+					SelectionRange: d.rangeOf(variable.Identifier.Span),
+				})
+			}
+		}
+	}
+	return symbols
+}
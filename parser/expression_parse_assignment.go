@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/diagnostic"
 	"github.com/yoh0xff/senbonzakura/lexer"
 )
 
@@ -47,7 +48,13 @@ func parseAssignmentExpression(parser *Parser) ast.Expression {
 	}
 
 	if !isNextTokenValidAssignmentTarget(left) {
-		panic("Invalid left-hand side in the assignment expression")
+		// The assignment is still parsed so that the rest of the source can be checked
+		reportError(
+			parser,
+			diagnostic.CodeInvalidAssignmentTarget,
+			assignmentOperatorToken.Span(),
+			"Invalid left-hand side in the assignment expression",
+		)
 	}
 
 	right := parseAssignmentExpression(parser)
@@ -56,5 +63,6 @@ func parseAssignmentExpression(parser *Parser) ast.Expression {
 		Operator: assignmentOperator,
 		Left:     left,
 		Right:    right,
+		Span:     left.GetSpan().Join(right.GetSpan()),
 	}
 }
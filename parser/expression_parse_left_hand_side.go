@@ -50,9 +50,11 @@ func parseCallMemberExpression(parser *Parser) ast.Expression {
 //	| CallExpression
 //	;
 func parseCallExpression(parser *Parser, callee ast.Expression) ast.Expression {
+	arguments := parseArguments(parser)
 	callExpression := &ast.CallExpression{
 		Callee:    callee,
-		Arguments: parseArguments(parser),
+		Arguments: arguments,
+		Span:      callee.GetSpan().Join(parser.previous.Span()),
 	}
 
 	// Check for chained calls
@@ -127,18 +129,20 @@ func parseMemberExpression(parser *Parser) ast.Expression {
 				Computed: false,
 				Object:   object,
 				Property: property,
+				Span:     object.GetSpan().Join(property.GetSpan()),
 			}
 		}
 
 		if isNextTokenOfType(parser, lexer.TokenOpeningBracket) {
 			eatToken(parser, lexer.TokenOpeningBracket)
-			property := ParseRootExpression(parser)
+			property := parseExpression(parser)
 			eatToken(parser, lexer.TokenClosingBracket)
 
 			object = &ast.MemberExpression{
 				Computed: true,
 				Object:   object,
 				Property: property,
+				Span:     object.GetSpan().Join(parser.previous.Span()),
 			}
 		}
 	}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/diagnostic"
 	"github.com/yoh0xff/senbonzakura/lexer"
 )
 
@@ -45,6 +46,7 @@ func parseBooleanLiteralExpression(parser *Parser) ast.Expression {
 
 	return &ast.BooleanLiteralExpression{
 		Value: boolValue,
+		Span:  token.Span(),
 	}
 }
 
@@ -55,9 +57,11 @@ func parseBooleanLiteralExpression(parser *Parser) ast.Expression {
 //	: NIL
 //	;
 func parseNilLiteralExpression(parser *Parser) ast.Expression {
-	eatToken(parser, lexer.TokenNil)
+	token := eatToken(parser, lexer.TokenNil)
 
-	return &ast.NilLiteralExpression{}
+	return &ast.NilLiteralExpression{
+		Span: token.Span(),
+	}
 }
 
 // parseNumericLiteralExpression parses numeric literals
@@ -69,17 +73,90 @@ func parseNilLiteralExpression(parser *Parser) ast.Expression {
 func parseNumericLiteralExpression(parser *Parser) ast.Expression {
 	token := eatToken(parser, lexer.TokenNumber)
 	tokenValue := parser.source[token.Start:token.End]
-	tokenValue = strings.TrimSpace(tokenValue)
 
-	// Parse the number as int32
-	numValue, err := strconv.ParseInt(tokenValue, 10, 32)
+	literal := &ast.NumericLiteralExpression{
+		Kind: ast.IntegerLiteral,
+		Raw:  tokenValue,
+		Span: token.Span(),
+	}
+
+	// Split the base prefix from the digits
+	digits, base := tokenValue, 10
+	if len(tokenValue) > 2 && tokenValue[0] == '0' {
+		switch tokenValue[1] {
+		case 'x', 'X':
+			digits, base = tokenValue[2:], 16
+		case 'b', 'B':
+			digits, base = tokenValue[2:], 2
+		case 'o', 'O':
+			digits, base = tokenValue[2:], 8
+		}
+	}
+
+	if !hasValidNumericSeparators(digits, base) {
+		reportError(
+			parser,
+			diagnostic.CodeInvalidNumericLiteral,
+			token.Span(),
+			"Invalid numeric literal '%s', '_' separators are only allowed between digits",
+			tokenValue,
+		)
+		return literal
+	}
+	digits = strings.ReplaceAll(digits, "_", "")
+
+	if base == 10 && strings.ContainsAny(digits, ".eE") {
+		numValue, err := strconv.ParseFloat(digits, 64)
+		if err != nil {
+			reportError(
+				parser,
+				diagnostic.CodeNumericLiteralOverflow,
+				token.Span(),
+				"Numeric literal '%s' is out of the 64-bit floating point range",
+				tokenValue,
+			)
+			return literal
+		}
+
+		literal.Kind = ast.FloatLiteral
+		literal.FloatValue = numValue
+		return literal
+	}
+
+	numValue, err := strconv.ParseInt(digits, base, 64)
 	if err != nil {
-		panic("Invalid numeric literal: " + err.Error())
+		reportError(
+			parser,
+			diagnostic.CodeNumericLiteralOverflow,
+			token.Span(),
+			"Numeric literal '%s' is out of the 64-bit integer range",
+			tokenValue,
+		)
+		return literal
+	}
+
+	literal.IntValue = numValue
+	literal.FloatValue = float64(numValue)
+	return literal
+}
+
+// hasValidNumericSeparators checks that every '_' in the digits is surrounded by digits of the base
+func hasValidNumericSeparators(digits string, base int) bool {
+	isBaseDigit := func(c byte) bool {
+		value := strings.IndexByte("0123456789abcdef", c|0x20) // lower case letters
+		return value >= 0 && value < base
 	}
 
-	return &ast.NumericLiteralExpression{
-		Value: int32(numValue),
+	for i := 0; i < len(digits); i++ {
+		if digits[i] != '_' {
+			continue
+		}
+		if i == 0 || i == len(digits)-1 || !isBaseDigit(digits[i-1]) || !isBaseDigit(digits[i+1]) {
+			return false
+		}
 	}
+
+	return true
 }
 
 // parseStringLiteralExpression parses string literals
@@ -90,10 +167,69 @@ func parseNumericLiteralExpression(parser *Parser) ast.Expression {
 //	;
 func parseStringLiteralExpression(parser *Parser) ast.Expression {
 	token := eatToken(parser, lexer.TokenString)
-	// Remove the surrounding quotes
-	tokenValue := parser.source[token.Start+1 : token.End-1]
+	tokenValue := parser.source[token.Start:token.End]
+
+	value, escapeErrors := lexer.DecodeStringLiteral(tokenValue)
+	for _, escapeError := range escapeErrors {
+		start := token.Start + escapeError.Offset
+		reportError(
+			parser,
+			diagnostic.CodeInvalidEscapeSequence,
+			parser.Lines().Span(token.File, start, start+escapeError.Length),
+			"%s",
+			escapeError.Message,
+		)
+	}
 
 	return &ast.StringLiteralExpression{
-		Value: tokenValue,
+		Value: value,
+		Raw:   tokenValue,
+		Span:  token.Span(),
+	}
+}
+
+// parseTemplateLiteralExpression parses template literals with substitutions
+//
+// TemplateLiteral
+//
+//	: TEMPLATE_HEAD Expression TemplateSpans
+//	;
+//
+// TemplateSpans
+//
+//	: TEMPLATE_TAIL
+//	| TEMPLATE_MIDDLE Expression TemplateSpans
+//	;
+//
+// Template text is raw like in raw strings, escape sequences are not decoded
+func parseTemplateLiteralExpression(parser *Parser) ast.Expression {
+	startToken := eatToken(parser, lexer.TokenTemplateHead)
+
+	// Head and middle chunks end with '${', the tail chunk ends with '`'
+	chunks := []string{parser.source[startToken.Start+1 : startToken.End-2]}
+	var expressions []ast.Expression
+
+	for {
+		expressions = append(expressions, parseExpression(parser))
+
+		if isNextTokenOfType(parser, lexer.TokenTemplateMiddle) {
+			middleToken := eatToken(parser, lexer.TokenTemplateMiddle)
+			chunks = append(chunks, parser.source[middleToken.Start+1:middleToken.End-2])
+			continue
+		}
+
+		tailToken := eatToken(parser, lexer.TokenTemplateTail)
+		tailText := parser.source[tailToken.Start+1 : tailToken.End]
+		if strings.HasSuffix(tailText, "`") {
+			tailText = tailText[:len(tailText)-1]
+		}
+		chunks = append(chunks, tailText)
+		break
+	}
+
+	return &ast.TemplateLiteralExpression{
+		Chunks:      chunks,
+		Expressions: expressions,
+		Span:        spanFrom(parser, startToken),
 	}
 }
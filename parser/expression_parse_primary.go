@@ -2,6 +2,7 @@ package parser
 
 import (
 	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/diagnostic"
 	"github.com/yoh0xff/senbonzakura/lexer"
 )
 
@@ -11,6 +12,7 @@ import (
 //
 //	: LiteralExpression
 //	| GroupExpression
+//	| TemplateLiteralExpression
 //	| IdentifierExpression
 //	| ThisExpression
 //	;
@@ -22,6 +24,8 @@ func parsePrimaryExpression(parser *Parser) ast.Expression {
 	switch parser.lookahead.TokenType {
 	case lexer.TokenOpeningParenthesis:
 		return parseGroupExpression(parser)
+	case lexer.TokenTemplateHead:
+		return parseTemplateLiteralExpression(parser)
 	case lexer.TokenIdentifier:
 		return parseIdentifierExpression(parser)
 	case lexer.TokenThisKeyword:
@@ -31,7 +35,14 @@ func parsePrimaryExpression(parser *Parser) ast.Expression {
 	case lexer.TokenNewKeyword:
 		return parseNewExpression(parser)
 	default:
-		return parseLeftHandSideExpression(parser)
+		failWithError(
+			parser,
+			diagnostic.CodeExpectedExpression,
+			parser.lookahead.Span(),
+			"Expected expression, found: %s",
+			describeToken(parser, parser.lookahead),
+		)
+		return nil
 	}
 }
 
@@ -43,9 +54,10 @@ func parsePrimaryExpression(parser *Parser) ast.Expression {
 //	;
 func parseGroupExpression(parser *Parser) ast.Expression {
 	eatToken(parser, lexer.TokenOpeningParenthesis)
-	expression := ParseRootExpression(parser)
+	expression := parseExpression(parser)
 	eatToken(parser, lexer.TokenClosingParenthesis)
 
+	// The group itself has no node, the inner expression keeps its own span
 	return expression
 }
 
@@ -61,6 +73,7 @@ func parseIdentifierExpression(parser *Parser) ast.Expression {
 
 	return &ast.IdentifierExpression{
 		Name: identifierValue,
+		Span: identifierToken.Span(),
 	}
 }
 
@@ -71,8 +84,10 @@ func parseIdentifierExpression(parser *Parser) ast.Expression {
 //	: this
 //	;
 func parseThisExpression(parser *Parser) ast.Expression {
-	eatToken(parser, lexer.TokenThisKeyword)
-	return &ast.ThisExpression{}
+	token := eatToken(parser, lexer.TokenThisKeyword)
+	return &ast.ThisExpression{
+		Span: token.Span(),
+	}
 }
 
 // parseSuperExpression parses 'super' expressions
@@ -82,8 +97,10 @@ func parseThisExpression(parser *Parser) ast.Expression {
 //	: super
 //	;
 func parseSuperExpression(parser *Parser) ast.Expression {
-	eatToken(parser, lexer.TokenSuperKeyword)
-	return &ast.SuperExpression{}
+	token := eatToken(parser, lexer.TokenSuperKeyword)
+	return &ast.SuperExpression{
+		Span: token.Span(),
+	}
 }
 
 // parseNewExpression parses 'new' expressions
@@ -93,7 +110,7 @@ func parseSuperExpression(parser *Parser) ast.Expression {
 //	: new MemberExpression Arguments
 //	;
 func parseNewExpression(parser *Parser) ast.Expression {
-	eatToken(parser, lexer.TokenNewKeyword)
+	startToken := eatToken(parser, lexer.TokenNewKeyword)
 
 	callee := parseMemberExpression(parser)
 	arguments := parseArguments(parser)
@@ -101,5 +118,6 @@ func parseNewExpression(parser *Parser) ast.Expression {
 	return &ast.NewExpression{
 		Callee:    callee,
 		Arguments: arguments,
+		Span:      spanFrom(parser, startToken),
 	}
 }
@@ -47,9 +47,12 @@ func parseUnaryExpression(parser *Parser) ast.Expression {
 		}
 
 		// Create a unary expression node
+		right := parseUnaryExpression(parser) // Recursive call for right operand
+
 		return &ast.UnaryExpression{
 			Operator: operator,
-			Right:    parseUnaryExpression(parser), // Recursive call for right operand
+			Right:    right,
+			Span:     spanFrom(parser, operatorToken),
 		}
 	}
 
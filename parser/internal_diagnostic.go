@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/yoh0xff/senbonzakura/diagnostic"
+	"github.com/yoh0xff/senbonzakura/lexer"
+	"github.com/yoh0xff/senbonzakura/source"
+)
+
+// bailout is raised to unwind the parser after an unrecoverable syntax error
+type bailout struct{}
+
+// recoverBailout stops a bailout raised by the parser and calls onBailout, other panics are propagated
+func recoverBailout(parser *Parser, onBailout func()) {
+	if r := recover(); r != nil {
+		if _, ok := r.(bailout); !ok {
+			panic(r)
+		}
+		onBailout()
+	}
+}
+
+// reportError records an error diagnostic and continues parsing
+func reportError(parser *Parser, code diagnostic.Code, span source.Span, format string, args ...any) {
+	parser.diagnostics = append(parser.diagnostics, diagnostic.NewError(code, span, format, args...))
+}
+
+// failWithError records an error diagnostic and aborts parsing of the current construct
+func failWithError(parser *Parser, code diagnostic.Code, span source.Span, format string, args ...any) {
+	reportError(parser, code, span, format, args...)
+	panic(bailout{})
+}
+
+// failUnexpectedToken reports the lookahead as unexpected and aborts parsing of the current construct
+func failUnexpectedToken(parser *Parser, expected string) {
+	failWithError(
+		parser,
+		diagnostic.CodeUnexpectedToken,
+		parser.lookahead.Span(),
+		"Unexpected token: %s, expected %s",
+		describeToken(parser, parser.lookahead), expected,
+	)
+}
+
+// describeToken returns a human-readable description of the token
+func describeToken(parser *Parser, token lexer.Token) string {
+	if token.TokenType == lexer.TokenEnd {
+		return "end of input"
+	}
+	return fmt.Sprintf("'%s' (%s)", parser.source[token.Start:token.End], token.TokenType.String())
+}
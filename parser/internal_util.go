@@ -2,38 +2,75 @@ package parser
 
 import (
 	"fmt"
+
 	"github.com/yoh0xff/senbonzakura/ast"
 	"github.com/yoh0xff/senbonzakura/lexer"
+	"github.com/yoh0xff/senbonzakura/source"
 )
 
+// nextToken pulls the next meaningful token from the lexer
+//
+// Invalid tokens are skipped here, the lexer has already reported them
+func nextToken(parser *Parser) lexer.Token {
+	token := parser.lexer.NextToken()
+	collectComments(parser, token)
+	for token.TokenType == lexer.TokenInvalid {
+		token = parser.lexer.NextToken()
+		collectComments(parser, token)
+	}
+	return token
+}
+
+// collectComments records the comments in the trivia of the token
+func collectComments(parser *Parser, token lexer.Token) {
+	if token.Trivia == nil {
+		return
+	}
+	for _, trivia := range [][]lexer.Trivia{token.Trivia.Leading, token.Trivia.Trailing} {
+		for _, piece := range trivia {
+			if piece.Kind == lexer.TriviaComment {
+				parser.comments = append(parser.comments, ast.Comment{Text: piece.Text, Span: piece.Span})
+			}
+		}
+	}
+}
+
+// advance consumes the lookahead token and returns it
+func advance(parser *Parser) lexer.Token {
+	preToken := parser.lookahead
+	parser.previous = preToken
+	parser.lookahead = nextToken(parser)
+	return preToken
+}
+
 // eatToken expects a token of a given type
 func eatToken(parser *Parser, tokenType lexer.TokenType) lexer.Token {
 	if parser.lookahead.TokenType != tokenType {
-		panic(fmt.Sprintf(
-			"Unexpected token: %s, expected token: '%s'",
-			parser.lookahead.TokenType.String(), tokenType.String(),
-		))
+		failUnexpectedToken(parser, fmt.Sprintf("'%s'", tokenType.String()))
 	}
 
-	preToken := parser.lookahead
-	parser.lookahead = parser.lexer.NextToken()
-	return preToken
+	return advance(parser)
 }
 
 // eatAnyOfToken expects a token of any given types
 func eatAnyOfToken(parser *Parser, tokenTypes []lexer.TokenType) lexer.Token {
 	for _, tokenType := range tokenTypes {
 		if parser.lookahead.TokenType == tokenType {
-			preToken := parser.lookahead
-			parser.lookahead = parser.lexer.NextToken()
-			return preToken
+			return advance(parser)
 		}
 	}
 
-	panic(fmt.Sprintf(
-		"Unexpected token: %s, expected tokens: '%v'",
-		parser.lookahead.TokenType.String(), tokenTypes,
-	))
+	failUnexpectedToken(parser, fmt.Sprintf("any of '%v'", tokenTypes))
+	return lexer.Token{}
+}
+
+// spanFrom returns the span from the start token up to the last consumed token
+func spanFrom(parser *Parser, startToken lexer.Token) source.Span {
+	span := startToken.Span()
+	if parser.previous.End > span.End {
+		span.End = parser.previous.End
+	}
+	return span
 }
 
 // isNextTokenOfType checks the current token type
@@ -115,6 +152,7 @@ func parseBinaryExpression(
 			Operator: operator,
 			Left:     left,
 			Right:    right,
+			Span:     left.GetSpan().Join(right.GetSpan()),
 		}
 	}
 
@@ -141,6 +179,7 @@ func parseLogicalExpression(
 			Operator: operator,
 			Left:     left,
 			Right:    right,
+			Span:     left.GetSpan().Join(right.GetSpan()),
 		}
 	}
 
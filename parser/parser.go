@@ -2,24 +2,67 @@ package parser
 
 import (
 	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/diagnostic"
 	"github.com/yoh0xff/senbonzakura/lexer"
+	"github.com/yoh0xff/senbonzakura/source"
 )
 
 type Parser struct {
-	source    string
-	lexer     *lexer.Lexer
-	lookahead lexer.Token
+	source      string
+	lexer       *lexer.Lexer
+	lookahead   lexer.Token
+	previous    lexer.Token // last consumed token, used to compute node spans
+	diagnostics []diagnostic.Diagnostic
+	comments    []ast.Comment // comments of the tokens read so far, only collected with trivia
+}
+
+// Config defines the options of the parser
+type Config struct {
+	File     string // name of the source file recorded in all spans, can be empty
+	Comments bool   // whether to collect the comments of the source, see Comments
 }
 
 func NewParser(source string) *Parser {
-	lexerInstance := lexer.NewLexer(source)
-	lookahead := lexerInstance.NextToken()
+	return NewParserWithFile("", source)
+}
+
+// NewParserWithFile creates a parser for source read from the given file, the file name is recorded in all spans
+func NewParserWithFile(file string, source string) *Parser {
+	return NewParserWithConfig(source, Config{File: file})
+}
+
+// NewParserWithConfig creates a parser with the given configuration
+func NewParserWithConfig(source string, config Config) *Parser {
+	lexerInstance := lexer.NewLexerWithConfig(source, lexer.Config{File: config.File, Trivia: config.Comments})
 
-	return &Parser{
-		source:    source,
-		lexer:     lexerInstance,
-		lookahead: lookahead,
+	parser := &Parser{
+		source: source,
+		lexer:  lexerInstance,
 	}
+	parser.lookahead = nextToken(parser)
+	parser.previous = lexer.Token{TokenType: lexer.TokenEnd, Line: 1, Column: 1, File: config.File}
+
+	return parser
+}
+
+// Comments returns the comments read so far in source order, when the parser is configured to collect them
+//
+// ast.NewCommentMap associates them with the nodes of the parsed tree
+func (p *Parser) Comments() []ast.Comment {
+	return p.comments
+}
+
+// Lines returns the line table of the parsed source
+func (p *Parser) Lines() *source.LineTable {
+	return p.lexer.Lines()
+}
+
+// Diagnostics returns all problems found so far by the lexer and the parser, ordered by position
+func (p *Parser) Diagnostics() []diagnostic.Diagnostic {
+	diagnostics := append([]diagnostic.Diagnostic{}, p.lexer.Diagnostics()...)
+	diagnostics = append(diagnostics, p.diagnostics...)
+	diagnostic.Sort(diagnostics)
+	return diagnostics
 }
 
 // ParseRootStatement entry point to parse statement
@@ -29,11 +72,20 @@ func NewParser(source string) *Parser {
 //
 //	: StatementList
 //	;
-func ParseRootStatement(parser *Parser) ast.Statement {
+//
+// Syntax errors don't stop parsing, the statements that failed are replaced by
+// ast.ErrorStatement placeholders and all problems are returned as diagnostics
+func ParseRootStatement(parser *Parser) (ast.Statement, []diagnostic.Diagnostic) {
+	startToken := parser.lookahead
 	statementList := parseStatementList(parser, lexer.TokenType(-1)) // -1 as a sentinel value indicating no stop token
+
+	// The program always covers the whole source
+	span := parser.lexer.Lines().Span(startToken.File, 0, len(parser.source))
+
 	return &ast.ProgramStatement{
 		Body: statementList,
-	}
+		Span: span,
+	}, parser.Diagnostics()
 }
 
 // ParseRootExpression entry point to parse expression
@@ -42,6 +94,28 @@ func ParseRootStatement(parser *Parser) ast.Statement {
 //
 //	: AssignmentExpression
 //	;
-func ParseRootExpression(parser *Parser) ast.Expression {
+//
+// The returned expression is nil if parsing had to be aborted, the diagnostics describe why
+func ParseRootExpression(parser *Parser) (expression ast.Expression, diagnostics []diagnostic.Diagnostic) {
+	defer recoverBailout(parser, func() {
+		expression = nil
+		diagnostics = parser.Diagnostics()
+	})
+
+	expression = parseExpression(parser)
+	if !isNextTokenOfType(parser, lexer.TokenEnd) {
+		failUnexpectedToken(parser, "end of input")
+	}
+
+	return expression, parser.Diagnostics()
+}
+
+// parseExpression parses an expression
+//
+// Expression
+//
+//	: AssignmentExpression
+//	;
+func parseExpression(parser *Parser) ast.Expression {
 	return parseAssignmentExpression(parser)
 }
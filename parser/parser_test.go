@@ -0,0 +1,410 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/diagnostic"
+)
+
+func TestParseRootStatementWithoutErrors(t *testing.T) {
+	source := `let x: number = 42;`
+
+	statement, diagnostics := ParseRootStatement(NewParser(source))
+
+	if len(diagnostics) != 0 {
+		t.Fatalf("Expected no diagnostics, got %v", diagnostics)
+	}
+
+	if statement == nil {
+		t.Fatalf("Expected program statement, got nil")
+	}
+}
+
+func TestParseRootStatementUnexpectedToken(t *testing.T) {
+	source := `let x: number = 42`
+
+	_, diagnostics := ParseRootStatement(NewParser(source))
+
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+	}
+
+	if diagnostics[0].Code != diagnostic.CodeUnexpectedToken {
+		t.Errorf("Expected diagnostic code %s, got %s", diagnostic.CodeUnexpectedToken, diagnostics[0].Code)
+	}
+
+	if diagnostics[0].Span.Start != len(source) {
+		t.Errorf("Expected diagnostic at %d, got %d", len(source), diagnostics[0].Span.Start)
+	}
+}
+
+func TestParseRootStatementMissingType(t *testing.T) {
+	source := `let x: = 42;`
+
+	_, diagnostics := ParseRootStatement(NewParser(source))
+
+	if len(diagnostics) != 1 || diagnostics[0].Code != diagnostic.CodeExpectedType {
+		t.Fatalf("Expected a single %s diagnostic, got %v", diagnostic.CodeExpectedType, diagnostics)
+	}
+}
+
+func TestParseRootStatementInvalidToken(t *testing.T) {
+	source := `let x: number = 4 @ 2;`
+
+	_, diagnostics := ParseRootStatement(NewParser(source))
+
+	if len(diagnostics) == 0 || diagnostics[0].Code != diagnostic.CodeInvalidToken {
+		t.Fatalf("Expected %s diagnostic first, got %v", diagnostic.CodeInvalidToken, diagnostics)
+	}
+}
+
+func TestParseRootStatementInvalidAssignmentTarget(t *testing.T) {
+	source := `1 = 2;`
+
+	statement, diagnostics := ParseRootStatement(NewParser(source))
+
+	if len(diagnostics) != 1 || diagnostics[0].Code != diagnostic.CodeInvalidAssignmentTarget {
+		t.Fatalf("Expected a single %s diagnostic, got %v", diagnostic.CodeInvalidAssignmentTarget, diagnostics)
+	}
+
+	if statement == nil {
+		t.Errorf("Expected the statement to be parsed despite the invalid assignment target")
+	}
+}
+
+func TestParseRootExpressionMissingOperand(t *testing.T) {
+	source := `1 + `
+
+	expression, diagnostics := ParseRootExpression(NewParser(source))
+
+	if expression != nil {
+		t.Errorf("Expected nil expression, got %v", expression)
+	}
+
+	if len(diagnostics) != 1 || diagnostics[0].Code != diagnostic.CodeExpectedExpression {
+		t.Fatalf("Expected a single %s diagnostic, got %v", diagnostic.CodeExpectedExpression, diagnostics)
+	}
+}
+
+func TestParseRootStatementReportsAllErrors(t *testing.T) {
+	source := `
+		let a: number = ;
+		let b: number = 2;
+		def f(x: number): number {
+			return x +;
+			let c: = 3;
+		}
+		let d: number = 4 let e: number = 5;
+	`
+
+	statement, diagnostics := ParseRootStatement(NewParser(source))
+
+	if len(diagnostics) != 4 {
+		t.Fatalf("Expected 4 diagnostics, got %d: %v", len(diagnostics), diagnostics)
+	}
+
+	program := statement.(*ast.ProgramStatement)
+	expected := []ast.NodeType{
+		ast.NodeErrorStatement,
+		ast.NodeVariableDeclarationStatement,
+		ast.NodeFunctionDeclarationStatement,
+		ast.NodeErrorStatement,
+		ast.NodeVariableDeclarationStatement,
+	}
+
+	if len(program.Body) != len(expected) {
+		t.Fatalf("Expected %d statements, got %d", len(expected), len(program.Body))
+	}
+
+	for i, nodeType := range expected {
+		if program.Body[i].NodeType() != nodeType {
+			t.Errorf("Statement %d: expected %s, got %s", i, nodeType, program.Body[i].NodeType())
+		}
+	}
+
+	function := program.Body[2].(*ast.FunctionDeclarationStatement)
+	if len(function.Body.Body) != 2 || function.Body.Body[0].NodeType() != ast.NodeErrorStatement {
+		t.Errorf("Expected function body with 2 statements starting with an error placeholder")
+	}
+}
+
+func TestParseRootStatementStrayClosingBrace(t *testing.T) {
+	source := `} let a: number = 1;`
+
+	statement, diagnostics := ParseRootStatement(NewParser(source))
+
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+	}
+
+	program := statement.(*ast.ProgramStatement)
+	if len(program.Body) != 2 || program.Body[1].NodeType() != ast.NodeVariableDeclarationStatement {
+		t.Errorf("Expected parsing to continue after the stray brace")
+	}
+}
+
+func TestParseRootStatementMissingClosingBrace(t *testing.T) {
+	source := `def f(): void { let a: number = 1;`
+
+	statement, diagnostics := ParseRootStatement(NewParser(source))
+
+	if len(diagnostics) != 1 || diagnostics[0].Code != diagnostic.CodeUnexpectedToken {
+		t.Fatalf("Expected a single %s diagnostic, got %v", diagnostic.CodeUnexpectedToken, diagnostics)
+	}
+
+	if statement == nil {
+		t.Fatalf("Expected program statement, got nil")
+	}
+}
+
+func TestParseSpans(t *testing.T) {
+	source := "let x: number = 1;\nif (x > 0) {\n  x = x + 1;\n}"
+
+	statement, diagnostics := ParseRootStatement(NewParserWithFile("test.sbz", source))
+	if len(diagnostics) != 0 {
+		t.Fatalf("Expected no diagnostics, got %v", diagnostics)
+	}
+
+	program := statement.(*ast.ProgramStatement)
+	ifStatement := program.Body[1].(*ast.IfStatement)
+	assignment := ifStatement.Consequent.Body[0].(*ast.ExpressionStatement).Expression.(*ast.AssignmentExpression)
+	addition := assignment.Right.(*ast.BinaryExpression)
+
+	tests := []struct {
+		node   ast.Node
+		text   string
+		line   int
+		column int
+	}{
+		{program.Body[0], "let x: number = 1;", 1, 1},
+		{ifStatement, "if (x > 0) {\n  x = x + 1;\n}", 2, 1},
+		{ifStatement.Consequent, "{\n  x = x + 1;\n}", 2, 12},
+		{assignment, "x = x + 1", 3, 3},
+		{addition, "x + 1", 3, 7},
+	}
+
+	for _, test := range tests {
+		span := test.node.GetSpan()
+		if text := source[span.Start:span.End]; text != test.text {
+			t.Errorf("%s: expected text %q, got %q", test.node.NodeType(), test.text, text)
+		}
+
+		if span.Line != test.line || span.Column != test.column || span.File != "test.sbz" {
+			t.Errorf("%s: expected position test.sbz:%d:%d, got %s", test.node.NodeType(), test.line, test.column, span)
+		}
+	}
+}
+
+func TestParseNumericLiterals(t *testing.T) {
+	tests := []struct {
+		source     string
+		kind       ast.NumericLiteralKind
+		intValue   int64
+		floatValue float64
+	}{
+		{"42", ast.IntegerLiteral, 42, 42},
+		{"1_000_000", ast.IntegerLiteral, 1000000, 1000000},
+		{"0xFF", ast.IntegerLiteral, 255, 255},
+		{"0xdead_beef", ast.IntegerLiteral, 0xdeadbeef, 0xdeadbeef},
+		{"0b1010", ast.IntegerLiteral, 10, 10},
+		{"0o17", ast.IntegerLiteral, 15, 15},
+		{"9223372036854775807", ast.IntegerLiteral, 9223372036854775807, 9223372036854775807},
+		{"3.14", ast.FloatLiteral, 0, 3.14},
+		{"1e9", ast.FloatLiteral, 0, 1e9},
+		{"2.5E-3", ast.FloatLiteral, 0, 2.5e-3},
+		{"1_000.000_1", ast.FloatLiteral, 0, 1000.0001},
+	}
+
+	for _, test := range tests {
+		expression, diagnostics := ParseRootExpression(NewParser(test.source))
+		if len(diagnostics) != 0 {
+			t.Errorf("%s: expected no diagnostics, got %v", test.source, diagnostics)
+			continue
+		}
+
+		literal := expression.(*ast.NumericLiteralExpression)
+		if literal.Kind != test.kind || literal.IntValue != test.intValue || literal.FloatValue != test.floatValue {
+			t.Errorf(
+				"%s: expected (%d, %d, %g), got (%d, %d, %g)",
+				test.source, test.kind, test.intValue, test.floatValue,
+				literal.Kind, literal.IntValue, literal.FloatValue,
+			)
+		}
+
+		if literal.Raw != test.source {
+			t.Errorf("%s: expected raw text to be kept, got %s", test.source, literal.Raw)
+		}
+	}
+}
+
+func TestParseInvalidNumericLiterals(t *testing.T) {
+	tests := []struct {
+		source string
+		code   diagnostic.Code
+	}{
+		{"1__0", diagnostic.CodeInvalidNumericLiteral},
+		{"1_", diagnostic.CodeInvalidNumericLiteral},
+		{"0x_FF", diagnostic.CodeInvalidNumericLiteral},
+		{"1_.5", diagnostic.CodeInvalidNumericLiteral},
+		{"9223372036854775808", diagnostic.CodeNumericLiteralOverflow},
+		{"0x1_0000_0000_0000_0000", diagnostic.CodeNumericLiteralOverflow},
+		{"1e400", diagnostic.CodeNumericLiteralOverflow},
+	}
+
+	for _, test := range tests {
+		_, diagnostics := ParseRootExpression(NewParser(test.source))
+		if len(diagnostics) != 1 || diagnostics[0].Code != test.code {
+			t.Errorf("%s: expected a single %s diagnostic, got %v", test.source, test.code, diagnostics)
+		}
+	}
+}
+
+func TestParseStringLiterals(t *testing.T) {
+	source := "let a: string = \"line\\n\\u{1F600}\";\nlet b: string = `raw\n\\n`;"
+
+	statement, diagnostics := ParseRootStatement(NewParser(source))
+	if len(diagnostics) != 0 {
+		t.Fatalf("Expected no diagnostics, got %v", diagnostics)
+	}
+
+	program := statement.(*ast.ProgramStatement)
+	first := program.Body[0].(*ast.VariableDeclarationStatement).Variables[0].Initializer.(*ast.StringLiteralExpression)
+	second := program.Body[1].(*ast.VariableDeclarationStatement).Variables[0].Initializer.(*ast.StringLiteralExpression)
+
+	if first.Value != "line\n😀" || first.Raw != "\"line\\n\\u{1F600}\"" {
+		t.Errorf("Unexpected escaped string literal %q (raw %q)", first.Value, first.Raw)
+	}
+
+	if second.Value != "raw\n\\n" {
+		t.Errorf("Unexpected raw string literal %q", second.Value)
+	}
+}
+
+func TestParseInvalidEscapeSequence(t *testing.T) {
+	source := "let a: string = 1;\nlet b: string = \"ok \\q\";"
+
+	_, diagnostics := ParseRootStatement(NewParser(source))
+	if len(diagnostics) != 1 || diagnostics[0].Code != diagnostic.CodeInvalidEscapeSequence {
+		t.Fatalf("Expected a single %s diagnostic, got %v", diagnostic.CodeInvalidEscapeSequence, diagnostics)
+	}
+
+	span := diagnostics[0].Span
+	if source[span.Start:span.End] != "\\q" || span.Line != 2 || span.Column != 21 {
+		t.Errorf("Expected diagnostic on '\\q' at 2:21, got %q at %s", source[span.Start:span.End], span)
+	}
+}
+
+func TestParseTemplateLiteral(t *testing.T) {
+	source := "`${name} is ${age + 1} years old`"
+
+	expression, diagnostics := ParseRootExpression(NewParser(source))
+	if len(diagnostics) != 0 {
+		t.Fatalf("Expected no diagnostics, got %v", diagnostics)
+	}
+
+	template := expression.(*ast.TemplateLiteralExpression)
+	expectedChunks := []string{"", " is ", " years old"}
+
+	if len(template.Chunks) != len(expectedChunks) || len(template.Expressions) != 2 {
+		t.Fatalf("Expected 3 chunks and 2 expressions, got %q and %d", template.Chunks, len(template.Expressions))
+	}
+
+	for i, chunk := range expectedChunks {
+		if template.Chunks[i] != chunk {
+			t.Errorf("Chunk %d: expected %q, got %q", i, chunk, template.Chunks[i])
+		}
+	}
+
+	if template.Expressions[1].NodeType() != ast.NodeBinaryExpression {
+		t.Errorf("Expected binary expression in the second substitution, got %s", template.Expressions[1].NodeType())
+	}
+
+	if template.Span.Start != 0 || template.Span.End != len(source) {
+		t.Errorf("Expected template span to cover the whole source, got %v", template.Span)
+	}
+}
+
+func TestParseUnterminatedTemplateLiteral(t *testing.T) {
+	source := "let s: string = `a ${x} b;"
+
+	statement, diagnostics := ParseRootStatement(NewParser(source))
+	if len(diagnostics) == 0 || diagnostics[0].Code != diagnostic.CodeUnterminatedString {
+		t.Fatalf("Expected %s diagnostic, got %v", diagnostic.CodeUnterminatedString, diagnostics)
+	}
+
+	if statement == nil {
+		t.Fatalf("Expected program statement, got nil")
+	}
+}
+
+func TestParseComments(t *testing.T) {
+	source := "// a\nx; /* b */ @\n// c"
+
+	parser := NewParserWithConfig(source, Config{File: "test", Comments: true})
+	ParseRootStatement(parser)
+
+	comments := parser.Comments()
+	if len(comments) != 3 {
+		t.Fatalf("Expected 3 comments, got %v", comments)
+	}
+	for i, text := range []string{"// a", "/* b */", "// c"} {
+		if comments[i].Text != text {
+			t.Errorf("Comment %d: expected %q, got %q", i, text, comments[i].Text)
+		}
+	}
+
+	if comments := NewParser(source).Comments(); len(comments) != 0 {
+		t.Errorf("Expected no comments by default, got %v", comments)
+	}
+}
+
+func TestCommentMap(t *testing.T) {
+	source := `// leads f
+def f(a: number) { // leads y
+    let y: number = a; // trails y
+    // dangles in f
+}
+
+if (a) { // dangles in then
+} /* leads else */ else {
+    g(1, /* leads call */ 2);
+}
+// dangles in program`
+
+	parser := NewParserWithConfig(source, Config{Comments: true})
+	root, _ := ParseRootStatement(parser)
+	program := root.(*ast.ProgramStatement)
+	function := program.Body[0].(*ast.FunctionDeclarationStatement)
+	declaration := function.Body.Body[0]
+	conditional := program.Body[1].(*ast.IfStatement)
+	call := conditional.Alternative.Body[0]
+
+	comments := ast.NewCommentMap(parser.Lines(), program, parser.Comments())
+
+	tests := []struct {
+		name     string
+		comments []ast.Comment
+		expected []string
+	}{
+		{"leading f", comments.Leading[function], []string{"// leads f"}},
+		{"leading y", comments.Leading[declaration], []string{"// leads y"}},
+		{"trailing y", comments.Trailing[declaration], []string{"// trails y"}},
+		{"dangling f", comments.Dangling[function.Body], []string{"// dangles in f"}},
+		{"dangling then", comments.Dangling[conditional.Consequent], []string{"// dangles in then"}},
+		{"leading else", comments.Leading[conditional.Alternative], []string{"/* leads else */"}},
+		{"leading call", comments.Leading[call], []string{"/* leads call */"}},
+		{"dangling program", comments.Dangling[program], []string{"// dangles in program"}},
+	}
+
+	for _, test := range tests {
+		var texts []string
+		for _, comment := range test.comments {
+			texts = append(texts, comment.Text)
+		}
+		if strings.Join(texts, ", ") != strings.Join(test.expected, ", ") {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, texts)
+		}
+	}
+}
@@ -12,7 +12,7 @@ import (
 //	: '{' OptStatementList '}'
 //	;
 func parseBlockStatement(parser *Parser) ast.Statement {
-	eatToken(parser, lexer.TokenOpeningBrace)
+	startToken := eatToken(parser, lexer.TokenOpeningBrace)
 
 	var block []ast.Statement
 	if !isNextTokenOfType(parser, lexer.TokenClosingBrace) {
@@ -25,6 +25,7 @@ func parseBlockStatement(parser *Parser) ast.Statement {
 
 	return &ast.BlockStatement{
 		Body: block,
+		Span: spanFrom(parser, startToken),
 	}
 }
 
@@ -40,13 +41,61 @@ func parseStatementList(parser *Parser, stopTokenType lexer.TokenType) []ast.Sta
 
 	for !isNextTokenOfType(parser, lexer.TokenEnd) &&
 		(stopTokenType == -1 || !isNextTokenOfType(parser, stopTokenType)) {
-		statement := parseStatement(parser)
+		statement := parseStatementWithRecovery(parser)
 		statementList = append(statementList, statement)
 	}
 
 	return statementList
 }
 
+// parseStatementWithRecovery parses a statement, on syntax error it skips to the next
+// synchronization point and returns an error placeholder, so parsing can continue
+func parseStatementWithRecovery(parser *Parser) (statement ast.Statement) {
+	startToken := parser.lookahead
+
+	defer recoverBailout(parser, func() {
+		synchronize(parser, startToken)
+		statement = &ast.ErrorStatement{
+			Span: spanFrom(parser, startToken),
+		}
+	})
+
+	return parseStatement(parser)
+}
+
+// synchronize skips tokens until a point where a new statement can start
+//
+// Synchronization points are:
+//   - after ';'
+//   - before '}'
+//   - before a statement keyword
+func synchronize(parser *Parser, startToken lexer.Token) {
+	// Always make progress, otherwise the same statement would fail forever
+	if parser.lookahead.Start == startToken.Start && !isNextTokenOfType(parser, lexer.TokenEnd) {
+		advance(parser)
+	}
+
+	for !isNextTokenOfType(parser, lexer.TokenEnd) {
+		switch parser.lookahead.TokenType {
+		case lexer.TokenStatementEnd:
+			advance(parser)
+			return
+		case lexer.TokenClosingBrace,
+			lexer.TokenLetKeyword,
+			lexer.TokenIfKeyword,
+			lexer.TokenWhileKeyword,
+			lexer.TokenDoKeyword,
+			lexer.TokenForKeyword,
+			lexer.TokenDefKeyword,
+			lexer.TokenReturnKeyword,
+			lexer.TokenClassKeyword:
+			return
+		default:
+			advance(parser)
+		}
+	}
+}
+
 // parseStatement parses a statement
 //
 // Statement
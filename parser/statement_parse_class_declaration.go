@@ -12,7 +12,7 @@ import (
 //	: class IdentifierExpression [ClassExtendsExpression] BlockStatement
 //	;
 func parseClassDeclarationStatement(parser *Parser) ast.Statement {
-	eatToken(parser, lexer.TokenClassKeyword)
+	startToken := eatToken(parser, lexer.TokenClassKeyword)
 
 	// Parse the class name (identifier)
 	name := parseIdentifierExpression(parser).(*ast.IdentifierExpression)
@@ -30,6 +30,7 @@ func parseClassDeclarationStatement(parser *Parser) ast.Statement {
 		Name:       name,
 		SuperClass: superClass,
 		Body:       body,
+		Span:       spanFrom(parser, startToken),
 	}
 }
 
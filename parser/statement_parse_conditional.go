@@ -12,11 +12,11 @@ import (
 //	: if '(' Expression ')' Statement [else Statement]
 //	;
 func parseIfStatement(parser *Parser) ast.Statement {
-	eatToken(parser, lexer.TokenIfKeyword)
+	startToken := eatToken(parser, lexer.TokenIfKeyword)
 
 	// Parse the condition
 	eatToken(parser, lexer.TokenOpeningParenthesis)
-	condition := ParseRootExpression(parser)
+	condition := parseExpression(parser)
 	eatToken(parser, lexer.TokenClosingParenthesis)
 
 	// Parse the consequent (then block)
@@ -26,6 +26,7 @@ func parseIfStatement(parser *Parser) ast.Statement {
 		// If the consequent is not a block statement, wrap it in one
 		consequentBlock = &ast.BlockStatement{
 			Body: []ast.Statement{consequent},
+			Span: consequent.GetSpan(),
 		}
 	}
 
@@ -43,6 +44,7 @@ func parseIfStatement(parser *Parser) ast.Statement {
 			// Otherwise, wrap it in a block statement
 			alternativeBlock = &ast.BlockStatement{
 				Body: []ast.Statement{alternative},
+				Span: alternative.GetSpan(),
 			}
 		}
 	}
@@ -51,5 +53,6 @@ func parseIfStatement(parser *Parser) ast.Statement {
 		Condition:   condition,
 		Consequent:  consequentBlock,
 		Alternative: alternativeBlock, // will be nil if there's no else clause
+		Span:        spanFrom(parser, startToken),
 	}
 }
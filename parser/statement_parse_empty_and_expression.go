@@ -12,8 +12,10 @@ import (
 //	: ';'
 //	;
 func parseEmptyStatement(parser *Parser) ast.Statement {
-	eatToken(parser, lexer.TokenStatementEnd)
-	return &ast.EmptyStatement{}
+	token := eatToken(parser, lexer.TokenStatementEnd)
+	return &ast.EmptyStatement{
+		Span: token.Span(),
+	}
 }
 
 // parseExpressionStatement parses expression statements
@@ -23,7 +25,8 @@ func parseEmptyStatement(parser *Parser) ast.Statement {
 //	: Expression ';'
 //	;
 func parseExpressionStatement(parser *Parser, consumeStatementEnd bool) ast.Statement {
-	expression := ParseRootExpression(parser)
+	startToken := parser.lookahead
+	expression := parseExpression(parser)
 
 	if consumeStatementEnd {
 		eatToken(parser, lexer.TokenStatementEnd)
@@ -31,5 +34,6 @@ func parseExpressionStatement(parser *Parser, consumeStatementEnd bool) ast.Stat
 
 	return &ast.ExpressionStatement{
 		Expression: expression,
+		Span:       spanFrom(parser, startToken),
 	}
 }
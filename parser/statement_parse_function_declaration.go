@@ -12,7 +12,7 @@ import (
 //	: def '(' [FormalParameterList] ')' [':' Type] BlockStatement
 //	;
 func parseFunctionDeclarationStatement(parser *Parser) ast.Statement {
-	eatToken(parser, lexer.TokenDefKeyword)
+	startToken := eatToken(parser, lexer.TokenDefKeyword)
 	name := parseIdentifierExpression(parser).(*ast.IdentifierExpression)
 
 	eatToken(parser, lexer.TokenOpeningParenthesis)
@@ -30,7 +30,8 @@ func parseFunctionDeclarationStatement(parser *Parser) ast.Statement {
 		eatToken(parser, lexer.TokenColon)
 		returnType = parseType(parser)
 	} else {
-		returnType = &ast.VoidType{}
+		// Implicit void return type, positioned at the end of the parameter list
+		returnType = &ast.VoidType{Span: parser.previous.Span()}
 	}
 
 	body := parseBlockStatement(parser).(*ast.BlockStatement)
@@ -40,6 +41,7 @@ func parseFunctionDeclarationStatement(parser *Parser) ast.Statement {
 		Parameters: parameters,
 		ReturnType: returnType,
 		Body:       body,
+		Span:       spanFrom(parser, startToken),
 	}
 }
 
@@ -54,29 +56,36 @@ func parseFormalParameterListExpression(parser *Parser) []ast.Parameter {
 	parameters := []ast.Parameter{}
 
 	// Parse first parameter
-	paramName := parseIdentifierExpression(parser)
-	eatToken(parser, lexer.TokenColon)
-	paramType := parseType(parser)
-	parameters = append(parameters, ast.Parameter{
-		Name: paramName,
-		Type: paramType,
-	})
+	parameters = append(parameters, parseFormalParameter(parser))
 
 	// Parse additional parameters if any
 	for isNextTokenOfType(parser, lexer.TokenComma) {
 		eatToken(parser, lexer.TokenComma)
-		paramName := parseIdentifierExpression(parser)
-		eatToken(parser, lexer.TokenColon)
-		paramType := parseType(parser)
-		parameters = append(parameters, ast.Parameter{
-			Name: paramName,
-			Type: paramType,
-		})
+		parameters = append(parameters, parseFormalParameter(parser))
 	}
 
 	return parameters
 }
 
+// parseFormalParameter parses a single function parameter
+//
+// FormalParameter
+//
+//	: IdentifierExpression ':' Type
+//	;
+func parseFormalParameter(parser *Parser) ast.Parameter {
+	startToken := parser.lookahead
+	paramName := parseIdentifierExpression(parser)
+	eatToken(parser, lexer.TokenColon)
+	paramType := parseType(parser)
+
+	return ast.Parameter{
+		Name: paramName,
+		Type: paramType,
+		Span: spanFrom(parser, startToken),
+	}
+}
+
 // parseReturnStatement parses return statements
 //
 // ReturnStatement
@@ -84,14 +93,15 @@ func parseFormalParameterListExpression(parser *Parser) []ast.Parameter {
 //	: return [Expression] ';'
 //	;
 func parseReturnStatement(parser *Parser) ast.Statement {
-	eatToken(parser, lexer.TokenReturnKeyword)
+	startToken := eatToken(parser, lexer.TokenReturnKeyword)
 	var argument ast.Expression
 	if !isNextTokenOfType(parser, lexer.TokenStatementEnd) {
-		argument = ParseRootExpression(parser)
+		argument = parseExpression(parser)
 	}
 	eatToken(parser, lexer.TokenStatementEnd)
 
 	return &ast.ReturnStatement{
 		Argument: argument,
+		Span:     spanFrom(parser, startToken),
 	}
 }
@@ -12,10 +12,10 @@ import (
 //	: while '(' Expression ')' Statement ';'
 //	;
 func parseWhileStatement(parser *Parser) ast.Statement {
-	eatToken(parser, lexer.TokenWhileKeyword)
+	startToken := eatToken(parser, lexer.TokenWhileKeyword)
 
 	eatToken(parser, lexer.TokenOpeningParenthesis)
-	condition := ParseRootExpression(parser)
+	condition := parseExpression(parser)
 	eatToken(parser, lexer.TokenClosingParenthesis)
 
 	bodyStmt := parseStatement(parser)
@@ -25,12 +25,14 @@ func parseWhileStatement(parser *Parser) ast.Statement {
 	if !ok {
 		body = &ast.BlockStatement{
 			Body: []ast.Statement{bodyStmt},
+			Span: bodyStmt.GetSpan(),
 		}
 	}
 
 	return &ast.WhileStatement{
 		Condition: condition,
 		Body:      body,
+		Span:      spanFrom(parser, startToken),
 	}
 }
 
@@ -41,7 +43,7 @@ func parseWhileStatement(parser *Parser) ast.Statement {
 //	: do Statement while '(' Expression ')' ';'
 //	;
 func parseDoWhileStatement(parser *Parser) ast.Statement {
-	eatToken(parser, lexer.TokenDoKeyword)
+	startToken := eatToken(parser, lexer.TokenDoKeyword)
 
 	bodyStmt := parseStatement(parser)
 
@@ -50,13 +52,14 @@ func parseDoWhileStatement(parser *Parser) ast.Statement {
 	if !ok {
 		body = &ast.BlockStatement{
 			Body: []ast.Statement{bodyStmt},
+			Span: bodyStmt.GetSpan(),
 		}
 	}
 
 	eatToken(parser, lexer.TokenWhileKeyword)
 
 	eatToken(parser, lexer.TokenOpeningParenthesis)
-	condition := ParseRootExpression(parser)
+	condition := parseExpression(parser)
 	eatToken(parser, lexer.TokenClosingParenthesis)
 
 	eatToken(parser, lexer.TokenStatementEnd)
@@ -64,6 +67,7 @@ func parseDoWhileStatement(parser *Parser) ast.Statement {
 	return &ast.DoWhileStatement{
 		Body:      body,
 		Condition: condition,
+		Span:      spanFrom(parser, startToken),
 	}
 }
 
@@ -74,7 +78,7 @@ func parseDoWhileStatement(parser *Parser) ast.Statement {
 //	: for '(' [InitExpression] ';' [Expression] ';' [Expression] ')' Statement
 //	;
 func parseForStatement(parser *Parser) ast.Statement {
-	eatToken(parser, lexer.TokenForKeyword)
+	startToken := eatToken(parser, lexer.TokenForKeyword)
 	eatToken(parser, lexer.TokenOpeningParenthesis)
 
 	var initializer ast.Statement
@@ -85,13 +89,13 @@ func parseForStatement(parser *Parser) ast.Statement {
 
 	var condition ast.Expression
 	if !isNextTokenOfType(parser, lexer.TokenStatementEnd) {
-		condition = ParseRootExpression(parser)
+		condition = parseExpression(parser)
 	}
 	eatToken(parser, lexer.TokenStatementEnd)
 
 	var increment ast.Expression
 	if !isNextTokenOfType(parser, lexer.TokenClosingParenthesis) {
-		increment = ParseRootExpression(parser)
+		increment = parseExpression(parser)
 	}
 	eatToken(parser, lexer.TokenClosingParenthesis)
 
@@ -102,6 +106,7 @@ func parseForStatement(parser *Parser) ast.Statement {
 	if !ok {
 		body = &ast.BlockStatement{
 			Body: []ast.Statement{bodyStmt},
+			Span: bodyStmt.GetSpan(),
 		}
 	}
 
@@ -110,6 +115,7 @@ func parseForStatement(parser *Parser) ast.Statement {
 		Condition:   condition,
 		Increment:   increment,
 		Body:        body,
+		Span:        spanFrom(parser, startToken),
 	}
 }
 
@@ -19,7 +19,7 @@ import (
 func parseVariableDeclarationStatement(parser *Parser, consumeStatementEnd bool) ast.Statement {
 	var variables []*ast.VariableExpression
 
-	eatToken(parser, lexer.TokenLetKeyword)
+	startToken := eatToken(parser, lexer.TokenLetKeyword)
 	for {
 		// Parse a variable expression and append it to our list
 		varExpr := parseVariableExpression(parser).(*ast.VariableExpression)
@@ -40,6 +40,7 @@ func parseVariableDeclarationStatement(parser *Parser, consumeStatementEnd bool)
 
 	return &ast.VariableDeclarationStatement{
 		Variables: variables,
+		Span:      spanFrom(parser, startToken),
 	}
 }
 
@@ -50,6 +51,7 @@ func parseVariableDeclarationStatement(parser *Parser, consumeStatementEnd bool)
 //	: Identifier ':' Type ['=' Initializer]
 //	;
 func parseVariableExpression(parser *Parser) ast.Expression {
+	startToken := parser.lookahead
 	identifier := parseIdentifierExpression(parser).(*ast.IdentifierExpression)
 
 	// Require type annotation
@@ -67,5 +69,6 @@ func parseVariableExpression(parser *Parser) ast.Expression {
 		Identifier:     identifier,
 		TypeAnnotation: typeAnnotation,
 		Initializer:    initializer, // Will be nil if no initializer
+		Span:           spanFrom(parser, startToken),
 	}
 }
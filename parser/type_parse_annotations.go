@@ -1,29 +1,31 @@
 package parser
 
 import (
-	"fmt"
 	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/diagnostic"
 	"github.com/yoh0xff/senbonzakura/lexer"
 )
 
 // parseType parses type annotations
 func parseType(parser *Parser) ast.Type {
+	startToken := parser.lookahead
+
 	switch parser.lookahead.TokenType {
 	case lexer.TokenNumberTypeKeyword:
 		eatToken(parser, lexer.TokenNumberTypeKeyword)
-		return &ast.PrimitiveType{Kind: ast.NumberType}
+		return &ast.PrimitiveType{Kind: ast.NumberType, Span: startToken.Span()}
 
 	case lexer.TokenStringTypeKeyword:
 		eatToken(parser, lexer.TokenStringTypeKeyword)
-		return &ast.PrimitiveType{Kind: ast.StringType}
+		return &ast.PrimitiveType{Kind: ast.StringType, Span: startToken.Span()}
 
 	case lexer.TokenBooleanTypeKeyword:
 		eatToken(parser, lexer.TokenBooleanTypeKeyword)
-		return &ast.PrimitiveType{Kind: ast.BooleanType}
+		return &ast.PrimitiveType{Kind: ast.BooleanType, Span: startToken.Span()}
 
 	case lexer.TokenVoidTypeKeyword:
 		eatToken(parser, lexer.TokenVoidTypeKeyword)
-		return &ast.VoidType{}
+		return &ast.VoidType{Span: startToken.Span()}
 
 	case lexer.TokenIdentifier:
 		// Handle class types or custom types
@@ -50,12 +52,14 @@ func parseType(parser *Parser) ast.Type {
 			return &ast.GenericType{
 				Base:     typeName,
 				TypeArgs: typeArgs,
+				Span:     spanFrom(parser, startToken),
 			}
 		} else {
 			// For class types
 			return &ast.ClassType{
 				Name:       typeName,
 				SuperClass: nil, // No super class by default
+				Span:       startToken.Span(),
 			}
 		}
 
@@ -67,12 +71,17 @@ func parseType(parser *Parser) ast.Type {
 
 		return &ast.ArrayType{
 			ElementType: elementType,
+			Span:        spanFrom(parser, startToken),
 		}
 
 	default:
-		panic(fmt.Sprintf(
+		failWithError(
+			parser,
+			diagnostic.CodeExpectedType,
+			parser.lookahead.Span(),
 			"Expected type annotation, found: %s",
-			parser.lookahead.TokenType.String(),
-		))
+			describeToken(parser, parser.lookahead),
+		)
+		return nil
 	}
 }
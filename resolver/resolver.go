@@ -0,0 +1,160 @@
+package resolver
+
+import (
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/diagnostic"
+	"github.com/yoh0xff/senbonzakura/source"
+)
+
+// Resolution is the result of resolving the names of a program
+type Resolution struct {
+	Builtins *Scope // scope of the names declared by the host, parent of the program scope
+	Program  *Scope
+
+	// Uses binds every identifier that refers to a symbol
+	Uses map[*ast.IdentifierExpression]*Symbol
+
+	// Declarations binds every identifier that declares a symbol
+	Declarations map[*ast.IdentifierExpression]*Symbol
+
+	// TypeReferences binds class type annotations to the class symbols
+	TypeReferences map[*ast.ClassType]*Symbol
+
+	// Scopes maps nodes that open a scope to the scope, function and class bodies share
+	// the scope of their declaration
+	Scopes map[ast.Node]*Scope
+}
+
+// SymbolOf returns the symbol an identifier declares or refers to, nil if it is unresolved
+func (r *Resolution) SymbolOf(identifier *ast.IdentifierExpression) *Symbol {
+	if symbol, ok := r.Uses[identifier]; ok {
+		return symbol
+	}
+	return r.Declarations[identifier]
+}
+
+// Resolver walks the AST, builds the scopes and binds identifiers to their declarations
+type Resolver struct {
+	resolution  *Resolution
+	scope       *Scope
+	class       *ast.ClassDeclarationStatement // innermost enclosing class, nil outside of classes
+	diagnostics []diagnostic.Diagnostic
+}
+
+// Resolve resolves all names of the program
+//
+// Builtins are names provided by the host, they are visible everywhere and can be shadowed
+func Resolve(program ast.Statement, builtins ...string) (*Resolution, []diagnostic.Diagnostic) {
+	builtinScope := newScope(ScopeBuiltin, nil, nil)
+	for _, name := range builtins {
+		builtinScope.insert(&Symbol{Name: name, Kind: SymbolBuiltin, declared: true})
+	}
+
+	resolver := &Resolver{
+		resolution: &Resolution{
+			Builtins:       builtinScope,
+			Uses:           map[*ast.IdentifierExpression]*Symbol{},
+			Declarations:   map[*ast.IdentifierExpression]*Symbol{},
+			TypeReferences: map[*ast.ClassType]*Symbol{},
+			Scopes:         map[ast.Node]*Scope{},
+		},
+		scope: builtinScope,
+	}
+
+	program.Accept(resolver)
+
+	diagnostic.Sort(resolver.diagnostics)
+	return resolver.resolution, resolver.diagnostics
+}
+
+// VisitStatement implements the ast.Visitor interface
+func (r *Resolver) VisitStatement(statement ast.Statement) {
+	visitStatement(r, statement)
+}
+
+// VisitExpression implements the ast.Visitor interface
+func (r *Resolver) VisitExpression(expression ast.Expression) {
+	visitExpression(r, expression)
+}
+
+// enterScope opens a new scope nested in the current one
+func (r *Resolver) enterScope(kind ScopeKind, node ast.Node) *Scope {
+	r.scope = newScope(kind, node, r.scope)
+	r.resolution.Scopes[node] = r.scope
+	return r.scope
+}
+
+// exitScope returns to the parent scope
+func (r *Resolver) exitScope() {
+	r.scope = r.scope.Parent
+}
+
+// declare adds a symbol for the identifier to the current scope, duplicates are reported
+func (r *Resolver) declare(identifier *ast.IdentifierExpression, kind SymbolKind, declaration ast.Node) *Symbol {
+	symbol := &Symbol{
+		Name:        identifier.Name,
+		Kind:        kind,
+		Declaration: declaration,
+		Identifier:  identifier,
+	}
+	r.resolution.Declarations[identifier] = symbol
+
+	if existing := r.scope.LookupLocal(identifier.Name); existing != nil {
+		r.report(diagnostic.NewError(
+			diagnostic.CodeDuplicateDeclaration,
+			identifier.Span,
+			"'%s' is already declared in this scope",
+			identifier.Name,
+		).WithNote(existing.Span(), "previous declaration of '%s'", existing.Name))
+
+		// The duplicate is kept out of the scope, uses bind to the first declaration
+		symbol.Scope = r.scope
+		return symbol
+	}
+
+	r.scope.insert(symbol)
+	return symbol
+}
+
+// resolveUse binds the identifier to the visible symbol with its name
+func (r *Resolver) resolveUse(identifier *ast.IdentifierExpression) *Symbol {
+	crossedFunction := false
+
+	for scope := r.scope; scope != nil; scope = scope.Parent {
+		if scope.Kind == ScopeClass {
+			continue
+		}
+
+		if symbol, ok := scope.Symbols[identifier.Name]; ok {
+			// Functions can refer to variables declared after them, they run later
+			if !symbol.declared && !crossedFunction {
+				r.report(diagnostic.NewError(
+					diagnostic.CodeUsedBeforeDeclaration,
+					identifier.Span,
+					"'%s' is used before its declaration",
+					identifier.Name,
+				).WithNote(symbol.Span(), "'%s' is declared here", symbol.Name))
+			}
+
+			r.resolution.Uses[identifier] = symbol
+			return symbol
+		}
+
+		if scope.Kind == ScopeFunction {
+			crossedFunction = true
+		}
+	}
+
+	r.reportError(diagnostic.CodeUndeclaredName, identifier.Span, "'%s' is not declared", identifier.Name)
+	return nil
+}
+
+// report records a diagnostic
+func (r *Resolver) report(d diagnostic.Diagnostic) {
+	r.diagnostics = append(r.diagnostics, d)
+}
+
+// reportError records an error diagnostic
+func (r *Resolver) reportError(code diagnostic.Code, span source.Span, format string, args ...any) {
+	r.report(diagnostic.NewError(code, span, format, args...))
+}
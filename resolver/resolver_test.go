@@ -0,0 +1,98 @@
+package resolver
+
+import (
+	"testing"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/diagnostic"
+	"github.com/yoh0xff/senbonzakura/parser"
+)
+
+func resolveSource(t *testing.T, source string, builtins ...string) (*Resolution, []diagnostic.Diagnostic) {
+	t.Helper()
+
+	program, diagnostics := parser.ParseRootStatement(parser.NewParser(source))
+	if len(diagnostics) != 0 {
+		t.Fatalf("Expected no parse diagnostics, got %v", diagnostics)
+	}
+
+	return Resolve(program, builtins...)
+}
+
+func TestResolveDiagnostics(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected []diagnostic.Code
+	}{
+		{"declared", `let x: number = 1; let y: number = x + 1;`, nil},
+		{"undeclared", `let x: number = y;`, []diagnostic.Code{diagnostic.CodeUndeclaredName}},
+		{"duplicate", `let x: number = 1; let x: number = 2;`, []diagnostic.Code{diagnostic.CodeDuplicateDeclaration}},
+		{"shadowing", `let x: number = 1; { let x: number = 2; }`, nil},
+		{"used before declaration", `let y: number = x; let x: number = 1;`, []diagnostic.Code{diagnostic.CodeUsedBeforeDeclaration}},
+		{"own initializer", `let x: number = x;`, []diagnostic.Code{diagnostic.CodeUsedBeforeDeclaration}},
+		{"block scope", `{ let x: number = 1; } x;`, []diagnostic.Code{diagnostic.CodeUndeclaredName}},
+		{"forward function", `f(); def f(): void { g(); } def g(): void {}`, nil},
+		{"function reads later variable", `def f(): number { return x; } let x: number = 1;`, nil},
+		{"parameters", `def f(a: number): number { return a; } a;`, []diagnostic.Code{diagnostic.CodeUndeclaredName}},
+		{"parameter redeclared", `def f(a: number): void { let a: number = 1; }`, []diagnostic.Code{diagnostic.CodeDuplicateDeclaration}},
+		{"for scope", `for (let i: number = 0; i < 10; i = i + 1) { i; } i;`, []diagnostic.Code{diagnostic.CodeUndeclaredName}},
+		{"members are not names", `class A { let x: number = 1; def m(): number { return x; } }`, []diagnostic.Code{diagnostic.CodeUndeclaredName}},
+		{"members through this", `class A { let x: number = 1; def m(): number { return this.x; } }`, nil},
+		{"this outside class", `this;`, []diagnostic.Code{diagnostic.CodeInvalidThisOrSuper}},
+		{"super without superclass", `class A { def m(): void { super.m(); } }`, []diagnostic.Code{diagnostic.CodeInvalidThisOrSuper}},
+		{"super with superclass", `class A {} class B extends A { def m(): void { super.m(); } }`, nil},
+		{"unknown type", `let x: Foo = nil;`, []diagnostic.Code{diagnostic.CodeUnknownType}},
+		{"not a class", `def f(): void {} let x: f = nil;`, []diagnostic.Code{diagnostic.CodeNotAClass}},
+		{"statement in class body", `class A { print(1); }`, []diagnostic.Code{diagnostic.CodeInvalidClassMember, diagnostic.CodeUndeclaredName}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, diagnostics := resolveSource(t, tt.source)
+
+			if len(diagnostics) != len(tt.expected) {
+				t.Fatalf("Expected %d diagnostics, got %v", len(tt.expected), diagnostics)
+			}
+			for i, code := range tt.expected {
+				if diagnostics[i].Code != code {
+					t.Errorf("Expected diagnostic %d to be %s, got %s", i, code, diagnostics[i].Code)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveBindings(t *testing.T) {
+	source := `let x: number = 1; { let y: number = 2; print(x, y); }`
+
+	resolution, diagnostics := resolveSource(t, source, "print")
+	if len(diagnostics) != 0 {
+		t.Fatalf("Expected no diagnostics, got %v", diagnostics)
+	}
+
+	x := resolution.Program.LookupLocal("x")
+	if x == nil {
+		t.Fatalf("Expected x in the program scope")
+	}
+	if len(resolution.Program.Children) != 1 {
+		t.Fatalf("Expected 1 nested scope, got %d", len(resolution.Program.Children))
+	}
+	blockScope := resolution.Program.Children[0]
+	y := blockScope.LookupLocal("y")
+	if y == nil || resolution.Program.LookupLocal("y") != nil {
+		t.Fatalf("Expected y only in the block scope")
+	}
+
+	block := blockScope.Node.(*ast.BlockStatement)
+	call := block.Body[1].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+	if symbol := resolution.SymbolOf(call.Arguments[0].(*ast.IdentifierExpression)); symbol != x {
+		t.Errorf("Expected first argument to bind to x, got %v", symbol)
+	}
+	if symbol := resolution.SymbolOf(call.Arguments[1].(*ast.IdentifierExpression)); symbol != y {
+		t.Errorf("Expected second argument to bind to y, got %v", symbol)
+	}
+	if symbol := resolution.SymbolOf(call.Callee.(*ast.IdentifierExpression)); symbol == nil || symbol.Kind != SymbolBuiltin {
+		t.Errorf("Expected print to bind to the builtin, got %v", symbol)
+	}
+}
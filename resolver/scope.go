@@ -0,0 +1,91 @@
+package resolver
+
+import (
+	"fmt"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+)
+
+// ScopeKind represents the construct that opened a scope
+type ScopeKind int
+
+const (
+	ScopeProgram ScopeKind = iota
+	ScopeBlock
+	ScopeFunction
+	ScopeFor
+	ScopeClass
+	ScopeBuiltin
+)
+
+// String returns a string representation of the ScopeKind
+func (k ScopeKind) String() string {
+	switch k {
+	case ScopeProgram:
+		return "program"
+	case ScopeBlock:
+		return "block"
+	case ScopeFunction:
+		return "function"
+	case ScopeFor:
+		return "for"
+	case ScopeClass:
+		return "class"
+	case ScopeBuiltin:
+		return "builtin"
+	default:
+		return fmt.Sprintf("ScopeKind(%d)", k)
+	}
+}
+
+// Scope is a lexical scope with the symbols declared directly in it
+//
+// Class scopes hold the class members, they are not visible to name lookups,
+// members are only reachable through 'this' or an instance
+type Scope struct {
+	Kind     ScopeKind
+	Node     ast.Node // node that opened the scope, nil for the builtin scope
+	Parent   *Scope
+	Children []*Scope
+	Symbols  map[string]*Symbol
+	Order    []*Symbol // symbols in declaration order
+}
+
+// newScope creates a scope nested in the parent scope
+func newScope(kind ScopeKind, node ast.Node, parent *Scope) *Scope {
+	scope := &Scope{
+		Kind:    kind,
+		Node:    node,
+		Parent:  parent,
+		Symbols: map[string]*Symbol{},
+	}
+	if parent != nil {
+		parent.Children = append(parent.Children, scope)
+	}
+	return scope
+}
+
+// Lookup finds the symbol visible under the name from this scope, class scopes are skipped
+func (s *Scope) Lookup(name string) *Symbol {
+	for scope := s; scope != nil; scope = scope.Parent {
+		if scope.Kind == ScopeClass {
+			continue
+		}
+		if symbol, ok := scope.Symbols[name]; ok {
+			return symbol
+		}
+	}
+	return nil
+}
+
+// LookupLocal finds the symbol declared directly in this scope
+func (s *Scope) LookupLocal(name string) *Symbol {
+	return s.Symbols[name]
+}
+
+// insert adds the symbol to the scope
+func (s *Scope) insert(symbol *Symbol) {
+	symbol.Scope = s
+	s.Symbols[symbol.Name] = symbol
+	s.Order = append(s.Order, symbol)
+}
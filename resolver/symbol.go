@@ -0,0 +1,73 @@
+package resolver
+
+import (
+	"fmt"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/source"
+)
+
+// SymbolKind represents what a symbol was declared as
+type SymbolKind int
+
+const (
+	SymbolVariable SymbolKind = iota
+	SymbolParameter
+	SymbolFunction
+	SymbolClass
+	SymbolField
+	SymbolMethod
+	SymbolBuiltin
+)
+
+// String returns a string representation of the SymbolKind
+func (k SymbolKind) String() string {
+	switch k {
+	case SymbolVariable:
+		return "variable"
+	case SymbolParameter:
+		return "parameter"
+	case SymbolFunction:
+		return "function"
+	case SymbolClass:
+		return "class"
+	case SymbolField:
+		return "field"
+	case SymbolMethod:
+		return "method"
+	case SymbolBuiltin:
+		return "builtin"
+	default:
+		return fmt.Sprintf("SymbolKind(%d)", k)
+	}
+}
+
+// Symbol represents a declared name
+type Symbol struct {
+	Name string
+	Kind SymbolKind
+
+	// Declaration is the node that declares the symbol:
+	//   - *ast.VariableExpression for variables and fields
+	//   - *ast.IdentifierExpression (the parameter name) for parameters
+	//   - *ast.FunctionDeclarationStatement for functions and methods
+	//   - *ast.ClassDeclarationStatement for classes
+	//   - nil for builtins
+	Declaration ast.Node
+
+	// Identifier is the name in the declaration, nil for builtins
+	Identifier *ast.IdentifierExpression
+
+	// Scope is the scope the symbol is declared in
+	Scope *Scope
+
+	declared bool // false until the declaration is reached, used to find uses before declaration
+}
+
+// Span returns the span of the declared name
+func (s *Symbol) Span() source.Span {
+	if s.Identifier == nil {
+		return source.Span{}
+	}
+	return s.Identifier.Span
+}
@@ -0,0 +1,101 @@
+package resolver
+
+import (
+	"fmt"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/diagnostic"
+)
+
+func visitExpression(resolver *Resolver, expression ast.Expression) {
+	switch expression.NodeType() {
+	case ast.NodeVariableExpression:
+		visitVariableExpression(resolver, expression.(*ast.VariableExpression))
+	case ast.NodeAssignmentExpression:
+		expression := expression.(*ast.AssignmentExpression)
+		expression.Left.Accept(resolver)
+		expression.Right.Accept(resolver)
+	case ast.NodeBinaryExpression:
+		expression := expression.(*ast.BinaryExpression)
+		expression.Left.Accept(resolver)
+		expression.Right.Accept(resolver)
+	case ast.NodeUnaryExpression:
+		expression.(*ast.UnaryExpression).Right.Accept(resolver)
+	case ast.NodeLogicalExpression:
+		expression := expression.(*ast.LogicalExpression)
+		expression.Left.Accept(resolver)
+		expression.Right.Accept(resolver)
+	case ast.NodeBooleanLiteralExpression,
+		ast.NodeNilLiteralExpression,
+		ast.NodeNumericLiteralExpression,
+		ast.NodeStringLiteralExpression:
+		// Nothing to resolve
+	case ast.NodeTemplateLiteralExpression:
+		for _, part := range expression.(*ast.TemplateLiteralExpression).Expressions {
+			part.Accept(resolver)
+		}
+	case ast.NodeIdentifierExpression:
+		resolver.resolveUse(expression.(*ast.IdentifierExpression))
+	case ast.NodeMemberExpression:
+		visitMemberExpression(resolver, expression.(*ast.MemberExpression))
+	case ast.NodeCallExpression:
+		expression := expression.(*ast.CallExpression)
+		expression.Callee.Accept(resolver)
+		for _, arg := range expression.Arguments {
+			arg.Accept(resolver)
+		}
+	case ast.NodeThisExpression:
+		visitThisExpression(resolver, expression.(*ast.ThisExpression))
+	case ast.NodeSuperExpression:
+		visitSuperExpression(resolver, expression.(*ast.SuperExpression))
+	case ast.NodeNewExpression:
+		expression := expression.(*ast.NewExpression)
+		expression.Callee.Accept(resolver)
+		for _, arg := range expression.Arguments {
+			arg.Accept(resolver)
+		}
+	default:
+		panic(fmt.Errorf("unknown expression type: %T", expression))
+	}
+}
+
+func visitVariableExpression(resolver *Resolver, expression *ast.VariableExpression) {
+	resolveType(resolver, expression.TypeAnnotation)
+
+	// The variable is not usable in its own initializer
+	if expression.Initializer != nil {
+		expression.Initializer.Accept(resolver)
+	}
+
+	symbol, ok := resolver.resolution.Declarations[expression.Identifier]
+	if !ok {
+		// Variables outside of statement lists (for loop initializers) are declared here
+		symbol = resolver.declare(expression.Identifier, SymbolVariable, expression)
+	}
+	symbol.declared = true
+}
+
+func visitMemberExpression(resolver *Resolver, expression *ast.MemberExpression) {
+	expression.Object.Accept(resolver)
+
+	// Static properties are member names, they are not resolved in the scope
+	if expression.Computed {
+		expression.Property.Accept(resolver)
+	}
+}
+
+func visitThisExpression(resolver *Resolver, expression *ast.ThisExpression) {
+	if resolver.class == nil {
+		resolver.reportError(diagnostic.CodeInvalidThisOrSuper, expression.Span, "'this' can only be used inside a class")
+	}
+}
+
+func visitSuperExpression(resolver *Resolver, expression *ast.SuperExpression) {
+	if resolver.class == nil || resolver.class.SuperClass == nil {
+		resolver.reportError(
+			diagnostic.CodeInvalidThisOrSuper,
+			expression.Span,
+			"'super' can only be used inside a class that extends another class",
+		)
+	}
+}
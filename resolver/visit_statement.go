@@ -0,0 +1,241 @@
+package resolver
+
+import (
+	"fmt"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/diagnostic"
+)
+
+func visitStatement(resolver *Resolver, statement ast.Statement) {
+	switch statement.NodeType() {
+	case ast.NodeProgramStatement:
+		visitProgramStatement(resolver, statement.(*ast.ProgramStatement))
+	case ast.NodeBlockStatement:
+		visitBlockStatement(resolver, statement.(*ast.BlockStatement))
+	case ast.NodeEmptyStatement, ast.NodeErrorStatement:
+		// Nothing to resolve
+	case ast.NodeExpressionStatement:
+		statement.(*ast.ExpressionStatement).Expression.Accept(resolver)
+	case ast.NodeVariableDeclarationStatement:
+		visitVariableDeclarationStatement(resolver, statement.(*ast.VariableDeclarationStatement))
+	case ast.NodeIfStatement:
+		visitIfStatement(resolver, statement.(*ast.IfStatement))
+	case ast.NodeWhileStatement:
+		visitWhileStatement(resolver, statement.(*ast.WhileStatement))
+	case ast.NodeDoWhileStatement:
+		visitDoWhileStatement(resolver, statement.(*ast.DoWhileStatement))
+	case ast.NodeForStatement:
+		visitForStatement(resolver, statement.(*ast.ForStatement))
+	case ast.NodeFunctionDeclarationStatement:
+		visitFunctionDeclarationStatement(resolver, statement.(*ast.FunctionDeclarationStatement))
+	case ast.NodeReturnStatement:
+		visitReturnStatement(resolver, statement.(*ast.ReturnStatement))
+	case ast.NodeClassDeclarationStatement:
+		visitClassDeclarationStatement(resolver, statement.(*ast.ClassDeclarationStatement))
+	default:
+		panic(fmt.Errorf("unknown statement type: %T", statement))
+	}
+}
+
+func visitProgramStatement(resolver *Resolver, statement *ast.ProgramStatement) {
+	resolver.resolution.Program = resolver.enterScope(ScopeProgram, statement)
+	resolveStatementList(resolver, statement.Body)
+	resolver.exitScope()
+}
+
+func visitBlockStatement(resolver *Resolver, statement *ast.BlockStatement) {
+	resolver.enterScope(ScopeBlock, statement)
+	resolveStatementList(resolver, statement.Body)
+	resolver.exitScope()
+}
+
+// resolveStatementList resolves the statements of a scope
+//
+// All declarations are added to the scope first, functions and classes can be used anywhere
+// in the scope, variables become usable after their declaration
+func resolveStatementList(resolver *Resolver, statements []ast.Statement) {
+	for _, statement := range statements {
+		declareStatement(resolver, statement)
+	}
+
+	for _, statement := range statements {
+		statement.Accept(resolver)
+	}
+}
+
+// declareStatement adds the names declared by the statement to the current scope
+func declareStatement(resolver *Resolver, statement ast.Statement) {
+	inClassBody := resolver.scope.Kind == ScopeClass
+
+	switch statement := statement.(type) {
+	case *ast.VariableDeclarationStatement:
+		kind := SymbolVariable
+		if inClassBody {
+			kind = SymbolField
+		}
+		for _, variable := range statement.Variables {
+			resolver.declare(variable.Identifier, kind, variable)
+		}
+	case *ast.FunctionDeclarationStatement:
+		kind := SymbolFunction
+		if inClassBody {
+			kind = SymbolMethod
+		}
+		resolver.declare(statement.Name, kind, statement).declared = true
+	case *ast.ClassDeclarationStatement:
+		resolver.declare(statement.Name, SymbolClass, statement).declared = true
+	case *ast.EmptyStatement, *ast.ErrorStatement:
+		// Allowed everywhere
+	default:
+		if inClassBody {
+			resolver.reportError(
+				diagnostic.CodeInvalidClassMember,
+				statement.GetSpan(),
+				"Only field and method declarations are allowed in a class body",
+			)
+		}
+	}
+}
+
+func visitVariableDeclarationStatement(resolver *Resolver, statement *ast.VariableDeclarationStatement) {
+	for _, variable := range statement.Variables {
+		variable.Accept(resolver)
+	}
+}
+
+func visitIfStatement(resolver *Resolver, statement *ast.IfStatement) {
+	statement.Condition.Accept(resolver)
+	statement.Consequent.Accept(resolver)
+
+	if statement.Alternative != nil {
+		statement.Alternative.Accept(resolver)
+	}
+}
+
+func visitWhileStatement(resolver *Resolver, statement *ast.WhileStatement) {
+	statement.Condition.Accept(resolver)
+	statement.Body.Accept(resolver)
+}
+
+func visitDoWhileStatement(resolver *Resolver, statement *ast.DoWhileStatement) {
+	statement.Body.Accept(resolver)
+	statement.Condition.Accept(resolver)
+}
+
+func visitForStatement(resolver *Resolver, statement *ast.ForStatement) {
+	// The initializer variables are only visible inside the loop
+	resolver.enterScope(ScopeFor, statement)
+
+	if statement.Initializer != nil {
+		statement.Initializer.Accept(resolver)
+	}
+	if statement.Condition != nil {
+		statement.Condition.Accept(resolver)
+	}
+	if statement.Increment != nil {
+		statement.Increment.Accept(resolver)
+	}
+	statement.Body.Accept(resolver)
+
+	resolver.exitScope()
+}
+
+func visitFunctionDeclarationStatement(resolver *Resolver, statement *ast.FunctionDeclarationStatement) {
+	// Functions outside of statement lists (not possible today) are declared here
+	if _, ok := resolver.resolution.Declarations[statement.Name]; !ok {
+		resolver.declare(statement.Name, SymbolFunction, statement).declared = true
+	}
+
+	scope := resolver.enterScope(ScopeFunction, statement)
+	resolver.resolution.Scopes[statement.Body] = scope
+
+	for _, parameter := range statement.Parameters {
+		resolveType(resolver, parameter.Type)
+		if name, ok := parameter.Name.(*ast.IdentifierExpression); ok {
+			resolver.declare(name, SymbolParameter, name).declared = true
+		}
+	}
+	resolveType(resolver, statement.ReturnType)
+
+	// The body shares the function scope, so parameters can't be redeclared in it
+	resolveStatementList(resolver, statement.Body.Body)
+
+	resolver.exitScope()
+}
+
+func visitReturnStatement(resolver *Resolver, statement *ast.ReturnStatement) {
+	if statement.Argument != nil {
+		statement.Argument.Accept(resolver)
+	}
+}
+
+func visitClassDeclarationStatement(resolver *Resolver, statement *ast.ClassDeclarationStatement) {
+	if _, ok := resolver.resolution.Declarations[statement.Name]; !ok {
+		resolver.declare(statement.Name, SymbolClass, statement).declared = true
+	}
+
+	if statement.SuperClass != nil {
+		superClass := resolver.resolveUse(statement.SuperClass)
+		switch {
+		case superClass == nil:
+			// Already reported as undeclared
+		case superClass.Kind != SymbolClass:
+			resolver.reportError(
+				diagnostic.CodeNotAClass,
+				statement.SuperClass.Span,
+				"'%s' is a %s, only classes can be extended",
+				superClass.Name, superClass.Kind,
+			)
+		case superClass.Declaration == statement:
+			resolver.reportError(
+				diagnostic.CodeNotAClass,
+				statement.SuperClass.Span,
+				"Class '%s' can't extend itself",
+				statement.Name.Name,
+			)
+		}
+	}
+
+	enclosingClass := resolver.class
+	resolver.class = statement
+
+	scope := resolver.enterScope(ScopeClass, statement)
+	resolver.resolution.Scopes[statement.Body] = scope
+	resolveStatementList(resolver, statement.Body.Body)
+	resolver.exitScope()
+
+	resolver.class = enclosingClass
+}
+
+// resolveType binds class names in the type annotation to the class symbols
+func resolveType(resolver *Resolver, typeAnnotation ast.Type) {
+	switch t := typeAnnotation.(type) {
+	case *ast.ClassType:
+		symbol := resolver.scope.Lookup(t.Name)
+		switch {
+		case symbol == nil:
+			resolver.reportError(diagnostic.CodeUnknownType, t.Span, "Unknown type '%s'", t.Name)
+		case symbol.Kind != SymbolClass:
+			resolver.reportError(
+				diagnostic.CodeNotAClass,
+				t.Span,
+				"'%s' is a %s, not a type",
+				t.Name, symbol.Kind,
+			)
+		default:
+			resolver.resolution.TypeReferences[t] = symbol
+		}
+	case *ast.ArrayType:
+		resolveType(resolver, t.ElementType)
+	case *ast.FunctionType:
+		for _, param := range t.Params {
+			resolveType(resolver, param)
+		}
+		resolveType(resolver, t.ReturnType)
+	case *ast.GenericType:
+		for _, arg := range t.TypeArgs {
+			resolveType(resolver, arg)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+// Package senbonzakura embeds scripts in Go programs
+//
+// Scripts are parsed, checked and compiled to bytecode once, then hosts call their functions with Go values:
+//
+//	program, err := senbonzakura.CompileWithConfig(source, senbonzakura.Config{
+//		Functions: map[string]any{"log": func(message string) { fmt.Println(message) }},
+//	})
+//	result, err := program.Call(ctx, "main", 1, "two")
+package senbonzakura
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/yoh0xff/senbonzakura/checker"
+	"github.com/yoh0xff/senbonzakura/diagnostic"
+	"github.com/yoh0xff/senbonzakura/parser"
+	"github.com/yoh0xff/senbonzakura/vm"
+)
+
+// RuntimeError is an error raised while a script runs
+type RuntimeError = vm.RuntimeError
+
+// Errors of scripts stopped by a limit, test them with errors.Is
+var (
+	ErrFuelExhausted = vm.ErrFuelExhausted
+	ErrStackOverflow = vm.ErrStackOverflow
+	ErrOutOfMemory   = vm.ErrOutOfMemory
+)
+
+// CompileError is returned when the source has syntax, name or type errors
+type CompileError struct {
+	Diagnostics []diagnostic.Diagnostic
+}
+
+// Error implements the error interface, it lists the errors one per line
+func (e *CompileError) Error() string {
+	var lines []string
+	for _, d := range e.Diagnostics {
+		if d.Severity == diagnostic.SeverityError {
+			lines = append(lines, d.String())
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Config defines the options of a compiled program
+//
+// Limits apply to the top-level statements and to every call separately
+type Config struct {
+	File      string         // name of the source file in error positions, can be empty
+	Stdout    io.Writer      // output of the print builtin, nil discards it
+	Functions map[string]any // Go functions callable from scripts by name, see Program.Call for calls back
+	MaxFrames int            // maximum number of nested calls, 0 for the default
+	Fuel      int64          // maximum number of executed instructions, 0 for no limit
+	MaxHeap   int64          // maximum number of bytes allocated by strings and objects, 0 for no limit
+}
+
+// Program is a compiled script
+//
+// The top-level statements run once, on the first call or when Run is called. A program runs one call at a time,
+// concurrent calls wait for each other
+type Program struct {
+	mutex       sync.Mutex
+	current     atomic.Pointer[callToken] // token of the call holding the lock, nil between calls
+	machine     *vm.VM
+	function    *vm.Function
+	initialized bool
+	ctx         context.Context // context of the call being executed, passed to Go functions
+	classes     map[reflect.Type]*vm.Class
+}
+
+// callToken identifies a call holding the lock of a program, the contexts passed to Go functions carry it
+type callToken struct {
+	program *Program // makes every token a distinct allocation
+}
+
+// callKey is the context key of the call token
+type callKey struct{}
+
+// Compile compiles the script with the print builtin writing to the standard output
+func Compile(source string) (*Program, error) {
+	return CompileWithConfig(source, Config{Stdout: os.Stdout})
+}
+
+// CompileWithConfig compiles the script with the given configuration
+//
+// Errors in the script are returned as *CompileError, unsupported Go function signatures as plain errors
+func CompileWithConfig(source string, config Config) (*Program, error) {
+	program := &Program{
+		machine: vm.NewVMWithConfig(vm.Config{
+			Stdout:    config.Stdout,
+			MaxFrames: config.MaxFrames,
+			Fuel:      config.Fuel,
+			MaxHeap:   config.MaxHeap,
+		}),
+		ctx:     context.Background(),
+		classes: map[reflect.Type]*vm.Class{},
+	}
+
+	builtins := []checker.Builtin{{
+		Name: "print",
+		Type: &checker.Function{Params: []checker.Type{checker.Any}, Return: checker.Void, Variadic: true},
+	}}
+	for name, fn := range config.Functions {
+		native, signature, err := program.wrapFunction(name, fn)
+		if err != nil {
+			return nil, err
+		}
+		program.machine.Define(name, vm.ObjectValue(native))
+		builtins = append(builtins, checker.Builtin{Name: name, Type: signature})
+	}
+
+	ast, diagnostics := parser.ParseRootStatement(parser.NewParserWithFile(config.File, source))
+	if diagnostic.HasErrors(diagnostics) {
+		return nil, &CompileError{Diagnostics: diagnostics}
+	}
+
+	_, diagnostics = checker.Check(ast, builtins...)
+	if diagnostic.HasErrors(diagnostics) {
+		return nil, &CompileError{Diagnostics: diagnostics}
+	}
+
+	function, diagnostics := vm.Compile(ast, program.machine.Globals())
+	if diagnostic.HasErrors(diagnostics) {
+		return nil, &CompileError{Diagnostics: diagnostics}
+	}
+	program.function = function
+
+	return program, nil
+}
+
+// Run executes the top-level statements if they did not run yet
+//
+// Called from a Go function with the context it received, Run returns at once, the statements are running or
+// already ran
+func (p *Program) Run(ctx context.Context) error {
+	ctx, nested, leave := p.enter(ctx)
+	defer leave()
+
+	if nested {
+		return nil
+	}
+	return p.initialize(ctx)
+}
+
+// enter acquires the program for a call and returns the context to run it with
+//
+// A context carrying the token of the current call comes from a Go function called by the script, the call is
+// nested in the current one and runs without waiting for the lock. The returned function releases the program
+func (p *Program) enter(ctx context.Context) (context.Context, bool, func()) {
+	if token := p.current.Load(); token != nil && ctx.Value(callKey{}) == token {
+		outer := p.ctx
+		p.ctx = ctx
+		return ctx, true, func() { p.ctx = outer }
+	}
+
+	p.mutex.Lock()
+	token := &callToken{program: p}
+	p.current.Store(token)
+	p.ctx = context.WithValue(ctx, callKey{}, token)
+	return p.ctx, false, func() {
+		p.ctx = context.Background()
+		p.current.Store(nil)
+		p.mutex.Unlock()
+	}
+}
+
+// initialize runs the top-level statements once, the lock must be held
+func (p *Program) initialize(ctx context.Context) error {
+	if p.initialized {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := p.machine.RunContext(ctx, p.function); err != nil {
+		return err
+	}
+	p.initialized = true
+	return nil
+}
+
+// Call calls a script function with Go arguments and returns its result as a Go value
+//
+// Arguments are marshaled like values passed to Go functions. The script stops with a runtime error wrapping
+// the context error when the context is done, the context is also passed to Go functions the script calls.
+//
+// Go functions called by the script can call back into the program with the context they received, or one
+// derived from it, from the goroutine running them. The nested call runs on top of the current one, with its
+// budgets and the context of the outer call. Calls back with another context wait for the current call to end,
+// a deadlock
+func (p *Program) Call(ctx context.Context, name string, args ...any) (any, error) {
+	ctx, nested, leave := p.enter(ctx)
+	defer leave()
+
+	if !nested {
+		if err := p.initialize(ctx); err != nil {
+			return nil, err
+		}
+	}
+	callee, ok := p.machine.Globals().Get(name)
+	if !ok {
+		return nil, fmt.Errorf("senbonzakura: undefined function '%s'", name)
+	}
+	return p.call(ctx, callee, args)
+}
+
+// call calls the callee with marshaled arguments, the program must be entered
+func (p *Program) call(ctx context.Context, callee vm.Value, args []any) (any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	values := make([]vm.Value, len(args))
+	for i, arg := range args {
+		value, err := p.toValue(arg)
+		if err != nil {
+			return nil, fmt.Errorf("senbonzakura: argument %d: %w", i+1, err)
+		}
+		values[i] = value
+	}
+
+	result, err := p.machine.CallContext(ctx, callee, values...)
+	if err != nil {
+		// Errors without a position were raised before the callee ran, like a wrong number of arguments
+		var runtimeError *RuntimeError
+		if !errors.As(err, &runtimeError) {
+			return nil, fmt.Errorf("senbonzakura: %w", err)
+		}
+		return nil, err
+	}
+	return p.fromValue(result), nil
+}
+
+// Global returns the value of a global variable of the script as a Go value
+//
+// Like Call, Go functions called by the script pass the context they received
+func (p *Program) Global(ctx context.Context, name string) (any, bool) {
+	_, _, leave := p.enter(ctx)
+	defer leave()
+
+	value, ok := p.machine.Globals().Get(name)
+	if !ok {
+		return nil, false
+	}
+	return p.fromValue(value), true
+}
@@ -0,0 +1,315 @@
+package senbonzakura
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgramCall(t *testing.T) {
+	var output strings.Builder
+	program, err := CompileWithConfig(`
+		let calls: number = 0;
+		def greet(name: string, times: number): string {
+			calls += 1;
+			let result: string = "";
+			for (let i: number = 0; i < times; i += 1) { result = result + "hi " + name + "; "; }
+			return result;
+		}
+		def isPositive(n: number): boolean { return n > 0; }
+		print("loaded");`, Config{Stdout: &output})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	result, err := program.Call(context.Background(), "greet", "Ann", 2)
+	if err != nil || result != "hi Ann; hi Ann; " {
+		t.Errorf("Expected greeting, got %v (%v)", result, err)
+	}
+	result, err = program.Call(context.Background(), "isPositive", 4)
+	if err != nil || result != true {
+		t.Errorf("Expected true, got %v (%v)", result, err)
+	}
+
+	if output.String() != "loaded\n" {
+		t.Errorf("Expected top-level statements to run once, got output %q", output.String())
+	}
+	if calls, _ := program.Global(context.Background(), "calls"); calls != 1.0 {
+		t.Errorf("Expected 1 call, got %v", calls)
+	}
+
+	if _, err := program.Call(context.Background(), "missing"); err == nil {
+		t.Errorf("Expected an error for an undefined function")
+	}
+	_, err = program.Call(context.Background(), "greet", "Ann")
+	if err == nil || err.Error() != "senbonzakura: Function 'greet' expects 2 arguments, got 1" {
+		t.Errorf("Expected an error for a missing argument, got %v", err)
+	}
+	_, err = program.Call(context.Background(), "calls")
+	if err == nil || err.Error() != "senbonzakura: Cannot call a value of type number" {
+		t.Errorf("Expected an error for a global that is not a function, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := program.Call(ctx, "greet", "Ann", 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+type point struct {
+	X     float64
+	Y     int
+	Label string `senbonzakura:"name"`
+}
+
+func TestProgramGoFunctions(t *testing.T) {
+	type key struct{}
+	var logged []string
+
+	program, err := CompileWithConfig(`
+		class Point {
+			def constructor(x: number, y: number) { this.x = x; this.y = y; this.name = "p"; }
+			def sum(): number { return this.x + this.y; }
+		}
+		def run(): number {
+			log("start", 1, true);
+			let p: Point = new Point(3, 4);
+			return distance(p, shift(p, 10));
+		}
+		def make(x: number): Point { return new Point(x, x * 2); }
+		def fail(): void { check(-1); }`,
+		Config{Functions: map[string]any{
+			"log": func(ctx context.Context, parts ...any) {
+				for _, part := range parts {
+					logged = append(logged, fmt.Sprint(part))
+				}
+				logged = append(logged, ctx.Value(key{}).(string))
+			},
+			"shift": func(p point, by int) *point {
+				return &point{X: p.X + float64(by), Y: p.Y + by, Label: p.Label + "'"}
+			},
+			"distance": func(a, b point) float64 {
+				return (b.X - a.X) + float64(b.Y-a.Y)
+			},
+			"check": func(n int) error {
+				if n < 0 {
+					return errors.New("negative value")
+				}
+				return nil
+			},
+		}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), key{}, "ctx")
+	result, err := program.Call(ctx, "run")
+	if err != nil || result != 20.0 {
+		t.Errorf("Expected 20, got %v (%v)", result, err)
+	}
+	if strings.Join(logged, ",") != "start,1,true,ctx" {
+		t.Errorf("Expected logged values, got %v", logged)
+	}
+
+	result, err = program.Call(ctx, "make", 5)
+	object, ok := result.(*Object)
+	if err != nil || !ok {
+		t.Fatalf("Expected an object, got %v (%v)", result, err)
+	}
+	var decoded point
+	if err := object.Decode(&decoded); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if object.Class() != "Point" || decoded != (point{X: 5, Y: 10, Label: "p"}) {
+		t.Errorf("Expected Point(5, 10), got %s %+v", object.Class(), decoded)
+	}
+	if err := object.Set("x", 1); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if x, _ := object.Get("x"); x != 1.0 {
+		t.Errorf("Expected x 1, got %v", x)
+	}
+
+	_, err = program.Call(ctx, "fail")
+	var runtimeError *RuntimeError
+	if !errors.As(err, &runtimeError) || runtimeError.Message != "negative value" {
+		t.Errorf("Expected runtime error 'negative value', got %v", err)
+	}
+}
+
+func TestProgramCallBack(t *testing.T) {
+	var program *Program
+	program, err := CompileWithConfig(`
+		let base: number = 1;
+		let loaded: number = twice(2);
+		def double(n: number): number { return n * 2 + base; }
+		def run(n: number): number { base = 10; return twice(n) + 1; }`,
+		Config{Functions: map[string]any{
+			"twice": func(ctx context.Context, n float64) (float64, error) {
+				if err := program.Run(ctx); err != nil {
+					return 0, err
+				}
+				base, _ := program.Global(ctx, "base")
+				result, err := program.Call(ctx, "double", n)
+				if err != nil {
+					return 0, err
+				}
+				return result.(float64) + base.(float64), nil
+			},
+		}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		result, err := program.Call(context.Background(), "run", 3)
+		if err != nil || result != 27.0 {
+			t.Errorf("Expected 27, got %v (%v)", result, err)
+		}
+		if loaded, _ := program.Global(context.Background(), "loaded"); loaded != 6.0 {
+			t.Errorf("Expected 6, got %v", loaded)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected calls back from Go functions to return")
+	}
+}
+
+type node struct {
+	Value int
+	Next  *node
+}
+
+func TestProgramCyclicArguments(t *testing.T) {
+	program, err := Compile(`
+		class Node { let value: number; let next: Node; }
+		def third(n: Node): number { return n.next.next.value; }
+		def same(n: Node): boolean { return n.next.next == n; }`)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	first := &node{Value: 1}
+	first.Next = &node{Value: 2, Next: first}
+	result, err := program.Call(context.Background(), "third", first)
+	if err != nil || result != 1.0 {
+		t.Errorf("Expected 1, got %v (%v)", result, err)
+	}
+	result, err = program.Call(context.Background(), "same", first)
+	if err != nil || result != true {
+		t.Errorf("Expected the cycle to be kept, got %v (%v)", result, err)
+	}
+}
+
+func TestProgramIntegerArguments(t *testing.T) {
+	program, err := CompileWithConfig(`
+		def signed(n: number): number { return toInt(n); }
+		def unsigned(n: number): number { return toUint(n); }`,
+		Config{Functions: map[string]any{
+			"toInt":  func(n int64) int64 { return n },
+			"toUint": func(n uint64) uint64 { return n },
+		}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		function string
+		arg      float64
+		valid    bool
+	}{
+		{"signed", -1 << 63, true},
+		{"signed", 1 << 62, true},
+		{"signed", 1 << 63, false},
+		{"signed", 1e20, false},
+		{"signed", -1e20, false},
+		{"signed", math.Inf(1), false},
+		{"signed", math.Inf(-1), false},
+		{"unsigned", 1 << 63, true},
+		{"unsigned", 1 << 64, false},
+		{"unsigned", 1e20, false},
+		{"unsigned", -1e20, false},
+		{"unsigned", math.Inf(1), false},
+		{"unsigned", math.Inf(-1), false},
+	}
+	for _, tt := range tests {
+		result, err := program.Call(context.Background(), tt.function, tt.arg)
+		if tt.valid && (err != nil || result != tt.arg) {
+			t.Errorf("%s(%g): Expected %g, got %v (%v)", tt.function, tt.arg, tt.arg, result, err)
+		}
+		if !tt.valid && (err == nil || !strings.Contains(err.Error(), "cannot use number value")) {
+			t.Errorf("%s(%g): Expected an error, got %v", tt.function, tt.arg, result)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		source    string
+		functions map[string]any
+		message   string
+	}{
+		{"syntax error", `let x: number = ;`, nil, "P0"},
+		{"type error", `let x: number = "a";`, nil, "T0001"},
+		{"argument type", `square("a");`, map[string]any{"square": func(n float64) float64 { return n * n }}, "T0001"},
+		{"not a function", ``, map[string]any{"x": 1}, "not a function"},
+		{"results", ``, map[string]any{"f": func() (int, int) { return 0, 0 }}, "must be an error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileWithConfig(tt.source, Config{Functions: tt.functions})
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Expected error containing %q, got %v", tt.message, err)
+			}
+		})
+	}
+}
+
+func TestProgramLimits(t *testing.T) {
+	program, err := CompileWithConfig(`
+		def spin(): void { while (true) {} }
+		def grow(): void { let s: string = "ab"; while (true) { s = s + s; } }
+		def deep(n: number): number { return deep(n + 1); }`,
+		Config{Fuel: 100000, MaxHeap: 1 << 20, MaxFrames: 100})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		function string
+		args     []any
+		err      error
+	}{
+		{"spin", nil, ErrFuelExhausted},
+		{"grow", nil, ErrOutOfMemory},
+		{"deep", []any{0}, ErrStackOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.function, func(t *testing.T) {
+			_, err := program.Call(context.Background(), tt.function, tt.args...)
+			var runtimeError *RuntimeError
+			if !errors.Is(err, tt.err) || !errors.As(err, &runtimeError) || runtimeError.Span.Line == 0 {
+				t.Errorf("Expected a positioned error wrapping %v, got %v", tt.err, err)
+			}
+		})
+	}
+
+	// Without fuel a deadline still stops the script
+	program, _ = CompileWithConfig(`def spin(): void { while (true) {} }`, Config{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := program.Call(ctx, "spin"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package source
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// LineTable converts byte offsets of a source text into line and column positions
+type LineTable struct {
+	text       string
+	lineStarts []int // byte offset of the first character of every line
+}
+
+// NewLineTable creates a line table for the given source text
+func NewLineTable(text string) *LineTable {
+	lineStarts := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+
+	return &LineTable{
+		text:       text,
+		lineStarts: lineStarts,
+	}
+}
+
+// LineCount returns the number of lines in the source text
+func (t *LineTable) LineCount() int {
+	return len(t.lineStarts)
+}
+
+// Position returns the 1-based line and column (in runes) of the byte offset
+func (t *LineTable) Position(offset int) (line int, column int) {
+	offset = max(0, min(offset, len(t.text)))
+
+	// Find the last line that starts at or before the offset
+	index := sort.Search(len(t.lineStarts), func(i int) bool {
+		return t.lineStarts[i] > offset
+	}) - 1
+
+	lineStart := t.lineStarts[index]
+	return index + 1, utf8.RuneCountInString(t.text[lineStart:offset]) + 1
+}
+
+// Offset returns the byte offset of the 1-based line and column (in runes)
+//
+// Positions past the end of a line are clamped to the end of that line
+func (t *LineTable) Offset(line int, column int) int {
+	if line < 1 {
+		return 0
+	}
+	if line > len(t.lineStarts) {
+		return len(t.text)
+	}
+
+	offset := t.lineStarts[line-1]
+	for column > 1 && offset < len(t.text) && t.text[offset] != '\n' {
+		_, size := utf8.DecodeRuneInString(t.text[offset:])
+		offset += size
+		column--
+	}
+
+	return offset
+}
+
+// LineStart returns the byte offset where the 1-based line starts
+func (t *LineTable) LineStart(line int) int {
+	return t.Offset(line, 1)
+}
+
+// Span creates a span for the byte range with line and column information filled in
+func (t *LineTable) Span(file string, start int, end int) Span {
+	line, column := t.Position(start)
+	return Span{
+		File:   file,
+		Start:  start,
+		End:    end,
+		Line:   line,
+		Column: column,
+	}
+}
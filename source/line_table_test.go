@@ -0,0 +1,62 @@
+package source
+
+import "testing"
+
+func TestLineTablePosition(t *testing.T) {
+	text := "let a;\n\nlet ü = 1;\n"
+	table := NewLineTable(text)
+
+	tests := []struct {
+		offset int
+		line   int
+		column int
+	}{
+		{0, 1, 1},
+		{4, 1, 5},
+		{6, 1, 7},
+		{7, 2, 1},
+		{8, 3, 1},
+		{14, 3, 6}, // 'ü' takes two bytes but a single column
+		{len(text), 4, 1},
+	}
+
+	for _, test := range tests {
+		line, column := table.Position(test.offset)
+		if line != test.line || column != test.column {
+			t.Errorf("Offset %d: expected %d:%d, got %d:%d", test.offset, test.line, test.column, line, column)
+		}
+	}
+}
+
+func TestLineTableOffset(t *testing.T) {
+	text := "let a;\nlet ü = 1;"
+	table := NewLineTable(text)
+
+	for offset := 0; offset <= len(text); offset++ {
+		// Skip offsets inside multibyte characters
+		if offset == 12 {
+			continue
+		}
+
+		line, column := table.Position(offset)
+		if got := table.Offset(line, column); got != offset {
+			t.Errorf("Offset %d: round trip through %d:%d gave %d", offset, line, column, got)
+		}
+	}
+
+	if table.LineCount() != 2 {
+		t.Errorf("Expected 2 lines, got %d", table.LineCount())
+	}
+}
+
+func TestSpanJoin(t *testing.T) {
+	left := Span{Start: 4, End: 5, Line: 1, Column: 5}
+	right := Span{Start: 8, End: 10, Line: 2, Column: 2}
+
+	joined := right.Join(left)
+	expected := Span{Start: 4, End: 10, Line: 1, Column: 5}
+
+	if joined != expected {
+		t.Errorf("Expected %v, got %v", expected, joined)
+	}
+}
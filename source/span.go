@@ -0,0 +1,44 @@
+package source
+
+import "fmt"
+
+// Span represents a range of source text
+type Span struct {
+	File   string // Source file name, can be empty
+	Start  int    // Start offset in bytes
+	End    int    // End offset in bytes
+	Line   int    // Line of the start offset, 1-based (0 if unknown)
+	Column int    // Column of the start offset in runes, 1-based (0 if unknown)
+}
+
+// String returns a string representation of the Span
+//
+// Spans with a known position are printed as file:line:col, otherwise as byte offsets
+func (s Span) String() string {
+	if s.Line == 0 {
+		return fmt.Sprintf("%d..%d", s.Start, s.End)
+	}
+	if s.File == "" {
+		return fmt.Sprintf("%d:%d", s.Line, s.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", s.File, s.Line, s.Column)
+}
+
+// IsValid checks if the span was set
+func (s Span) IsValid() bool {
+	return s.End > s.Start || s.Line > 0
+}
+
+// Join returns the smallest span that covers both spans
+func (s Span) Join(other Span) Span {
+	result := s
+	if other.Start < result.Start {
+		result.Start = other.Start
+		result.Line = other.Line
+		result.Column = other.Column
+	}
+	if other.End > result.End {
+		result.End = other.End
+	}
+	return result
+}
@@ -0,0 +1,300 @@
+package senbonzakura
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yoh0xff/senbonzakura/vm"
+)
+
+// Object is an instance of a script class seen from Go
+//
+// Objects share their fields with the script, they must not be used while another goroutine calls the program
+type Object struct {
+	program  *Program
+	instance *vm.Instance
+}
+
+// Class returns the name of the class of the object
+func (o *Object) Class() string {
+	return o.instance.Class.Name
+}
+
+// Get returns a field of the object as a Go value
+func (o *Object) Get(name string) (any, bool) {
+	value, ok := o.instance.Fields[name]
+	if !ok {
+		return nil, false
+	}
+	return o.program.fromValue(value), true
+}
+
+// Set assigns a field of the object from a Go value
+func (o *Object) Set(name string, value any) error {
+	converted, err := o.program.toValue(value)
+	if err != nil {
+		return err
+	}
+	o.instance.Fields[name] = converted
+	return nil
+}
+
+// Decode copies the fields of the object to the exported fields of the struct pointed to by target
+//
+// Struct fields map to script fields by the `senbonzakura` tag or by their name with a lowercase first letter
+func (o *Object) Decode(target any) error {
+	pointer := reflect.ValueOf(target)
+	if pointer.Kind() != reflect.Pointer || pointer.IsNil() || pointer.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("senbonzakura: cannot decode into %T, expected a pointer to a struct", target)
+	}
+	return o.program.decodeStruct(o.instance, pointer.Elem())
+}
+
+var (
+	valueType  = reflect.TypeFor[vm.Value]()
+	objectType = reflect.TypeFor[*Object]()
+)
+
+// toValue converts a Go value to a script value
+//
+// Booleans, numbers and strings map to their script types, structs and pointers to structs become instances
+// of a class named after the struct type. A pointer met again while converting becomes the same instance, so
+// cyclic structures stay cyclic. Objects and vm.Value are passed unchanged
+func (p *Program) toValue(value any) (vm.Value, error) {
+	return p.convertValue(value, map[structPointer]*vm.Instance{})
+}
+
+// structPointer identifies a converted struct, the type tells a struct from its first field at the same address
+type structPointer struct {
+	address uintptr
+	typ     reflect.Type
+}
+
+// convertValue converts a Go value to a script value, reusing the instances created for the pointers in seen
+func (p *Program) convertValue(value any, seen map[structPointer]*vm.Instance) (vm.Value, error) {
+	switch value := value.(type) {
+	case nil:
+		return vm.NilValue(), nil
+	case vm.Value:
+		return value, nil
+	case *Object:
+		if value == nil {
+			return vm.NilValue(), nil
+		}
+		return vm.ObjectValue(value.instance), nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return vm.BoolValue(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return vm.NumberValue(float64(rv.Int())), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return vm.NumberValue(float64(rv.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return vm.NumberValue(rv.Float()), nil
+	case reflect.String:
+		return vm.StringValue(rv.String()), nil
+	case reflect.Struct:
+		return p.structToValue(rv, nil, seen)
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return vm.NilValue(), nil
+		}
+		if rv.Elem().Kind() == reflect.Struct {
+			key := structPointer{address: rv.Pointer(), typ: rv.Type()}
+			if instance, ok := seen[key]; ok {
+				return vm.ObjectValue(instance), nil
+			}
+			return p.structToValue(rv.Elem(), &key, seen)
+		}
+	}
+	return vm.Value{}, fmt.Errorf("cannot pass a value of type %T to a script", value)
+}
+
+// structToValue creates an instance with the exported fields of the struct
+//
+// The instance is recorded under key before its fields are converted, key is nil for structs passed by value
+func (p *Program) structToValue(rv reflect.Value, key *structPointer, seen map[structPointer]*vm.Instance) (vm.Value, error) {
+	class, ok := p.classes[rv.Type()]
+	if !ok {
+		class = &vm.Class{Name: rv.Type().Name(), Methods: map[string]*vm.Closure{}}
+		p.classes[rv.Type()] = class
+	}
+
+	instance := &vm.Instance{Class: class, Fields: map[string]vm.Value{}}
+	if key != nil {
+		seen[*key] = instance
+	}
+	for i := range rv.NumField() {
+		name, ok := fieldName(rv.Type().Field(i))
+		if !ok {
+			continue
+		}
+		value, err := p.convertValue(rv.Field(i).Interface(), seen)
+		if err != nil {
+			return vm.Value{}, fmt.Errorf("field %s: %w", rv.Type().Field(i).Name, err)
+		}
+		instance.Fields[name] = value
+	}
+	return vm.ObjectValue(instance), nil
+}
+
+// fromValue converts a script value to a Go value
+//
+// Numbers are float64, instances are *Object, functions and classes are returned as vm.Value
+func (p *Program) fromValue(value vm.Value) any {
+	switch value.Kind() {
+	case vm.KindNil:
+		return nil
+	case vm.KindBool:
+		return value.AsBool()
+	case vm.KindNumber:
+		return value.AsNumber()
+	}
+
+	switch object := value.AsObject().(type) {
+	case string:
+		return object
+	case *vm.Instance:
+		return &Object{program: p, instance: object}
+	default:
+		return value
+	}
+}
+
+// decodeValue converts a script value to a Go value of the target type
+func (p *Program) decodeValue(value vm.Value, target reflect.Type) (reflect.Value, error) {
+	switch target {
+	case valueType:
+		return reflect.ValueOf(value), nil
+	case objectType:
+		if value.IsNil() {
+			return reflect.Zero(target), nil
+		}
+		if instance, ok := value.AsObject().(*vm.Instance); ok {
+			return reflect.ValueOf(&Object{program: p, instance: instance}), nil
+		}
+		return reflect.Value{}, mismatch(value, target)
+	}
+
+	switch target.Kind() {
+	case reflect.Interface:
+		converted := p.fromValue(value)
+		if converted == nil {
+			return reflect.Zero(target), nil
+		}
+		if !reflect.TypeOf(converted).AssignableTo(target) {
+			return reflect.Value{}, mismatch(value, target)
+		}
+		return reflect.ValueOf(converted).Convert(target), nil
+
+	case reflect.Bool:
+		if value.Kind() != vm.KindBool {
+			return reflect.Value{}, mismatch(value, target)
+		}
+		return reflect.ValueOf(value.AsBool()).Convert(target), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number := value.AsNumber()
+		result := reflect.New(target).Elem()
+		// The range is checked on the float, converting a float out of the range of int64 is undefined
+		if value.Kind() != vm.KindNumber || number != math.Trunc(number) || number < -(1<<63) || number >= 1<<63 ||
+			result.OverflowInt(int64(number)) {
+			return reflect.Value{}, mismatch(value, target)
+		}
+		result.SetInt(int64(number))
+		return result, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		number := value.AsNumber()
+		result := reflect.New(target).Elem()
+		if value.Kind() != vm.KindNumber || number != math.Trunc(number) || number < 0 || number >= 1<<64 ||
+			result.OverflowUint(uint64(number)) {
+			return reflect.Value{}, mismatch(value, target)
+		}
+		result.SetUint(uint64(number))
+		return result, nil
+
+	case reflect.Float32, reflect.Float64:
+		if value.Kind() != vm.KindNumber {
+			return reflect.Value{}, mismatch(value, target)
+		}
+		return reflect.ValueOf(value.AsNumber()).Convert(target), nil
+
+	case reflect.String:
+		s, ok := value.AsString()
+		if !ok {
+			return reflect.Value{}, mismatch(value, target)
+		}
+		return reflect.ValueOf(s).Convert(target), nil
+
+	case reflect.Struct:
+		instance, ok := value.AsObject().(*vm.Instance)
+		if !ok {
+			return reflect.Value{}, mismatch(value, target)
+		}
+		result := reflect.New(target).Elem()
+		return result, p.decodeStruct(instance, result)
+
+	case reflect.Pointer:
+		if target.Elem().Kind() != reflect.Struct {
+			break
+		}
+		if value.IsNil() {
+			return reflect.Zero(target), nil
+		}
+		instance, ok := value.AsObject().(*vm.Instance)
+		if !ok {
+			return reflect.Value{}, mismatch(value, target)
+		}
+		result := reflect.New(target.Elem())
+		return result, p.decodeStruct(instance, result.Elem())
+	}
+
+	return reflect.Value{}, fmt.Errorf("cannot convert script values to %s", target)
+}
+
+// decodeStruct assigns the fields of the instance to the exported fields of the struct, missing fields are skipped
+func (p *Program) decodeStruct(instance *vm.Instance, rv reflect.Value) error {
+	for i := range rv.NumField() {
+		field := rv.Type().Field(i)
+		name, ok := fieldName(field)
+		if !ok {
+			continue
+		}
+		value, ok := instance.Fields[name]
+		if !ok {
+			continue
+		}
+
+		decoded, err := p.decodeValue(value, field.Type)
+		if err != nil {
+			return fmt.Errorf("field '%s': %w", name, err)
+		}
+		rv.Field(i).Set(decoded)
+	}
+	return nil
+}
+
+// fieldName returns the script name of an exported struct field
+func fieldName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+
+	if tag, ok := field.Tag.Lookup("senbonzakura"); ok {
+		return tag, tag != "-"
+	}
+	first, size := utf8.DecodeRuneInString(field.Name)
+	return string(unicode.ToLower(first)) + field.Name[size:], true
+}
+
+// mismatch reports a script value that cannot be converted to the Go type
+func mismatch(value vm.Value, target reflect.Type) error {
+	return fmt.Errorf("cannot use %s value %s as %s", value.TypeName(), value, target)
+}
@@ -1,48 +1,159 @@
 package visitor_s_expression
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+)
+
+// ANSI colors of the parts of the output
+const (
+	colorReset      = "\x1b[0m"
+	colorTag        = "\x1b[36m" // cyan
+	colorString     = "\x1b[32m" // green
+	colorLiteral    = "\x1b[33m" // yellow, numbers and booleans
+	colorOperator   = "\x1b[35m" // magenta
+	colorType       = "\x1b[34m" // blue, names of type annotations
+	colorAnnotation = "\x1b[90m" // gray, positions and checked types
+)
 
 // writeIndent writes the appropriate indentation based on the current indent level
 func (v *SExpressionVisitor) writeIndent() {
 	if v.config.Pretty && v.indentLevel > 0 {
 		indent := strings.Repeat(" ", v.indentLevel*v.config.IndentSize)
-		v.buffer.WriteString(indent)
+		v.write(indent)
 	}
 }
 
-// beginExpression starts a new S-expression with the given tag
+// beginExpression starts a new S-expression with the given tag, the indent is written by writeSpaceOrNewLine
 func (v *SExpressionVisitor) beginExpression(tag string) {
-	v.writeIndent()
-	v.buffer.WriteString("(")
-	v.buffer.WriteString(tag)
+	v.write("(")
+	v.writeColored(colorTag, tag)
 
 	if v.config.Pretty {
 		v.indentLevel++
 	}
 }
 
+// beginNode starts the S-expression of a node, followed by the annotations selected in the config
+//
+// Annotations are keyword and value pairs, :at 3:5 for the position and :type "number" for the checked type
+func (v *SExpressionVisitor) beginNode(tag string, node ast.Node) {
+	v.beginExpression(tag)
+
+	if span := node.GetSpan(); v.config.Positions && span.Line > 0 {
+		v.writeColored(colorAnnotation, fmt.Sprintf(" :at %d:%d", span.Line, span.Column))
+	}
+	if expression, ok := node.(ast.Expression); ok && v.config.Types != nil {
+		if t := v.config.Types.TypeOf(expression); t != nil {
+			v.writeColored(colorAnnotation, fmt.Sprintf(" :type %q", t.String()))
+		}
+	}
+}
+
 // endExpression closes the current S-expression
 func (v *SExpressionVisitor) endExpression() {
 	if v.config.Pretty {
 		v.indentLevel--
 	}
 
-	v.buffer.WriteString(")")
+	v.write(")")
 }
 
 // writeSpaceOrNewLine writes a space or a newline based on formatting rules
 func (v *SExpressionVisitor) writeSpaceOrNewLine() {
 	if v.config.Pretty {
-		v.buffer.WriteString("\n")
+		v.write("\n")
 		v.writeIndent()
 	} else {
-		v.buffer.WriteString(" ")
+		v.write(" ")
+	}
+}
+
+// write writes visible text to the output, a visitor with a width budget stops once the text goes past it
+func (v *SExpressionVisitor) write(s string) {
+	v.buffer.WriteString(s)
+	if v.limited {
+		if v.budget -= utf8.RuneCountInString(s); v.budget < 0 {
+			panic(tooWide{})
+		}
 	}
 }
 
 // writeString writes a string to the output
 func (v *SExpressionVisitor) writeString(s string) {
-	v.buffer.WriteString(s)
+	v.write(s)
+}
+
+// writeColored writes a string to the output, in the color if colors are enabled
+func (v *SExpressionVisitor) writeColored(color string, s string) {
+	if !v.config.Color {
+		v.write(s)
+		return
+	}
+	v.buffer.WriteString(color)
+	v.write(s)
+	v.buffer.WriteString(colorReset)
+}
+
+// tooWide is raised to stop writing a flat form at the first character past the maximum width
+type tooWide struct{}
+
+// writeFlat writes a form on a single line if it fits in the maximum width, returns false if it does not
+//
+// The form is written by the function to a compact visitor with the rest of the line as budget, the visitor
+// stops as soon as the form goes past it so nested forms are not written in full again at every level
+func (v *SExpressionVisitor) writeFlat(write func(flat *SExpressionVisitor)) bool {
+	if !v.config.Pretty || v.config.MaxWidth <= 0 {
+		return false
+	}
+
+	output := v.buffer.String()
+	line := output[strings.LastIndexByte(output, '\n')+1:]
+
+	config := v.config
+	config.Pretty = false
+	flat := NewSExpressionVisitorWithConfig(config)
+	flat.limited, flat.budget = true, v.config.MaxWidth-visibleWidth(line)
+	if flat.budget < 0 || !writeWithin(flat, write) {
+		return false
+	}
+	v.buffer.WriteString(flat.String())
+	return true
+}
+
+// writeWithin runs the function on the visitor with a budget and tells if it completed within it
+func writeWithin(flat *SExpressionVisitor, write func(flat *SExpressionVisitor)) (fits bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(tooWide); !ok {
+				panic(r)
+			}
+			fits = false
+		}
+	}()
+	write(flat)
+	return true
+}
+
+// visibleWidth returns the number of runes of the text, without ANSI escape codes
+func visibleWidth(text string) int {
+	width := 0
+	for i := 0; i < len(text); {
+		if text[i] == '\x1b' {
+			end := strings.IndexByte(text[i:], 'm')
+			if end >= 0 {
+				i += end + 1
+				continue
+			}
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		width++
+	}
+	return width
 }
 
 // String returns the final S-expression string
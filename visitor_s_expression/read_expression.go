@@ -0,0 +1,141 @@
+package visitor_s_expression
+
+import (
+	"strconv"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+)
+
+func readExpression(f *form) ast.Expression {
+	switch tagOf(f) {
+	case "init":
+		return readVariableExpression(f)
+	case "assign":
+		items := expectList(f, "assign", 3, 3)
+		expression := &ast.AssignmentExpression{Left: readExpression(items[1]), Right: readExpression(items[2])}
+		readOperator(items[0], &expression.Operator)
+		return expression
+	case "binary":
+		items := expectList(f, "binary", 3, 3)
+		expression := &ast.BinaryExpression{Left: readExpression(items[1]), Right: readExpression(items[2])}
+		readOperator(items[0], &expression.Operator)
+		return expression
+	case "unary":
+		items := expectList(f, "unary", 2, 2)
+		expression := &ast.UnaryExpression{Right: readExpression(items[1])}
+		readOperator(items[0], &expression.Operator)
+		return expression
+	case "logical":
+		items := expectList(f, "logical", 3, 3)
+		expression := &ast.LogicalExpression{Left: readExpression(items[1]), Right: readExpression(items[2])}
+		readOperator(items[0], &expression.Operator)
+		return expression
+	case "boolean":
+		return readBooleanLiteralExpression(f)
+	case "nil":
+		expectList(f, "nil", 0, 0)
+		return &ast.NilLiteralExpression{}
+	case "number":
+		return readNumericLiteralExpression(f)
+	case "string":
+		return &ast.StringLiteralExpression{Value: expectString(expectList(f, "string", 1, 1)[0])}
+	case "id":
+		return readIdentifierExpression(f)
+	case "member":
+		return readMemberExpression(f)
+	case "call":
+		items := expectList(f, "call", 1, 2)
+		return &ast.CallExpression{Callee: readExpression(items[0]), Arguments: readArguments(items[1:])}
+	case "this":
+		expectList(f, "this", 0, 0)
+		return &ast.ThisExpression{}
+	case "super":
+		expectList(f, "super", 0, 0)
+		return &ast.SuperExpression{}
+	case "new":
+		items := expectList(f, "new", 1, 2)
+		return &ast.NewExpression{Callee: readExpression(items[0]), Arguments: readArguments(items[1:])}
+	case "template":
+		return readTemplateLiteralExpression(f)
+	default:
+		failAt(f, "expected an expression, got %s", f)
+		return nil
+	}
+}
+
+func readVariableExpression(f *form) *ast.VariableExpression {
+	items := expectList(f, "init", 2, 3)
+	expression := &ast.VariableExpression{
+		Identifier:     readIdentifierExpression(items[0]),
+		TypeAnnotation: readTypeAnnotation(items[1]),
+	}
+	if len(items) == 3 {
+		expression.Initializer = readExpression(items[2])
+	}
+	return expression
+}
+
+func readBooleanLiteralExpression(f *form) *ast.BooleanLiteralExpression {
+	value := expectList(f, "boolean", 1, 1)[0]
+	switch expectSymbol(value) {
+	case "true":
+		return &ast.BooleanLiteralExpression{Value: true}
+	case "false":
+		return &ast.BooleanLiteralExpression{Value: false}
+	default:
+		failAt(value, "expected true or false, got %s", value)
+		return nil
+	}
+}
+
+// readNumericLiteralExpression reads a number written by formatNumber, floats have a fraction or an exponent
+func readNumericLiteralExpression(f *form) *ast.NumericLiteralExpression {
+	value := expectList(f, "number", 1, 1)[0]
+	text := expectSymbol(value)
+
+	if intValue, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return &ast.NumericLiteralExpression{Kind: ast.IntegerLiteral, IntValue: intValue, FloatValue: float64(intValue)}
+	}
+	floatValue, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		failAt(value, "invalid number %s", text)
+	}
+	return &ast.NumericLiteralExpression{Kind: ast.FloatLiteral, FloatValue: floatValue}
+}
+
+func readIdentifierExpression(f *form) *ast.IdentifierExpression {
+	return &ast.IdentifierExpression{Name: expectSymbol(expectList(f, "id", 1, 1)[0])}
+}
+
+func readMemberExpression(f *form) *ast.MemberExpression {
+	items := expectList(f, "member", 3, 3)
+	expression := &ast.MemberExpression{Object: readExpression(items[1]), Property: readExpression(items[2])}
+
+	switch expectString(items[0]) {
+	case "computed":
+		expression.Computed = true
+	case "static":
+		expression.Computed = false
+	default:
+		failAt(items[0], "expected \"computed\" or \"static\", got %s", items[0])
+	}
+	return expression
+}
+
+func readTemplateLiteralExpression(f *form) *ast.TemplateLiteralExpression {
+	items := expectList(f, "template", 1, -1)
+	if len(items)%2 == 0 {
+		failAt(f, "expected a template to start and end with a chunk")
+	}
+
+	// Chunks and expressions interleave, starting and ending with a chunk
+	expression := &ast.TemplateLiteralExpression{}
+	for i, item := range items {
+		if i%2 == 0 {
+			expression.Chunks = append(expression.Chunks, expectString(item))
+		} else {
+			expression.Expressions = append(expression.Expressions, readExpression(item))
+		}
+	}
+	return expression
+}
@@ -0,0 +1,182 @@
+package visitor_s_expression
+
+import "github.com/yoh0xff/senbonzakura/ast"
+
+func readStatement(f *form) ast.Statement {
+	switch tagOf(f) {
+	case "program":
+		return &ast.ProgramStatement{Body: readStatements(expectList(f, "program", 0, -1))}
+	case "block":
+		return readBlockStatement(f)
+	case "empty":
+		expectList(f, "empty", 0, 0)
+		return &ast.EmptyStatement{}
+	case "expr":
+		items := expectList(f, "expr", 1, 1)
+		return &ast.ExpressionStatement{Expression: readExpression(items[0])}
+	case "let":
+		return readVariableDeclarationStatement(f)
+	case "if":
+		return readConditionalStatement(f)
+	case "while":
+		items := expectList(f, "while", 2, 2)
+		return &ast.WhileStatement{Condition: readExpression(items[0]), Body: readBlockStatement(items[1])}
+	case "do-while":
+		items := expectList(f, "do-while", 2, 2)
+		return &ast.DoWhileStatement{Body: readBlockStatement(items[0]), Condition: readExpression(items[1])}
+	case "for":
+		return readForStatement(f)
+	case "def":
+		return readFunctionDeclarationStatement(f)
+	case "return":
+		items := expectList(f, "return", 0, 1)
+		statement := &ast.ReturnStatement{}
+		if len(items) > 0 {
+			statement.Argument = readExpression(items[0])
+		}
+		return statement
+	case "class":
+		return readClassDeclarationStatement(f)
+	case "error":
+		expectList(f, "error", 0, 0)
+		return &ast.ErrorStatement{}
+	default:
+		failAt(f, "expected a statement, got %s", f)
+		return nil
+	}
+}
+
+func readStatements(items []*form) []ast.Statement {
+	var statements []ast.Statement
+	for _, item := range items {
+		statements = append(statements, readStatement(item))
+	}
+	return statements
+}
+
+func readBlockStatement(f *form) *ast.BlockStatement {
+	return &ast.BlockStatement{Body: readStatements(expectList(f, "block", 0, -1))}
+}
+
+func readVariableDeclarationStatement(f *form) *ast.VariableDeclarationStatement {
+	statement := &ast.VariableDeclarationStatement{}
+	for _, item := range expectList(f, "let", 1, -1) {
+		statement.Variables = append(statement.Variables, readVariableExpression(item))
+	}
+	return statement
+}
+
+func readConditionalStatement(f *form) *ast.IfStatement {
+	items := expectList(f, "if", 2, 3)
+	statement := &ast.IfStatement{
+		Condition:  readExpression(items[0]),
+		Consequent: readBlockStatement(items[1]),
+	}
+	if len(items) > 2 {
+		statement.Alternative = readBlockStatement(items[2])
+	}
+	return statement
+}
+
+func readForStatement(f *form) *ast.ForStatement {
+	items := expectList(f, "for", 4, 4)
+	statement := &ast.ForStatement{Body: readBlockStatement(items[3])}
+
+	// Missing parts are written as (none)
+	if tagOf(items[0]) != "none" {
+		statement.Initializer = readStatement(items[0])
+	}
+	if tagOf(items[1]) != "none" {
+		statement.Condition = readExpression(items[1])
+	}
+	if tagOf(items[2]) != "none" {
+		statement.Increment = readExpression(items[2])
+	}
+	return statement
+}
+
+func readFunctionDeclarationStatement(f *form) *ast.FunctionDeclarationStatement {
+	items := expectList(f, "def", 3, 4)
+	statement := &ast.FunctionDeclarationStatement{Name: readIdentifierExpression(items[0])}
+
+	// Parameters are only written when there are some
+	if len(items) == 4 {
+		for _, item := range expectList(items[1], "params", 1, -1) {
+			param := expectList(item, "param", 2, 2)
+			statement.Parameters = append(statement.Parameters, ast.Parameter{
+				Name: readIdentifierExpression(param[0]),
+				Type: readTypeAnnotation(param[1]),
+			})
+		}
+		items = items[1:]
+	}
+
+	statement.ReturnType = readType(expectList(items[1], "return_type", 1, 1)[0])
+	statement.Body = readBlockStatement(items[2])
+	return statement
+}
+
+func readClassDeclarationStatement(f *form) *ast.ClassDeclarationStatement {
+	items := expectList(f, "class", 2, 3)
+	statement := &ast.ClassDeclarationStatement{Name: readIdentifierExpression(items[0])}
+
+	if len(items) == 3 {
+		statement.SuperClass = readIdentifierExpression(expectList(items[1], "extends", 1, 1)[0])
+		items = items[1:]
+	}
+
+	statement.Body = readBlockStatement(items[1])
+	return statement
+}
+
+// readTypeAnnotation reads a (type ...) list
+func readTypeAnnotation(f *form) ast.Type {
+	return readType(expectList(f, "type", 1, 1)[0])
+}
+
+// Helper function to read type annotations
+func readType(f *form) ast.Type {
+	if !f.list {
+		switch expectSymbol(f) {
+		case "Number":
+			return &ast.PrimitiveType{Kind: ast.NumberType}
+		case "Boolean":
+			return &ast.PrimitiveType{Kind: ast.BooleanType}
+		case "String":
+			return &ast.PrimitiveType{Kind: ast.StringType}
+		case "void":
+			return &ast.VoidType{}
+		}
+		failAt(f, "unknown type %s", f)
+	}
+
+	switch tagOf(f) {
+	case "array":
+		return &ast.ArrayType{ElementType: readType(expectList(f, "array", 1, 1)[0])}
+	case "function":
+		items := expectList(f, "function", 2, 2)
+		t := &ast.FunctionType{ReturnType: readType(items[1])}
+		for _, param := range expectList(items[0], "params", 0, -1) {
+			t.Params = append(t.Params, readType(param))
+		}
+		return t
+	case "class-type":
+		items := expectList(f, "class-type", 1, 2)
+		t := &ast.ClassType{Name: expectSymbol(items[0])}
+		if len(items) == 2 {
+			superClass := expectSymbol(expectList(items[1], "extends", 1, 1)[0])
+			t.SuperClass = &superClass
+		}
+		return t
+	case "generic":
+		items := expectList(f, "generic", 2, 2)
+		t := &ast.GenericType{Base: expectSymbol(items[0])}
+		for _, arg := range expectList(items[1], "args", 0, -1) {
+			t.TypeArgs = append(t.TypeArgs, readType(arg))
+		}
+		return t
+	default:
+		failAt(f, "expected a type, got %s", f)
+		return nil
+	}
+}
@@ -0,0 +1,231 @@
+package visitor_s_expression
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+)
+
+// ReadStatement reads a statement from the S-expression text written by the visitor, like (program (let ...))
+//
+// Pretty and compact text are both accepted, annotations like :at 3:5 are skipped. The text does not hold spans
+// and raw literals, they are left empty in the nodes
+func ReadStatement(text string) (statement ast.Statement, err error) {
+	defer recoverReadError(&err)
+	return readStatement(readRoot(text)), nil
+}
+
+// ReadExpression reads an expression from the S-expression text written by the visitor, like (binary "+" ...)
+func ReadExpression(text string) (expression ast.Expression, err error) {
+	defer recoverReadError(&err)
+	return readExpression(readRoot(text)), nil
+}
+
+// form is a parsed S-expression, a list or an atom
+type form struct {
+	list   bool
+	items  []*form // items of a list
+	atom   string  // symbol or unquoted string of an atom
+	quoted bool    // whether the atom is a quoted string
+	line   int
+	column int
+}
+
+// String describes the form for errors
+func (f *form) String() string {
+	switch {
+	case f.list && len(f.items) > 0 && !f.items[0].list:
+		return fmt.Sprintf("(%s ...)", f.items[0].atom)
+	case f.list:
+		return "list"
+	case f.quoted:
+		return strconv.Quote(f.atom)
+	default:
+		return f.atom
+	}
+}
+
+// readError is raised to unwind the reader at the first error
+type readError struct {
+	err error
+}
+
+// recoverReadError stops a read error and stores it in err, other panics are propagated
+func recoverReadError(err *error) {
+	if r := recover(); r != nil {
+		readErr, ok := r.(readError)
+		if !ok {
+			panic(r)
+		}
+		*err = readErr.err
+	}
+}
+
+// failAt aborts reading with an error at the position of the form
+func failAt(f *form, format string, args ...any) {
+	panic(readError{fmt.Errorf("%d:%d: %s", f.line, f.column, fmt.Sprintf(format, args...))})
+}
+
+// Parsing of the text to forms
+
+// scanner reads forms from the text and tracks the position
+type scanner struct {
+	text   string
+	offset int
+	line   int
+	column int
+}
+
+// readRoot parses the single form of the text
+func readRoot(text string) *form {
+	s := &scanner{text: text, line: 1, column: 1}
+	root := s.readForm()
+	s.skipSpace()
+	if s.offset < len(s.text) {
+		s.fail("unexpected %q after the end of the expression", s.text[s.offset])
+	}
+	return root
+}
+
+// readForm parses a list or an atom
+func (s *scanner) readForm() *form {
+	s.skipSpace()
+	f := &form{line: s.line, column: s.column}
+	if s.offset >= len(s.text) {
+		s.fail("unexpected end of input")
+	}
+
+	switch s.text[s.offset] {
+	case '(':
+		f.list = true
+		s.advance(1)
+		for s.skipSpace(); s.offset < len(s.text) && s.text[s.offset] != ')'; s.skipSpace() {
+			item := s.readForm()
+			if len(f.items) > 0 && !item.list && !item.quoted && strings.HasPrefix(item.atom, ":") {
+				// Skip the value of the annotation
+				s.readForm()
+				continue
+			}
+			f.items = append(f.items, item)
+		}
+		if s.offset >= len(s.text) {
+			failAt(f, "unclosed list")
+		}
+		s.advance(1)
+	case ')':
+		s.fail("unexpected ')'")
+	case '"':
+		// Strings are quoted by strconv.Quote, a backslash escapes the next byte
+		end := s.offset + 1
+		for end < len(s.text) && s.text[end] != '"' {
+			if s.text[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(s.text) {
+			s.fail("unterminated string")
+		}
+		value, err := strconv.Unquote(s.text[s.offset : end+1])
+		if err != nil {
+			s.fail("invalid string %s", s.text[s.offset:end+1])
+		}
+		f.atom, f.quoted = value, true
+		s.advance(end + 1 - s.offset)
+	default:
+		end := s.offset
+		for end < len(s.text) && !strings.ContainsRune("()\"", rune(s.text[end])) &&
+			!unicode.IsSpace(rune(s.text[end])) {
+			end++
+		}
+		f.atom = s.text[s.offset:end]
+		s.advance(end - s.offset)
+	}
+	return f
+}
+
+// skipSpace skips whitespace before the next form
+func (s *scanner) skipSpace() {
+	for s.offset < len(s.text) && unicode.IsSpace(rune(s.text[s.offset])) {
+		s.advance(1)
+	}
+}
+
+// advance moves the offset by n bytes, updating the line and column
+func (s *scanner) advance(n int) {
+	for _, r := range s.text[s.offset : s.offset+n] {
+		if r == '\n' {
+			s.line++
+			s.column = 1
+		} else {
+			s.column++
+		}
+	}
+	s.offset += n
+}
+
+// fail aborts reading with an error at the current position
+func (s *scanner) fail(format string, args ...any) {
+	failAt(&form{line: s.line, column: s.column}, format, args...)
+}
+
+// Helpers to take forms apart
+
+// expectList checks that the form is a list with the tag and the number of items, the tag excluded, in the
+// range, max -1 means no limit
+func expectList(f *form, tag string, min int, max int) []*form {
+	if !f.list || len(f.items) == 0 || f.items[0].list || f.items[0].quoted || f.items[0].atom != tag {
+		failAt(f, "expected (%s ...), got %s", tag, f)
+	}
+	items := f.items[1:]
+	if len(items) < min || max >= 0 && len(items) > max {
+		failAt(f, "unexpected number of items in %s: %d", f, len(items))
+	}
+	return items
+}
+
+// tagOf returns the tag of a list, or "" for atoms and empty lists
+func tagOf(f *form) string {
+	if !f.list || len(f.items) == 0 || f.items[0].list || f.items[0].quoted {
+		return ""
+	}
+	return f.items[0].atom
+}
+
+// expectSymbol returns the text of an unquoted atom
+func expectSymbol(f *form) string {
+	if f.list || f.quoted {
+		failAt(f, "expected a symbol, got %s", f)
+	}
+	return f.atom
+}
+
+// expectString returns the value of a quoted atom
+func expectString(f *form) string {
+	if f.list || !f.quoted {
+		failAt(f, "expected a string, got %s", f)
+	}
+	return f.atom
+}
+
+// readOperator reads a quoted operator with the text decoding of its type
+func readOperator(f *form, operator interface{ UnmarshalText(text []byte) error }) {
+	if err := operator.UnmarshalText([]byte(expectString(f))); err != nil {
+		failAt(f, "%s", err)
+	}
+}
+
+// readArguments reads the optional (args ...) list of calls
+func readArguments(items []*form) []ast.Expression {
+	if len(items) == 0 {
+		return nil
+	}
+	var arguments []ast.Expression
+	for _, item := range expectList(items[0], "args", 1, -1) {
+		arguments = append(arguments, readExpression(item))
+	}
+	return arguments
+}
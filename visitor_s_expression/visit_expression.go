@@ -2,6 +2,7 @@ package visitor_s_expression
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/yoh0xff/senbonzakura/ast"
@@ -22,7 +23,7 @@ func visitExpression(visitor *SExpressionVisitor, expression ast.Expression) {
 	case ast.NodeBooleanLiteralExpression:
 		visitBooleanLiteralExpression(visitor, expression.(*ast.BooleanLiteralExpression))
 	case ast.NodeNilLiteralExpression:
-		visitNilLiteralExpression(visitor)
+		visitNilLiteralExpression(visitor, expression.(*ast.NilLiteralExpression))
 	case ast.NodeNumericLiteralExpression:
 		visitNumericLiteralExpression(visitor, expression.(*ast.NumericLiteralExpression))
 	case ast.NodeStringLiteralExpression:
@@ -34,18 +35,20 @@ func visitExpression(visitor *SExpressionVisitor, expression ast.Expression) {
 	case ast.NodeCallExpression:
 		visitCallExpression(visitor, expression.(*ast.CallExpression))
 	case ast.NodeThisExpression:
-		visitThisExpression(visitor)
+		visitThisExpression(visitor, expression.(*ast.ThisExpression))
 	case ast.NodeSuperExpression:
-		visitSuperExpression(visitor)
+		visitSuperExpression(visitor, expression.(*ast.SuperExpression))
 	case ast.NodeNewExpression:
 		visitNewExpression(visitor, expression.(*ast.NewExpression))
+	case ast.NodeTemplateLiteralExpression:
+		visitTemplateLiteralExpression(visitor, expression.(*ast.TemplateLiteralExpression))
 	default:
 		panic(fmt.Errorf("unknown expression type: %T", expression))
 	}
 }
 
 func visitVariableExpression(visitor *SExpressionVisitor, expression *ast.VariableExpression) {
-	visitor.beginExpression("init")
+	visitor.beginNode("init", expression)
 
 	// Process identifier
 	visitor.writeSpaceOrNewLine()
@@ -54,6 +57,7 @@ func visitVariableExpression(visitor *SExpressionVisitor, expression *ast.Variab
 	// Add type annotation to S-expression
 	visitor.writeSpaceOrNewLine()
 	visitor.beginExpression("type")
+	visitor.writeString(" ")
 	visitType(visitor, expression.TypeAnnotation)
 	visitor.endExpression()
 
@@ -67,11 +71,11 @@ func visitVariableExpression(visitor *SExpressionVisitor, expression *ast.Variab
 }
 
 func visitAssignmentExpression(visitor *SExpressionVisitor, expression *ast.AssignmentExpression) {
-	visitor.beginExpression("assign")
+	visitor.beginNode("assign", expression)
 
 	// Write the operator
 	visitor.writeSpaceOrNewLine()
-	visitor.writeString(fmt.Sprintf("\"%s\"", expression.Operator.String()))
+	visitor.writeColored(colorOperator, strconv.Quote(expression.Operator.String()))
 
 	// Process left operand
 	visitor.writeSpaceOrNewLine()
@@ -85,10 +89,10 @@ func visitAssignmentExpression(visitor *SExpressionVisitor, expression *ast.Assi
 }
 
 func visitBinaryExpression(visitor *SExpressionVisitor, expression *ast.BinaryExpression) {
-	visitor.beginExpression("binary")
+	visitor.beginNode("binary", expression)
 
 	visitor.writeSpaceOrNewLine()
-	visitor.writeString(fmt.Sprintf("\"%s\"", expression.Operator.String()))
+	visitor.writeColored(colorOperator, strconv.Quote(expression.Operator.String()))
 
 	visitor.writeSpaceOrNewLine()
 	expression.Left.Accept(visitor)
@@ -100,10 +104,10 @@ func visitBinaryExpression(visitor *SExpressionVisitor, expression *ast.BinaryEx
 }
 
 func visitUnaryExpression(visitor *SExpressionVisitor, expression *ast.UnaryExpression) {
-	visitor.beginExpression("unary")
+	visitor.beginNode("unary", expression)
 
 	visitor.writeSpaceOrNewLine()
-	visitor.writeString(fmt.Sprintf("\"%s\"", expression.Operator.String()))
+	visitor.writeColored(colorOperator, strconv.Quote(expression.Operator.String()))
 
 	visitor.writeSpaceOrNewLine()
 	expression.Right.Accept(visitor)
@@ -112,10 +116,10 @@ func visitUnaryExpression(visitor *SExpressionVisitor, expression *ast.UnaryExpr
 }
 
 func visitLogicalExpression(visitor *SExpressionVisitor, expression *ast.LogicalExpression) {
-	visitor.beginExpression("logical")
+	visitor.beginNode("logical", expression)
 
 	visitor.writeSpaceOrNewLine()
-	visitor.writeString(fmt.Sprintf("\"%s\"", expression.Operator.String()))
+	visitor.writeColored(colorOperator, strconv.Quote(expression.Operator.String()))
 
 	visitor.writeSpaceOrNewLine()
 	expression.Left.Accept(visitor)
@@ -127,34 +131,49 @@ func visitLogicalExpression(visitor *SExpressionVisitor, expression *ast.Logical
 }
 
 func visitBooleanLiteralExpression(visitor *SExpressionVisitor, expression *ast.BooleanLiteralExpression) {
-	visitor.beginExpression("boolean")
-	visitor.writeString(fmt.Sprintf(" %t", expression.Value))
+	visitor.beginNode("boolean", expression)
+	visitor.writeString(" ")
+	visitor.writeColored(colorLiteral, strconv.FormatBool(expression.Value))
 	visitor.endExpression()
 }
 
-func visitNilLiteralExpression(visitor *SExpressionVisitor) {
-	visitor.beginExpression("nil")
+func visitNilLiteralExpression(visitor *SExpressionVisitor, expression *ast.NilLiteralExpression) {
+	visitor.beginNode("nil", expression)
 	visitor.endExpression()
 }
 
 func visitNumericLiteralExpression(visitor *SExpressionVisitor, expression *ast.NumericLiteralExpression) {
-	visitor.beginExpression("number")
-	visitor.writeString(fmt.Sprintf(" %d", expression.Value))
+	visitor.beginNode("number", expression)
+	visitor.writeString(" ")
+	visitor.writeColored(colorLiteral, formatNumber(expression))
 	visitor.endExpression()
 }
 
+// formatNumber formats the numeric literal value, floats always keep a fraction or an exponent
+func formatNumber(expression *ast.NumericLiteralExpression) string {
+	if expression.Kind == ast.IntegerLiteral {
+		return strconv.FormatInt(expression.IntValue, 10)
+	}
+
+	formatted := strconv.FormatFloat(expression.FloatValue, 'g', -1, 64)
+	if !strings.ContainsAny(formatted, ".e") {
+		formatted += ".0"
+	}
+	return formatted
+}
+
 func visitStringLiteralExpression(visitor *SExpressionVisitor, expression *ast.StringLiteralExpression) {
-	visitor.beginExpression("string")
+	visitor.beginNode("string", expression)
 
-	// Replace double quotes with escaped quotes
-	escaped := strings.Replace(expression.Value, "\"", "\\\"", -1)
-	visitor.writeString(fmt.Sprintf(" \"%s\"", escaped))
+	// Quote the value, so escaped characters don't break the output
+	visitor.writeString(" ")
+	visitor.writeColored(colorString, strconv.Quote(expression.Value))
 
 	visitor.endExpression()
 }
 
 func visitIdentifierExpression(visitor *SExpressionVisitor, expression *ast.IdentifierExpression) {
-	visitor.beginExpression("id")
+	visitor.beginNode("id", expression)
 
 	// Write the identifier name
 	visitor.writeString(fmt.Sprintf(" %s", expression.Name))
@@ -163,14 +182,14 @@ func visitIdentifierExpression(visitor *SExpressionVisitor, expression *ast.Iden
 }
 
 func visitMemberExpression(visitor *SExpressionVisitor, expression *ast.MemberExpression) {
-	visitor.beginExpression("member")
+	visitor.beginNode("member", expression)
 
 	// Indicate whether the member access is computed (bracket notation) or not (dot notation)
 	visitor.writeSpaceOrNewLine()
 	if expression.Computed {
-		visitor.writeString("\"computed\"")
+		visitor.writeColored(colorOperator, "\"computed\"")
 	} else {
-		visitor.writeString("\"static\"")
+		visitor.writeColored(colorOperator, "\"static\"")
 	}
 
 	// Process object expression (the left part of the member access)
@@ -185,58 +204,71 @@ func visitMemberExpression(visitor *SExpressionVisitor, expression *ast.MemberEx
 }
 
 func visitCallExpression(visitor *SExpressionVisitor, expression *ast.CallExpression) {
-	visitor.beginExpression("call")
+	visitor.beginNode("call", expression)
 
 	// Process callee expression
 	visitor.writeSpaceOrNewLine()
 	expression.Callee.Accept(visitor)
 
 	if len(expression.Arguments) > 0 {
-		// Create args expression
 		visitor.writeSpaceOrNewLine()
-		visitor.beginExpression("args")
+		visitArguments(visitor, expression.Arguments)
+	}
 
-		// Process each argument
-		for _, arg := range expression.Arguments {
-			visitor.writeSpaceOrNewLine()
-			arg.Accept(visitor)
-		}
+	visitor.endExpression()
+}
 
-		visitor.endExpression() // Close args expression
+// visitArguments writes the (args ...) list of calls, on one line if it fits
+func visitArguments(visitor *SExpressionVisitor, arguments []ast.Expression) {
+	if visitor.writeFlat(func(flat *SExpressionVisitor) { visitArguments(flat, arguments) }) {
+		return
 	}
 
+	visitor.beginExpression("args")
+	for _, arg := range arguments {
+		visitor.writeSpaceOrNewLine()
+		arg.Accept(visitor)
+	}
 	visitor.endExpression()
 }
 
-func visitThisExpression(visitor *SExpressionVisitor) {
-	visitor.beginExpression("this")
+func visitThisExpression(visitor *SExpressionVisitor, expression *ast.ThisExpression) {
+	visitor.beginNode("this", expression)
 	visitor.endExpression()
 }
 
-func visitSuperExpression(visitor *SExpressionVisitor) {
-	visitor.beginExpression("super")
+func visitSuperExpression(visitor *SExpressionVisitor, expression *ast.SuperExpression) {
+	visitor.beginNode("super", expression)
 	visitor.endExpression()
 }
 
 func visitNewExpression(visitor *SExpressionVisitor, expression *ast.NewExpression) {
-	visitor.beginExpression("new")
+	visitor.beginNode("new", expression)
 
 	// Process callee expression
 	visitor.writeSpaceOrNewLine()
 	expression.Callee.Accept(visitor)
 
 	if len(expression.Arguments) > 0 {
-		// Create args expression
 		visitor.writeSpaceOrNewLine()
-		visitor.beginExpression("args")
+		visitArguments(visitor, expression.Arguments)
+	}
+
+	visitor.endExpression()
+}
+
+func visitTemplateLiteralExpression(visitor *SExpressionVisitor, expression *ast.TemplateLiteralExpression) {
+	visitor.beginNode("template", expression)
+
+	// Chunks and expressions interleave, starting and ending with a chunk
+	for i, chunk := range expression.Chunks {
+		visitor.writeSpaceOrNewLine()
+		visitor.writeColored(colorString, strconv.Quote(chunk))
 
-		// Process each argument
-		for _, arg := range expression.Arguments {
+		if i < len(expression.Expressions) {
 			visitor.writeSpaceOrNewLine()
-			arg.Accept(visitor)
+			expression.Expressions[i].Accept(visitor)
 		}
-
-		visitor.endExpression() // Close args expression
 	}
 
 	visitor.endExpression()
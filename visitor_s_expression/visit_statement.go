@@ -13,7 +13,7 @@ func visitStatement(visitor *SExpressionVisitor, statement ast.Statement) {
 	case ast.NodeBlockStatement:
 		visitBlockStatement(visitor, statement.(*ast.BlockStatement))
 	case ast.NodeEmptyStatement:
-		visitEmptyStatement(visitor)
+		visitEmptyStatement(visitor, statement.(*ast.EmptyStatement))
 	case ast.NodeExpressionStatement:
 		visitExpressionStatement(visitor, statement.(*ast.ExpressionStatement))
 	case ast.NodeVariableDeclarationStatement:
@@ -32,13 +32,15 @@ func visitStatement(visitor *SExpressionVisitor, statement ast.Statement) {
 		visitReturnStatement(visitor, statement.(*ast.ReturnStatement))
 	case ast.NodeClassDeclarationStatement:
 		visitClassDeclarationStatement(visitor, statement.(*ast.ClassDeclarationStatement))
+	case ast.NodeErrorStatement:
+		visitErrorStatement(visitor, statement.(*ast.ErrorStatement))
 	default:
 		panic(fmt.Errorf("unknown statement type: %T", statement))
 	}
 }
 
 func visitProgramStatement(visitor *SExpressionVisitor, statement *ast.ProgramStatement) {
-	visitor.beginExpression("program")
+	visitor.beginNode("program", statement)
 
 	if len(statement.Body) > 0 {
 		for _, stmt := range statement.Body {
@@ -51,7 +53,7 @@ func visitProgramStatement(visitor *SExpressionVisitor, statement *ast.ProgramSt
 }
 
 func visitBlockStatement(visitor *SExpressionVisitor, statement *ast.BlockStatement) {
-	visitor.beginExpression("block")
+	visitor.beginNode("block", statement)
 
 	if len(statement.Body) > 0 {
 		for _, stmt := range statement.Body {
@@ -63,20 +65,20 @@ func visitBlockStatement(visitor *SExpressionVisitor, statement *ast.BlockStatem
 	visitor.endExpression()
 }
 
-func visitEmptyStatement(visitor *SExpressionVisitor) {
-	visitor.beginExpression("empty")
+func visitEmptyStatement(visitor *SExpressionVisitor, statement *ast.EmptyStatement) {
+	visitor.beginNode("empty", statement)
 	visitor.endExpression()
 }
 
 func visitExpressionStatement(visitor *SExpressionVisitor, statement *ast.ExpressionStatement) {
-	visitor.beginExpression("expr")
+	visitor.beginNode("expr", statement)
 	visitor.writeSpaceOrNewLine()
 	statement.Expression.Accept(visitor)
 	visitor.endExpression()
 }
 
 func visitVariableDeclarationStatement(visitor *SExpressionVisitor, statement *ast.VariableDeclarationStatement) {
-	visitor.beginExpression("let")
+	visitor.beginNode("let", statement)
 
 	for _, variable := range statement.Variables {
 		visitor.writeSpaceOrNewLine()
@@ -87,7 +89,7 @@ func visitVariableDeclarationStatement(visitor *SExpressionVisitor, statement *a
 }
 
 func visitConditionalStatement(visitor *SExpressionVisitor, statement *ast.IfStatement) {
-	visitor.beginExpression("if")
+	visitor.beginNode("if", statement)
 
 	// Process condition
 	visitor.writeSpaceOrNewLine()
@@ -107,7 +109,7 @@ func visitConditionalStatement(visitor *SExpressionVisitor, statement *ast.IfSta
 }
 
 func visitWhileStatement(visitor *SExpressionVisitor, statement *ast.WhileStatement) {
-	visitor.beginExpression("while")
+	visitor.beginNode("while", statement)
 
 	// Process condition
 	visitor.writeSpaceOrNewLine()
@@ -121,7 +123,7 @@ func visitWhileStatement(visitor *SExpressionVisitor, statement *ast.WhileStatem
 }
 
 func visitDoWhileStatement(visitor *SExpressionVisitor, statement *ast.DoWhileStatement) {
-	visitor.beginExpression("do-while")
+	visitor.beginNode("do-while", statement)
 
 	// Process body first (unlike while, do-while executes body first)
 	visitor.writeSpaceOrNewLine()
@@ -135,24 +137,28 @@ func visitDoWhileStatement(visitor *SExpressionVisitor, statement *ast.DoWhileSt
 }
 
 func visitForStatement(visitor *SExpressionVisitor, statement *ast.ForStatement) {
-	visitor.beginExpression("for")
+	visitor.beginNode("for", statement)
 
-	// Process initializer if present
+	// Missing parts are written as (none), so the remaining ones keep their place
+	visitor.writeSpaceOrNewLine()
 	if statement.Initializer != nil {
-		visitor.writeSpaceOrNewLine()
 		statement.Initializer.Accept(visitor)
+	} else {
+		visitNone(visitor)
 	}
 
-	// Process condition if present
+	visitor.writeSpaceOrNewLine()
 	if statement.Condition != nil {
-		visitor.writeSpaceOrNewLine()
 		statement.Condition.Accept(visitor)
+	} else {
+		visitNone(visitor)
 	}
 
-	// Process increment if present
+	visitor.writeSpaceOrNewLine()
 	if statement.Increment != nil {
-		visitor.writeSpaceOrNewLine()
 		statement.Increment.Accept(visitor)
+	} else {
+		visitNone(visitor)
 	}
 
 	// Process body
@@ -163,7 +169,7 @@ func visitForStatement(visitor *SExpressionVisitor, statement *ast.ForStatement)
 }
 
 func visitFunctionDeclarationStatement(visitor *SExpressionVisitor, statement *ast.FunctionDeclarationStatement) {
-	visitor.beginExpression("def")
+	visitor.beginNode("def", statement)
 
 	// Process function name
 	visitor.writeSpaceOrNewLine()
@@ -174,15 +180,16 @@ func visitFunctionDeclarationStatement(visitor *SExpressionVisitor, statement *a
 		visitor.writeSpaceOrNewLine()
 		visitor.beginExpression("params")
 
-		for _, param := range statement.Parameters {
+		for i, param := range statement.Parameters {
 			visitor.writeSpaceOrNewLine()
-			visitor.beginExpression("param")
+			visitor.beginNode("param", &statement.Parameters[i])
 
 			visitor.writeSpaceOrNewLine()
 			param.Name.Accept(visitor)
 
 			visitor.writeSpaceOrNewLine()
 			visitor.beginExpression("type")
+			visitor.writeString(" ")
 			visitType(visitor, param.Type)
 			visitor.endExpression()
 
@@ -195,6 +202,7 @@ func visitFunctionDeclarationStatement(visitor *SExpressionVisitor, statement *a
 	// Process return type
 	visitor.writeSpaceOrNewLine()
 	visitor.beginExpression("return_type")
+	visitor.writeString(" ")
 	visitType(visitor, statement.ReturnType)
 	visitor.endExpression()
 
@@ -206,7 +214,7 @@ func visitFunctionDeclarationStatement(visitor *SExpressionVisitor, statement *a
 }
 
 func visitReturnStatement(visitor *SExpressionVisitor, statement *ast.ReturnStatement) {
-	visitor.beginExpression("return")
+	visitor.beginNode("return", statement)
 
 	// Process return argument if present
 	if statement.Argument != nil {
@@ -218,7 +226,7 @@ func visitReturnStatement(visitor *SExpressionVisitor, statement *ast.ReturnStat
 }
 
 func visitClassDeclarationStatement(visitor *SExpressionVisitor, statement *ast.ClassDeclarationStatement) {
-	visitor.beginExpression("class")
+	visitor.beginNode("class", statement)
 
 	// Process class name
 	visitor.writeSpaceOrNewLine()
@@ -240,11 +248,26 @@ func visitClassDeclarationStatement(visitor *SExpressionVisitor, statement *ast.
 	visitor.endExpression()
 }
 
+func visitErrorStatement(visitor *SExpressionVisitor, statement *ast.ErrorStatement) {
+	visitor.beginNode("error", statement)
+	visitor.endExpression()
+}
+
+// visitNone writes a missing optional part of a statement
+func visitNone(visitor *SExpressionVisitor) {
+	visitor.beginExpression("none")
+	visitor.endExpression()
+}
+
 // Helper function to visit type annotations
 func visitType(visitor *SExpressionVisitor, typeAnnotation ast.Type) {
+	if visitor.writeFlat(func(flat *SExpressionVisitor) { visitType(flat, typeAnnotation) }) {
+		return
+	}
+
 	switch t := typeAnnotation.(type) {
 	case *ast.PrimitiveType:
-		visitor.writeString(t.String())
+		visitor.writeColored(colorType, t.String())
 	case *ast.ArrayType:
 		visitor.beginExpression("array")
 		visitor.writeSpaceOrNewLine()
@@ -269,19 +292,19 @@ func visitType(visitor *SExpressionVisitor, typeAnnotation ast.Type) {
 	case *ast.ClassType:
 		visitor.beginExpression("class-type")
 		visitor.writeSpaceOrNewLine()
-		visitor.writeString(t.Name)
+		visitor.writeColored(colorType, t.Name)
 		if t.SuperClass != nil {
 			visitor.writeSpaceOrNewLine()
 			visitor.beginExpression("extends")
 			visitor.writeSpaceOrNewLine()
-			visitor.writeString(*t.SuperClass)
+			visitor.writeColored(colorType, *t.SuperClass)
 			visitor.endExpression()
 		}
 		visitor.endExpression()
 	case *ast.GenericType:
 		visitor.beginExpression("generic")
 		visitor.writeSpaceOrNewLine()
-		visitor.writeString(t.Base)
+		visitor.writeColored(colorType, t.Base)
 		visitor.writeSpaceOrNewLine()
 		visitor.beginExpression("args")
 		for _, arg := range t.TypeArgs {
@@ -291,7 +314,7 @@ func visitType(visitor *SExpressionVisitor, typeAnnotation ast.Type) {
 		visitor.endExpression()
 		visitor.endExpression()
 	case *ast.VoidType:
-		visitor.writeString("void")
+		visitor.writeColored(colorType, "void")
 	default:
 		panic(fmt.Errorf("unknown type annotation: %T", typeAnnotation))
 	}
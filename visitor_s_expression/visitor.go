@@ -4,12 +4,17 @@ import (
 	"strings"
 
 	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/checker"
 )
 
 // SExpressionConfig defines formatting options for the visitor
 type SExpressionConfig struct {
-	Pretty     bool // whether to use pretty formatting
-	IndentSize int  // size of each indent level
+	Pretty     bool          // whether to use pretty formatting
+	IndentSize int           // size of each indent level
+	MaxWidth   int           // with Pretty, forms fitting in the line are kept on it, 0 breaks every form
+	Positions  bool          // whether to annotate statements and expressions with their line:col
+	Types      *checker.Info // checked program to annotate expressions with their types, nil to leave them out
+	Color      bool          // whether to colorize the output with ANSI escape codes
 }
 
 // SExpressionVisitor walks the AST and outputs S-expressions
@@ -17,6 +22,8 @@ type SExpressionVisitor struct {
 	config      SExpressionConfig
 	indentLevel int
 	buffer      strings.Builder
+	limited     bool // whether the output is bounded by the budget, for forms tried on a single line
+	budget      int  // number of visible characters that can still be written when limited
 }
 
 // NewSExpressionVisitor creates a new visitor with default configuration
@@ -38,10 +45,14 @@ func NewSExpressionVisitorWithConfig(config SExpressionConfig) *SExpressionVisit
 
 // VisitStatement implements the ast.Visitor interface
 func (v *SExpressionVisitor) VisitStatement(statement ast.Statement) {
-	visitStatement(v, statement)
+	if !v.writeFlat(func(flat *SExpressionVisitor) { visitStatement(flat, statement) }) {
+		visitStatement(v, statement)
+	}
 }
 
 // VisitExpression implements the ast.Visitor interface
 func (v *SExpressionVisitor) VisitExpression(expression ast.Expression) {
-	visitExpression(v, expression)
+	if !v.writeFlat(func(flat *SExpressionVisitor) { visitExpression(flat, expression) }) {
+		visitExpression(v, expression)
+	}
 }
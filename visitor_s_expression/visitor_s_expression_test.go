@@ -0,0 +1,178 @@
+package visitor_s_expression
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/checker"
+	"github.com/yoh0xff/senbonzakura/parser"
+)
+
+const roundTripSource = `class A extends B {
+    let x: number = 0x1F, y: Map[string, [A]];
+    def constructor(x: number, f: boolean) { super.init(); this.x = x; }
+}
+def f(a: [number]): boolean {
+    for (let i: number = 0; i <= 3; i += 1) { a[i] = -a[i] * 2.5 / 1e21; }
+    for (; i < 3;) {} for (;; i -= 1) {} for (i = 0;;) ;
+    while (!(a == nil) || false) { return true; }
+    do { ; } while (m != nil && +1 > 0);
+    if (a) { log("a\n\"", 'b', ` + "`t ${a} (x)`" + `); } else if (b) { new A(1).x *= 2; } else {}
+    return;
+}
+def g() {}`
+
+func TestReadRoundTrip(t *testing.T) {
+	program, diagnostics := parser.ParseRootStatement(parser.NewParser(roundTripSource))
+	if len(diagnostics) != 0 {
+		t.Fatalf("Expected no diagnostics, got %v", diagnostics)
+	}
+	superClass := "B"
+	program.(*ast.ProgramStatement).Body = append(program.(*ast.ProgramStatement).Body,
+		&ast.VariableDeclarationStatement{Variables: []*ast.VariableExpression{{
+			Identifier: &ast.IdentifierExpression{Name: "h"},
+			TypeAnnotation: &ast.FunctionType{
+				Params:     []ast.Type{&ast.ClassType{Name: "A", SuperClass: &superClass}, &ast.VoidType{}},
+				ReturnType: &ast.FunctionType{ReturnType: &ast.VoidType{}},
+			},
+		}}},
+		&ast.ErrorStatement{},
+	)
+
+	for _, pretty := range []bool{false, true} {
+		printed := printTree(program, pretty)
+		read, err := ReadStatement(printed)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if again := printTree(read, pretty); again != printed {
+			t.Errorf("Expected the same text after reading, got\n%s\nthen\n%s", printed, again)
+		}
+		if !ast.Equal(withoutRaw(program), read) {
+			t.Errorf("Expected the read tree to equal the parsed tree, pretty %t", pretty)
+		}
+	}
+}
+
+func TestReadExpression(t *testing.T) {
+	expression, err := ReadExpression(`(binary "+" (id a) (number 1.0))`)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	binary, ok := expression.(*ast.BinaryExpression)
+	if !ok || binary.Operator != ast.OperatorAdd {
+		t.Fatalf("Expected an addition, got %v", expression)
+	}
+	if number := binary.Right.(*ast.NumericLiteralExpression); number.Kind != ast.FloatLiteral || number.FloatValue != 1 {
+		t.Errorf("Expected the float 1, got %v", number)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"", "1:1: unexpected end of input"},
+		{"(program", "1:1: unclosed list"},
+		{"(program))", "1:10: unexpected ')' after the end of the expression"},
+		{"(program\n  (expr (string \"a)))", "2:17: unterminated string"},
+		{"(program (expr (id)))", "1:16: unexpected number of items in (id ...): 0"},
+		{"(program (foo))", "1:10: expected a statement, got (foo ...)"},
+		{"(expr (block))", "1:7: expected an expression, got (block ...)"},
+		{"(expr (binary \"**\" (id a) (id b)))", "1:15: unknown BinaryOperator '**'"},
+		{"(if (id a) (expr (id b)))", "1:12: expected (block ...), got (expr ...)"},
+		{"(let (init (id a) (type Integer)))", "1:25: unknown type Integer"},
+		{"(expr (number 1x))", "1:15: invalid number 1x"},
+	}
+
+	for _, test := range tests {
+		_, err := ReadStatement(test.text)
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("%q: Expected error %q, got %v", test.text, test.expected, err)
+		}
+	}
+}
+
+func TestVisitorConfig(t *testing.T) {
+	source := "let a: number = 1;\nif (a > 2) { a = -a; }"
+	program, _ := parser.ParseRootStatement(parser.NewParser(source))
+	info, diagnostics := checker.Check(program)
+	if len(diagnostics) != 0 {
+		t.Fatalf("Expected no diagnostics, got %v", diagnostics)
+	}
+
+	tests := []struct {
+		name     string
+		config   SExpressionConfig
+		expected string
+	}{
+		{"positions", SExpressionConfig{Positions: true},
+			`(program :at 1:1 (let :at 1:1 (init :at 1:5 (id :at 1:5 a) (type Number) (number :at 1:17 1))) ` +
+				`(if :at 2:1 (binary :at 2:5 ">" (id :at 2:5 a) (number :at 2:9 2)) (block :at 2:12 ` +
+				`(expr :at 2:14 (assign :at 2:14 "=" (id :at 2:14 a) (unary :at 2:18 "-" (id :at 2:19 a)))))))`},
+		{"types", SExpressionConfig{Types: info},
+			`(program (let (init :type "number" (id a) (type Number) (number :type "number" 1))) ` +
+				`(if (binary :type "boolean" ">" (id :type "number" a) (number :type "number" 2)) (block ` +
+				`(expr (assign :type "number" "=" (id :type "number" a) (unary :type "number" "-" (id :type "number" a)))))))`},
+		{"max width", SExpressionConfig{Pretty: true, IndentSize: 2, MaxWidth: 50},
+			"(program\n  (let (init (id a) (type Number) (number 1)))\n  (if\n    (binary \">\" (id a) (number 2))\n" +
+				"    (block\n      (expr\n        (assign \"=\" (id a) (unary \"-\" (id a)))))))"},
+		{"color", SExpressionConfig{Color: true},
+			"(\x1b[36mprogram\x1b[0m (\x1b[36mlet\x1b[0m (\x1b[36minit\x1b[0m (\x1b[36mid\x1b[0m a) " +
+				"(\x1b[36mtype\x1b[0m \x1b[34mNumber\x1b[0m) (\x1b[36mnumber\x1b[0m \x1b[33m1\x1b[0m))) " +
+				"(\x1b[36mif\x1b[0m (\x1b[36mbinary\x1b[0m \x1b[35m\">\"\x1b[0m"},
+	}
+
+	for _, test := range tests {
+		visitor := NewSExpressionVisitorWithConfig(test.config)
+		program.Accept(visitor)
+		if !strings.HasPrefix(visitor.String(), test.expected) {
+			t.Errorf("%s: Expected\n%s\ngot\n%s", test.name, test.expected, visitor.String())
+		}
+
+		// Annotations are skipped by the reader
+		if test.config.Color {
+			continue
+		}
+		read, err := ReadStatement(visitor.String())
+		if err != nil {
+			t.Errorf("%s: Expected no error, got %v", test.name, err)
+		} else if !ast.Equal(withoutRaw(program), read) {
+			t.Errorf("%s: Expected the read tree to equal the parsed tree", test.name)
+		}
+	}
+}
+
+// printTree writes the statement as an S-expression
+func printTree(statement ast.Statement, pretty bool) string {
+	visitor := NewSExpressionVisitorWithConfig(SExpressionConfig{Pretty: pretty, IndentSize: 2})
+	statement.Accept(visitor)
+	return visitor.String()
+}
+
+// withoutRaw clears the raw text of the literals in the tree, S-expressions do not hold it
+func withoutRaw(node ast.Node) ast.Node {
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.StringLiteralExpression:
+			node.Raw = ""
+		case *ast.NumericLiteralExpression:
+			node.Raw = ""
+		}
+		return true
+	})
+	return node
+}
+
+func BenchmarkMaxWidthDeepTree(b *testing.B) {
+	source := "x = 1" + strings.Repeat(" + 1", 2000) + ";"
+	program, _ := parser.ParseRootStatement(parser.NewParser(source))
+
+	for b.Loop() {
+		visitor := NewSExpressionVisitorWithConfig(SExpressionConfig{Pretty: true, IndentSize: 2, MaxWidth: 80})
+		program.Accept(visitor)
+	}
+}
@@ -0,0 +1,159 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/yoh0xff/senbonzakura/source"
+)
+
+// Opcode represents a bytecode instruction
+//
+// Operands follow the opcode, 16-bit operands are big endian
+type Opcode byte
+
+const (
+	OpConstant     Opcode = iota // constant index (u16)
+	OpNil                        // -
+	OpUndefined                  // name constant (u16), marks a local slot before its declaration runs
+	OpTrue                       // -
+	OpFalse                      // -
+	OpPop                        // -
+	OpDup                        // -
+	OpDup2                       // -
+	OpGetLocal                   // slot (u8)
+	OpSetLocal                   // slot (u8)
+	OpGetGlobal                  // global slot (u16)
+	OpDefineGlobal               // global slot (u16)
+	OpSetGlobal                  // global slot (u16)
+	OpGetUpvalue                 // upvalue index (u8)
+	OpSetUpvalue                 // upvalue index (u8)
+	OpCloseUpvalue               // -
+	OpGetProperty                // name constant (u16)
+	OpSetProperty                // name constant (u16)
+	OpGetIndex                   // -
+	OpSetIndex                   // -
+	OpGetSuper                   // name constant (u16)
+	OpEqual                      // -
+	OpNotEqual                   // -
+	OpGreater                    // -
+	OpGreaterEqual               // -
+	OpLess                       // -
+	OpLessEqual                  // -
+	OpAdd                        // -
+	OpSubtract                   // -
+	OpMultiply                   // -
+	OpDivide                     // -
+	OpNot                        // -
+	OpNegate                     // -
+	OpPositive                   // -
+	OpTemplate                   // part count (u8)
+	OpJump                       // forward offset (u16)
+	OpJumpIfFalse                // forward offset (u16), the condition stays on the stack
+	OpLoop                       // backward offset (u16)
+	OpCall                       // argument count (u8)
+	OpInvoke                     // name constant (u16), argument count (u8)
+	OpSuperInvoke                // name constant (u16), argument count (u8)
+	OpSuperCall                  // argument count (u8)
+	OpNew                        // argument count (u8)
+	OpClosure                    // function constant (u16), then (is local (u8), index (u8)) per upvalue
+	OpReturn                     // -
+	OpClass                      // name constant (u16)
+	OpInherit                    // -
+	OpMethod                     // name constant (u16)
+	OpFields                     // -
+)
+
+// opcodeNames are the names printed by the disassembler
+var opcodeNames = [...]string{
+	OpConstant:     "OP_CONSTANT",
+	OpNil:          "OP_NIL",
+	OpUndefined:    "OP_UNDEFINED",
+	OpTrue:         "OP_TRUE",
+	OpFalse:        "OP_FALSE",
+	OpPop:          "OP_POP",
+	OpDup:          "OP_DUP",
+	OpDup2:         "OP_DUP2",
+	OpGetLocal:     "OP_GET_LOCAL",
+	OpSetLocal:     "OP_SET_LOCAL",
+	OpGetGlobal:    "OP_GET_GLOBAL",
+	OpDefineGlobal: "OP_DEFINE_GLOBAL",
+	OpSetGlobal:    "OP_SET_GLOBAL",
+	OpGetUpvalue:   "OP_GET_UPVALUE",
+	OpSetUpvalue:   "OP_SET_UPVALUE",
+	OpCloseUpvalue: "OP_CLOSE_UPVALUE",
+	OpGetProperty:  "OP_GET_PROPERTY",
+	OpSetProperty:  "OP_SET_PROPERTY",
+	OpGetIndex:     "OP_GET_INDEX",
+	OpSetIndex:     "OP_SET_INDEX",
+	OpGetSuper:     "OP_GET_SUPER",
+	OpEqual:        "OP_EQUAL",
+	OpNotEqual:     "OP_NOT_EQUAL",
+	OpGreater:      "OP_GREATER",
+	OpGreaterEqual: "OP_GREATER_EQUAL",
+	OpLess:         "OP_LESS",
+	OpLessEqual:    "OP_LESS_EQUAL",
+	OpAdd:          "OP_ADD",
+	OpSubtract:     "OP_SUBTRACT",
+	OpMultiply:     "OP_MULTIPLY",
+	OpDivide:       "OP_DIVIDE",
+	OpNot:          "OP_NOT",
+	OpNegate:       "OP_NEGATE",
+	OpPositive:     "OP_POSITIVE",
+	OpTemplate:     "OP_TEMPLATE",
+	OpJump:         "OP_JUMP",
+	OpJumpIfFalse:  "OP_JUMP_IF_FALSE",
+	OpLoop:         "OP_LOOP",
+	OpCall:         "OP_CALL",
+	OpInvoke:       "OP_INVOKE",
+	OpSuperInvoke:  "OP_SUPER_INVOKE",
+	OpSuperCall:    "OP_SUPER_CALL",
+	OpNew:          "OP_NEW",
+	OpClosure:      "OP_CLOSURE",
+	OpReturn:       "OP_RETURN",
+	OpClass:        "OP_CLASS",
+	OpInherit:      "OP_INHERIT",
+	OpMethod:       "OP_METHOD",
+	OpFields:       "OP_FIELDS",
+}
+
+// String returns the name of the Opcode
+func (op Opcode) String() string {
+	if int(op) < len(opcodeNames) {
+		return opcodeNames[op]
+	}
+	return fmt.Sprintf("Opcode(%d)", byte(op))
+}
+
+// Chunk is a sequence of bytecode with its constant pool
+type Chunk struct {
+	Code      []byte
+	Constants []Value
+	Spans     []source.Span // source position of every byte of code
+}
+
+// write appends a byte of code
+func (c *Chunk) write(b byte, span source.Span) {
+	c.Code = append(c.Code, b)
+	c.Spans = append(c.Spans, span)
+}
+
+// addConstant adds a value to the constant pool and returns its index, equal constants are shared
+func (c *Chunk) addConstant(value Value) int {
+	for i, constant := range c.Constants {
+		if constant.kind == value.kind && constant.kind != KindObject && constant.Equal(value) {
+			return i
+		}
+		if s, ok := value.AsString(); ok {
+			if other, ok := constant.AsString(); ok && other == s {
+				return i
+			}
+		}
+	}
+	c.Constants = append(c.Constants, value)
+	return len(c.Constants) - 1
+}
+
+// readU16 reads a 16-bit operand
+func (c *Chunk) readU16(offset int) int {
+	return int(c.Code[offset])<<8 | int(c.Code[offset+1])
+}
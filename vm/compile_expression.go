@@ -0,0 +1,287 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/source"
+)
+
+func visitExpression(compiler *Compiler, expression ast.Expression) {
+	switch expression.NodeType() {
+	case ast.NodeAssignmentExpression:
+		visitAssignmentExpression(compiler, expression.(*ast.AssignmentExpression))
+	case ast.NodeBinaryExpression:
+		expression := expression.(*ast.BinaryExpression)
+		expression.Left.Accept(compiler)
+		expression.Right.Accept(compiler)
+		compiler.emitOp(binaryOpcodes[expression.Operator], expression.Span)
+	case ast.NodeUnaryExpression:
+		visitUnaryExpression(compiler, expression.(*ast.UnaryExpression))
+	case ast.NodeLogicalExpression:
+		visitLogicalExpression(compiler, expression.(*ast.LogicalExpression))
+	case ast.NodeBooleanLiteralExpression:
+		if expression.(*ast.BooleanLiteralExpression).Value {
+			compiler.emitOp(OpTrue, expression.GetSpan())
+		} else {
+			compiler.emitOp(OpFalse, expression.GetSpan())
+		}
+	case ast.NodeNilLiteralExpression:
+		compiler.emitOp(OpNil, expression.GetSpan())
+	case ast.NodeNumericLiteralExpression:
+		compiler.emitConstant(NumberValue(expression.(*ast.NumericLiteralExpression).FloatValue), expression.GetSpan())
+	case ast.NodeStringLiteralExpression:
+		compiler.emitConstant(StringValue(expression.(*ast.StringLiteralExpression).Value), expression.GetSpan())
+	case ast.NodeTemplateLiteralExpression:
+		visitTemplateLiteralExpression(compiler, expression.(*ast.TemplateLiteralExpression))
+	case ast.NodeIdentifierExpression:
+		expression := expression.(*ast.IdentifierExpression)
+		compiler.emitGet(expression.Name, expression.Span)
+	case ast.NodeMemberExpression:
+		visitMemberExpression(compiler, expression.(*ast.MemberExpression))
+	case ast.NodeCallExpression:
+		visitCallExpression(compiler, expression.(*ast.CallExpression))
+	case ast.NodeThisExpression:
+		if compiler.class == nil {
+			compiler.reportError(expression.GetSpan(), "'this' can only be used inside a class")
+			return
+		}
+		compiler.emitGet("this", expression.GetSpan())
+	case ast.NodeSuperExpression:
+		compiler.reportError(expression.GetSpan(), "'super' can only be used to call the superclass constructor or a superclass method")
+	case ast.NodeNewExpression:
+		expression := expression.(*ast.NewExpression)
+		expression.Callee.Accept(compiler)
+		argc := compileArguments(compiler, expression.Arguments, expression.Span)
+		compiler.emitOp(OpNew, expression.Span)
+		compiler.emitByte(argc, expression.Span)
+	case ast.NodeVariableExpression:
+		compiler.reportError(expression.GetSpan(), "Variable declaration is not an expression")
+	default:
+		panic(fmt.Errorf("unknown expression type: %T", expression))
+	}
+}
+
+// binaryOpcodes maps binary operators to their instructions
+var binaryOpcodes = map[ast.BinaryOperator]Opcode{
+	ast.OperatorAdd:                  OpAdd,
+	ast.OperatorSubtract:             OpSubtract,
+	ast.OperatorMultiply:             OpMultiply,
+	ast.OperatorDivide:               OpDivide,
+	ast.OperatorEqual:                OpEqual,
+	ast.OperatorNotEqual:             OpNotEqual,
+	ast.OperatorGreaterThan:          OpGreater,
+	ast.OperatorGreaterThanOrEqualTo: OpGreaterEqual,
+	ast.OperatorLessThan:             OpLess,
+	ast.OperatorLessThanOrEqualTo:    OpLessEqual,
+}
+
+// compoundOpcodes maps compound assignment operators to the instruction combining both sides
+var compoundOpcodes = map[ast.AssignmentOperator]Opcode{
+	ast.OperatorAssignAdd:      OpAdd,
+	ast.OperatorAssignSubtract: OpSubtract,
+	ast.OperatorAssignMultiply: OpMultiply,
+	ast.OperatorAssignDivide:   OpDivide,
+}
+
+func visitAssignmentExpression(compiler *Compiler, expression *ast.AssignmentExpression) {
+	compound := expression.Operator != ast.OperatorAssign
+
+	// compileValue pushes the assigned value, the current value is already on the stack for compound assignments
+	compileValue := func() {
+		expression.Right.Accept(compiler)
+		if compound {
+			compiler.emitOp(compoundOpcodes[expression.Operator], expression.Span)
+		}
+	}
+
+	switch target := expression.Left.(type) {
+	case *ast.IdentifierExpression:
+		if compound {
+			compiler.emitGet(target.Name, target.Span)
+		}
+		compileValue()
+		compiler.emitSet(target.Name, target.Span)
+
+	case *ast.MemberExpression:
+		if _, ok := target.Object.(*ast.SuperExpression); ok {
+			compiler.reportError(target.Span, "Cannot assign to a superclass method")
+			return
+		}
+
+		target.Object.Accept(compiler)
+		if target.Computed {
+			target.Property.Accept(compiler)
+			if compound {
+				compiler.emitOp(OpDup2, target.Span)
+				compiler.emitOp(OpGetIndex, target.Span)
+			}
+			compileValue()
+			compiler.emitOp(OpSetIndex, target.Span)
+			return
+		}
+
+		name := compiler.propertyConstant(target)
+		if compound {
+			compiler.emitOp(OpDup, target.Span)
+			compiler.emitOperand(OpGetProperty, name, target.Span)
+		}
+		compileValue()
+		compiler.emitOperand(OpSetProperty, name, target.Span)
+
+	default:
+		compiler.reportError(expression.Left.GetSpan(), "Invalid assignment target")
+	}
+}
+
+func visitUnaryExpression(compiler *Compiler, expression *ast.UnaryExpression) {
+	expression.Right.Accept(compiler)
+
+	switch expression.Operator {
+	case ast.OperatorNot:
+		compiler.emitOp(OpNot, expression.Span)
+	case ast.OperatorMinus:
+		compiler.emitOp(OpNegate, expression.Span)
+	case ast.OperatorPlus:
+		compiler.emitOp(OpPositive, expression.Span)
+	}
+}
+
+// visitLogicalExpression short-circuits, the left operand stays on the stack if it decides the result
+func visitLogicalExpression(compiler *Compiler, expression *ast.LogicalExpression) {
+	expression.Left.Accept(compiler)
+
+	if expression.Operator == ast.OperatorAnd {
+		endJump := compiler.emitJump(OpJumpIfFalse, expression.Left.GetSpan())
+		compiler.emitOp(OpPop, expression.Span)
+		expression.Right.Accept(compiler)
+		compiler.patchJump(endJump, expression.Span)
+		return
+	}
+
+	elseJump := compiler.emitJump(OpJumpIfFalse, expression.Left.GetSpan())
+	endJump := compiler.emitJump(OpJump, expression.Span)
+	compiler.patchJump(elseJump, expression.Span)
+	compiler.emitOp(OpPop, expression.Span)
+	expression.Right.Accept(compiler)
+	compiler.patchJump(endJump, expression.Span)
+}
+
+// visitTemplateLiteralExpression pushes the chunks and the embedded values and concatenates them
+func visitTemplateLiteralExpression(compiler *Compiler, expression *ast.TemplateLiteralExpression) {
+	parts := 0
+	for i, chunk := range expression.Chunks {
+		if chunk != "" {
+			compiler.emitConstant(StringValue(chunk), expression.Span)
+			parts++
+		}
+		if i < len(expression.Expressions) {
+			expression.Expressions[i].Accept(compiler)
+			parts++
+		}
+	}
+
+	if parts == 0 {
+		compiler.emitConstant(StringValue(""), expression.Span)
+		return
+	}
+	if parts > 255 {
+		compiler.reportLimit(expression.Span, "Too many parts in template literal")
+		return
+	}
+	compiler.emitOp(OpTemplate, expression.Span)
+	compiler.emitByte(byte(parts), expression.Span)
+}
+
+func visitMemberExpression(compiler *Compiler, expression *ast.MemberExpression) {
+	// super.method returns the superclass method bound to the current instance
+	if _, ok := expression.Object.(*ast.SuperExpression); ok && !expression.Computed {
+		if !compiler.checkSuper(expression.Object.GetSpan()) {
+			return
+		}
+		compiler.emitGet("this", expression.Span)
+		compiler.emitGet("super", expression.Object.GetSpan())
+		compiler.emitOperand(OpGetSuper, compiler.propertyConstant(expression), expression.Span)
+		return
+	}
+
+	expression.Object.Accept(compiler)
+	if expression.Computed {
+		expression.Property.Accept(compiler)
+		compiler.emitOp(OpGetIndex, expression.Span)
+		return
+	}
+	compiler.emitOperand(OpGetProperty, compiler.propertyConstant(expression), expression.Span)
+}
+
+// visitCallExpression calls methods directly without creating bound methods
+func visitCallExpression(compiler *Compiler, expression *ast.CallExpression) {
+	switch callee := expression.Callee.(type) {
+	case *ast.SuperExpression:
+		// super(...) runs the superclass constructor on the current instance
+		if !compiler.checkSuper(callee.Span) {
+			return
+		}
+		compiler.emitGet("this", callee.Span)
+		argc := compileArguments(compiler, expression.Arguments, expression.Span)
+		compiler.emitGet("super", callee.Span)
+		compiler.emitOp(OpSuperCall, expression.Span)
+		compiler.emitByte(argc, expression.Span)
+		return
+
+	case *ast.MemberExpression:
+		if callee.Computed {
+			break
+		}
+		name := compiler.propertyConstant(callee)
+
+		if _, ok := callee.Object.(*ast.SuperExpression); ok {
+			if !compiler.checkSuper(callee.Object.GetSpan()) {
+				return
+			}
+			compiler.emitGet("this", callee.Span)
+			argc := compileArguments(compiler, expression.Arguments, expression.Span)
+			compiler.emitGet("super", callee.Object.GetSpan())
+			compiler.emitOperand(OpSuperInvoke, name, expression.Span)
+			compiler.emitByte(argc, expression.Span)
+			return
+		}
+
+		callee.Object.Accept(compiler)
+		argc := compileArguments(compiler, expression.Arguments, expression.Span)
+		compiler.emitOperand(OpInvoke, name, expression.Span)
+		compiler.emitByte(argc, expression.Span)
+		return
+	}
+
+	expression.Callee.Accept(compiler)
+	argc := compileArguments(compiler, expression.Arguments, expression.Span)
+	compiler.emitOp(OpCall, expression.Span)
+	compiler.emitByte(argc, expression.Span)
+}
+
+// compileArguments pushes the arguments and returns their count
+func compileArguments(compiler *Compiler, arguments []ast.Expression, span source.Span) byte {
+	if len(arguments) > maxArguments {
+		compiler.reportLimit(span, "Cannot pass more than %d arguments", maxArguments)
+	}
+	for _, argument := range arguments {
+		argument.Accept(compiler)
+	}
+	return byte(len(arguments))
+}
+
+// propertyConstant returns the constant holding the name of a non computed property
+func (c *Compiler) propertyConstant(expression *ast.MemberExpression) int {
+	identifier := expression.Property.(*ast.IdentifierExpression)
+	return c.makeConstant(StringValue(identifier.Name), identifier.Span)
+}
+
+// checkSuper reports 'super' used outside of a class that extends another class
+func (c *Compiler) checkSuper(span source.Span) bool {
+	if c.class == nil || !c.class.hasSuper {
+		c.reportError(span, "'super' can only be used inside a class that extends another class")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,328 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/source"
+)
+
+func visitStatement(compiler *Compiler, statement ast.Statement) {
+	switch statement.NodeType() {
+	case ast.NodeProgramStatement:
+		compileStatementList(compiler, statement.(*ast.ProgramStatement).Body)
+	case ast.NodeBlockStatement:
+		compileBlock(compiler, statement.(*ast.BlockStatement))
+	case ast.NodeEmptyStatement:
+		// Nothing to compile
+	case ast.NodeErrorStatement:
+		compiler.reportError(statement.GetSpan(), "Cannot compile a statement with syntax errors")
+	case ast.NodeExpressionStatement:
+		statement := statement.(*ast.ExpressionStatement)
+		statement.Expression.Accept(compiler)
+		compiler.emitOp(OpPop, statement.Span)
+	case ast.NodeVariableDeclarationStatement:
+		visitVariableDeclarationStatement(compiler, statement.(*ast.VariableDeclarationStatement))
+	case ast.NodeIfStatement:
+		visitIfStatement(compiler, statement.(*ast.IfStatement))
+	case ast.NodeWhileStatement:
+		visitWhileStatement(compiler, statement.(*ast.WhileStatement))
+	case ast.NodeDoWhileStatement:
+		visitDoWhileStatement(compiler, statement.(*ast.DoWhileStatement))
+	case ast.NodeForStatement:
+		visitForStatement(compiler, statement.(*ast.ForStatement))
+	case ast.NodeFunctionDeclarationStatement, ast.NodeClassDeclarationStatement:
+		// Compiled when the enclosing statement list starts
+	case ast.NodeReturnStatement:
+		visitReturnStatement(compiler, statement.(*ast.ReturnStatement))
+	default:
+		panic(fmt.Errorf("unknown statement type: %T", statement))
+	}
+}
+
+func compileBlock(compiler *Compiler, block *ast.BlockStatement) {
+	compiler.beginScope()
+	compileStatementList(compiler, block.Body)
+	compiler.endScope(block.Span)
+}
+
+// compileStatementList compiles the statements in the current scope
+//
+// All names declared in the list exist when it starts, like the resolver allows. Local variables get their
+// slots up front, then functions and classes are created, so they can capture variables declared after them,
+// classes after the classes they extend. Until its declaration runs, reading a slot is a runtime error like
+// reading a global that was never defined
+func compileStatementList(compiler *Compiler, statements []ast.Statement) {
+	if !compiler.isGlobalScope() {
+		for _, statement := range statements {
+			for _, name := range declaredNames(statement) {
+				compiler.addLocal(name.Name, name.Span)
+				compiler.emitOperand(OpUndefined, compiler.makeConstant(StringValue(name.Name), name.Span), name.Span)
+			}
+		}
+	}
+
+	for _, statement := range statements {
+		if function, ok := statement.(*ast.FunctionDeclarationStatement); ok {
+			compileFunction(compiler, function, functionPlain)
+			compiler.emitDefine(function.Name.Name, function.Name.Span)
+		}
+	}
+	for _, class := range ast.ClassesInOrder(statements) {
+		compileClass(compiler, class)
+	}
+
+	for _, statement := range statements {
+		statement.Accept(compiler)
+	}
+}
+
+// declaredNames returns the names the statement declares in its scope
+func declaredNames(statement ast.Statement) []*ast.IdentifierExpression {
+	switch statement := statement.(type) {
+	case *ast.VariableDeclarationStatement:
+		names := make([]*ast.IdentifierExpression, len(statement.Variables))
+		for i, variable := range statement.Variables {
+			names[i] = variable.Identifier
+		}
+		return names
+	case *ast.FunctionDeclarationStatement:
+		return []*ast.IdentifierExpression{statement.Name}
+	case *ast.ClassDeclarationStatement:
+		return []*ast.IdentifierExpression{statement.Name}
+	default:
+		return nil
+	}
+}
+
+func visitVariableDeclarationStatement(compiler *Compiler, statement *ast.VariableDeclarationStatement) {
+	for _, variable := range statement.Variables {
+		if variable.Initializer != nil {
+			variable.Initializer.Accept(compiler)
+		} else {
+			emitZero(compiler, variable.TypeAnnotation, variable.Span)
+		}
+		compiler.emitDefine(variable.Identifier.Name, variable.Identifier.Span)
+	}
+}
+
+// emitZero pushes the value of a variable declared without an initializer, primitives start from their zero value
+// like in the wasm backend, other types from nil
+func emitZero(compiler *Compiler, typeAnnotation ast.Type, span source.Span) {
+	primitive, ok := typeAnnotation.(*ast.PrimitiveType)
+	if !ok {
+		compiler.emitOp(OpNil, span)
+		return
+	}
+	switch primitive.Kind {
+	case ast.NumberType:
+		compiler.emitConstant(NumberValue(0), span)
+	case ast.BooleanType:
+		compiler.emitOp(OpFalse, span)
+	default:
+		compiler.emitConstant(StringValue(""), span)
+	}
+}
+
+func visitIfStatement(compiler *Compiler, statement *ast.IfStatement) {
+	statement.Condition.Accept(compiler)
+	elseJump := compiler.emitJump(OpJumpIfFalse, statement.Condition.GetSpan())
+	compiler.emitOp(OpPop, statement.Span)
+	compileBlock(compiler, statement.Consequent)
+
+	endJump := compiler.emitJump(OpJump, statement.Span)
+	compiler.patchJump(elseJump, statement.Span)
+	compiler.emitOp(OpPop, statement.Span)
+	if statement.Alternative != nil {
+		compileBlock(compiler, statement.Alternative)
+	}
+	compiler.patchJump(endJump, statement.Span)
+}
+
+func visitWhileStatement(compiler *Compiler, statement *ast.WhileStatement) {
+	start := len(compiler.chunk().Code)
+	statement.Condition.Accept(compiler)
+	exitJump := compiler.emitJump(OpJumpIfFalse, statement.Condition.GetSpan())
+	compiler.emitOp(OpPop, statement.Span)
+
+	compileBlock(compiler, statement.Body)
+	compiler.emitLoop(start, statement.Span)
+
+	compiler.patchJump(exitJump, statement.Span)
+	compiler.emitOp(OpPop, statement.Span)
+}
+
+func visitDoWhileStatement(compiler *Compiler, statement *ast.DoWhileStatement) {
+	start := len(compiler.chunk().Code)
+	compileBlock(compiler, statement.Body)
+
+	statement.Condition.Accept(compiler)
+	exitJump := compiler.emitJump(OpJumpIfFalse, statement.Condition.GetSpan())
+	compiler.emitOp(OpPop, statement.Span)
+	compiler.emitLoop(start, statement.Span)
+
+	compiler.patchJump(exitJump, statement.Span)
+	compiler.emitOp(OpPop, statement.Span)
+}
+
+// visitForStatement compiles the loop in its own scope, the initializer variables are only visible in the loop
+func visitForStatement(compiler *Compiler, statement *ast.ForStatement) {
+	compiler.beginScope()
+	if statement.Initializer != nil {
+		compileStatementList(compiler, []ast.Statement{statement.Initializer})
+	}
+
+	start := len(compiler.chunk().Code)
+	exitJump := -1
+	if statement.Condition != nil {
+		statement.Condition.Accept(compiler)
+		exitJump = compiler.emitJump(OpJumpIfFalse, statement.Condition.GetSpan())
+		compiler.emitOp(OpPop, statement.Span)
+	}
+
+	compileBlock(compiler, statement.Body)
+	if statement.Increment != nil {
+		statement.Increment.Accept(compiler)
+		compiler.emitOp(OpPop, statement.Increment.GetSpan())
+	}
+	compiler.emitLoop(start, statement.Span)
+
+	if exitJump >= 0 {
+		compiler.patchJump(exitJump, statement.Span)
+		compiler.emitOp(OpPop, statement.Span)
+	}
+	compiler.endScope(statement.Span)
+}
+
+func visitReturnStatement(compiler *Compiler, statement *ast.ReturnStatement) {
+	if compiler.function.kind == functionScript {
+		compiler.reportError(statement.Span, "Cannot return from top-level code")
+		return
+	}
+
+	if statement.Argument == nil {
+		compiler.emitReturn(statement.Span)
+		return
+	}
+
+	statement.Argument.Accept(compiler)
+	if compiler.function.kind == functionConstructor {
+		// The constructor result is always the instance
+		compiler.emitOp(OpPop, statement.Span)
+		compiler.emitReturn(statement.Span)
+		return
+	}
+	compiler.emitOp(OpReturn, statement.Span)
+}
+
+// compileFunction compiles the declaration and pushes a closure of it
+func compileFunction(compiler *Compiler, declaration *ast.FunctionDeclarationStatement, kind functionKind) {
+	if len(declaration.Parameters) > maxArguments {
+		compiler.reportLimit(declaration.Name.Span, "Function '%s' has more than %d parameters", declaration.Name.Name, maxArguments)
+	}
+
+	function := &Function{Name: declaration.Name.Name, Arity: len(declaration.Parameters)}
+	compiler.beginFunction(function, kind)
+	compiler.beginScope()
+
+	// The body shares the scope of the parameters
+	for _, parameter := range declaration.Parameters {
+		name := ""
+		if identifier, ok := parameter.Name.(*ast.IdentifierExpression); ok {
+			name = identifier.Name
+		}
+		compiler.addLocal(name, parameter.Span)
+	}
+	compileStatementList(compiler, declaration.Body.Body)
+
+	function, upvalues := compiler.endFunction(declaration.Body.Span)
+	compileClosure(compiler, function, upvalues, declaration.Span)
+}
+
+// compileClosure pushes a closure of the compiled function with the variables it captures
+func compileClosure(compiler *Compiler, function *Function, upvalues []upvalueRef, span source.Span) {
+	compiler.emitOperand(OpClosure, compiler.makeConstant(ObjectValue(function), span), span)
+	for _, upvalue := range upvalues {
+		isLocal := byte(0)
+		if upvalue.isLocal {
+			isLocal = 1
+		}
+		compiler.emitByte(isLocal, span)
+		compiler.emitByte(byte(upvalue.index), span)
+	}
+}
+
+// compileClass creates the class with its methods and field initializer and assigns it to its variable
+//
+// The superclass is kept in a local named 'super' around the methods, so they capture it like any variable
+func compileClass(compiler *Compiler, statement *ast.ClassDeclarationStatement) {
+	name := statement.Name.Name
+	compiler.emitOperand(OpClass, compiler.makeConstant(StringValue(name), statement.Name.Span), statement.Name.Span)
+	compiler.emitDefine(name, statement.Name.Span)
+
+	class := &classCompiler{enclosing: compiler.class}
+	compiler.class = class
+	defer func() { compiler.class = class.enclosing }()
+
+	if statement.SuperClass != nil {
+		class.hasSuper = true
+		compiler.beginScope()
+		compiler.emitGet(statement.SuperClass.Name, statement.SuperClass.Span)
+		compiler.addLocal("super", statement.SuperClass.Span)
+		compiler.emitGet(name, statement.Name.Span)
+		compiler.emitOp(OpInherit, statement.SuperClass.Span)
+	}
+
+	compiler.emitGet(name, statement.Name.Span)
+
+	var fields []*ast.VariableExpression
+	for _, member := range statement.Body.Body {
+		switch member := member.(type) {
+		case *ast.VariableDeclarationStatement:
+			fields = append(fields, member.Variables...)
+		case *ast.FunctionDeclarationStatement:
+			kind := functionMethod
+			if member.Name.Name == "constructor" {
+				kind = functionConstructor
+			}
+			compileFunction(compiler, member, kind)
+			compiler.emitOperand(OpMethod, compiler.makeConstant(StringValue(member.Name.Name), member.Name.Span), member.Name.Span)
+		case *ast.EmptyStatement:
+			// Nothing to compile
+		default:
+			compiler.reportError(member.GetSpan(), "Only fields and methods can be declared in a class body")
+		}
+	}
+
+	if len(fields) > 0 {
+		compileFields(compiler, name, fields, statement.Body)
+	}
+
+	compiler.emitOp(OpPop, statement.Span)
+	if class.hasSuper {
+		compiler.endScope(statement.Span)
+	}
+}
+
+// compileFields compiles a method that assigns the initial values of the fields to 'this'
+func compileFields(compiler *Compiler, className string, fields []*ast.VariableExpression, body *ast.BlockStatement) {
+	compiler.beginFunction(&Function{Name: className + " fields"}, functionMethod)
+	compiler.beginScope()
+
+	for _, field := range fields {
+		compiler.emitOp(OpGetLocal, field.Span)
+		compiler.emitByte(0, field.Span)
+		if field.Initializer != nil {
+			field.Initializer.Accept(compiler)
+		} else {
+			emitZero(compiler, field.TypeAnnotation, field.Span)
+		}
+		name := compiler.makeConstant(StringValue(field.Identifier.Name), field.Identifier.Span)
+		compiler.emitOperand(OpSetProperty, name, field.Span)
+		compiler.emitOp(OpPop, field.Span)
+	}
+
+	function, upvalues := compiler.endFunction(body.Span)
+	compileClosure(compiler, function, upvalues, body.Span)
+	compiler.emitOp(OpFields, body.Span)
+}
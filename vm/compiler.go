@@ -0,0 +1,316 @@
+package vm
+
+import (
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/diagnostic"
+	"github.com/yoh0xff/senbonzakura/source"
+)
+
+// ScriptName is the name of the function that runs the top-level statements of a program
+const ScriptName = "<script>"
+
+const (
+	maxLocals    = 256
+	maxUpvalues  = 256
+	maxConstants = 1 << 16
+	maxJump      = 1<<16 - 1
+	maxArguments = 255
+)
+
+// functionKind tells how a function is called, it decides what slot 0 holds and what it returns
+type functionKind int
+
+const (
+	functionScript functionKind = iota
+	functionPlain
+	functionMethod      // slot 0 holds 'this'
+	functionConstructor // slot 0 holds 'this', which is also the result
+)
+
+// local is a variable in a stack slot of the function being compiled
+type local struct {
+	name     string
+	depth    int
+	captured bool // captured by a nested function, its upvalue is closed when the scope exits
+}
+
+// upvalueRef tells where a closure finds a captured variable when it is created
+type upvalueRef struct {
+	index   int
+	isLocal bool // slot of the enclosing function, otherwise upvalue of the enclosing function
+}
+
+// functionCompiler holds the state of a function being compiled
+type functionCompiler struct {
+	enclosing  *functionCompiler
+	function   *Function
+	kind       functionKind
+	locals     []local
+	upvalues   []upvalueRef
+	scopeDepth int
+}
+
+// classCompiler holds the state of a class being compiled
+type classCompiler struct {
+	enclosing *classCompiler
+	hasSuper  bool
+}
+
+// Compiler lowers a program to bytecode
+//
+// Top-level declarations become globals, all other variables live in stack slots of their function.
+// Variables captured by nested functions are reached through upvalues
+type Compiler struct {
+	globals     *Globals
+	function    *functionCompiler
+	class       *classCompiler
+	diagnostics []diagnostic.Diagnostic
+}
+
+// Compile lowers the program to the function that runs its top-level statements
+//
+// Global names are resolved to slots of the globals, the program should be resolved without errors.
+// Returns a nil function if the program cannot be compiled
+func Compile(program ast.Statement, globals *Globals) (*Function, []diagnostic.Diagnostic) {
+	compiler := &Compiler{globals: globals}
+
+	compiler.beginFunction(&Function{Name: ScriptName}, functionScript)
+	program.Accept(compiler)
+	function, _ := compiler.endFunction(program.GetSpan())
+
+	if diagnostic.HasErrors(compiler.diagnostics) {
+		return nil, compiler.diagnostics
+	}
+	return function, compiler.diagnostics
+}
+
+// VisitStatement implements the ast.Visitor interface
+func (c *Compiler) VisitStatement(statement ast.Statement) {
+	visitStatement(c, statement)
+}
+
+// VisitExpression implements the ast.Visitor interface
+func (c *Compiler) VisitExpression(expression ast.Expression) {
+	visitExpression(c, expression)
+}
+
+// beginFunction makes the function the target of the emitted code, slot 0 holds the callee or 'this'
+func (c *Compiler) beginFunction(function *Function, kind functionKind) {
+	c.function = &functionCompiler{
+		enclosing: c.function,
+		function:  function,
+		kind:      kind,
+	}
+
+	name := ""
+	if kind == functionMethod || kind == functionConstructor {
+		name = "this"
+	}
+	c.function.locals = append(c.function.locals, local{name: name})
+}
+
+// endFunction emits the implicit return and restores the enclosing function
+func (c *Compiler) endFunction(span source.Span) (*Function, []upvalueRef) {
+	c.emitReturn(span)
+
+	function := c.function
+	function.function.UpvalueCount = len(function.upvalues)
+	c.function = function.enclosing
+	return function.function, function.upvalues
+}
+
+// beginScope starts a block scope
+func (c *Compiler) beginScope() {
+	c.function.scopeDepth++
+}
+
+// endScope discards the locals of the innermost scope, captured locals are moved to their upvalues
+func (c *Compiler) endScope(span source.Span) {
+	function := c.function
+	function.scopeDepth--
+
+	for len(function.locals) > 0 && function.locals[len(function.locals)-1].depth > function.scopeDepth {
+		if function.locals[len(function.locals)-1].captured {
+			c.emitOp(OpCloseUpvalue, span)
+		} else {
+			c.emitOp(OpPop, span)
+		}
+		function.locals = function.locals[:len(function.locals)-1]
+	}
+}
+
+// addLocal declares a local in the current scope, its value is the next value pushed on the stack
+func (c *Compiler) addLocal(name string, span source.Span) int {
+	if len(c.function.locals) == maxLocals {
+		c.reportLimit(span, "Too many local variables in function '%s'", c.function.function.Name)
+		return 0
+	}
+	c.function.locals = append(c.function.locals, local{name: name, depth: c.function.scopeDepth})
+	return len(c.function.locals) - 1
+}
+
+// resolveLocal returns the slot of the innermost local with the name, -1 if there is none
+func resolveLocal(function *functionCompiler, name string) int {
+	for i := len(function.locals) - 1; i >= 0; i-- {
+		if function.locals[i].name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// resolveUpvalue returns the upvalue of a variable of an enclosing function, -1 if there is none
+func (c *Compiler) resolveUpvalue(function *functionCompiler, name string, span source.Span) int {
+	if function.enclosing == nil {
+		return -1
+	}
+
+	if slot := resolveLocal(function.enclosing, name); slot >= 0 {
+		function.enclosing.locals[slot].captured = true
+		return c.addUpvalue(function, slot, true, span)
+	}
+	if index := c.resolveUpvalue(function.enclosing, name, span); index >= 0 {
+		return c.addUpvalue(function, index, false, span)
+	}
+	return -1
+}
+
+// addUpvalue returns the index of the upvalue, functions capture every variable once
+func (c *Compiler) addUpvalue(function *functionCompiler, index int, isLocal bool, span source.Span) int {
+	for i, upvalue := range function.upvalues {
+		if upvalue.index == index && upvalue.isLocal == isLocal {
+			return i
+		}
+	}
+
+	if len(function.upvalues) == maxUpvalues {
+		c.reportLimit(span, "Too many captured variables in function '%s'", function.function.Name)
+		return 0
+	}
+	function.upvalues = append(function.upvalues, upvalueRef{index: index, isLocal: isLocal})
+	return len(function.upvalues) - 1
+}
+
+// isGlobalScope tells if declarations of the current scope become globals
+func (c *Compiler) isGlobalScope() bool {
+	return c.function.kind == functionScript && c.function.scopeDepth == 0
+}
+
+// emitGet pushes the value of the variable
+func (c *Compiler) emitGet(name string, span source.Span) {
+	if slot := resolveLocal(c.function, name); slot >= 0 {
+		c.emitOp(OpGetLocal, span)
+		c.emitByte(byte(slot), span)
+	} else if index := c.resolveUpvalue(c.function, name, span); index >= 0 {
+		c.emitOp(OpGetUpvalue, span)
+		c.emitByte(byte(index), span)
+	} else {
+		c.emitOperand(OpGetGlobal, c.globals.slot(name), span)
+	}
+}
+
+// emitSet assigns the value on top of the stack to the variable, the value stays on the stack
+func (c *Compiler) emitSet(name string, span source.Span) {
+	if slot := resolveLocal(c.function, name); slot >= 0 {
+		c.emitOp(OpSetLocal, span)
+		c.emitByte(byte(slot), span)
+	} else if index := c.resolveUpvalue(c.function, name, span); index >= 0 {
+		c.emitOp(OpSetUpvalue, span)
+		c.emitByte(byte(index), span)
+	} else {
+		c.emitOperand(OpSetGlobal, c.globals.slot(name), span)
+	}
+}
+
+// emitDefine initializes a declared variable with the value on top of the stack and pops it
+func (c *Compiler) emitDefine(name string, span source.Span) {
+	if c.isGlobalScope() {
+		c.emitOperand(OpDefineGlobal, c.globals.slot(name), span)
+		return
+	}
+	c.emitSet(name, span)
+	c.emitOp(OpPop, span)
+}
+
+// chunk returns the chunk of the function being compiled
+func (c *Compiler) chunk() *Chunk {
+	return &c.function.function.Chunk
+}
+
+// emitByte appends a byte to the current chunk
+func (c *Compiler) emitByte(b byte, span source.Span) {
+	c.chunk().write(b, span)
+}
+
+// emitOp appends an instruction without operands
+func (c *Compiler) emitOp(op Opcode, span source.Span) {
+	c.chunk().write(byte(op), span)
+}
+
+// emitOperand appends an instruction with a 16-bit operand
+func (c *Compiler) emitOperand(op Opcode, operand int, span source.Span) {
+	c.emitOp(op, span)
+	c.emitByte(byte(operand>>8), span)
+	c.emitByte(byte(operand), span)
+}
+
+// emitConstant pushes the constant
+func (c *Compiler) emitConstant(value Value, span source.Span) {
+	c.emitOperand(OpConstant, c.makeConstant(value, span), span)
+}
+
+// makeConstant adds the value to the constant pool of the current chunk
+func (c *Compiler) makeConstant(value Value, span source.Span) int {
+	index := c.chunk().addConstant(value)
+	if index >= maxConstants {
+		c.reportLimit(span, "Too many constants in function '%s'", c.function.function.Name)
+		return 0
+	}
+	return index
+}
+
+// emitReturn emits the implicit return, constructors return 'this' and other functions nil
+func (c *Compiler) emitReturn(span source.Span) {
+	if c.function.kind == functionConstructor {
+		c.emitOp(OpGetLocal, span)
+		c.emitByte(0, span)
+	} else {
+		c.emitOp(OpNil, span)
+	}
+	c.emitOp(OpReturn, span)
+}
+
+// emitJump emits a forward jump and returns the position of its operand to patch
+func (c *Compiler) emitJump(op Opcode, span source.Span) int {
+	c.emitOperand(op, 0xffff, span)
+	return len(c.chunk().Code) - 2
+}
+
+// patchJump makes the jump land on the next emitted instruction
+func (c *Compiler) patchJump(position int, span source.Span) {
+	jump := len(c.chunk().Code) - position - 2
+	if jump > maxJump {
+		c.reportLimit(span, "Too much code to jump over")
+	}
+	c.chunk().Code[position] = byte(jump >> 8)
+	c.chunk().Code[position+1] = byte(jump)
+}
+
+// emitLoop emits a backward jump to the start of the loop
+func (c *Compiler) emitLoop(start int, span source.Span) {
+	jump := len(c.chunk().Code) - start + 3
+	if jump > maxJump {
+		c.reportLimit(span, "Loop body is too large")
+	}
+	c.emitOperand(OpLoop, jump, span)
+}
+
+// reportError records an unsupported feature diagnostic
+func (c *Compiler) reportError(span source.Span, format string, args ...any) {
+	c.diagnostics = append(c.diagnostics, diagnostic.NewError(diagnostic.CodeUnsupportedFeature, span, format, args...))
+}
+
+// reportLimit records a diagnostic for code that exceeds the limits of the bytecode
+func (c *Compiler) reportLimit(span source.Span, format string, args ...any) {
+	c.diagnostics = append(c.diagnostics, diagnostic.NewError(diagnostic.CodeCompilerLimit, span, format, args...))
+}
@@ -0,0 +1,91 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Disassemble returns a listing of the bytecode of the function and of the functions it creates
+//
+// Every line shows the offset, the source line and column when it changes, the instruction and its operands
+func Disassemble(function *Function) string {
+	var builder strings.Builder
+	disassembleFunction(&builder, function)
+	return builder.String()
+}
+
+func disassembleFunction(builder *strings.Builder, function *Function) {
+	fmt.Fprintf(builder, "== %s ==\n", function.Name)
+
+	chunk := &function.Chunk
+	for offset := 0; offset < len(chunk.Code); {
+		offset = DisassembleInstruction(builder, chunk, offset)
+	}
+
+	for _, constant := range chunk.Constants {
+		if nested, ok := constant.object.(*Function); ok {
+			builder.WriteString("\n")
+			disassembleFunction(builder, nested)
+		}
+	}
+}
+
+// DisassembleInstruction writes the instruction at the offset and returns the offset of the next one
+func DisassembleInstruction(builder *strings.Builder, chunk *Chunk, offset int) int {
+	fmt.Fprintf(builder, "%04d ", offset)
+	span := chunk.Spans[offset]
+	if offset > 0 && span.Line == chunk.Spans[offset-1].Line && span.Column == chunk.Spans[offset-1].Column {
+		builder.WriteString("      | ")
+	} else {
+		fmt.Fprintf(builder, "%7s ", fmt.Sprintf("%d:%d", span.Line, span.Column))
+	}
+
+	op := Opcode(chunk.Code[offset])
+	switch op {
+	case OpConstant, OpUndefined, OpGetProperty, OpSetProperty, OpGetSuper, OpClass, OpMethod:
+		index := chunk.readU16(offset + 1)
+		fmt.Fprintf(builder, "%-18s %4d %s\n", op, index, quoteConstant(chunk.Constants[index]))
+		return offset + 3
+	case OpGetGlobal, OpDefineGlobal, OpSetGlobal:
+		fmt.Fprintf(builder, "%-18s %4d\n", op, chunk.readU16(offset+1))
+		return offset + 3
+	case OpGetLocal, OpSetLocal, OpGetUpvalue, OpSetUpvalue, OpTemplate, OpCall, OpSuperCall, OpNew:
+		fmt.Fprintf(builder, "%-18s %4d\n", op, chunk.Code[offset+1])
+		return offset + 2
+	case OpJump, OpJumpIfFalse:
+		fmt.Fprintf(builder, "%-18s %4d -> %d\n", op, offset, offset+3+chunk.readU16(offset+1))
+		return offset + 3
+	case OpLoop:
+		fmt.Fprintf(builder, "%-18s %4d -> %d\n", op, offset, offset+3-chunk.readU16(offset+1))
+		return offset + 3
+	case OpInvoke, OpSuperInvoke:
+		index := chunk.readU16(offset + 1)
+		fmt.Fprintf(builder, "%-18s (%d args) %4d %s\n", op, chunk.Code[offset+3], index, quoteConstant(chunk.Constants[index]))
+		return offset + 4
+	case OpClosure:
+		index := chunk.readU16(offset + 1)
+		function := chunk.Constants[index].object.(*Function)
+		fmt.Fprintf(builder, "%-18s %4d %s\n", op, index, chunk.Constants[index])
+		offset += 3
+		for range function.UpvalueCount {
+			kind := "upvalue"
+			if chunk.Code[offset] == 1 {
+				kind = "local"
+			}
+			fmt.Fprintf(builder, "%04d      |                      %s %d\n", offset, kind, chunk.Code[offset+1])
+			offset += 2
+		}
+		return offset
+	default:
+		fmt.Fprintf(builder, "%s\n", op)
+		return offset + 1
+	}
+}
+
+// quoteConstant prints strings quoted, so they can be told apart from numbers
+func quoteConstant(value Value) string {
+	if s, ok := value.AsString(); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return value.String()
+}
@@ -0,0 +1,78 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors of programs stopped by a limit, runtime errors wrap them, so they can be tested with errors.Is
+var (
+	ErrFuelExhausted = errors.New("fuel exhausted")
+	ErrStackOverflow = errors.New("stack overflow")
+	ErrOutOfMemory   = errors.New("out of memory")
+)
+
+// checkInterval is the number of instructions between two checks of the context
+const checkInterval = 1024
+
+// Approximate sizes in bytes of the allocated objects, counted against Config.MaxHeap
+const (
+	sizeString      = 16
+	sizeInstance    = 64
+	sizeField       = 48
+	sizeClosure     = 48
+	sizeUpvalue     = 56
+	sizeBoundMethod = 48
+	sizeClass       = 96
+)
+
+// limitError creates an error for a limit, the span is filled in by the instruction that hit it
+func limitError(err error, format string, args ...any) *RuntimeError {
+	return &RuntimeError{Message: fmt.Sprintf(format, args...), Err: err}
+}
+
+// startLimits resets the budgets for a run that starts from an empty stack
+func (vm *VM) startLimits() {
+	vm.steps = 0
+	vm.interval = 0
+	vm.ticks = 0
+	vm.heap = 0
+}
+
+// checkpoint accounts the instructions executed since the last checkpoint, checks the fuel and the context
+// and sets the number of instructions that can run until the next checkpoint
+func (vm *VM) checkpoint() error {
+	vm.steps += int64(vm.interval)
+	if vm.config.Fuel > 0 && vm.steps >= vm.config.Fuel {
+		return limitError(ErrFuelExhausted, "Fuel exhausted after %d instructions", vm.steps)
+	}
+
+	select {
+	case <-vm.ctx.Done():
+		return limitError(vm.ctx.Err(), "Execution stopped: %s", vm.ctx.Err())
+	default:
+	}
+
+	vm.interval = checkInterval
+	if vm.config.Fuel > 0 {
+		vm.interval = int(min(int64(checkInterval), vm.config.Fuel-vm.steps))
+	}
+	vm.ticks = vm.interval
+	return nil
+}
+
+// allocate counts the bytes against the heap limit
+//
+// Memory reclaimed by the garbage collector is not returned, the limit bounds what a run allocates in total
+func (vm *VM) allocate(bytes int) error {
+	vm.heap += int64(bytes)
+	if vm.config.MaxHeap > 0 && vm.heap > vm.config.MaxHeap {
+		return limitError(ErrOutOfMemory, "Out of memory, more than %d bytes allocated", vm.config.MaxHeap)
+	}
+	return nil
+}
+
+// Steps returns the number of instructions executed by the last run or call
+func (vm *VM) Steps() int64 {
+	return vm.steps + int64(vm.interval-vm.ticks)
+}
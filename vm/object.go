@@ -0,0 +1,105 @@
+package vm
+
+// Function is a compiled function with its bytecode
+type Function struct {
+	Name         string
+	Arity        int
+	UpvalueCount int
+	Chunk        Chunk
+}
+
+// Closure is a function with the variables it captured from enclosing functions
+type Closure struct {
+	Function *Function
+	Upvalues []*Upvalue
+}
+
+// Upvalue is a captured variable
+//
+// While the variable is on the stack the upvalue refers to its slot, it holds the value once the scope exits
+type Upvalue struct {
+	slot   int
+	closed Value
+	open   bool
+	next   *Upvalue // next open upvalue, lower in the stack
+}
+
+// Native is a function implemented by the host
+type Native struct {
+	Name  string
+	Arity int // number of parameters, -1 for any number of arguments
+	Fn    func(args []Value) (Value, error)
+}
+
+// Class is a class with its methods, inherited methods are copied when the class is created
+type Class struct {
+	Name        string
+	Super       *Class
+	Methods     map[string]*Closure
+	Constructor *Closure // can be nil, inherited from the superclass if not declared
+	Fields      *Closure // initializes the fields declared in the class body, can be nil
+}
+
+// Instance is an instance of a class
+type Instance struct {
+	Class  *Class
+	Fields map[string]Value
+}
+
+// BoundMethod is a method with the instance it was accessed on
+type BoundMethod struct {
+	Receiver Value
+	Method   *Closure
+}
+
+// Globals holds the global variables, the compiler resolves global names to slots
+//
+// The same globals can be shared by several compiled programs, hosts define natives in them
+type Globals struct {
+	slots  map[string]int
+	names  []string
+	values []Value
+}
+
+// NewGlobals creates an empty global table
+func NewGlobals() *Globals {
+	return &Globals{slots: map[string]int{}}
+}
+
+// slot returns the slot of the global, a new undefined slot is created for unknown names
+func (g *Globals) slot(name string) int {
+	if slot, ok := g.slots[name]; ok {
+		return slot
+	}
+
+	slot := len(g.values)
+	g.slots[name] = slot
+	g.names = append(g.names, name)
+	g.values = append(g.values, Value{kind: kindUndefined})
+	return slot
+}
+
+// Get returns the value of a defined global
+func (g *Globals) Get(name string) (Value, bool) {
+	slot, ok := g.slots[name]
+	if !ok || g.values[slot].kind == kindUndefined {
+		return Value{}, false
+	}
+	return g.values[slot], true
+}
+
+// Set defines or updates a global
+func (g *Globals) Set(name string, value Value) {
+	g.values[g.slot(name)] = value
+}
+
+// Names returns the names of the defined globals in order of creation
+func (g *Globals) Names() []string {
+	var names []string
+	for slot, name := range g.names {
+		if g.values[slot].kind != kindUndefined {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,163 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// ValueKind represents the kind of a runtime value
+type ValueKind uint8
+
+const (
+	KindNil ValueKind = iota
+	KindBool
+	KindNumber
+	KindObject    // strings, functions, classes, instances
+	kindUndefined // global slot that was never assigned, or local slot before its declaration, holding the name
+)
+
+// Value is a runtime value
+//
+// Numbers and booleans are stored inline, so arithmetic doesn't allocate.
+// Objects are strings, *Closure, *Native, *BoundMethod, *Class and *Instance
+type Value struct {
+	kind   ValueKind
+	number float64 // value of numbers, 1 or 0 for booleans
+	object any
+}
+
+// NilValue returns the nil value
+func NilValue() Value {
+	return Value{}
+}
+
+// BoolValue returns a boolean value
+func BoolValue(b bool) Value {
+	if b {
+		return Value{kind: KindBool, number: 1}
+	}
+	return Value{kind: KindBool}
+}
+
+// NumberValue returns a number value
+func NumberValue(n float64) Value {
+	return Value{kind: KindNumber, number: n}
+}
+
+// StringValue returns a string value
+func StringValue(s string) Value {
+	return Value{kind: KindObject, object: s}
+}
+
+// ObjectValue returns a value holding an object
+func ObjectValue(object any) Value {
+	return Value{kind: KindObject, object: object}
+}
+
+// Kind returns the kind of the value
+func (v Value) Kind() ValueKind {
+	return v.kind
+}
+
+// IsNil tells if the value is nil
+func (v Value) IsNil() bool {
+	return v.kind == KindNil
+}
+
+// AsBool returns the boolean, false for other kinds
+func (v Value) AsBool() bool {
+	return v.kind == KindBool && v.number != 0
+}
+
+// AsNumber returns the number, 0 for other kinds
+func (v Value) AsNumber() float64 {
+	if v.kind != KindNumber {
+		return 0
+	}
+	return v.number
+}
+
+// AsString returns the string if the value is a string
+func (v Value) AsString() (string, bool) {
+	s, ok := v.object.(string)
+	return s, ok
+}
+
+// AsObject returns the object, nil for other kinds
+func (v Value) AsObject() any {
+	return v.object
+}
+
+// String returns the text of the value as printed by scripts
+func (v Value) String() string {
+	switch v.kind {
+	case KindNil:
+		return "nil"
+	case KindBool:
+		return strconv.FormatBool(v.AsBool())
+	case KindNumber:
+		return formatNumber(v.number)
+	case kindUndefined:
+		return "<undefined>"
+	}
+
+	switch object := v.object.(type) {
+	case string:
+		return object
+	case *Closure:
+		return fmt.Sprintf("<fn %s>", object.Function.Name)
+	case *BoundMethod:
+		return fmt.Sprintf("<fn %s>", object.Method.Function.Name)
+	case *Native:
+		return fmt.Sprintf("<native fn %s>", object.Name)
+	case *Class:
+		return fmt.Sprintf("<class %s>", object.Name)
+	case *Instance:
+		return fmt.Sprintf("<%s instance>", object.Class.Name)
+	case *Function:
+		return fmt.Sprintf("<fn %s>", object.Name)
+	default:
+		return fmt.Sprintf("%v", object)
+	}
+}
+
+// TypeName returns the name of the type of the value for error messages
+func (v Value) TypeName() string {
+	switch v.kind {
+	case KindNil:
+		return "nil"
+	case KindBool:
+		return "boolean"
+	case KindNumber:
+		return "number"
+	case kindUndefined:
+		return "undefined"
+	}
+
+	switch object := v.object.(type) {
+	case string:
+		return "string"
+	case *Closure, *BoundMethod, *Native, *Function:
+		return "function"
+	case *Class:
+		return "class"
+	case *Instance:
+		return object.Class.Name
+	default:
+		return fmt.Sprintf("%T", object)
+	}
+}
+
+// Equal tells if both values are equal, strings compare by content and other objects by identity
+func (v Value) Equal(other Value) bool {
+	return v.kind == other.kind && v.number == other.number && v.object == other.object
+}
+
+// formatNumber prints integers without a fraction and other numbers in the shortest form
+func formatNumber(value float64) string {
+	if value == math.Trunc(value) && math.Abs(value) < 1e15 {
+		return strconv.FormatFloat(value, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
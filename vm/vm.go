@@ -0,0 +1,798 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yoh0xff/senbonzakura/source"
+)
+
+// RuntimeError is an error raised while a program runs
+type RuntimeError struct {
+	Message string
+	Span    source.Span // position of the instruction that failed
+	Err     error       // limit or context error that stopped the program, nil for other errors
+}
+
+// Error implements the error interface
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("runtime error at %s: %s", e.Span, e.Message)
+}
+
+// Unwrap returns the limit or context error that stopped the program
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+// Config defines the options of the virtual machine
+//
+// Limits apply to every run and every call from the host, calls made by native functions share the budgets
+// of the run that called them
+type Config struct {
+	Stdout    io.Writer // output of the print builtin
+	MaxFrames int       // maximum number of nested calls
+	Fuel      int64     // maximum number of executed instructions, 0 for no limit
+	MaxHeap   int64     // maximum number of bytes allocated by strings and objects, 0 for no limit
+}
+
+// frame is a function call being executed
+type frame struct {
+	closure *Closure
+	ip      int // offset of the next instruction
+	base    int // stack slot of the callee, slot 0 of the function
+}
+
+// VM is a stack-based virtual machine running compiled programs
+//
+// Globals persist between runs, so the same machine can run several programs one after another
+type VM struct {
+	config       Config
+	globals      *Globals
+	stack        []Value
+	sp           int // index of the next free stack slot
+	frames       []frame
+	openUpvalues *Upvalue // upvalues still referring to stack slots, sorted by slot from the top
+
+	ctx      context.Context
+	steps    int64 // instructions executed until the last checkpoint
+	interval int   // instructions allowed between the last checkpoint and the next one
+	ticks    int   // instructions left until the next checkpoint
+	heap     int64 // bytes allocated by the run
+}
+
+// NewVM creates a new virtual machine with default configuration
+func NewVM() *VM {
+	return NewVMWithConfig(Config{
+		Stdout:    os.Stdout,
+		MaxFrames: 1000,
+	})
+}
+
+// NewVMWithConfig creates a new virtual machine with the given configuration
+func NewVMWithConfig(config Config) *VM {
+	if config.Stdout == nil {
+		config.Stdout = io.Discard
+	}
+	if config.MaxFrames <= 0 {
+		config.MaxFrames = 1000
+	}
+
+	vm := &VM{
+		config:  config,
+		globals: NewGlobals(),
+		stack:   make([]Value, 256),
+		frames:  make([]frame, 0, config.MaxFrames),
+		ctx:     context.Background(),
+	}
+
+	vm.Define("print", ObjectValue(&Native{
+		Name:  "print",
+		Arity: -1,
+		Fn: func(args []Value) (Value, error) {
+			parts := make([]string, len(args))
+			for i, arg := range args {
+				parts[i] = arg.String()
+			}
+			_, err := fmt.Fprintln(vm.config.Stdout, strings.Join(parts, " "))
+			return NilValue(), err
+		},
+	}))
+
+	return vm
+}
+
+// Globals returns the globals of the machine, programs run by it must be compiled with them
+func (vm *VM) Globals() *Globals {
+	return vm.globals
+}
+
+// Define creates or replaces a global variable, hosts use it to expose native functions
+func (vm *VM) Define(name string, value Value) {
+	vm.globals.Set(name, value)
+}
+
+// Run executes a compiled program, errors are *RuntimeError
+func (vm *VM) Run(function *Function) error {
+	return vm.RunContext(context.Background(), function)
+}
+
+// RunContext executes a compiled program until it completes or the context is done
+func (vm *VM) RunContext(ctx context.Context, function *Function) error {
+	vm.reset()
+	vm.ctx = ctx
+	vm.startLimits()
+
+	closure := &Closure{Function: function}
+	vm.push(ObjectValue(closure))
+	if err := vm.call(closure, 0); err != nil {
+		vm.reset()
+		return err
+	}
+	if err := vm.run(0); err != nil {
+		vm.reset()
+		return err
+	}
+
+	vm.pop()
+	return nil
+}
+
+// Call calls a script or native function with the arguments
+func (vm *VM) Call(callee Value, args ...Value) (Value, error) {
+	return vm.CallContext(context.Background(), callee, args...)
+}
+
+// CallContext calls a script or native function until it completes or the context is done
+//
+// Native functions can call back into the machine, nested calls keep the context and budgets of the outer run.
+// Errors raised before any script code runs, like a wrong number of arguments, have no position and are returned
+// as they are
+func (vm *VM) CallContext(ctx context.Context, callee Value, args ...Value) (Value, error) {
+	sp, depth := vm.sp, len(vm.frames)
+	if depth == 0 {
+		vm.ctx = ctx
+		vm.startLimits()
+	}
+	unwind := func(err error) (Value, error) {
+		vm.closeUpvalues(sp)
+		vm.sp, vm.frames = sp, vm.frames[:depth]
+		return NilValue(), err
+	}
+
+	vm.push(callee)
+	for _, arg := range args {
+		vm.push(arg)
+	}
+	if err := vm.callValue(callee, len(args)); err != nil {
+		return unwind(err)
+	}
+	if len(vm.frames) > depth {
+		if err := vm.run(depth); err != nil {
+			return unwind(err)
+		}
+	}
+	return vm.pop(), nil
+}
+
+// reset discards the stack and all calls
+func (vm *VM) reset() {
+	vm.sp = 0
+	vm.frames = vm.frames[:0]
+	vm.openUpvalues = nil
+}
+
+// push pushes the value on the stack, the stack grows as needed
+func (vm *VM) push(value Value) {
+	if vm.sp == len(vm.stack) {
+		vm.stack = append(vm.stack, make([]Value, len(vm.stack))...)
+	}
+	vm.stack[vm.sp] = value
+	vm.sp++
+}
+
+// pop removes the value on top of the stack
+func (vm *VM) pop() Value {
+	vm.sp--
+	return vm.stack[vm.sp]
+}
+
+// peek returns the value the distance below the top of the stack
+func (vm *VM) peek(distance int) Value {
+	return vm.stack[vm.sp-1-distance]
+}
+
+// run executes instructions until the frame count drops back to depth
+func (vm *VM) run(depth int) error {
+	frame, chunk, code := vm.current()
+
+	for {
+		if vm.ticks == 0 {
+			if err := vm.checkpoint(); err != nil {
+				return vm.withSpan(err, chunk.Spans[frame.ip])
+			}
+		}
+		vm.ticks--
+
+		op := Opcode(code[frame.ip])
+		frame.ip++
+
+		switch op {
+		case OpConstant:
+			vm.push(chunk.Constants[vm.readU16(frame, code)])
+		case OpNil:
+			vm.push(Value{})
+		case OpUndefined:
+			vm.push(Value{kind: kindUndefined, object: chunk.Constants[vm.readU16(frame, code)].object})
+		case OpTrue:
+			vm.push(BoolValue(true))
+		case OpFalse:
+			vm.push(BoolValue(false))
+		case OpPop:
+			vm.sp--
+		case OpDup:
+			vm.push(vm.peek(0))
+		case OpDup2:
+			vm.push(vm.peek(1))
+			vm.push(vm.peek(1))
+
+		case OpGetLocal:
+			slot := int(code[frame.ip])
+			frame.ip++
+			value := vm.stack[frame.base+slot]
+			if value.kind == kindUndefined {
+				return vm.fail(frame, "Variable '%s' used before initialization", value.object)
+			}
+			vm.push(value)
+		case OpSetLocal:
+			slot := int(code[frame.ip])
+			frame.ip++
+			vm.stack[frame.base+slot] = vm.peek(0)
+		case OpGetGlobal:
+			slot := vm.readU16(frame, code)
+			value := vm.globals.values[slot]
+			if value.kind == kindUndefined {
+				return vm.fail(frame, "Undefined variable '%s'", vm.globals.names[slot])
+			}
+			vm.push(value)
+		case OpDefineGlobal:
+			vm.globals.values[vm.readU16(frame, code)] = vm.pop()
+		case OpSetGlobal:
+			slot := vm.readU16(frame, code)
+			if vm.globals.values[slot].kind == kindUndefined {
+				return vm.fail(frame, "Undefined variable '%s'", vm.globals.names[slot])
+			}
+			vm.globals.values[slot] = vm.peek(0)
+		case OpGetUpvalue:
+			upvalue := frame.closure.Upvalues[code[frame.ip]]
+			frame.ip++
+			value := upvalue.closed
+			if upvalue.open {
+				value = vm.stack[upvalue.slot]
+			}
+			if value.kind == kindUndefined {
+				return vm.fail(frame, "Variable '%s' used before initialization", value.object)
+			}
+			vm.push(value)
+		case OpSetUpvalue:
+			upvalue := frame.closure.Upvalues[code[frame.ip]]
+			frame.ip++
+			if upvalue.open {
+				vm.stack[upvalue.slot] = vm.peek(0)
+			} else {
+				upvalue.closed = vm.peek(0)
+			}
+		case OpCloseUpvalue:
+			vm.closeUpvalues(vm.sp - 1)
+			vm.sp--
+
+		case OpGetProperty:
+			name, _ := chunk.Constants[vm.readU16(frame, code)].AsString()
+			value, err := vm.getProperty(vm.pop(), name, false)
+			if err != nil {
+				return vm.withSpan(err, chunk.Spans[frame.ip-1])
+			}
+			vm.push(value)
+		case OpSetProperty:
+			name, _ := chunk.Constants[vm.readU16(frame, code)].AsString()
+			value := vm.pop()
+			if err := vm.setProperty(vm.pop(), name, value); err != nil {
+				return vm.withSpan(err, chunk.Spans[frame.ip-1])
+			}
+			vm.push(value)
+		case OpGetIndex:
+			key := vm.pop()
+			name, ok := key.AsString()
+			if !ok {
+				return vm.fail(frame, "Property name must be a string, got %s", key.TypeName())
+			}
+			value, err := vm.getProperty(vm.pop(), name, true)
+			if err != nil {
+				return vm.withSpan(err, chunk.Spans[frame.ip-1])
+			}
+			vm.push(value)
+		case OpSetIndex:
+			value := vm.pop()
+			key := vm.pop()
+			name, ok := key.AsString()
+			if !ok {
+				return vm.fail(frame, "Property name must be a string, got %s", key.TypeName())
+			}
+			if err := vm.setProperty(vm.pop(), name, value); err != nil {
+				return vm.withSpan(err, chunk.Spans[frame.ip-1])
+			}
+			vm.push(value)
+		case OpGetSuper:
+			name, _ := chunk.Constants[vm.readU16(frame, code)].AsString()
+			superClass := vm.pop().object.(*Class)
+			method, ok := superClass.Methods[name]
+			if !ok {
+				return vm.fail(frame, "Superclass '%s' has no method '%s'", superClass.Name, name)
+			}
+			if err := vm.allocate(sizeBoundMethod); err != nil {
+				return vm.withSpan(err, chunk.Spans[frame.ip-1])
+			}
+			vm.push(ObjectValue(&BoundMethod{Receiver: vm.pop(), Method: method}))
+
+		case OpEqual:
+			right := vm.pop()
+			vm.push(BoolValue(vm.pop().Equal(right)))
+		case OpNotEqual:
+			right := vm.pop()
+			vm.push(BoolValue(!vm.pop().Equal(right)))
+		case OpGreater, OpGreaterEqual, OpLess, OpLessEqual, OpAdd, OpSubtract, OpMultiply, OpDivide:
+			right := vm.stack[vm.sp-1]
+			left := vm.stack[vm.sp-2]
+			if left.kind == KindNumber && right.kind == KindNumber {
+				vm.sp--
+				vm.stack[vm.sp-1] = numberOperation(op, left.number, right.number)
+				continue
+			}
+			// Charge the concatenation before building it
+			l, leftString := left.AsString()
+			r, rightString := right.AsString()
+			if op == OpAdd && leftString && rightString {
+				if err := vm.allocate(sizeString + len(l) + len(r)); err != nil {
+					return vm.withSpan(err, chunk.Spans[frame.ip-1])
+				}
+			}
+			value, ok := stringOperation(op, left, right)
+			if !ok {
+				return vm.fail(frame, "Cannot apply operator %s to %s and %s", operatorSymbols[op], left.TypeName(), right.TypeName())
+			}
+			vm.sp--
+			vm.stack[vm.sp-1] = value
+		case OpNot:
+			operand := vm.peek(0)
+			if operand.kind != KindBool {
+				return vm.fail(frame, "Cannot apply operator ! to %s", operand.TypeName())
+			}
+			vm.stack[vm.sp-1] = BoolValue(!operand.AsBool())
+		case OpNegate, OpPositive:
+			operand := vm.peek(0)
+			if operand.kind != KindNumber {
+				return vm.fail(frame, "Cannot apply operator %s to %s", operatorSymbols[op], operand.TypeName())
+			}
+			if op == OpNegate {
+				vm.stack[vm.sp-1] = NumberValue(-operand.number)
+			}
+		case OpTemplate:
+			parts := int(code[frame.ip])
+			frame.ip++
+			texts := make([]string, parts)
+			size := 0
+			for i, part := range vm.stack[vm.sp-parts : vm.sp] {
+				texts[i] = part.String()
+				size += len(texts[i])
+			}
+			vm.sp -= parts
+			// Charge the string before joining the parts
+			if err := vm.allocate(sizeString + size); err != nil {
+				return vm.withSpan(err, chunk.Spans[frame.ip-1])
+			}
+			vm.push(StringValue(strings.Join(texts, "")))
+
+		case OpJump:
+			offset := vm.readU16(frame, code)
+			frame.ip += offset
+		case OpJumpIfFalse:
+			offset := vm.readU16(frame, code)
+			condition := vm.peek(0)
+			if condition.kind != KindBool {
+				return vm.fail(frame, "Condition must be a boolean, got %s", condition.TypeName())
+			}
+			if condition.number == 0 {
+				frame.ip += offset
+			}
+		case OpLoop:
+			offset := vm.readU16(frame, code)
+			frame.ip -= offset
+
+		case OpCall:
+			argc := int(code[frame.ip])
+			frame.ip++
+			if err := vm.callValue(vm.peek(argc), argc); err != nil {
+				return vm.withSpan(err, chunk.Spans[frame.ip-1])
+			}
+			frame, chunk, code = vm.current()
+		case OpInvoke:
+			name, _ := chunk.Constants[vm.readU16(frame, code)].AsString()
+			argc := int(code[frame.ip])
+			frame.ip++
+			if err := vm.invoke(name, argc); err != nil {
+				return vm.withSpan(err, chunk.Spans[frame.ip-1])
+			}
+			frame, chunk, code = vm.current()
+		case OpSuperInvoke:
+			name, _ := chunk.Constants[vm.readU16(frame, code)].AsString()
+			argc := int(code[frame.ip])
+			frame.ip++
+			superClass := vm.pop().object.(*Class)
+			method, ok := superClass.Methods[name]
+			if !ok {
+				return vm.fail(frame, "Superclass '%s' has no method '%s'", superClass.Name, name)
+			}
+			if err := vm.call(method, argc); err != nil {
+				return vm.withSpan(err, chunk.Spans[frame.ip-1])
+			}
+			frame, chunk, code = vm.current()
+		case OpSuperCall:
+			argc := int(code[frame.ip])
+			frame.ip++
+			superClass := vm.pop().object.(*Class)
+			if superClass.Constructor == nil {
+				if argc > 0 {
+					return vm.fail(frame, "Expected 0 arguments, got %d", argc)
+				}
+				vm.stack[vm.sp-1] = Value{}
+				continue
+			}
+			if err := vm.call(superClass.Constructor, argc); err != nil {
+				return vm.withSpan(err, chunk.Spans[frame.ip-1])
+			}
+			frame, chunk, code = vm.current()
+		case OpNew:
+			argc := int(code[frame.ip])
+			frame.ip++
+			if err := vm.instantiate(argc); err != nil {
+				return vm.withSpan(err, chunk.Spans[frame.ip-1])
+			}
+			frame, chunk, code = vm.current()
+		case OpClosure:
+			function := chunk.Constants[vm.readU16(frame, code)].object.(*Function)
+			if err := vm.allocate(sizeClosure + function.UpvalueCount*sizeUpvalue); err != nil {
+				return vm.withSpan(err, chunk.Spans[frame.ip-1])
+			}
+			closure := &Closure{Function: function, Upvalues: make([]*Upvalue, function.UpvalueCount)}
+			for i := range closure.Upvalues {
+				isLocal, index := code[frame.ip], int(code[frame.ip+1])
+				frame.ip += 2
+				if isLocal == 1 {
+					closure.Upvalues[i] = vm.captureUpvalue(frame.base + index)
+				} else {
+					closure.Upvalues[i] = frame.closure.Upvalues[index]
+				}
+			}
+			vm.push(ObjectValue(closure))
+		case OpReturn:
+			result := vm.pop()
+			vm.closeUpvalues(frame.base)
+			vm.sp = frame.base
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			vm.push(result)
+			if len(vm.frames) == depth {
+				return nil
+			}
+			frame, chunk, code = vm.current()
+
+		case OpClass:
+			name, _ := chunk.Constants[vm.readU16(frame, code)].AsString()
+			if err := vm.allocate(sizeClass); err != nil {
+				return vm.withSpan(err, chunk.Spans[frame.ip-1])
+			}
+			vm.push(ObjectValue(&Class{Name: name, Methods: map[string]*Closure{}}))
+		case OpInherit:
+			superClass, ok := vm.peek(1).object.(*Class)
+			if !ok {
+				return vm.fail(frame, "Superclass must be a class, got %s", vm.peek(1).TypeName())
+			}
+			class := vm.pop().object.(*Class)
+			class.Super = superClass
+			class.Constructor = superClass.Constructor
+			for name, method := range superClass.Methods {
+				class.Methods[name] = method
+			}
+		case OpMethod:
+			name, _ := chunk.Constants[vm.readU16(frame, code)].AsString()
+			method := vm.pop().object.(*Closure)
+			class := vm.peek(0).object.(*Class)
+			if name == "constructor" {
+				class.Constructor = method
+			} else {
+				class.Methods[name] = method
+			}
+		case OpFields:
+			fields := vm.pop().object.(*Closure)
+			vm.peek(0).object.(*Class).Fields = fields
+
+		default:
+			return vm.fail(frame, "Unknown opcode %s", op)
+		}
+	}
+}
+
+// current returns the frame on top with its code
+func (vm *VM) current() (*frame, *Chunk, []byte) {
+	frame := &vm.frames[len(vm.frames)-1]
+	chunk := &frame.closure.Function.Chunk
+	return frame, chunk, chunk.Code
+}
+
+// readU16 reads the 16-bit operand at the instruction pointer of the frame
+func (vm *VM) readU16(frame *frame, code []byte) int {
+	frame.ip += 2
+	return int(code[frame.ip-2])<<8 | int(code[frame.ip-1])
+}
+
+// fail creates a runtime error at the instruction being executed by the frame
+func (vm *VM) fail(frame *frame, format string, args ...any) error {
+	return &RuntimeError{Message: fmt.Sprintf(format, args...), Span: frame.closure.Function.Chunk.Spans[frame.ip-1]}
+}
+
+// withSpan positions errors at the instruction, errors raised inside a callee keep their span
+func (vm *VM) withSpan(err error, span source.Span) error {
+	if runtimeError, ok := err.(*RuntimeError); ok {
+		if runtimeError.Span == (source.Span{}) {
+			runtimeError.Span = span
+		}
+		return runtimeError
+	}
+	return &RuntimeError{Message: err.Error(), Span: span}
+}
+
+// callValue calls the callee below the arguments on the stack
+//
+// Script functions get a new frame, native functions complete immediately and leave their result
+func (vm *VM) callValue(callee Value, argc int) error {
+	switch object := callee.object.(type) {
+	case *Closure:
+		return vm.call(object, argc)
+	case *BoundMethod:
+		vm.stack[vm.sp-argc-1] = object.Receiver
+		return vm.call(object.Method, argc)
+	case *Native:
+		if object.Arity >= 0 && argc != object.Arity {
+			return fmt.Errorf("Expected %d arguments, got %d", object.Arity, argc)
+		}
+		result, err := object.Fn(vm.stack[vm.sp-argc : vm.sp])
+		if err != nil {
+			return err
+		}
+		vm.sp -= argc + 1
+		vm.push(result)
+		return nil
+	case *Class:
+		return fmt.Errorf("Class '%s' must be instantiated with 'new'", object.Name)
+	default:
+		return fmt.Errorf("Cannot call a value of type %s", callee.TypeName())
+	}
+}
+
+// call pushes a frame for the closure, the callee and the arguments are already on the stack
+func (vm *VM) call(closure *Closure, argc int) error {
+	if argc != closure.Function.Arity {
+		return fmt.Errorf("Function '%s' expects %d arguments, got %d", closure.Function.Name, closure.Function.Arity, argc)
+	}
+	if len(vm.frames) == cap(vm.frames) {
+		return limitError(ErrStackOverflow, "Stack overflow, more than %d nested calls", cap(vm.frames))
+	}
+
+	vm.frames = append(vm.frames, frame{closure: closure, base: vm.sp - argc - 1})
+	return nil
+}
+
+// invoke calls a method of the receiver below the arguments without creating a bound method
+func (vm *VM) invoke(name string, argc int) error {
+	receiver := vm.peek(argc)
+
+	if instance, ok := receiver.object.(*Instance); ok {
+		if field, ok := instance.Fields[name]; ok {
+			vm.stack[vm.sp-argc-1] = field
+			return vm.callValue(field, argc)
+		}
+		if method, ok := instance.Class.Methods[name]; ok {
+			return vm.call(method, argc)
+		}
+	}
+
+	callee, err := vm.getProperty(receiver, name, false)
+	if err != nil {
+		return err
+	}
+	vm.stack[vm.sp-argc-1] = callee
+	return vm.callValue(callee, argc)
+}
+
+// instantiate creates an instance of the class below the arguments, initializes its fields from the base class
+// down and calls the constructor
+func (vm *VM) instantiate(argc int) error {
+	callee := vm.peek(argc)
+	class, ok := callee.object.(*Class)
+	if !ok {
+		return fmt.Errorf("Cannot instantiate a value of type %s", callee.TypeName())
+	}
+
+	if err := vm.allocate(sizeInstance); err != nil {
+		return err
+	}
+	instance := &Instance{Class: class, Fields: map[string]Value{}}
+	vm.stack[vm.sp-argc-1] = ObjectValue(instance)
+	if err := vm.initializeFields(class, instance); err != nil {
+		return err
+	}
+
+	if class.Constructor == nil {
+		if argc > 0 {
+			return fmt.Errorf("Class '%s' expects 0 arguments, got %d", class.Name, argc)
+		}
+		return nil
+	}
+	return vm.call(class.Constructor, argc)
+}
+
+// initializeFields runs the field initializers of the class and its superclasses
+func (vm *VM) initializeFields(class *Class, instance *Instance) error {
+	if class.Super != nil {
+		if err := vm.initializeFields(class.Super, instance); err != nil {
+			return err
+		}
+	}
+	if class.Fields == nil {
+		return nil
+	}
+
+	depth := len(vm.frames)
+	vm.push(ObjectValue(instance))
+	if err := vm.call(class.Fields, 0); err != nil {
+		return err
+	}
+	if err := vm.run(depth); err != nil {
+		return err
+	}
+	vm.pop()
+	return nil
+}
+
+// captureUpvalue returns the upvalue of the stack slot, closures capturing the same variable share it
+func (vm *VM) captureUpvalue(slot int) *Upvalue {
+	var previous *Upvalue
+	upvalue := vm.openUpvalues
+	for upvalue != nil && upvalue.slot > slot {
+		previous = upvalue
+		upvalue = upvalue.next
+	}
+	if upvalue != nil && upvalue.slot == slot {
+		return upvalue
+	}
+
+	created := &Upvalue{slot: slot, open: true, next: upvalue}
+	if previous == nil {
+		vm.openUpvalues = created
+	} else {
+		previous.next = created
+	}
+	return created
+}
+
+// closeUpvalues moves the values of the slots from last upward into their upvalues
+func (vm *VM) closeUpvalues(last int) {
+	for vm.openUpvalues != nil && vm.openUpvalues.slot >= last {
+		upvalue := vm.openUpvalues
+		upvalue.closed = vm.stack[upvalue.slot]
+		upvalue.open = false
+		vm.openUpvalues = upvalue.next
+	}
+}
+
+// getProperty returns the field of an instance, a method bound to it or the length of a string
+func (vm *VM) getProperty(object Value, name string, computed bool) (Value, error) {
+	switch target := object.object.(type) {
+	case *Instance:
+		if value, ok := target.Fields[name]; ok {
+			return value, nil
+		}
+		if method, ok := target.Class.Methods[name]; ok {
+			if err := vm.allocate(sizeBoundMethod); err != nil {
+				return Value{}, err
+			}
+			return ObjectValue(&BoundMethod{Receiver: object, Method: method}), nil
+		}
+		return Value{}, fmt.Errorf("Undefined property '%s' of %s", name, target.Class.Name)
+	case string:
+		if !computed && name == "length" {
+			return NumberValue(float64(utf8.RuneCountInString(target))), nil
+		}
+	}
+	return Value{}, fmt.Errorf("Type %s has no property '%s'", object.TypeName(), name)
+}
+
+// setProperty assigns a field of an instance
+func (vm *VM) setProperty(object Value, name string, value Value) error {
+	instance, ok := object.object.(*Instance)
+	if !ok {
+		return fmt.Errorf("Only instances have fields, got %s", object.TypeName())
+	}
+	if _, ok := instance.Fields[name]; !ok {
+		if err := vm.allocate(sizeField); err != nil {
+			return err
+		}
+	}
+	instance.Fields[name] = value
+	return nil
+}
+
+// operatorSymbols are the operators of the instructions in error messages
+var operatorSymbols = map[Opcode]string{
+	OpAdd:          "+",
+	OpSubtract:     "-",
+	OpMultiply:     "*",
+	OpDivide:       "/",
+	OpGreater:      ">",
+	OpGreaterEqual: ">=",
+	OpLess:         "<",
+	OpLessEqual:    "<=",
+	OpNegate:       "-",
+	OpPositive:     "+",
+}
+
+// numberOperation applies an arithmetic or comparison instruction to numbers
+func numberOperation(op Opcode, left, right float64) Value {
+	switch op {
+	case OpAdd:
+		return NumberValue(left + right)
+	case OpSubtract:
+		return NumberValue(left - right)
+	case OpMultiply:
+		return NumberValue(left * right)
+	case OpDivide:
+		return NumberValue(left / right)
+	case OpGreater:
+		return BoolValue(left > right)
+	case OpGreaterEqual:
+		return BoolValue(left >= right)
+	case OpLess:
+		return BoolValue(left < right)
+	default:
+		return BoolValue(left <= right)
+	}
+}
+
+// stringOperation concatenates or compares strings
+func stringOperation(op Opcode, left, right Value) (Value, bool) {
+	l, ok := left.AsString()
+	if !ok {
+		return Value{}, false
+	}
+	r, ok := right.AsString()
+	if !ok {
+		return Value{}, false
+	}
+
+	switch op {
+	case OpAdd:
+		return StringValue(l + r), true
+	case OpGreater:
+		return BoolValue(l > r), true
+	case OpGreaterEqual:
+		return BoolValue(l >= r), true
+	case OpLess:
+		return BoolValue(l < r), true
+	case OpLessEqual:
+		return BoolValue(l <= r), true
+	default:
+		return Value{}, false
+	}
+}
@@ -0,0 +1,444 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yoh0xff/senbonzakura/interpreter"
+	"github.com/yoh0xff/senbonzakura/parser"
+)
+
+func compileSource(t testing.TB, machine *VM, source string) *Function {
+	t.Helper()
+
+	program, diagnostics := parser.ParseRootStatement(parser.NewParser(source))
+	if len(diagnostics) != 0 {
+		t.Fatalf("Expected no parse diagnostics, got %v", diagnostics)
+	}
+
+	function, diagnostics := Compile(program, machine.Globals())
+	if len(diagnostics) != 0 {
+		t.Fatalf("Expected no compile diagnostics, got %v", diagnostics)
+	}
+	return function
+}
+
+func runSource(t *testing.T, source string) (string, error) {
+	t.Helper()
+
+	var output strings.Builder
+	machine := NewVMWithConfig(Config{Stdout: &output, MaxFrames: 100})
+	err := machine.Run(compileSource(t, machine, source))
+	return output.String(), err
+}
+
+func TestVMOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"arithmetic", `print(1 + 2 * 3, 10 / 4, -(2 - 5), 0.1 + 0.2);`, "7 2.5 3 0.30000000000000004\n"},
+		{"strings", "let name: string = \"world\"; print(\"hello \" + name, `${name}!`, name.length);", "hello world world! 5\n"},
+		{"comparison", `print(1 < 2, 2 <= 1, "a" < "b", 1 == 1, nil == nil, "a" != "a");`, "true false true true true false\n"},
+		{"logical", `def f(): boolean { print("called"); return true; } print(false && f(), true || f(), true && f());`, "called\nfalse true true\n"},
+		{"compound assignment", `let x: number = 10; x += 5; x -= 3; x *= 2; x /= 4; print(x);`, "6\n"},
+		{"block scope", `let x: number = 1; { let x: number = 2; print(x); } print(x);`, "2\n1\n"},
+		{"if else", `let x: number = 5; if (x > 3) { print("big"); } else { print("small"); }`, "big\n"},
+		{"while", `let i: number = 0; while (i < 3) { print(i); i = i + 1; }`, "0\n1\n2\n"},
+		{"do while", `let i: number = 10; do { print(i); } while (i < 3);`, "10\n"},
+		{"for", `for (let i: number = 0; i < 3; i += 1) { print(i); }`, "0\n1\n2\n"},
+		{"recursion", `def fib(n: number): number { if (n < 2) { return n; } return fib(n - 1) + fib(n - 2); } print(fib(15));`, "610\n"},
+		{"hoisting", `print(double(2)); def double(n: number): number { return n * 2; }`, "4\n"},
+		{
+			"superclass declared later",
+			`class B extends A { def f(): number { return this.g() + 1; } } class A { def g(): number { return 1; } }
+			print(new B().f()); def h(): void { class D extends C {} class C { let v: number = 3; } print(new D().v); } h();`,
+			"2\n3\n",
+		},
+		{
+			"zero values",
+			`let y: number; let s: string; let b: boolean; let a: [number]; class P { let x: number; } print(y + 1, s + "a", !b, a, new P().x + 1);`,
+			"1 a true nil 1\n",
+		},
+		{
+			"closures",
+			`def counter(): Function {
+				let count: number = 0;
+				def next(): number { count += 1; return count; }
+				return next;
+			}
+			let a: Function = counter();
+			let b: Function = counter();
+			print(a(), a(), b());`,
+			"1 2 1\n",
+		},
+		{
+			"closures over loop blocks",
+			`def keep(n: number): Function { def get(): number { return n; } return get; }
+			let first: Function = nil;
+			for (let i: number = 0; i < 3; i += 1) { let j: number = i * 10; if (i == 1) { first = keep(j); } }
+			print(first());`,
+			"10\n",
+		},
+		{
+			"classes",
+			`class Person {
+				def constructor(name: string, age: number) { this.name = name; this.age = age; }
+				def greet(): string { return "I am " + this.name; }
+			}
+			let p: Person = new Person("Ann", 30);
+			print(p.greet(), p.age);
+			p.age += 1;
+			print(p.age);`,
+			"I am Ann 30\n31\n",
+		},
+		{
+			"fields",
+			`class Counter { let count: number = 1; def inc(): void { this.count += 1; } }
+			let c: Counter = new Counter(); c.inc(); c.inc(); print(c.count);`,
+			"3\n",
+		},
+		{
+			"inheritance",
+			`class Animal {
+				def constructor(name: string) { this.name = name; }
+				def speak(): string { return this.name + " makes a sound"; }
+				def describe(): string { return this.speak(); }
+			}
+			class Dog extends Animal {
+				def constructor(name: string) { super(name); this.tricks = 2; }
+				def speak(): string { return super.speak() + ", woof"; }
+			}
+			let d: Animal = new Dog("Rex");
+			print(d.describe());`,
+			"Rex makes a sound, woof\n",
+		},
+		{
+			"inherited constructor",
+			`class A { def constructor(x: number) { this.x = x; } } class B extends A {} print(new B(7).x);`,
+			"7\n",
+		},
+		{
+			"methods are bound",
+			`class A { let v: number = 4; def get(): number { return this.v; } } let f: Function = new A().get; print(f());`,
+			"4\n",
+		},
+		{
+			"shared upvalues",
+			`def pair(): Function {
+				let value: number = 0;
+				def set(n: number): void { value = n; }
+				def get(): number { return value; }
+				set(5);
+				return get;
+			}
+			print(pair()());`,
+			"5\n",
+		},
+		{
+			"computed members",
+			`class A { let x: number = 1; } let a: A = new A(); a["x"] += 2; a["y"] = "z"; print(a.x, a["y"]);`,
+			"3 z\n",
+		},
+		{
+			"inherited fields",
+			`class A { let x: number = 1; } class B extends A { let y: number = this.x + 1; } let b: B = new B(); print(b.x, b.y);`,
+			"1 2\n",
+		},
+		{"stringify", `class A {} def f(): void {} print(nil, true, A, new A(), f, print, 1e21);`, "nil true <class A> <A instance> <fn f> <native fn print> 1e+21\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := runSource(t, tt.source)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if output != tt.expected {
+				t.Errorf("Expected output %q, got %q", tt.expected, output)
+			}
+		})
+	}
+}
+
+func TestVMRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		message string
+		line    int
+		column  int
+	}{
+		{"undefined variable", `let x: number = 1;
+print(y);`, "Undefined variable 'y'", 2, 7},
+		{"local before initialization", `def h(): void { def f(): number { return x; } print(f() + 1); let x: number = 2; } h();`,
+			"Variable 'x' used before initialization", 1, 42},
+		{"operand types", `print(1 + "a");`, "Cannot apply operator + to number and string", 1, 7},
+		{"condition", `if (1) {}`, "Condition must be a boolean, got number", 1, 5},
+		{"arity", `def f(a: number): void {} f();`, "Function 'f' expects 1 arguments, got 0", 1, 27},
+		{"not callable", `let x: number = 1; x();`, "Cannot call a value of type number", 1, 20},
+		{"undefined property", `class A {} new A().x;`, "Undefined property 'x' of A", 1, 12},
+		{"class without new", `class A {} A();`, "Class 'A' must be instantiated with 'new'", 1, 12},
+		{"stack overflow", `def f(): void { f(); } f();`, "Stack overflow, more than 100 nested calls", 1, 17},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runSource(t, tt.source)
+
+			var runtimeError *RuntimeError
+			if !errors.As(err, &runtimeError) {
+				t.Fatalf("Expected a runtime error, got %v", err)
+			}
+			if runtimeError.Message != tt.message {
+				t.Errorf("Expected message %q, got %q", tt.message, runtimeError.Message)
+			}
+			if runtimeError.Span.Line != tt.line || runtimeError.Span.Column != tt.column {
+				t.Errorf("Expected error at %d:%d, got %s", tt.line, tt.column, runtimeError.Span)
+			}
+		})
+	}
+}
+
+func TestVMHost(t *testing.T) {
+	machine := NewVMWithConfig(Config{})
+	machine.Define("add", ObjectValue(&Native{
+		Name:  "add",
+		Arity: 2,
+		Fn: func(args []Value) (Value, error) {
+			return NumberValue(args[0].AsNumber() + args[1].AsNumber()), nil
+		},
+	}))
+
+	function := compileSource(t, machine, `let total: number = add(2, 3); def twice(n: number): number { return n * 2; }`)
+	if err := machine.Run(function); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if total, _ := machine.Globals().Get("total"); total.AsNumber() != 5 {
+		t.Errorf("Expected total 5, got %v", total)
+	}
+
+	twice, _ := machine.Globals().Get("twice")
+	result, err := machine.Call(twice, NumberValue(21))
+	if err != nil || result.AsNumber() != 42 {
+		t.Errorf("Expected 42, got %v (%v)", result, err)
+	}
+
+	// Globals persist, a second program sees the functions of the first one
+	function = compileSource(t, machine, `let again: number = twice(total);`)
+	if err := machine.Run(function); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if again, _ := machine.Globals().Get("again"); again.AsNumber() != 10 {
+		t.Errorf("Expected again 10, got %v", again)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		message string
+	}{
+		{"return at top level", `return 1;`, "Cannot return from top-level code"},
+		{"this outside class", `print(this);`, "'this' can only be used inside a class"},
+		{"super without superclass", `class A { def f(): void { super.f(); } }`, "'super' can only be used inside a class that extends another class"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, _ := parser.ParseRootStatement(parser.NewParser(tt.source))
+			function, diagnostics := Compile(program, NewGlobals())
+			if function != nil {
+				t.Errorf("Expected no function")
+			}
+			if len(diagnostics) != 1 || diagnostics[0].Message != tt.message {
+				t.Errorf("Expected diagnostic %q, got %v", tt.message, diagnostics)
+			}
+		})
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	machine := NewVMWithConfig(Config{})
+	function := compileSource(t, machine, `def add(a: number, b: number): number {
+	return a + b;
+}
+print(add(1, 2));`)
+
+	expected := `== <script> ==
+0000     1:1 OP_CLOSURE            0 <fn add>
+0003     1:5 OP_DEFINE_GLOBAL      1
+0006     4:1 OP_GET_GLOBAL         0
+0009     4:7 OP_GET_GLOBAL         1
+0012    4:11 OP_CONSTANT           1 1
+0015    4:14 OP_CONSTANT           2 2
+0018     4:7 OP_CALL               2
+0020     4:1 OP_CALL               1
+0022       | OP_POP
+0023     1:1 OP_NIL
+0024       | OP_RETURN
+
+== add ==
+0000     2:9 OP_GET_LOCAL          1
+0002    2:13 OP_GET_LOCAL          2
+0004     2:9 OP_ADD
+0005     2:2 OP_RETURN
+0006    1:39 OP_NIL
+0007       | OP_RETURN
+`
+	if output := Disassemble(function); output != expected {
+		t.Errorf("Expected listing:\n%s\ngot:\n%s", expected, output)
+	}
+}
+
+// Loop-heavy programs comparing the bytecode VM with direct AST evaluation
+const (
+	benchmarkForLoop = `let total: number = 0;
+for (let i: number = 0; i < 100000; i += 1) {
+	if (i / 2 > 100) { total += i; } else { total -= 1; }
+}`
+	benchmarkWhileLoop = `def sum(n: number): number {
+	let total: number = 0;
+	let i: number = 0;
+	while (i < n) { total = total + i * 2; i = i + 1; }
+	return total;
+}
+let total: number = sum(100000);`
+	benchmarkCalls = `def fib(n: number): number { if (n < 2) { return n; } return fib(n - 1) + fib(n - 2); }
+let total: number = fib(20);`
+)
+
+func benchmarkVM(b *testing.B, source string) {
+	machine := NewVMWithConfig(Config{})
+	function := compileSource(b, machine, source)
+
+	b.ResetTimer()
+	for range b.N {
+		if err := machine.Run(function); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkInterpreter(b *testing.B, source string) {
+	program, _ := parser.ParseRootStatement(parser.NewParser(source))
+	evaluator := interpreter.NewInterpreterWithConfig(interpreter.Config{})
+
+	b.ResetTimer()
+	for range b.N {
+		if err := evaluator.Run(program); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkVMForLoop(b *testing.B)            { benchmarkVM(b, benchmarkForLoop) }
+func BenchmarkInterpreterForLoop(b *testing.B)   { benchmarkInterpreter(b, benchmarkForLoop) }
+func BenchmarkVMWhileLoop(b *testing.B)          { benchmarkVM(b, benchmarkWhileLoop) }
+func BenchmarkInterpreterWhileLoop(b *testing.B) { benchmarkInterpreter(b, benchmarkWhileLoop) }
+func BenchmarkVMCalls(b *testing.B)              { benchmarkVM(b, benchmarkCalls) }
+func BenchmarkInterpreterCalls(b *testing.B)     { benchmarkInterpreter(b, benchmarkCalls) }
+
+func TestVMMatchesInterpreter(t *testing.T) {
+	sources := []string{
+		`let x: number = 1; { def f(): number { return x; } print(f()); let x: number = 2; print(f()); }`,
+		`let x: number = 1; { def f(): number { return x; } let x: number = 2; print(f()); } print(x);`,
+		`def h(): void { def f(): number { return x; } print(f() + 1); let x: number = 2; } h();`,
+		`def h(): void { def set(): void { x = 3; } let x: number = 1; set(); print(x); } h();`,
+		`def outer(): Function { let n: number = 0; def next(): number { n += 1; return n; } return next; }
+		let a: Function = outer(); let b: Function = outer(); a(); print(a(), b());`,
+		`for (let i: number = 0; i < 2; i += 1) { let i: number = 10; print(i); }`,
+		`class A { let v: number = w; } let w: number = 5; print(new A().v);`,
+		`def f(): number { return g; } print(f()); let g: number = 1;`,
+	}
+
+	for _, source := range sources {
+		program, diagnostics := parser.ParseRootStatement(parser.NewParser(source))
+		if len(diagnostics) != 0 {
+			t.Fatalf("Expected no parse diagnostics, got %v", diagnostics)
+		}
+
+		var expected strings.Builder
+		evaluator := interpreter.NewInterpreterWithConfig(interpreter.Config{Stdout: &expected})
+		if err := evaluator.Run(program); err != nil {
+			var runtimeError *interpreter.RuntimeError
+			errors.As(err, &runtimeError)
+			fmt.Fprintf(&expected, "error: %s\n", runtimeError.Message)
+		}
+
+		output, err := runSource(t, source)
+		if err != nil {
+			var runtimeError *RuntimeError
+			errors.As(err, &runtimeError)
+			output += fmt.Sprintf("error: %s\n", runtimeError.Message)
+		}
+
+		if output != expected.String() {
+			t.Errorf("%s\nExpected the VM to print like the interpreter\n%s\ngot\n%s", source, expected.String(), output)
+		}
+	}
+}
+
+func TestVMLimits(t *testing.T) {
+	deadline, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	tests := []struct {
+		name   string
+		config Config
+		ctx    context.Context
+		source string
+		err    error
+		line   int
+	}{
+		{"fuel", Config{Fuel: 10000}, context.Background(), `let i: number = 0;
+while (true) { i += 1; }`, ErrFuelExhausted, 2},
+		{"stack overflow", Config{MaxFrames: 50}, context.Background(), `def f(n: number): number {
+	return f(n + 1);
+}
+f(0);`, ErrStackOverflow, 2},
+		{"heap", Config{MaxHeap: 1 << 20}, context.Background(), `let s: string = "ab";
+while (true) { s = s + s; }`, ErrOutOfMemory, 2},
+		{"template", Config{MaxHeap: 1 << 20}, context.Background(), "let s: string = \"ab\";\nwhile (true) { s = `${s}${s}`; }", ErrOutOfMemory, 2},
+		{"objects", Config{MaxHeap: 1 << 16}, context.Background(), `class Node {}
+let head: Node = nil;
+while (true) { let node: Node = new Node(); node.next = head; head = node; }`, ErrOutOfMemory, 3},
+		{"deadline", Config{}, deadline, `while (true) {}`, context.DeadlineExceeded, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			machine := NewVMWithConfig(tt.config)
+			err := machine.RunContext(tt.ctx, compileSource(t, machine, tt.source))
+
+			var runtimeError *RuntimeError
+			if !errors.Is(err, tt.err) || !errors.As(err, &runtimeError) {
+				t.Fatalf("Expected a runtime error wrapping %v, got %v", tt.err, err)
+			}
+			if runtimeError.Span.Line != tt.line {
+				t.Errorf("Expected error at line %d, got %s", tt.line, runtimeError.Span)
+			}
+		})
+	}
+
+	// The budgets start over for every call
+	machine := NewVMWithConfig(Config{Fuel: 1000})
+	if err := machine.Run(compileSource(t, machine, `def f(): number { let x: number = 0; while (x < 50) { x += 1; } return x; }`)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	f, _ := machine.Globals().Get("f")
+	for range 3 {
+		if _, err := machine.Call(f); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if steps := machine.Steps(); steps < 300 || steps > 1000 {
+		t.Errorf("Expected between 300 and 1000 steps, got %d", steps)
+	}
+}
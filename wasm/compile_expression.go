@@ -0,0 +1,197 @@
+package wasm
+
+import (
+	"fmt"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/checker"
+)
+
+func visitExpression(compiler *Compiler, expression ast.Expression) {
+	// Values the backend can't represent are reported once for the outermost expression
+	if t := compiler.info.TypeOf(expression); t != nil && t != checker.Void {
+		if _, ok := compiler.valueType(t, expression.GetSpan()); !ok {
+			compiler.emit(Instruction{Opcode: OpUnreachable})
+			return
+		}
+	}
+
+	switch expression.NodeType() {
+	case ast.NodeAssignmentExpression:
+		visitAssignmentExpression(compiler, expression.(*ast.AssignmentExpression))
+	case ast.NodeBinaryExpression:
+		visitBinaryExpression(compiler, expression.(*ast.BinaryExpression))
+	case ast.NodeUnaryExpression:
+		visitUnaryExpression(compiler, expression.(*ast.UnaryExpression))
+	case ast.NodeLogicalExpression:
+		visitLogicalExpression(compiler, expression.(*ast.LogicalExpression))
+	case ast.NodeBooleanLiteralExpression:
+		value := int32(0)
+		if expression.(*ast.BooleanLiteralExpression).Value {
+			value = 1
+		}
+		compiler.emit(Instruction{Opcode: OpI32Const, I32: value})
+	case ast.NodeNumericLiteralExpression:
+		compiler.emit(Instruction{Opcode: OpF64Const, F64: expression.(*ast.NumericLiteralExpression).FloatValue})
+	case ast.NodeIdentifierExpression:
+		visitIdentifierExpression(compiler, expression.(*ast.IdentifierExpression))
+	case ast.NodeCallExpression:
+		visitCallExpression(compiler, expression.(*ast.CallExpression))
+	case ast.NodeVariableExpression,
+		ast.NodeNilLiteralExpression,
+		ast.NodeStringLiteralExpression,
+		ast.NodeTemplateLiteralExpression,
+		ast.NodeMemberExpression,
+		ast.NodeThisExpression,
+		ast.NodeSuperExpression,
+		ast.NodeNewExpression:
+		compiler.reportError(expression.GetSpan(), "Expression %s is not supported", expression.NodeType())
+		compiler.emit(Instruction{Opcode: OpUnreachable})
+	default:
+		panic(fmt.Errorf("unknown expression type: %T", expression))
+	}
+}
+
+func visitAssignmentExpression(compiler *Compiler, expression *ast.AssignmentExpression) {
+	identifier, ok := expression.Left.(*ast.IdentifierExpression)
+	if !ok {
+		compiler.reportError(expression.Left.GetSpan(), "Only variables can be assigned")
+		compiler.emit(Instruction{Opcode: OpUnreachable})
+		return
+	}
+
+	get, set, ok := compiler.variable(identifier)
+	if !ok {
+		return
+	}
+
+	if expression.Operator != ast.OperatorAssign {
+		compiler.emit(get)
+	}
+	expression.Right.Accept(compiler)
+
+	switch expression.Operator {
+	case ast.OperatorAssignAdd:
+		compiler.emit(Instruction{Opcode: OpF64Add})
+	case ast.OperatorAssignSubtract:
+		compiler.emit(Instruction{Opcode: OpF64Sub})
+	case ast.OperatorAssignMultiply:
+		compiler.emit(Instruction{Opcode: OpF64Mul})
+	case ast.OperatorAssignDivide:
+		compiler.emit(Instruction{Opcode: OpF64Div})
+	}
+
+	// The assignment is an expression, its value stays on the stack
+	if set.Opcode == OpLocalSet {
+		compiler.emit(Instruction{Opcode: OpLocalTee, Index: set.Index})
+	} else {
+		compiler.emit(set, get)
+	}
+}
+
+// binaryOpcodes maps number operators to instructions
+var binaryOpcodes = map[ast.BinaryOperator]Opcode{
+	ast.OperatorAdd:                  OpF64Add,
+	ast.OperatorSubtract:             OpF64Sub,
+	ast.OperatorMultiply:             OpF64Mul,
+	ast.OperatorDivide:               OpF64Div,
+	ast.OperatorEqual:                OpF64Eq,
+	ast.OperatorNotEqual:             OpF64Ne,
+	ast.OperatorGreaterThan:          OpF64Gt,
+	ast.OperatorGreaterThanOrEqualTo: OpF64Ge,
+	ast.OperatorLessThan:             OpF64Lt,
+	ast.OperatorLessThanOrEqualTo:    OpF64Le,
+}
+
+func visitBinaryExpression(compiler *Compiler, expression *ast.BinaryExpression) {
+	expression.Left.Accept(compiler)
+	expression.Right.Accept(compiler)
+
+	// Booleans are only compared for equality
+	if compiler.info.TypeOf(expression.Left) == checker.Boolean {
+		switch expression.Operator {
+		case ast.OperatorEqual:
+			compiler.emit(Instruction{Opcode: OpI32Eq})
+			return
+		case ast.OperatorNotEqual:
+			compiler.emit(Instruction{Opcode: OpI32Ne})
+			return
+		}
+	}
+
+	compiler.emit(Instruction{Opcode: binaryOpcodes[expression.Operator]})
+}
+
+func visitUnaryExpression(compiler *Compiler, expression *ast.UnaryExpression) {
+	expression.Right.Accept(compiler)
+
+	switch expression.Operator {
+	case ast.OperatorMinus:
+		compiler.emit(Instruction{Opcode: OpF64Neg})
+	case ast.OperatorNot:
+		compiler.emit(Instruction{Opcode: OpI32Eqz})
+	}
+}
+
+// visitLogicalExpression compiles the short-circuit operators with an if that has a result
+//
+//	left            left
+//	if (result i32) if (result i32)
+//	  right           i32.const 1
+//	else            else
+//	  i32.const 0     right
+//	end             end
+func visitLogicalExpression(compiler *Compiler, expression *ast.LogicalExpression) {
+	expression.Left.Accept(compiler)
+	compiler.emit(Instruction{Opcode: OpIf, Block: BlockType(I32)})
+
+	if expression.Operator == ast.OperatorAnd {
+		expression.Right.Accept(compiler)
+		compiler.emit(Instruction{Opcode: OpElse}, Instruction{Opcode: OpI32Const, I32: 0})
+	} else {
+		compiler.emit(Instruction{Opcode: OpI32Const, I32: 1}, Instruction{Opcode: OpElse})
+		expression.Right.Accept(compiler)
+	}
+
+	compiler.emit(Instruction{Opcode: OpEnd})
+}
+
+func visitIdentifierExpression(compiler *Compiler, expression *ast.IdentifierExpression) {
+	if get, _, ok := compiler.variable(expression); ok {
+		compiler.emit(get)
+	}
+}
+
+func visitCallExpression(compiler *Compiler, expression *ast.CallExpression) {
+	var index uint32
+	ok := false
+	if identifier, isIdentifier := expression.Callee.(*ast.IdentifierExpression); isIdentifier {
+		index, ok = compiler.functions[compiler.info.Resolution.Uses[identifier]]
+	}
+	if !ok {
+		compiler.reportError(expression.Callee.GetSpan(), "Only top-level functions and imported builtins can be called")
+		compiler.emit(Instruction{Opcode: OpUnreachable})
+		return
+	}
+
+	for _, argument := range expression.Arguments {
+		argument.Accept(compiler)
+	}
+	compiler.emit(Instruction{Opcode: OpCall, Index: index})
+}
+
+// variable returns the instructions that read and write the variable the identifier refers to
+func (c *Compiler) variable(identifier *ast.IdentifierExpression) (get, set Instruction, ok bool) {
+	symbol := c.info.Resolution.Uses[identifier]
+
+	if index, ok := c.locals[symbol]; ok {
+		return Instruction{Opcode: OpLocalGet, Index: index}, Instruction{Opcode: OpLocalSet, Index: index}, true
+	}
+	if index, ok := c.globals[symbol]; ok {
+		return Instruction{Opcode: OpGlobalGet, Index: index}, Instruction{Opcode: OpGlobalSet, Index: index}, true
+	}
+
+	c.reportError(identifier.Span, "'%s' can't be used as a value", identifier.Name)
+	c.emit(Instruction{Opcode: OpUnreachable})
+	return Instruction{}, Instruction{}, false
+}
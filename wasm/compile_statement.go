@@ -0,0 +1,202 @@
+package wasm
+
+import (
+	"fmt"
+
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/checker"
+)
+
+func visitStatement(compiler *Compiler, statement ast.Statement) {
+	switch statement.NodeType() {
+	case ast.NodeBlockStatement:
+		for _, statement := range statement.(*ast.BlockStatement).Body {
+			statement.Accept(compiler)
+		}
+	case ast.NodeEmptyStatement, ast.NodeErrorStatement:
+		// Nothing to compile
+	case ast.NodeExpressionStatement:
+		visitExpressionStatement(compiler, statement.(*ast.ExpressionStatement))
+	case ast.NodeVariableDeclarationStatement:
+		for _, variable := range statement.(*ast.VariableDeclarationStatement).Variables {
+			compileVariable(compiler, variable)
+		}
+	case ast.NodeIfStatement:
+		visitIfStatement(compiler, statement.(*ast.IfStatement))
+	case ast.NodeWhileStatement:
+		visitWhileStatement(compiler, statement.(*ast.WhileStatement))
+	case ast.NodeDoWhileStatement:
+		visitDoWhileStatement(compiler, statement.(*ast.DoWhileStatement))
+	case ast.NodeForStatement:
+		visitForStatement(compiler, statement.(*ast.ForStatement))
+	case ast.NodeReturnStatement:
+		statement := statement.(*ast.ReturnStatement)
+		if statement.Argument != nil {
+			statement.Argument.Accept(compiler)
+		}
+		compiler.emit(Instruction{Opcode: OpReturn})
+	case ast.NodeFunctionDeclarationStatement:
+		compiler.reportError(statement.GetSpan(), "Nested functions are not supported")
+	case ast.NodeClassDeclarationStatement:
+		compiler.reportError(statement.GetSpan(), "Classes are not supported")
+	default:
+		panic(fmt.Errorf("unknown statement type: %T", statement))
+	}
+}
+
+func visitExpressionStatement(compiler *Compiler, statement *ast.ExpressionStatement) {
+	statement.Expression.Accept(compiler)
+
+	if t := compiler.info.TypeOf(statement.Expression); t != nil && t != checker.Void {
+		compiler.emit(Instruction{Opcode: OpDrop})
+	}
+}
+
+// compileVariable stores the initial value of a variable, variables without initializer start at zero
+func compileVariable(compiler *Compiler, variable *ast.VariableExpression) {
+	symbol := compiler.info.Resolution.Declarations[variable.Identifier]
+
+	index, global := compiler.globals[symbol]
+	if !global && symbol.Scope == compiler.info.Resolution.Program {
+		// Top-level variable of an unsupported type, already reported
+		return
+	}
+	if !global {
+		valueType, ok := compiler.valueType(compiler.info.Symbols[symbol], variable.Span)
+		if !ok {
+			return
+		}
+		index = compiler.declareLocal(symbol, valueType)
+	}
+
+	if variable.Initializer != nil {
+		variable.Initializer.Accept(compiler)
+	} else {
+		valueType, _ := lowerType(compiler.info.Symbols[symbol])
+		compiler.emit(zeroValue(valueType))
+	}
+
+	if global {
+		compiler.emit(Instruction{Opcode: OpGlobalSet, Index: index})
+	} else {
+		compiler.emit(Instruction{Opcode: OpLocalSet, Index: index})
+	}
+}
+
+// visitIfStatement compiles
+//
+//	condition
+//	if
+//	  consequent
+//	else
+//	  alternative
+//	end
+func visitIfStatement(compiler *Compiler, statement *ast.IfStatement) {
+	statement.Condition.Accept(compiler)
+	compiler.emit(Instruction{Opcode: OpIf, Block: BlockEmpty})
+	statement.Consequent.Accept(compiler)
+
+	if statement.Alternative != nil {
+		compiler.emit(Instruction{Opcode: OpElse})
+		statement.Alternative.Accept(compiler)
+	}
+
+	compiler.emit(Instruction{Opcode: OpEnd})
+}
+
+// visitWhileStatement compiles
+//
+//	block
+//	  loop
+//	    condition
+//	    i32.eqz
+//	    br_if 1
+//	    body
+//	    br 0
+//	  end
+//	end
+func visitWhileStatement(compiler *Compiler, statement *ast.WhileStatement) {
+	compiler.emit(
+		Instruction{Opcode: OpBlock, Block: BlockEmpty},
+		Instruction{Opcode: OpLoop, Block: BlockEmpty},
+	)
+
+	statement.Condition.Accept(compiler)
+	compiler.emit(
+		Instruction{Opcode: OpI32Eqz},
+		Instruction{Opcode: OpBrIf, Index: 1},
+	)
+
+	statement.Body.Accept(compiler)
+	compiler.emit(
+		Instruction{Opcode: OpBr, Index: 0},
+		Instruction{Opcode: OpEnd},
+		Instruction{Opcode: OpEnd},
+	)
+}
+
+// visitDoWhileStatement compiles
+//
+//	loop
+//	  body
+//	  condition
+//	  br_if 0
+//	end
+func visitDoWhileStatement(compiler *Compiler, statement *ast.DoWhileStatement) {
+	compiler.emit(Instruction{Opcode: OpLoop, Block: BlockEmpty})
+
+	statement.Body.Accept(compiler)
+	statement.Condition.Accept(compiler)
+
+	compiler.emit(
+		Instruction{Opcode: OpBrIf, Index: 0},
+		Instruction{Opcode: OpEnd},
+	)
+}
+
+// visitForStatement compiles
+//
+//	initializer
+//	block
+//	  loop
+//	    condition
+//	    i32.eqz
+//	    br_if 1
+//	    body
+//	    increment
+//	    br 0
+//	  end
+//	end
+func visitForStatement(compiler *Compiler, statement *ast.ForStatement) {
+	if statement.Initializer != nil {
+		statement.Initializer.Accept(compiler)
+	}
+
+	compiler.emit(
+		Instruction{Opcode: OpBlock, Block: BlockEmpty},
+		Instruction{Opcode: OpLoop, Block: BlockEmpty},
+	)
+
+	if statement.Condition != nil {
+		statement.Condition.Accept(compiler)
+		compiler.emit(
+			Instruction{Opcode: OpI32Eqz},
+			Instruction{Opcode: OpBrIf, Index: 1},
+		)
+	}
+
+	statement.Body.Accept(compiler)
+
+	if statement.Increment != nil {
+		statement.Increment.Accept(compiler)
+		if t := compiler.info.TypeOf(statement.Increment); t != nil && t != checker.Void {
+			compiler.emit(Instruction{Opcode: OpDrop})
+		}
+	}
+
+	compiler.emit(
+		Instruction{Opcode: OpBr, Index: 0},
+		Instruction{Opcode: OpEnd},
+		Instruction{Opcode: OpEnd},
+	)
+}
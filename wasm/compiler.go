@@ -0,0 +1,275 @@
+package wasm
+
+import (
+	"github.com/yoh0xff/senbonzakura/ast"
+	"github.com/yoh0xff/senbonzakura/checker"
+	"github.com/yoh0xff/senbonzakura/diagnostic"
+	"github.com/yoh0xff/senbonzakura/resolver"
+	"github.com/yoh0xff/senbonzakura/source"
+)
+
+// StartFunction is the exported function that runs the top-level statements of the program
+const StartFunction = "_start"
+
+// ImportModule is the module name of the host functions imported for builtins
+const ImportModule = "env"
+
+// Compiler lowers a checked program to a WebAssembly module
+//
+// Numbers are lowered to f64 and booleans to i32. Top-level functions are exported under their names,
+// top-level variables become mutable globals and the remaining top-level statements run in StartFunction
+type Compiler struct {
+	info        *checker.Info
+	module      *Module
+	functions   map[*resolver.Symbol]uint32 // function index of functions and imported builtins
+	globals     map[*resolver.Symbol]uint32
+	function    *Function // function being compiled
+	locals      map[*resolver.Symbol]uint32
+	diagnostics []diagnostic.Diagnostic
+}
+
+// Compile lowers the program to a module, the program must be checked without errors
+//
+// Returns a nil module if the program uses features the backend does not support
+func Compile(program ast.Statement, info *checker.Info) (*Module, []diagnostic.Diagnostic) {
+	compiler := &Compiler{
+		info:      info,
+		module:    &Module{},
+		functions: map[*resolver.Symbol]uint32{},
+		globals:   map[*resolver.Symbol]uint32{},
+	}
+
+	compileProgram(compiler, program.(*ast.ProgramStatement))
+
+	if diagnostic.HasErrors(compiler.diagnostics) {
+		return nil, compiler.diagnostics
+	}
+	return compiler.module, compiler.diagnostics
+}
+
+// VisitStatement implements the ast.Visitor interface
+func (c *Compiler) VisitStatement(statement ast.Statement) {
+	visitStatement(c, statement)
+}
+
+// VisitExpression implements the ast.Visitor interface
+func (c *Compiler) VisitExpression(expression ast.Expression) {
+	visitExpression(c, expression)
+}
+
+// compileProgram declares the imports, functions and globals, then compiles the function bodies
+func compileProgram(compiler *Compiler, program *ast.ProgramStatement) {
+	declareImports(compiler)
+
+	// Function indexes are assigned before any body is compiled, functions can call each other
+	var declarations []*ast.FunctionDeclarationStatement
+	var topLevel []ast.Statement
+	for _, statement := range program.Body {
+		switch statement := statement.(type) {
+		case *ast.FunctionDeclarationStatement:
+			if declareFunction(compiler, statement) {
+				declarations = append(declarations, statement)
+			}
+		case *ast.VariableDeclarationStatement:
+			for _, variable := range statement.Variables {
+				declareGlobal(compiler, variable)
+			}
+			topLevel = append(topLevel, statement)
+		default:
+			topLevel = append(topLevel, statement)
+		}
+	}
+
+	start := &Function{Name: StartFunction}
+	compiler.module.Functions = append(compiler.module.Functions, start)
+	startIndex := compiler.module.FunctionIndex(len(compiler.module.Functions) - 1)
+	compiler.module.Exports = append(compiler.module.Exports, Export{Name: StartFunction, Function: startIndex})
+
+	for i, declaration := range declarations {
+		compileFunction(compiler, compiler.module.Functions[i], declaration)
+	}
+
+	compiler.beginFunction(start)
+	for _, statement := range topLevel {
+		statement.Accept(compiler)
+	}
+}
+
+// declareImports imports the builtins used by the program from the host
+func declareImports(compiler *Compiler) {
+	used := map[*resolver.Symbol]bool{}
+	for _, symbol := range compiler.info.Resolution.Uses {
+		used[symbol] = true
+	}
+
+	for _, symbol := range compiler.info.Resolution.Builtins.Order {
+		if !used[symbol] {
+			continue
+		}
+		signature, ok := compiler.info.Symbols[symbol].(*checker.Function)
+		if !ok || signature.Variadic {
+			continue
+		}
+		funcType, ok := lowerSignature(signature)
+		if !ok {
+			continue
+		}
+
+		compiler.functions[symbol] = uint32(len(compiler.module.Imports))
+		compiler.module.Imports = append(compiler.module.Imports, Import{
+			Module: ImportModule,
+			Name:   symbol.Name,
+			Type:   funcType,
+		})
+	}
+}
+
+// declareFunction adds an exported function for a top-level function declaration
+func declareFunction(compiler *Compiler, declaration *ast.FunctionDeclarationStatement) bool {
+	if declaration.Name.Name == StartFunction {
+		compiler.reportError(declaration.Name.Span, "Function name '%s' is reserved for the top-level statements", StartFunction)
+		return false
+	}
+
+	funcType, ok := lowerSignature(compiler.info.Functions[declaration])
+	if !ok {
+		compiler.reportError(
+			declaration.Name.Span,
+			"Function '%s' has type %s, only number and boolean values are supported",
+			declaration.Name.Name, compiler.info.Functions[declaration],
+		)
+		return false
+	}
+
+	function := &Function{Name: declaration.Name.Name, Type: funcType}
+	for _, parameter := range declaration.Parameters {
+		name := ""
+		if identifier, ok := parameter.Name.(*ast.IdentifierExpression); ok {
+			name = identifier.Name
+		}
+		function.ParamNames = append(function.ParamNames, name)
+	}
+
+	compiler.module.Functions = append(compiler.module.Functions, function)
+	index := compiler.module.FunctionIndex(len(compiler.module.Functions) - 1)
+	compiler.functions[compiler.info.Resolution.Declarations[declaration.Name]] = index
+	compiler.module.Exports = append(compiler.module.Exports, Export{Name: function.Name, Function: index})
+	return true
+}
+
+// declareGlobal adds a global for a top-level variable, it is initialized in the start function
+func declareGlobal(compiler *Compiler, variable *ast.VariableExpression) {
+	symbol := compiler.info.Resolution.Declarations[variable.Identifier]
+	valueType, ok := compiler.valueType(compiler.info.Symbols[symbol], variable.Span)
+	if !ok {
+		return
+	}
+
+	compiler.globals[symbol] = uint32(len(compiler.module.Globals))
+	compiler.module.Globals = append(compiler.module.Globals, Global{
+		Name:    symbol.Name,
+		Type:    valueType,
+		Mutable: true,
+		Init:    zeroValue(valueType),
+	})
+}
+
+// compileFunction compiles the body of a top-level function
+func compileFunction(compiler *Compiler, function *Function, declaration *ast.FunctionDeclarationStatement) {
+	compiler.beginFunction(function)
+
+	for i, parameter := range declaration.Parameters {
+		if identifier, ok := parameter.Name.(*ast.IdentifierExpression); ok {
+			compiler.locals[compiler.info.Resolution.Declarations[identifier]] = uint32(i)
+		}
+	}
+
+	for _, statement := range declaration.Body.Body {
+		statement.Accept(compiler)
+	}
+
+	// The checker guarantees a return on every path, the end of the body is never reached
+	if len(function.Type.Results) > 0 {
+		compiler.emit(Instruction{Opcode: OpUnreachable})
+	}
+}
+
+// beginFunction makes the function the target of the emitted instructions
+func (c *Compiler) beginFunction(function *Function) {
+	c.function = function
+	c.locals = map[*resolver.Symbol]uint32{}
+}
+
+// declareLocal adds a local to the current function
+func (c *Compiler) declareLocal(symbol *resolver.Symbol, valueType ValueType) uint32 {
+	index := uint32(len(c.function.Type.Params) + len(c.function.Locals))
+	c.function.Locals = append(c.function.Locals, valueType)
+	c.function.LocalNames = append(c.function.LocalNames, symbol.Name)
+	c.locals[symbol] = index
+	return index
+}
+
+// emit appends instructions to the current function
+func (c *Compiler) emit(instructions ...Instruction) {
+	c.function.Body = append(c.function.Body, instructions...)
+}
+
+// valueType lowers a checked type to a value type, unsupported types are reported
+func (c *Compiler) valueType(t checker.Type, span source.Span) (ValueType, bool) {
+	if valueType, ok := lowerType(t); ok {
+		return valueType, true
+	}
+	c.reportError(span, "Values of type %s are not supported, only number and boolean", t)
+	return 0, false
+}
+
+// lowerType lowers number to f64 and boolean to i32
+func lowerType(t checker.Type) (ValueType, bool) {
+	switch t {
+	case checker.Number:
+		return F64, true
+	case checker.Boolean:
+		return I32, true
+	default:
+		return 0, false
+	}
+}
+
+// lowerSignature lowers a function type, void returns have no results
+func lowerSignature(signature *checker.Function) (FuncType, bool) {
+	if signature == nil {
+		return FuncType{}, false
+	}
+
+	var funcType FuncType
+	for _, param := range signature.Params {
+		valueType, ok := lowerType(param)
+		if !ok {
+			return FuncType{}, false
+		}
+		funcType.Params = append(funcType.Params, valueType)
+	}
+
+	if signature.Return != checker.Void {
+		valueType, ok := lowerType(signature.Return)
+		if !ok {
+			return FuncType{}, false
+		}
+		funcType.Results = []ValueType{valueType}
+	}
+
+	return funcType, true
+}
+
+// zeroValue returns the constant instruction of the zero value of the type
+func zeroValue(valueType ValueType) Instruction {
+	if valueType == F64 {
+		return Instruction{Opcode: OpF64Const}
+	}
+	return Instruction{Opcode: OpI32Const}
+}
+
+// reportError records an unsupported feature diagnostic
+func (c *Compiler) reportError(span source.Span, format string, args ...any) {
+	c.diagnostics = append(c.diagnostics, diagnostic.NewError(diagnostic.CodeUnsupportedFeature, span, format, args...))
+}
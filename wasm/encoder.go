@@ -0,0 +1,235 @@
+package wasm
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Binary format constants
+var (
+	magic   = []byte{0x00, 0x61, 0x73, 0x6d}
+	version = []byte{0x01, 0x00, 0x00, 0x00}
+)
+
+// Section identifiers
+const (
+	sectionCustom   byte = 0
+	sectionType     byte = 1
+	sectionImport   byte = 2
+	sectionFunction byte = 3
+	sectionGlobal   byte = 6
+	sectionExport   byte = 7
+	sectionCode     byte = 10
+)
+
+// externalFunction is the kind of function imports and exports
+const externalFunction byte = 0x00
+
+// funcTypeForm introduces a function type in the type section
+const funcTypeForm byte = 0x60
+
+// Encode encodes the module in the WebAssembly binary format
+func Encode(module *Module) []byte {
+	types := collectTypes(module)
+
+	out := append([]byte{}, magic...)
+	out = append(out, version...)
+
+	out = appendSection(out, sectionType, encodeTypeSection(types))
+	if len(module.Imports) > 0 {
+		out = appendSection(out, sectionImport, encodeImportSection(module, types))
+	}
+	out = appendSection(out, sectionFunction, encodeFunctionSection(module, types))
+	if len(module.Globals) > 0 {
+		out = appendSection(out, sectionGlobal, encodeGlobalSection(module))
+	}
+	if len(module.Exports) > 0 {
+		out = appendSection(out, sectionExport, encodeExportSection(module))
+	}
+	out = appendSection(out, sectionCode, encodeCodeSection(module))
+
+	return out
+}
+
+// collectTypes returns the distinct signatures of the module in order of first use
+func collectTypes(module *Module) []FuncType {
+	var types []FuncType
+
+	add := func(t FuncType) {
+		if typeIndex(types, t) < 0 {
+			types = append(types, t)
+		}
+	}
+	for _, imp := range module.Imports {
+		add(imp.Type)
+	}
+	for _, function := range module.Functions {
+		add(function.Type)
+	}
+
+	return types
+}
+
+// typeIndex returns the index of the signature, -1 if it is missing
+func typeIndex(types []FuncType, t FuncType) int {
+	for i, other := range types {
+		if other.Equal(t) {
+			return i
+		}
+	}
+	return -1
+}
+
+func encodeTypeSection(types []FuncType) []byte {
+	out := appendU32(nil, uint32(len(types)))
+	for _, t := range types {
+		out = append(out, funcTypeForm)
+		out = appendValueTypes(out, t.Params)
+		out = appendValueTypes(out, t.Results)
+	}
+	return out
+}
+
+func encodeImportSection(module *Module, types []FuncType) []byte {
+	out := appendU32(nil, uint32(len(module.Imports)))
+	for _, imp := range module.Imports {
+		out = appendName(out, imp.Module)
+		out = appendName(out, imp.Name)
+		out = append(out, externalFunction)
+		out = appendU32(out, uint32(typeIndex(types, imp.Type)))
+	}
+	return out
+}
+
+func encodeFunctionSection(module *Module, types []FuncType) []byte {
+	out := appendU32(nil, uint32(len(module.Functions)))
+	for _, function := range module.Functions {
+		out = appendU32(out, uint32(typeIndex(types, function.Type)))
+	}
+	return out
+}
+
+func encodeGlobalSection(module *Module) []byte {
+	out := appendU32(nil, uint32(len(module.Globals)))
+	for _, global := range module.Globals {
+		out = append(out, byte(global.Type))
+		if global.Mutable {
+			out = append(out, 0x01)
+		} else {
+			out = append(out, 0x00)
+		}
+		out = appendInstruction(out, global.Init)
+		out = append(out, byte(OpEnd))
+	}
+	return out
+}
+
+func encodeExportSection(module *Module) []byte {
+	out := appendU32(nil, uint32(len(module.Exports)))
+	for _, export := range module.Exports {
+		out = appendName(out, export.Name)
+		out = append(out, externalFunction)
+		out = appendU32(out, export.Function)
+	}
+	return out
+}
+
+func encodeCodeSection(module *Module) []byte {
+	out := appendU32(nil, uint32(len(module.Functions)))
+	for _, function := range module.Functions {
+		out = appendU32AndBytes(out, encodeFunctionBody(function))
+	}
+	return out
+}
+
+// encodeFunctionBody encodes the locals and the instructions, consecutive locals of the same type are grouped
+func encodeFunctionBody(function *Function) []byte {
+	type localGroup struct {
+		count     uint32
+		valueType ValueType
+	}
+
+	var groups []localGroup
+	for _, local := range function.Locals {
+		if len(groups) > 0 && groups[len(groups)-1].valueType == local {
+			groups[len(groups)-1].count++
+			continue
+		}
+		groups = append(groups, localGroup{count: 1, valueType: local})
+	}
+
+	out := appendU32(nil, uint32(len(groups)))
+	for _, group := range groups {
+		out = appendU32(out, group.count)
+		out = append(out, byte(group.valueType))
+	}
+
+	for _, instruction := range function.Body {
+		out = appendInstruction(out, instruction)
+	}
+	return append(out, byte(OpEnd))
+}
+
+// appendInstruction encodes the instruction with its immediates
+func appendInstruction(out []byte, instruction Instruction) []byte {
+	out = append(out, byte(instruction.Opcode))
+
+	switch instruction.Opcode {
+	case OpBlock, OpLoop, OpIf:
+		out = append(out, byte(instruction.Block))
+	case OpBr, OpBrIf, OpCall, OpLocalGet, OpLocalSet, OpLocalTee, OpGlobalGet, OpGlobalSet:
+		out = appendU32(out, instruction.Index)
+	case OpI32Const:
+		out = appendS32(out, instruction.I32)
+	case OpF64Const:
+		out = binary.LittleEndian.AppendUint64(out, math.Float64bits(instruction.F64))
+	}
+
+	return out
+}
+
+func appendSection(out []byte, id byte, content []byte) []byte {
+	out = append(out, id)
+	return appendU32AndBytes(out, content)
+}
+
+func appendU32AndBytes(out []byte, content []byte) []byte {
+	out = appendU32(out, uint32(len(content)))
+	return append(out, content...)
+}
+
+func appendName(out []byte, name string) []byte {
+	return appendU32AndBytes(out, []byte(name))
+}
+
+func appendValueTypes(out []byte, types []ValueType) []byte {
+	out = appendU32(out, uint32(len(types)))
+	for _, t := range types {
+		out = append(out, byte(t))
+	}
+	return out
+}
+
+// appendU32 encodes an unsigned LEB128 integer
+func appendU32(out []byte, value uint32) []byte {
+	for {
+		b := byte(value & 0x7f)
+		value >>= 7
+		if value == 0 {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+// appendS32 encodes a signed LEB128 integer
+func appendS32(out []byte, value int32) []byte {
+	for {
+		b := byte(value & 0x7f)
+		value >>= 7
+		if (value == 0 && b&0x40 == 0) || (value == -1 && b&0x40 != 0) {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
@@ -0,0 +1,132 @@
+package wasm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValueType represents the WebAssembly value types
+type ValueType byte
+
+const (
+	I32 ValueType = 0x7f
+	I64 ValueType = 0x7e
+	F32 ValueType = 0x7d
+	F64 ValueType = 0x7c
+)
+
+// String returns the text format name of the ValueType
+func (t ValueType) String() string {
+	switch t {
+	case I32:
+		return "i32"
+	case I64:
+		return "i64"
+	case F32:
+		return "f32"
+	case F64:
+		return "f64"
+	default:
+		return fmt.Sprintf("ValueType(0x%02x)", byte(t))
+	}
+}
+
+// BlockType is the result type of a structured instruction, either BlockEmpty or a ValueType
+type BlockType byte
+
+// BlockEmpty is the block type of blocks without a result
+const BlockEmpty BlockType = 0x40
+
+// FuncType is the signature of a function
+type FuncType struct {
+	Params  []ValueType
+	Results []ValueType
+}
+
+// Equal tells if both signatures are the same
+func (t FuncType) Equal(other FuncType) bool {
+	if len(t.Params) != len(other.Params) || len(t.Results) != len(other.Results) {
+		return false
+	}
+	for i := range t.Params {
+		if t.Params[i] != other.Params[i] {
+			return false
+		}
+	}
+	for i := range t.Results {
+		if t.Results[i] != other.Results[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns a string representation of the FuncType
+func (t FuncType) String() string {
+	params := make([]string, len(t.Params))
+	for i, param := range t.Params {
+		params[i] = param.String()
+	}
+	results := make([]string, len(t.Results))
+	for i, result := range t.Results {
+		results[i] = result.String()
+	}
+	return fmt.Sprintf("[%s] -> [%s]", strings.Join(params, " "), strings.Join(results, " "))
+}
+
+// Instruction is a single WebAssembly instruction
+//
+// Structured instructions are flat: block, loop and if are closed by an explicit end instruction
+type Instruction struct {
+	Opcode Opcode
+	Index  uint32    // local, global, function or label index
+	Block  BlockType // result type of block, loop and if
+	I32    int32     // i32.const value
+	F64    float64   // f64.const value
+}
+
+// Import is a function provided by the host
+type Import struct {
+	Module string
+	Name   string
+	Type   FuncType
+}
+
+// Global is a module global variable
+type Global struct {
+	Name    string
+	Type    ValueType
+	Mutable bool
+	Init    Instruction // constant instruction of the global type
+}
+
+// Function is a function defined in the module
+type Function struct {
+	Name       string
+	Type       FuncType
+	ParamNames []string
+	Locals     []ValueType // locals after the parameters
+	LocalNames []string
+	Body       []Instruction // without the final end instruction
+}
+
+// Export makes a function of the module visible to the host
+type Export struct {
+	Name     string
+	Function uint32 // index in the function index space, imports come first
+}
+
+// Module is a WebAssembly module
+//
+// The function index space starts with the imports followed by the functions
+type Module struct {
+	Imports   []Import
+	Functions []*Function
+	Globals   []Global
+	Exports   []Export
+}
+
+// FunctionIndex returns the index of a module function in the function index space
+func (m *Module) FunctionIndex(function int) uint32 {
+	return uint32(len(m.Imports) + function)
+}
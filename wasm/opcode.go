@@ -0,0 +1,116 @@
+package wasm
+
+import "fmt"
+
+// Opcode represents the WebAssembly instructions used by the code generator
+type Opcode byte
+
+const (
+	OpUnreachable Opcode = 0x00
+	OpNop         Opcode = 0x01
+	OpBlock       Opcode = 0x02
+	OpLoop        Opcode = 0x03
+	OpIf          Opcode = 0x04
+	OpElse        Opcode = 0x05
+	OpEnd         Opcode = 0x0b
+	OpBr          Opcode = 0x0c
+	OpBrIf        Opcode = 0x0d
+	OpReturn      Opcode = 0x0f
+	OpCall        Opcode = 0x10
+	OpDrop        Opcode = 0x1a
+	OpSelect      Opcode = 0x1b
+	OpLocalGet    Opcode = 0x20
+	OpLocalSet    Opcode = 0x21
+	OpLocalTee    Opcode = 0x22
+	OpGlobalGet   Opcode = 0x23
+	OpGlobalSet   Opcode = 0x24
+	OpI32Const    Opcode = 0x41
+	OpF64Const    Opcode = 0x44
+	OpI32Eqz      Opcode = 0x45
+	OpI32Eq       Opcode = 0x46
+	OpI32Ne       Opcode = 0x47
+	OpF64Eq       Opcode = 0x61
+	OpF64Ne       Opcode = 0x62
+	OpF64Lt       Opcode = 0x63
+	OpF64Gt       Opcode = 0x64
+	OpF64Le       Opcode = 0x65
+	OpF64Ge       Opcode = 0x66
+	OpI32And      Opcode = 0x71
+	OpI32Or       Opcode = 0x72
+	OpI32Xor      Opcode = 0x73
+	OpF64Neg      Opcode = 0x9a
+	OpF64Add      Opcode = 0xa0
+	OpF64Sub      Opcode = 0xa1
+	OpF64Mul      Opcode = 0xa2
+	OpF64Div      Opcode = 0xa3
+)
+
+// opcodeNames are the text format names of the opcodes
+var opcodeNames = map[Opcode]string{
+	OpUnreachable: "unreachable",
+	OpNop:         "nop",
+	OpBlock:       "block",
+	OpLoop:        "loop",
+	OpIf:          "if",
+	OpElse:        "else",
+	OpEnd:         "end",
+	OpBr:          "br",
+	OpBrIf:        "br_if",
+	OpReturn:      "return",
+	OpCall:        "call",
+	OpDrop:        "drop",
+	OpSelect:      "select",
+	OpLocalGet:    "local.get",
+	OpLocalSet:    "local.set",
+	OpLocalTee:    "local.tee",
+	OpGlobalGet:   "global.get",
+	OpGlobalSet:   "global.set",
+	OpI32Const:    "i32.const",
+	OpF64Const:    "f64.const",
+	OpI32Eqz:      "i32.eqz",
+	OpI32Eq:       "i32.eq",
+	OpI32Ne:       "i32.ne",
+	OpF64Eq:       "f64.eq",
+	OpF64Ne:       "f64.ne",
+	OpF64Lt:       "f64.lt",
+	OpF64Gt:       "f64.gt",
+	OpF64Le:       "f64.le",
+	OpF64Ge:       "f64.ge",
+	OpI32And:      "i32.and",
+	OpI32Or:       "i32.or",
+	OpI32Xor:      "i32.xor",
+	OpF64Neg:      "f64.neg",
+	OpF64Add:      "f64.add",
+	OpF64Sub:      "f64.sub",
+	OpF64Mul:      "f64.mul",
+	OpF64Div:      "f64.div",
+}
+
+// String returns the text format name of the Opcode
+func (op Opcode) String() string {
+	if name, ok := opcodeNames[op]; ok {
+		return name
+	}
+	return fmt.Sprintf("Opcode(0x%02x)", byte(op))
+}
+
+// numericSignatures are the operand and result types of the instructions without immediates
+var numericSignatures = map[Opcode]FuncType{
+	OpI32Eqz: {Params: []ValueType{I32}, Results: []ValueType{I32}},
+	OpI32Eq:  {Params: []ValueType{I32, I32}, Results: []ValueType{I32}},
+	OpI32Ne:  {Params: []ValueType{I32, I32}, Results: []ValueType{I32}},
+	OpF64Eq:  {Params: []ValueType{F64, F64}, Results: []ValueType{I32}},
+	OpF64Ne:  {Params: []ValueType{F64, F64}, Results: []ValueType{I32}},
+	OpF64Lt:  {Params: []ValueType{F64, F64}, Results: []ValueType{I32}},
+	OpF64Gt:  {Params: []ValueType{F64, F64}, Results: []ValueType{I32}},
+	OpF64Le:  {Params: []ValueType{F64, F64}, Results: []ValueType{I32}},
+	OpF64Ge:  {Params: []ValueType{F64, F64}, Results: []ValueType{I32}},
+	OpI32And: {Params: []ValueType{I32, I32}, Results: []ValueType{I32}},
+	OpI32Or:  {Params: []ValueType{I32, I32}, Results: []ValueType{I32}},
+	OpI32Xor: {Params: []ValueType{I32, I32}, Results: []ValueType{I32}},
+	OpF64Neg: {Params: []ValueType{F64}, Results: []ValueType{F64}},
+	OpF64Add: {Params: []ValueType{F64, F64}, Results: []ValueType{F64}},
+	OpF64Sub: {Params: []ValueType{F64, F64}, Results: []ValueType{F64}},
+	OpF64Mul: {Params: []ValueType{F64, F64}, Results: []ValueType{F64}},
+	OpF64Div: {Params: []ValueType{F64, F64}, Results: []ValueType{F64}},
+}
@@ -0,0 +1,304 @@
+package wasm
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// WATConfig defines formatting options for the text format printer
+type WATConfig struct {
+	Pretty     bool // whether to put every form and instruction on its own line
+	IndentSize int  // size of each indent level
+}
+
+// WATPrinter prints a module in the WebAssembly text format
+//
+// Instructions are printed in the flat form, block, loop and if bodies are indented in pretty mode
+type WATPrinter struct {
+	config      WATConfig
+	indentLevel int
+	buffer      strings.Builder
+
+	functionNames []string // names of the function index space
+	globalNames   []string
+	localNames    []string // names of the locals of the function being printed
+}
+
+// NewWATPrinter creates a new printer with default configuration
+func NewWATPrinter() *WATPrinter {
+	return NewWATPrinterWithConfig(WATConfig{
+		Pretty:     false,
+		IndentSize: 2,
+	})
+}
+
+// NewWATPrinterWithConfig creates a new printer with the given configuration
+func NewWATPrinterWithConfig(config WATConfig) *WATPrinter {
+	return &WATPrinter{
+		config:      config,
+		indentLevel: 0,
+		buffer:      strings.Builder{},
+	}
+}
+
+// PrintModule prints the module form with its imports, globals, functions and exports
+func (p *WATPrinter) PrintModule(module *Module) {
+	p.functionNames = nil
+	for _, imp := range module.Imports {
+		p.functionNames = append(p.functionNames, imp.Name)
+	}
+	for _, function := range module.Functions {
+		p.functionNames = append(p.functionNames, function.Name)
+	}
+	p.functionNames = uniqueNames(p.functionNames, "f")
+
+	p.globalNames = nil
+	for _, global := range module.Globals {
+		p.globalNames = append(p.globalNames, global.Name)
+	}
+	p.globalNames = uniqueNames(p.globalNames, "g")
+
+	p.beginForm("module")
+
+	for i, imp := range module.Imports {
+		p.writeSpaceOrNewLine()
+		p.beginForm(fmt.Sprintf("import %s %s", quote(imp.Module), quote(imp.Name)))
+		p.writeString(" ")
+		p.beginForm("func $" + p.functionNames[i])
+		p.printSignature(imp.Type, nil)
+		p.endForm()
+		p.endForm()
+	}
+
+	for i, global := range module.Globals {
+		p.writeSpaceOrNewLine()
+		p.beginForm("global $" + p.globalNames[i])
+		if global.Mutable {
+			p.writeString(fmt.Sprintf(" (mut %s)", global.Type))
+		} else {
+			p.writeString(" " + global.Type.String())
+		}
+		p.writeString(" (" + p.instruction(global.Init) + ")")
+		p.endForm()
+	}
+
+	for i, function := range module.Functions {
+		p.writeSpaceOrNewLine()
+		p.printFunction(function, p.functionNames[len(module.Imports)+i])
+	}
+
+	for _, export := range module.Exports {
+		p.writeSpaceOrNewLine()
+		p.beginForm("export " + quote(export.Name))
+		p.writeString(" (func $" + p.functionNames[export.Function] + ")")
+		p.endForm()
+	}
+
+	p.endLastForm()
+}
+
+// printFunction prints the func form with the signature, the locals and the body
+func (p *WATPrinter) printFunction(function *Function, name string) {
+	names := append([]string{}, function.ParamNames...)
+	for len(names) < len(function.Type.Params) {
+		names = append(names, "")
+	}
+	names = append(names, function.LocalNames...)
+	for len(names) < len(function.Type.Params)+len(function.Locals) {
+		names = append(names, "")
+	}
+	p.localNames = uniqueNames(names, "l")
+
+	p.beginForm("func $" + name)
+	p.printSignature(function.Type, p.localNames)
+
+	for i, local := range function.Locals {
+		p.writeSpaceOrNewLine()
+		p.writeString(fmt.Sprintf("(local $%s %s)", p.localNames[len(function.Type.Params)+i], local))
+	}
+
+	for _, instruction := range function.Body {
+		switch instruction.Opcode {
+		case OpEnd:
+			p.dedent()
+			p.writeSpaceOrNewLine()
+			p.writeString(p.instruction(instruction))
+		case OpElse:
+			p.dedent()
+			p.writeSpaceOrNewLine()
+			p.writeString(p.instruction(instruction))
+			p.indent()
+		case OpBlock, OpLoop, OpIf:
+			p.writeSpaceOrNewLine()
+			p.writeString(p.instruction(instruction))
+			p.indent()
+		default:
+			p.writeSpaceOrNewLine()
+			p.writeString(p.instruction(instruction))
+		}
+	}
+
+	p.endLastForm()
+}
+
+// printSignature prints the param and result forms, parameters are named if names are given
+func (p *WATPrinter) printSignature(funcType FuncType, names []string) {
+	for i, param := range funcType.Params {
+		if names != nil {
+			p.writeString(fmt.Sprintf(" (param $%s %s)", names[i], param))
+		} else {
+			p.writeString(fmt.Sprintf(" (param %s)", param))
+		}
+	}
+	for _, result := range funcType.Results {
+		p.writeString(fmt.Sprintf(" (result %s)", result))
+	}
+}
+
+// instruction returns the text of an instruction with its immediates
+func (p *WATPrinter) instruction(instruction Instruction) string {
+	switch instruction.Opcode {
+	case OpBlock, OpLoop, OpIf:
+		if instruction.Block == BlockEmpty {
+			return instruction.Opcode.String()
+		}
+		return fmt.Sprintf("%s (result %s)", instruction.Opcode, ValueType(instruction.Block))
+	case OpBr, OpBrIf:
+		return fmt.Sprintf("%s %d", instruction.Opcode, instruction.Index)
+	case OpCall:
+		return fmt.Sprintf("%s $%s", instruction.Opcode, nameAt(p.functionNames, instruction.Index))
+	case OpLocalGet, OpLocalSet, OpLocalTee:
+		return fmt.Sprintf("%s $%s", instruction.Opcode, nameAt(p.localNames, instruction.Index))
+	case OpGlobalGet, OpGlobalSet:
+		return fmt.Sprintf("%s $%s", instruction.Opcode, nameAt(p.globalNames, instruction.Index))
+	case OpI32Const:
+		return fmt.Sprintf("%s %d", instruction.Opcode, instruction.I32)
+	case OpF64Const:
+		return fmt.Sprintf("%s %s", instruction.Opcode, formatFloat(instruction.F64))
+	default:
+		return instruction.Opcode.String()
+	}
+}
+
+// nameAt returns the name of an index, indexes out of range are printed as numbers
+func nameAt(names []string, index uint32) string {
+	if int(index) < len(names) {
+		return names[index]
+	}
+	return strconv.FormatUint(uint64(index), 10)
+}
+
+// uniqueNames makes the names valid and distinct identifiers, missing names get the prefix and the index
+func uniqueNames(names []string, prefix string) []string {
+	seen := map[string]bool{}
+	result := make([]string, len(names))
+
+	for i, name := range names {
+		if name == "" {
+			name = fmt.Sprintf("%s%d", prefix, i)
+		}
+		candidate := name
+		for suffix := 1; seen[candidate]; suffix++ {
+			candidate = fmt.Sprintf("%s.%d", name, suffix)
+		}
+		seen[candidate] = true
+		result[i] = candidate
+	}
+
+	return result
+}
+
+// formatFloat formats a float in the text format syntax
+func formatFloat(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "nan"
+	case math.IsInf(value, 1):
+		return "inf"
+	case math.IsInf(value, -1):
+		return "-inf"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+// quote returns the string literal of the text format
+func quote(s string) string {
+	var builder strings.Builder
+	builder.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c == '"' || c == '\\' || c >= 0x7f {
+			builder.WriteString(fmt.Sprintf("\\%02x", c))
+			continue
+		}
+		builder.WriteByte(c)
+	}
+	builder.WriteByte('"')
+	return builder.String()
+}
+
+// writeIndent writes the appropriate indentation based on the current indent level
+func (p *WATPrinter) writeIndent() {
+	if p.config.Pretty && p.indentLevel > 0 {
+		indent := strings.Repeat(" ", p.indentLevel*p.config.IndentSize)
+		p.buffer.WriteString(indent)
+	}
+}
+
+// beginForm starts a new form with the given head
+func (p *WATPrinter) beginForm(head string) {
+	p.buffer.WriteString("(")
+	p.buffer.WriteString(head)
+	p.indent()
+}
+
+// endForm closes a form printed on a single line
+func (p *WATPrinter) endForm() {
+	p.dedent()
+	p.buffer.WriteString(")")
+}
+
+// endLastForm closes a form whose content was printed on separate lines
+func (p *WATPrinter) endLastForm() {
+	p.dedent()
+	if p.config.Pretty {
+		p.buffer.WriteString("\n")
+		p.writeIndent()
+	}
+	p.buffer.WriteString(")")
+}
+
+func (p *WATPrinter) indent() {
+	if p.config.Pretty {
+		p.indentLevel++
+	}
+}
+
+func (p *WATPrinter) dedent() {
+	if p.config.Pretty {
+		p.indentLevel--
+	}
+}
+
+// writeSpaceOrNewLine writes a space or a newline based on formatting rules
+func (p *WATPrinter) writeSpaceOrNewLine() {
+	if p.config.Pretty {
+		p.buffer.WriteString("\n")
+		p.writeIndent()
+	} else {
+		p.buffer.WriteString(" ")
+	}
+}
+
+// writeString writes a string to the output
+func (p *WATPrinter) writeString(s string) {
+	p.buffer.WriteString(s)
+}
+
+// String returns the printed text
+func (p *WATPrinter) String() string {
+	return p.buffer.String()
+}
@@ -0,0 +1,472 @@
+package wasm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ValidationError describes why a binary module is invalid
+type ValidationError struct {
+	Offset  int // byte offset in the binary
+	Message string
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid module at byte %d: %s", e.Offset, e.Message)
+}
+
+// errUnexpectedEnd is reported when the binary ends in the middle of a construct
+var errUnexpectedEnd = errors.New("unexpected end of input")
+
+// Validate checks that the binary is a well-formed module that passes WebAssembly validation
+//
+// Only the subset of the format produced by Encode is supported: function types, function imports,
+// mutable and immutable globals, function exports, custom sections and the instructions in Opcode
+func Validate(module []byte) error {
+	v := &validator{reader: reader{data: module}}
+
+	if err := v.validate(); err != nil {
+		return v.wrap(err)
+	}
+	return nil
+}
+
+// reader decodes the primitive values of the binary format
+type reader struct {
+	data   []byte
+	offset int
+	base   int // offset of data in the module
+}
+
+// position returns the offset of the next byte in the module
+func (r *reader) position() int {
+	return r.base + r.offset
+}
+
+func (r *reader) done() bool {
+	return r.offset >= len(r.data)
+}
+
+func (r *reader) byte() (byte, error) {
+	if r.done() {
+		return 0, errUnexpectedEnd
+	}
+	b := r.data[r.offset]
+	r.offset++
+	return b, nil
+}
+
+func (r *reader) bytes(n int) ([]byte, error) {
+	if n < 0 || r.offset+n > len(r.data) {
+		return nil, errUnexpectedEnd
+	}
+	b := r.data[r.offset : r.offset+n]
+	r.offset += n
+	return b, nil
+}
+
+// u32 decodes an unsigned LEB128 integer
+func (r *reader) u32() (uint32, error) {
+	var result uint32
+	for shift := 0; shift < 35; shift += 7 {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		if shift == 28 && b&0x70 != 0 {
+			return 0, errors.New("integer too large")
+		}
+		result |= uint32(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return result, nil
+		}
+	}
+	return 0, errors.New("integer representation too long")
+}
+
+// s32 decodes a signed LEB128 integer
+func (r *reader) s32() (int32, error) {
+	var result int64
+	for shift := 0; shift < 35; shift += 7 {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		result |= int64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			if shift < 57 && b&0x40 != 0 {
+				result |= -1 << (shift + 7)
+			}
+			if result < math.MinInt32 || result > math.MaxInt32 {
+				return 0, errors.New("integer too large")
+			}
+			return int32(result), nil
+		}
+	}
+	return 0, errors.New("integer representation too long")
+}
+
+func (r *reader) f64() (float64, error) {
+	b, err := r.bytes(8)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+}
+
+func (r *reader) name() (string, error) {
+	length, err := r.u32()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.bytes(int(length))
+	return string(b), err
+}
+
+func (r *reader) valueType() (ValueType, error) {
+	b, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	switch t := ValueType(b); t {
+	case I32, I64, F32, F64:
+		return t, nil
+	default:
+		return 0, fmt.Errorf("invalid value type 0x%02x", b)
+	}
+}
+
+func (r *reader) valueTypes() ([]ValueType, error) {
+	count, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	types := make([]ValueType, 0, count)
+	for i := uint32(0); i < count; i++ {
+		t, err := r.valueType()
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+// globalType is the type of a global variable
+type globalType struct {
+	valueType ValueType
+	mutable   bool
+}
+
+// validator holds the module declarations collected so far
+type validator struct {
+	reader    reader
+	types     []FuncType
+	functions []uint32 // type index of every function, imports first
+	imported  int      // number of imported functions
+	globals   []globalType
+	declared  int // number of functions declared in the function section
+}
+
+func (v *validator) fail(format string, args ...any) error {
+	return &ValidationError{Offset: v.reader.position(), Message: fmt.Sprintf(format, args...)}
+}
+
+// wrap adds the current position to decoding errors
+func (v *validator) wrap(err error) error {
+	var validationError *ValidationError
+	if errors.As(err, &validationError) {
+		return err
+	}
+	return &ValidationError{Offset: v.reader.position(), Message: err.Error()}
+}
+
+func (v *validator) validate() error {
+	header, err := v.reader.bytes(8)
+	if err != nil {
+		return v.fail("missing module header")
+	}
+	if !bytes.Equal(header[:4], magic) {
+		return &ValidationError{Offset: 0, Message: "invalid magic number"}
+	}
+	if !bytes.Equal(header[4:], version) {
+		return &ValidationError{Offset: 4, Message: "unsupported version"}
+	}
+
+	lastSection := byte(0)
+	sawCode := false
+	for !v.reader.done() {
+		id, err := v.reader.byte()
+		if err != nil {
+			return err
+		}
+		size, err := v.reader.u32()
+		if err != nil {
+			return err
+		}
+		content, err := v.reader.bytes(int(size))
+		if err != nil {
+			return v.fail("section %d is larger than the module", id)
+		}
+
+		if id != sectionCustom {
+			if id <= lastSection {
+				return v.fail("section %d is out of order or duplicated", id)
+			}
+			lastSection = id
+		}
+
+		outer := v.reader
+		v.reader = reader{data: content, base: outer.position() - len(content)}
+		if err := v.validateSection(id); err != nil {
+			return v.wrap(err)
+		}
+		if !v.reader.done() {
+			return v.fail("section %d has %d trailing bytes", id, len(content)-v.reader.offset)
+		}
+		v.reader = outer
+
+		sawCode = sawCode || id == sectionCode
+	}
+
+	if !sawCode && v.declared > 0 {
+		return v.fail("%d functions are declared without a code section", v.declared)
+	}
+	return nil
+}
+
+func (v *validator) validateSection(id byte) error {
+	switch id {
+	case sectionCustom:
+		if _, err := v.reader.name(); err != nil {
+			return err
+		}
+		v.reader.offset = len(v.reader.data)
+		return nil
+	case sectionType:
+		return v.validateTypeSection()
+	case sectionImport:
+		return v.validateImportSection()
+	case sectionFunction:
+		return v.validateFunctionSection()
+	case sectionGlobal:
+		return v.validateGlobalSection()
+	case sectionExport:
+		return v.validateExportSection()
+	case sectionCode:
+		return v.validateCodeSection()
+	default:
+		return v.fail("unsupported section %d", id)
+	}
+}
+
+func (v *validator) validateTypeSection() error {
+	count, err := v.reader.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		form, err := v.reader.byte()
+		if err != nil {
+			return err
+		}
+		if form != funcTypeForm {
+			return v.fail("invalid function type form 0x%02x", form)
+		}
+		params, err := v.reader.valueTypes()
+		if err != nil {
+			return err
+		}
+		results, err := v.reader.valueTypes()
+		if err != nil {
+			return err
+		}
+		if len(results) > 1 {
+			return v.fail("function type %d has %d results", i, len(results))
+		}
+		v.types = append(v.types, FuncType{Params: params, Results: results})
+	}
+	return nil
+}
+
+func (v *validator) typeIndex() (uint32, error) {
+	index, err := v.reader.u32()
+	if err != nil {
+		return 0, err
+	}
+	if int(index) >= len(v.types) {
+		return 0, v.fail("type index %d out of range", index)
+	}
+	return index, nil
+}
+
+func (v *validator) validateImportSection() error {
+	count, err := v.reader.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		if _, err := v.reader.name(); err != nil {
+			return err
+		}
+		if _, err := v.reader.name(); err != nil {
+			return err
+		}
+		kind, err := v.reader.byte()
+		if err != nil {
+			return err
+		}
+		if kind != externalFunction {
+			return v.fail("unsupported import kind 0x%02x", kind)
+		}
+		index, err := v.typeIndex()
+		if err != nil {
+			return err
+		}
+		v.functions = append(v.functions, index)
+		v.imported++
+	}
+	return nil
+}
+
+func (v *validator) validateFunctionSection() error {
+	count, err := v.reader.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		index, err := v.typeIndex()
+		if err != nil {
+			return err
+		}
+		v.functions = append(v.functions, index)
+	}
+	v.declared = int(count)
+	return nil
+}
+
+func (v *validator) validateGlobalSection() error {
+	count, err := v.reader.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		valueType, err := v.reader.valueType()
+		if err != nil {
+			return err
+		}
+		mutability, err := v.reader.byte()
+		if err != nil {
+			return err
+		}
+		if mutability > 1 {
+			return v.fail("invalid global mutability 0x%02x", mutability)
+		}
+
+		// The initializer is a single constant instruction
+		opcode, err := v.reader.byte()
+		if err != nil {
+			return err
+		}
+		var initType ValueType
+		switch Opcode(opcode) {
+		case OpI32Const:
+			_, err = v.reader.s32()
+			initType = I32
+		case OpF64Const:
+			_, err = v.reader.f64()
+			initType = F64
+		default:
+			return v.fail("unsupported global initializer %s", Opcode(opcode))
+		}
+		if err != nil {
+			return err
+		}
+		if initType != valueType {
+			return v.fail("global %d of type %s is initialized with %s", i, valueType, initType)
+		}
+		if end, err := v.reader.byte(); err != nil || Opcode(end) != OpEnd {
+			return v.fail("global %d initializer is not terminated", i)
+		}
+
+		v.globals = append(v.globals, globalType{valueType: valueType, mutable: mutability == 1})
+	}
+	return nil
+}
+
+func (v *validator) validateExportSection() error {
+	count, err := v.reader.u32()
+	if err != nil {
+		return err
+	}
+	names := map[string]bool{}
+	for i := uint32(0); i < count; i++ {
+		name, err := v.reader.name()
+		if err != nil {
+			return err
+		}
+		if names[name] {
+			return v.fail("duplicate export %q", name)
+		}
+		names[name] = true
+
+		kind, err := v.reader.byte()
+		if err != nil {
+			return err
+		}
+		if kind != externalFunction {
+			return v.fail("unsupported export kind 0x%02x", kind)
+		}
+		index, err := v.reader.u32()
+		if err != nil {
+			return err
+		}
+		if int(index) >= len(v.functions) {
+			return v.fail("export %q refers to missing function %d", name, index)
+		}
+	}
+	return nil
+}
+
+func (v *validator) validateCodeSection() error {
+	count, err := v.reader.u32()
+	if err != nil {
+		return err
+	}
+	if int(count) != v.declared {
+		return v.fail("code section has %d bodies for %d functions", count, v.declared)
+	}
+
+	for i := 0; i < int(count); i++ {
+		size, err := v.reader.u32()
+		if err != nil {
+			return err
+		}
+		start := v.reader.position()
+		body, err := v.reader.bytes(int(size))
+		if err != nil {
+			return err
+		}
+
+		function := &functionValidator{
+			validator: v,
+			reader:    reader{data: body, base: start},
+			signature: v.types[v.functions[v.imported+i]],
+		}
+		if err := function.validate(); err != nil {
+			var validationError *ValidationError
+			if errors.As(err, &validationError) {
+				return err
+			}
+			return &ValidationError{
+				Offset:  function.reader.position(),
+				Message: fmt.Sprintf("function %d: %s", v.imported+i, err),
+			}
+		}
+	}
+	return nil
+}
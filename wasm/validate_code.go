@@ -0,0 +1,322 @@
+package wasm
+
+import (
+	"fmt"
+)
+
+// unknownType is the operand type in unreachable code, it matches every value type
+const unknownType ValueType = 0
+
+// controlFrame is an open block, loop, if or the function body
+type controlFrame struct {
+	opcode      Opcode
+	results     []ValueType
+	height      int  // operand stack height at the start of the frame
+	unreachable bool // the rest of the frame can't be reached
+}
+
+// labelTypes returns the types a branch to the frame must provide, loops branch to their start
+func (f *controlFrame) labelTypes() []ValueType {
+	if f.opcode == OpLoop {
+		return nil
+	}
+	return f.results
+}
+
+// functionValidator type checks the instructions of a function body
+type functionValidator struct {
+	validator *validator
+	reader    reader
+	signature FuncType
+	locals    []ValueType
+	operands  []ValueType
+	frames    []*controlFrame
+}
+
+func (f *functionValidator) validate() error {
+	f.locals = append(f.locals, f.signature.Params...)
+
+	groups, err := f.reader.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < groups; i++ {
+		count, err := f.reader.u32()
+		if err != nil {
+			return err
+		}
+		t, err := f.reader.valueType()
+		if err != nil {
+			return err
+		}
+		if len(f.locals)+int(count) > 50000 {
+			return fmt.Errorf("too many locals")
+		}
+		for j := uint32(0); j < count; j++ {
+			f.locals = append(f.locals, t)
+		}
+	}
+
+	f.pushFrame(OpBlock, f.signature.Results)
+
+	for len(f.frames) > 0 {
+		opcode, err := f.reader.byte()
+		if err != nil {
+			return err
+		}
+		if err := f.instruction(Opcode(opcode)); err != nil {
+			return err
+		}
+	}
+
+	if !f.reader.done() {
+		return fmt.Errorf("instructions after the end of the function")
+	}
+	return nil
+}
+
+func (f *functionValidator) instruction(opcode Opcode) error {
+	if signature, ok := numericSignatures[opcode]; ok {
+		return f.apply(signature.Params, signature.Results)
+	}
+
+	switch opcode {
+	case OpUnreachable:
+		f.markUnreachable()
+	case OpNop:
+		// Nothing to check
+	case OpBlock, OpLoop, OpIf:
+		results, err := f.blockType()
+		if err != nil {
+			return err
+		}
+		if opcode == OpIf {
+			if err := f.pop(I32); err != nil {
+				return err
+			}
+		}
+		f.pushFrame(opcode, results)
+	case OpElse:
+		frame, err := f.popFrame()
+		if err != nil {
+			return err
+		}
+		if frame.opcode != OpIf {
+			return fmt.Errorf("else without if")
+		}
+		f.pushFrame(OpElse, frame.results)
+	case OpEnd:
+		frame, err := f.popFrame()
+		if err != nil {
+			return err
+		}
+		if frame.opcode == OpIf && len(frame.results) > 0 {
+			return fmt.Errorf("if without else can't have a result")
+		}
+		f.push(frame.results...)
+	case OpBr, OpBrIf:
+		frame, err := f.label()
+		if err != nil {
+			return err
+		}
+		if opcode == OpBrIf {
+			if err := f.pop(I32); err != nil {
+				return err
+			}
+		}
+		if err := f.pop(frame.labelTypes()...); err != nil {
+			return err
+		}
+		if opcode == OpBr {
+			f.markUnreachable()
+		} else {
+			f.push(frame.labelTypes()...)
+		}
+	case OpReturn:
+		if err := f.pop(f.signature.Results...); err != nil {
+			return err
+		}
+		f.markUnreachable()
+	case OpCall:
+		index, err := f.reader.u32()
+		if err != nil {
+			return err
+		}
+		if int(index) >= len(f.validator.functions) {
+			return fmt.Errorf("call of missing function %d", index)
+		}
+		signature := f.validator.types[f.validator.functions[index]]
+		return f.apply(signature.Params, signature.Results)
+	case OpDrop:
+		_, err := f.popAny()
+		return err
+	case OpSelect:
+		if err := f.pop(I32); err != nil {
+			return err
+		}
+		a, err := f.popAny()
+		if err != nil {
+			return err
+		}
+		b, err := f.popAny()
+		if err != nil {
+			return err
+		}
+		if a != b && a != unknownType && b != unknownType {
+			return fmt.Errorf("select operands have types %s and %s", b, a)
+		}
+		if a == unknownType {
+			a = b
+		}
+		f.push(a)
+	case OpLocalGet, OpLocalSet, OpLocalTee:
+		index, err := f.reader.u32()
+		if err != nil {
+			return err
+		}
+		if int(index) >= len(f.locals) {
+			return fmt.Errorf("local index %d out of range", index)
+		}
+		t := f.locals[index]
+		switch opcode {
+		case OpLocalGet:
+			f.push(t)
+		case OpLocalSet:
+			return f.pop(t)
+		default:
+			return f.apply([]ValueType{t}, []ValueType{t})
+		}
+	case OpGlobalGet, OpGlobalSet:
+		index, err := f.reader.u32()
+		if err != nil {
+			return err
+		}
+		if int(index) >= len(f.validator.globals) {
+			return fmt.Errorf("global index %d out of range", index)
+		}
+		global := f.validator.globals[index]
+		if opcode == OpGlobalGet {
+			f.push(global.valueType)
+			return nil
+		}
+		if !global.mutable {
+			return fmt.Errorf("global %d is immutable", index)
+		}
+		return f.pop(global.valueType)
+	case OpI32Const:
+		if _, err := f.reader.s32(); err != nil {
+			return err
+		}
+		f.push(I32)
+	case OpF64Const:
+		if _, err := f.reader.f64(); err != nil {
+			return err
+		}
+		f.push(F64)
+	default:
+		return fmt.Errorf("unsupported instruction %s", opcode)
+	}
+
+	return nil
+}
+
+// blockType decodes the result types of a structured instruction
+func (f *functionValidator) blockType() ([]ValueType, error) {
+	b, err := f.reader.byte()
+	if err != nil {
+		return nil, err
+	}
+	if BlockType(b) == BlockEmpty {
+		return nil, nil
+	}
+	switch t := ValueType(b); t {
+	case I32, I64, F32, F64:
+		return []ValueType{t}, nil
+	default:
+		return nil, fmt.Errorf("invalid block type 0x%02x", b)
+	}
+}
+
+// label decodes a label index and returns the targeted frame
+func (f *functionValidator) label() (*controlFrame, error) {
+	depth, err := f.reader.u32()
+	if err != nil {
+		return nil, err
+	}
+	if int(depth) >= len(f.frames) {
+		return nil, fmt.Errorf("label %d out of range", depth)
+	}
+	return f.frames[len(f.frames)-1-int(depth)], nil
+}
+
+// apply pops the operands and pushes the results of an instruction
+func (f *functionValidator) apply(params, results []ValueType) error {
+	if err := f.pop(params...); err != nil {
+		return err
+	}
+	f.push(results...)
+	return nil
+}
+
+func (f *functionValidator) push(types ...ValueType) {
+	f.operands = append(f.operands, types...)
+}
+
+// popAny pops an operand of any type
+func (f *functionValidator) popAny() (ValueType, error) {
+	frame := f.frames[len(f.frames)-1]
+	if len(f.operands) == frame.height {
+		if frame.unreachable {
+			return unknownType, nil
+		}
+		return 0, fmt.Errorf("operand stack underflow")
+	}
+	t := f.operands[len(f.operands)-1]
+	f.operands = f.operands[:len(f.operands)-1]
+	return t, nil
+}
+
+// pop pops operands of the expected types, the last type is on the top of the stack
+func (f *functionValidator) pop(expected ...ValueType) error {
+	for i := len(expected) - 1; i >= 0; i-- {
+		actual, err := f.popAny()
+		if err != nil {
+			return err
+		}
+		if actual != expected[i] && actual != unknownType {
+			return fmt.Errorf("expected operand of type %s, got %s", expected[i], actual)
+		}
+	}
+	return nil
+}
+
+func (f *functionValidator) pushFrame(opcode Opcode, results []ValueType) {
+	f.frames = append(f.frames, &controlFrame{
+		opcode:  opcode,
+		results: results,
+		height:  len(f.operands),
+	})
+}
+
+// popFrame closes the innermost frame, the operand stack must hold exactly its results
+func (f *functionValidator) popFrame() (*controlFrame, error) {
+	if len(f.frames) == 0 {
+		return nil, fmt.Errorf("end without block")
+	}
+	frame := f.frames[len(f.frames)-1]
+	if err := f.pop(frame.results...); err != nil {
+		return nil, err
+	}
+	if len(f.operands) != frame.height {
+		return nil, fmt.Errorf("%d values left on the operand stack", len(f.operands)-frame.height)
+	}
+	f.frames = f.frames[:len(f.frames)-1]
+	return frame, nil
+}
+
+// markUnreachable drops the operands of the innermost frame, the rest of it is not reachable
+func (f *functionValidator) markUnreachable() {
+	frame := f.frames[len(f.frames)-1]
+	f.operands = f.operands[:frame.height]
+	frame.unreachable = true
+}
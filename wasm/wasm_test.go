@@ -0,0 +1,319 @@
+package wasm
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/yoh0xff/senbonzakura/checker"
+	"github.com/yoh0xff/senbonzakura/diagnostic"
+	"github.com/yoh0xff/senbonzakura/parser"
+)
+
+func compileSource(t *testing.T, source string, builtins ...checker.Builtin) (*Module, []diagnostic.Diagnostic) {
+	t.Helper()
+
+	program, diagnostics := parser.ParseRootStatement(parser.NewParser(source))
+	if len(diagnostics) != 0 {
+		t.Fatalf("Expected no parse diagnostics, got %v", diagnostics)
+	}
+
+	info, diagnostics := checker.Check(program, builtins...)
+	if len(diagnostics) != 0 {
+		t.Fatalf("Expected no check diagnostics, got %v", diagnostics)
+	}
+
+	return Compile(program, info)
+}
+
+func TestCompileValidModules(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"empty", ``},
+		{"arithmetic", `def f(a: number, b: number): number { return -a + b * 2 / (a - 1); }`},
+		{"comparison", `def f(a: number, b: number): boolean { return a <= b && !(a == b) || a > 10; }`},
+		{"boolean equality", `def f(a: boolean, b: boolean): boolean { return a != b; }`},
+		{"locals", `def f(a: number): number { let x: number = a; let y: boolean; x += 1; x = x * 2; return x; }`},
+		{"if", `def abs(a: number): number { if (a < 0) { return -a; } else { return a; } }`},
+		{"while", `def sum(n: number): number { let s: number = 0; while (n > 0) { s = s + n; n -= 1; } return s; }`},
+		{"do while", `def f(n: number): number { do { n = n - 1; } while (n > 0); return n; }`},
+		{"for", `def f(n: number): number { let s: number = 0; for (let i: number = 0; i < n; i = i + 1) { s += i; } return s; }`},
+		{"calls", `def fib(n: number): number { if (n < 2) { return n; } return fib(n - 1) + fib(n - 2); } fib(10);`},
+		{"globals", `let counter: number = 1; def next(): number { counter += 1; return counter; } next(); { let local: number = counter; }`},
+		{"void functions", `def f(): void { return; } def g(): void { f(); } g();`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, diagnostics := compileSource(t, tt.source)
+			if len(diagnostics) != 0 {
+				t.Fatalf("Expected no diagnostics, got %v", diagnostics)
+			}
+
+			if err := Validate(Encode(module)); err != nil {
+				t.Errorf("Expected a valid module, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCompileExports(t *testing.T) {
+	module, diagnostics := compileSource(t, `def add(a: number, b: number): number { return a + b; } let x: number = add(1, 2);`)
+	if len(diagnostics) != 0 {
+		t.Fatalf("Expected no diagnostics, got %v", diagnostics)
+	}
+
+	exports := map[string]uint32{}
+	for _, export := range module.Exports {
+		exports[export.Name] = export.Function
+	}
+	if len(exports) != 2 {
+		t.Fatalf("Expected 2 exports, got %v", module.Exports)
+	}
+
+	add := module.Functions[exports["add"]]
+	expectedType := FuncType{Params: []ValueType{F64, F64}, Results: []ValueType{F64}}
+	if add.Name != "add" || !add.Type.Equal(expectedType) {
+		t.Errorf("Expected add with type %s, got %s with type %s", expectedType, add.Name, add.Type)
+	}
+	if start := module.Functions[exports[StartFunction]]; start.Name != StartFunction {
+		t.Errorf("Expected %s export, got %s", StartFunction, start.Name)
+	}
+	if len(module.Globals) != 1 || module.Globals[0].Type != F64 || !module.Globals[0].Mutable {
+		t.Errorf("Expected a mutable f64 global, got %v", module.Globals)
+	}
+}
+
+func TestCompileImports(t *testing.T) {
+	log := checker.Builtin{Name: "log", Type: &checker.Function{Params: []checker.Type{checker.Number}, Return: checker.Void}}
+
+	module, diagnostics := compileSource(t, `def f(): void { log(1); } log(2);`, log)
+	if len(diagnostics) != 0 {
+		t.Fatalf("Expected no diagnostics, got %v", diagnostics)
+	}
+
+	if len(module.Imports) != 1 || module.Imports[0].Module != ImportModule || module.Imports[0].Name != "log" {
+		t.Fatalf("Expected the log import, got %v", module.Imports)
+	}
+	if module.Exports[0].Function != 1 {
+		t.Errorf("Expected functions to follow the imports, got index %d", module.Exports[0].Function)
+	}
+	if err := Validate(Encode(module)); err != nil {
+		t.Errorf("Expected a valid module, got %v", err)
+	}
+}
+
+func TestCompileUnsupported(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"strings", `let s: string = "a";`},
+		{"string functions", `def f(s: string): void {}`},
+		{"classes", `class A {}`},
+		{"nested functions", `def f(): void { def g(): void {} }`},
+		{"reserved name", `def _start(): void {}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, diagnostics := compileSource(t, tt.source)
+
+			if module != nil {
+				t.Errorf("Expected no module")
+			}
+			if len(diagnostics) != 1 || diagnostics[0].Code != diagnostic.CodeUnsupportedFeature {
+				t.Errorf("Expected a single %s diagnostic, got %v", diagnostic.CodeUnsupportedFeature, diagnostics)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsInvalidModules(t *testing.T) {
+	valid := &Module{
+		Functions: []*Function{{
+			Name: "f",
+			Type: FuncType{Params: []ValueType{F64}, Results: []ValueType{F64}},
+			Body: []Instruction{{Opcode: OpLocalGet, Index: 0}},
+		}},
+		Exports: []Export{{Name: "f", Function: 0}},
+	}
+	if err := Validate(Encode(valid)); err != nil {
+		t.Fatalf("Expected a valid module, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		module *Module
+	}{
+		{"operand type", &Module{Functions: []*Function{{
+			Type: FuncType{Results: []ValueType{F64}},
+			Body: []Instruction{{Opcode: OpI32Const, I32: 1}},
+		}}}},
+		{"stack underflow", &Module{Functions: []*Function{{
+			Body: []Instruction{{Opcode: OpF64Add}},
+		}}}},
+		{"values left on the stack", &Module{Functions: []*Function{{
+			Body: []Instruction{{Opcode: OpF64Const}},
+		}}}},
+		{"missing local", &Module{Functions: []*Function{{
+			Body: []Instruction{{Opcode: OpLocalGet, Index: 0}, {Opcode: OpDrop}},
+		}}}},
+		{"missing function", &Module{Functions: []*Function{{
+			Body: []Instruction{{Opcode: OpCall, Index: 1}},
+		}}}},
+		{"missing label", &Module{Functions: []*Function{{
+			Body: []Instruction{{Opcode: OpBr, Index: 1}},
+		}}}},
+		{"unclosed block", &Module{Functions: []*Function{{
+			Body: []Instruction{{Opcode: OpBlock, Block: BlockEmpty}},
+		}}}},
+		{"if without else with result", &Module{Functions: []*Function{{
+			Type: FuncType{Results: []ValueType{I32}},
+			Body: []Instruction{{Opcode: OpI32Const}, {Opcode: OpIf, Block: BlockType(I32)}, {Opcode: OpI32Const}, {Opcode: OpEnd}},
+		}}}},
+		{"missing export", &Module{Exports: []Export{{Name: "f", Function: 0}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(Encode(tt.module)); err == nil {
+				t.Errorf("Expected a validation error")
+			}
+		})
+	}
+
+	binary := Encode(valid)
+	if err := Validate(append([]byte{0x00, 0x61, 0x73, 0x6e}, binary[4:]...)); err == nil {
+		t.Errorf("Expected invalid magic number error")
+	}
+	if err := Validate(binary[:len(binary)-1]); err == nil {
+		t.Errorf("Expected truncated module error")
+	}
+}
+
+func TestLEB128(t *testing.T) {
+	unsigned := []struct {
+		value    uint32
+		expected []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x80, 0x01}},
+		{624485, []byte{0xe5, 0x8e, 0x26}},
+	}
+	for _, tt := range unsigned {
+		if encoded := appendU32(nil, tt.value); !bytes.Equal(encoded, tt.expected) {
+			t.Errorf("Expected %d to encode as %x, got %x", tt.value, tt.expected, encoded)
+		}
+		r := reader{data: tt.expected}
+		if decoded, err := r.u32(); err != nil || decoded != tt.value {
+			t.Errorf("Expected %x to decode as %d, got %d (%v)", tt.expected, tt.value, decoded, err)
+		}
+	}
+
+	signed := []struct {
+		value    int32
+		expected []byte
+	}{
+		{0, []byte{0x00}},
+		{-1, []byte{0x7f}},
+		{63, []byte{0x3f}},
+		{64, []byte{0xc0, 0x00}},
+		{-123456, []byte{0xc0, 0xbb, 0x78}},
+	}
+	for _, tt := range signed {
+		if encoded := appendS32(nil, tt.value); !bytes.Equal(encoded, tt.expected) {
+			t.Errorf("Expected %d to encode as %x, got %x", tt.value, tt.expected, encoded)
+		}
+		r := reader{data: tt.expected}
+		if decoded, err := r.s32(); err != nil || decoded != tt.value {
+			t.Errorf("Expected %x to decode as %d, got %d (%v)", tt.expected, tt.value, decoded, err)
+		}
+	}
+}
+
+func TestWATPrinter(t *testing.T) {
+	log := checker.Builtin{Name: "log", Type: &checker.Function{Params: []checker.Type{checker.Number}, Return: checker.Void}}
+	source := `
+		let total: number = 0;
+		def count(n: number): number {
+			let i: number = 0;
+			while (i < n) {
+				{ let i: number = 1; }
+				i = i + 1;
+			}
+			return i;
+		}
+		total = count(3);
+		log(total);
+	`
+
+	module, diagnostics := compileSource(t, source, log)
+	if len(diagnostics) != 0 {
+		t.Fatalf("Expected no diagnostics, got %v", diagnostics)
+	}
+
+	printer := NewWATPrinterWithConfig(WATConfig{Pretty: true, IndentSize: 2})
+	printer.PrintModule(module)
+
+	expected := `(module
+  (import "env" "log" (func $log (param f64)))
+  (global $total (mut f64) (f64.const 0))
+  (func $count (param $n f64) (result f64)
+    (local $i f64)
+    (local $i.1 f64)
+    f64.const 0
+    local.set $i
+    block
+      loop
+        local.get $i
+        local.get $n
+        f64.lt
+        i32.eqz
+        br_if 1
+        f64.const 1
+        local.set $i.1
+        local.get $i
+        f64.const 1
+        f64.add
+        local.tee $i
+        drop
+        br 0
+      end
+    end
+    local.get $i
+    return
+    unreachable
+  )
+  (func $_start
+    f64.const 0
+    global.set $total
+    f64.const 3
+    call $count
+    global.set $total
+    global.get $total
+    drop
+    global.get $total
+    call $log
+  )
+  (export "count" (func $count))
+  (export "_start" (func $_start))
+)`
+	if printer.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, printer.String())
+	}
+
+	compact := NewWATPrinter()
+	compact.PrintModule(&Module{Functions: []*Function{{
+		Name: "f",
+		Type: FuncType{Params: []ValueType{I32}, Results: []ValueType{I32}},
+		Body: []Instruction{{Opcode: OpLocalGet}, {Opcode: OpIf, Block: BlockType(I32)}, {Opcode: OpI32Const, I32: -1}, {Opcode: OpElse}, {Opcode: OpI32Const}, {Opcode: OpEnd}},
+	}}})
+
+	expected = `(module (func $f (param $l0 i32) (result i32) local.get $l0 if (result i32) i32.const -1 else i32.const 0 end))`
+	if compact.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, compact.String())
+	}
+}